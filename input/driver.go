@@ -4,6 +4,7 @@ package input
 import (
 	"bytes"
 	"io"
+	"time"
 	"unicode/utf8"
 
 	"github.com/muesli/cancelreader"
@@ -29,9 +30,18 @@ type win32InputState struct {
 	lastWinsizeX, lastWinsizeY int16  // the last window size for the previous event to prevent multiple size events from firing
 }
 
+// DefaultEscTimeout is the default time a [Reader] waits for the rest of an
+// incomplete sequence before reporting what it has as key events.
+const DefaultEscTimeout = 50 * time.Millisecond
+
 // Reader represents an input event reader. It reads input events and parses
 // escape sequences from the terminal input buffer and translates them into
 // human-readable events.
+//
+// Sequences split across reads, such as large clipboard replies or pastes
+// over slow connections, are put back together before being parsed. See
+// [Reader.SetEscTimeout] for how long the reader waits for the rest of a
+// sequence.
 type Reader struct {
 	rd    cancelreader.CancelReader
 	table map[string]Key // table is a lookup table for key sequences.
@@ -42,7 +52,24 @@ type Reader struct {
 	// When nil, bracketed paste mode is disabled.
 	paste []byte
 
-	buf [256]byte // do we need a larger buffer?
+	buf [256]byte // buf is the buffer of a single read.
+
+	// pending holds the bytes read but not parsed yet, an incomplete
+	// sequence waiting for the next read. It grows as needed.
+	pending []byte
+
+	// escTimeout is how long to wait for the rest of an incomplete sequence.
+	escTimeout time.Duration
+
+	// inflight receives the result of a read into buf running in the
+	// background, after the reader stopped waiting for it.
+	inflight chan readResult
+
+	// err is a read error to report after the events read before it.
+	err error
+
+	// recorder records the raw input read, if set.
+	recorder *Recorder
 
 	// keyState keeps track of the current Windows Console API key events state.
 	// It is used to decode ANSI escape sequences and utf16 sequences.
@@ -75,9 +102,34 @@ func NewReader(r io.Reader, termType string, flags int) (*Reader, error) {
 	d.table = buildKeysTable(flags, termType)
 	d.term = termType
 	d.parser.flags = flags
+	d.escTimeout = DefaultEscTimeout
 	return d, nil
 }
 
+// readResult is the result of a read into the buffer of a [Reader].
+type readResult struct {
+	n   int
+	err error
+}
+
+// SetEscTimeout sets how long the reader waits for the rest of a sequence
+// split across reads before reporting what it has as key events. The default
+// is [DefaultEscTimeout].
+//
+// This is what tells a lone Escape key press apart from the start of an
+// escape sequence or of an Alt-modified key: terminals send those at once,
+// while more input after an Escape key press comes later.
+func (d *Reader) SetEscTimeout(timeout time.Duration) {
+	d.escTimeout = timeout
+}
+
+// SetRecorder sets a recorder for the raw input read by the reader, nil to
+// stop recording. Set it before reading events. On Windows, input read from
+// the console with the Console API isn't recorded.
+func (d *Reader) SetRecorder(rec *Recorder) {
+	d.recorder = rec
+}
+
 // SetLogger sets a logger for the reader.
 func (d *Reader) SetLogger(l Logger) {
 	d.logger = l
@@ -98,38 +150,117 @@ func (d *Reader) Close() error {
 	return d.rd.Close() //nolint:wrapcheck
 }
 
+// readEvents reads input and returns the events it completes. Bytes of an
+// incomplete sequence are kept for the next read, or reported as they are
+// when no more input comes within the escape timeout.
 func (d *Reader) readEvents() ([]Event, error) {
-	nb, err := d.rd.Read(d.buf[:])
-	if err != nil {
-		return nil, err //nolint:wrapcheck
+	if err := d.err; err != nil {
+		d.err = nil
+		return nil, err
+	}
+
+	for {
+		flush, err := d.fill()
+		if err != nil {
+			// Report the events read before the error first.
+			events := d.parseEvents(true)
+			if len(events) == 0 {
+				return nil, err
+			}
+			d.err = err
+			return events, nil
+		}
+
+		events := d.parseEvents(flush)
+		if len(events) > 0 || len(d.pending) == 0 {
+			return events, nil
+		}
+	}
+}
+
+// fill reads more input into the pending buffer. When bytes are pending, it
+// waits at most the escape timeout for more, and reports whether it timed
+// out and the pending bytes must be flushed.
+func (d *Reader) fill() (flush bool, err error) {
+	if len(d.pending) == 0 && d.inflight == nil {
+		n, err := d.read()
+		d.pending = append(d.pending, d.buf[:n]...)
+		return false, err //nolint:wrapcheck
+	}
+
+	if d.inflight == nil {
+		// Read in the background so that the read can be abandoned on
+		// timeout, and picked up by the next call.
+		inflight := make(chan readResult, 1)
+		go func() {
+			n, err := d.read()
+			inflight <- readResult{n, err}
+		}()
+		d.inflight = inflight
+	}
+
+	var timeout <-chan time.Time
+	if len(d.pending) > 0 {
+		timer := time.NewTimer(d.escTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case r := <-d.inflight:
+		d.inflight = nil
+		d.pending = append(d.pending, d.buf[:r.n]...)
+		return false, r.err
+	case <-timeout:
+		return true, nil
+	}
+}
+
+// read reads input into buf and records it.
+func (d *Reader) read() (int, error) {
+	n, err := d.rd.Read(d.buf[:])
+	if n > 0 && d.recorder != nil {
+		d.recorder.Write(d.buf[:n]) //nolint:errcheck
 	}
+	return n, err //nolint:wrapcheck
+}
 
+// parseEvents parses the pending bytes into events. Unless flush is set, it
+// stops at an incomplete sequence and keeps it pending.
+func (d *Reader) parseEvents(flush bool) []Event {
 	var events []Event
-	buf := d.buf[:nb]
+	buf := d.pending
+	defer func() {
+		d.pending = d.pending[:copy(d.pending, buf)]
+	}()
 
 	// Lookup table first
-	if bytes.HasPrefix(buf, []byte{'\x1b'}) {
+	if bytes.HasPrefix(buf, []byte{'\x1b'}) && (flush || !incompleteSequence(buf)) {
 		if k, ok := d.table[string(buf)]; ok {
 			if d.logger != nil {
 				d.logger.Printf("input: %q", buf)
 			}
 			events = append(events, KeyPressEvent(k))
-			return events, nil
+			buf = buf[len(buf):]
+			return events
 		}
 	}
 
-	var i int
-	for i < len(buf) {
-		nb, ev := d.parser.parseSequence(buf[i:])
+	for len(buf) > 0 {
+		if !flush && incompleteSequence(buf) {
+			break
+		}
+
+		nb, ev := d.parser.parseSequence(buf)
 		if d.logger != nil {
-			d.logger.Printf("input: %q", buf[i:i+nb])
+			d.logger.Printf("input: %q", buf[:nb])
 		}
 
 		// Handle bracketed-paste
 		if d.paste != nil {
 			if _, ok := ev.(PasteEndEvent); !ok {
-				d.paste = append(d.paste, buf[i])
-				i++
+				d.paste = append(d.paste, buf[0])
+				buf = buf[1:]
 				continue
 			}
 		}
@@ -137,7 +268,7 @@ func (d *Reader) readEvents() ([]Event, error) {
 		switch ev.(type) {
 		case UnknownEvent:
 			// If the sequence is not recognized by the parser, try looking it up.
-			if k, ok := d.table[string(buf[i:i+nb])]; ok {
+			if k, ok := d.table[string(buf[:nb])]; ok {
 				ev = KeyPressEvent(k)
 			}
 		case PasteStartEvent:
@@ -155,7 +286,7 @@ func (d *Reader) readEvents() ([]Event, error) {
 			d.paste = nil // reset the buffer
 			events = append(events, PasteEvent(paste))
 		case nil:
-			i++
+			buf = buf[1:]
 			continue
 		}
 
@@ -164,8 +295,8 @@ func (d *Reader) readEvents() ([]Event, error) {
 		} else {
 			events = append(events, ev)
 		}
-		i += nb
+		buf = buf[nb:]
 	}
 
-	return events, nil
+	return events
 }
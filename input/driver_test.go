@@ -1,9 +1,14 @@
 package input
 
 import (
+	"encoding/base64"
+	"image/color"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func BenchmarkDriver(b *testing.B) {
@@ -23,3 +28,98 @@ func BenchmarkDriver(b *testing.B) {
 		}
 	}
 }
+
+func readAllEvents(t *testing.T, r io.Reader) []Event {
+	t.Helper()
+	drv, err := NewReader(r, "dumb", 0)
+	if err != nil {
+		t.Fatalf("could not create driver: %v", err)
+	}
+
+	var events []Event
+	for {
+		evs, err := drv.ReadEvents()
+		events = append(events, evs...)
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("unexpected input error: %v", err)
+		}
+	}
+}
+
+func TestReadEvents_SplitReads(t *testing.T) {
+	clipboard := strings.Repeat("pony ", 200)
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{"escape", "\x1b", []Event{KeyPressEvent{Code: KeyEscape}}},
+		{"alt key", "\x1ba", []Event{KeyPressEvent{Code: 'a', Mod: ModAlt}}},
+		{"arrow", "\x1b[A", []Event{KeyPressEvent{Code: KeyUp}}},
+		{"alt arrow", "\x1b\x1b[A", []Event{KeyPressEvent{Code: KeyUp, Mod: ModAlt}}},
+		{"ss3", "\x1bOP", []Event{KeyPressEvent{Code: KeyF1}}},
+		{"utf8", "é😀", []Event{KeyPressEvent{Code: 'é', Text: "é"}, KeyPressEvent{Code: '😀', Text: "😀"}}},
+		{"sgr mouse", "\x1b[<0;10;20M", []Event{MouseClickEvent{X: 9, Y: 19, Button: MouseLeft}}},
+		{"x10 mouse", "\x1b[M !!", []Event{MouseClickEvent{X: 0, Y: 0, Button: MouseLeft}}},
+		{"paste", "\x1b[200~héllo\x1b[201~", []Event{PasteStartEvent{}, PasteEvent("héllo"), PasteEndEvent{}}},
+		{"osc 52", "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(clipboard)) + "\x1b\\", []Event{
+			ClipboardEvent{Selection: 'c', Content: clipboard},
+		}},
+		{"background color", "\x1b]11;rgb:0000/0000/0000\x07x", []Event{
+			BackgroundColorEvent{color.RGBA{A: 0xff}},
+			KeyPressEvent{Code: 'x', Text: "x"},
+		}},
+		{"alt bracket", "\x1b[", []Event{KeyPressEvent{Text: "[", Mod: ModAlt}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := readAllEvents(t, strings.NewReader(tc.input)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("single read:\n    %#v\nwant:\n    %#v", got, tc.want)
+			}
+			if got := readAllEvents(t, iotest.OneByteReader(strings.NewReader(tc.input))); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("one byte at a time:\n    %#v\nwant:\n    %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadEvents_EscTimeout(t *testing.T) {
+	read := func(timeout time.Duration, writes ...string) []Event {
+		t.Helper()
+		pr, pw := io.Pipe()
+		drv, err := NewReader(pr, "dumb", 0)
+		if err != nil {
+			t.Fatalf("could not create driver: %v", err)
+		}
+		defer drv.Close() //nolint:errcheck
+		drv.SetEscTimeout(timeout)
+
+		go func() {
+			for _, w := range writes {
+				pw.Write([]byte(w)) //nolint:errcheck
+			}
+		}()
+		events, err := drv.ReadEvents()
+		if err != nil {
+			t.Fatalf("unexpected input error: %v", err)
+		}
+		return events
+	}
+
+	// A lone escape is reported once no more input comes.
+	if got, want := read(10*time.Millisecond, "\x1b"), []Event{KeyPressEvent{Code: KeyEscape}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lone escape: got %#v, want %#v", got, want)
+	}
+
+	// More input within the timeout completes the sequence.
+	if got, want := read(time.Minute, "\x1b", "[", "B"), []Event{KeyPressEvent{Code: KeyDown}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("split sequence: got %#v, want %#v", got, want)
+	}
+	if got, want := read(time.Minute, "\x1b", "x"), []Event{KeyPressEvent{Code: 'x', Mod: ModAlt}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("split alt key: got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,280 @@
+// Package gesture recognizes mouse gestures from the mouse events of
+// [input]: double and triple clicks, drags, and the pointer entering and
+// leaving regions of the screen.
+//
+// A [Recognizer] is opt-in and stateful: feed it the events read from the
+// terminal, and it returns the gestures they complete. The input events are
+// not swallowed, so handle both.
+//
+// Example:
+//
+//	g := gesture.New()
+//	g.SetRegions(gesture.Region{ID: "save", Rect: image.Rect(0, 0, 8, 1)})
+//
+//	for _, ev := range events {
+//	    for _, ge := range g.Handle(ev, time.Now()) {
+//	        switch ge := ge.(type) {
+//	        case gesture.ClickEvent:
+//	            if ge.Count == 2 {
+//	                selectWord(ge.Mouse.X, ge.Mouse.Y)
+//	            }
+//	        case gesture.DragMoveEvent:
+//	            selectRange(ge.Origin, ge.Mouse)
+//	        case gesture.HoverEnterEvent:
+//	            highlight(ge.Region)
+//	        }
+//	    }
+//	}
+package gesture
+
+import (
+	"image"
+	"time"
+
+	"github.com/charmbracelet/x/input"
+)
+
+// DefaultClickInterval is the default longest time between the presses of
+// a double or triple click.
+const DefaultClickInterval = 500 * time.Millisecond
+
+// DefaultClickDistance is the default farthest distance, in cells, between
+// the presses of a double or triple click.
+const DefaultClickDistance = 1
+
+// DefaultDragThreshold is the default distance, in cells, the mouse moves
+// with a button held before a drag starts.
+const DefaultDragThreshold = 1
+
+// ClickEvent is a mouse button press. Count is 1 for a single click, 2 for
+// a double click, 3 for a triple click, and so on.
+type ClickEvent struct {
+	Mouse input.Mouse
+	Count int
+}
+
+// DragStartEvent is the start of a drag: the mouse moved with a button held
+// since it was pressed at Origin.
+type DragStartEvent struct {
+	Origin input.Mouse
+	Mouse  input.Mouse
+}
+
+// DragMoveEvent is a mouse motion during a drag that started at Origin.
+type DragMoveEvent struct {
+	Origin input.Mouse
+	Mouse  input.Mouse
+}
+
+// DragEndEvent is the end of a drag that started at Origin: the mouse
+// button was released at Mouse.
+type DragEndEvent struct {
+	Origin input.Mouse
+	Mouse  input.Mouse
+}
+
+// HoverEnterEvent is the mouse pointer entering a region.
+type HoverEnterEvent struct {
+	Region string
+	Mouse  input.Mouse
+}
+
+// HoverLeaveEvent is the mouse pointer leaving a region.
+type HoverLeaveEvent struct {
+	Region string
+	Mouse  input.Mouse
+}
+
+// Region is an area of the screen to report hover events for, in cells.
+type Region struct {
+	ID   string
+	Rect image.Rectangle
+}
+
+// Recognizer recognizes mouse gestures from input events.
+//
+// Drags need the terminal to report motion with a button held, and hover
+// events need it to report all motion, see the mouse tracking modes of the
+// terminal and [input.FlagMouseMode] on Windows.
+//
+// A Recognizer is not safe for concurrent use.
+type Recognizer struct {
+	interval  time.Duration
+	distance  int
+	threshold int
+	regions   []Region
+
+	// clicks is the number of presses of the last click, at lastClick.
+	clicks    int
+	lastClick input.Mouse
+	lastAt    time.Time
+
+	// pressed is the mouse button held and where it was pressed, if
+	// dragging is still possible.
+	pressed  *input.Mouse
+	dragging bool
+
+	hover string
+	mouse input.Mouse
+}
+
+// New creates a gesture recognizer with the default click interval, click
+// distance and drag threshold.
+func New() *Recognizer {
+	return &Recognizer{
+		interval:  DefaultClickInterval,
+		distance:  DefaultClickDistance,
+		threshold: DefaultDragThreshold,
+	}
+}
+
+// SetClickInterval sets the longest time between the presses of a double
+// or triple click.
+func (g *Recognizer) SetClickInterval(interval time.Duration) {
+	g.interval = interval
+}
+
+// SetClickDistance sets the farthest distance, in cells, between the
+// presses of a double or triple click. Zero means the same cell.
+func (g *Recognizer) SetClickDistance(distance int) {
+	g.distance = distance
+}
+
+// SetDragThreshold sets the distance, in cells, the mouse moves with a
+// button held before a drag starts, at least one.
+func (g *Recognizer) SetDragThreshold(threshold int) {
+	g.threshold = max(threshold, 1)
+}
+
+// SetRegions sets the regions to report hover events for. Later regions are
+// on top of earlier ones, and the mouse pointer hovers the topmost region
+// under it. The hovered region is updated on the next mouse event.
+func (g *Recognizer) SetRegions(regions ...Region) {
+	g.regions = regions
+}
+
+// Hovered returns the ID of the region under the mouse pointer, or an empty
+// string.
+func (g *Recognizer) Hovered() string {
+	return g.hover
+}
+
+// Dragging reports whether a drag is in progress.
+func (g *Recognizer) Dragging() bool {
+	return g.dragging
+}
+
+// Handle handles an input event at the given time and returns the gestures
+// it completes, if any. Events other than mouse events are ignored, except
+// [input.BlurEvent] which leaves the hovered region.
+func (g *Recognizer) Handle(ev input.Event, now time.Time) []input.Event {
+	var events []input.Event
+	switch ev := ev.(type) {
+	case input.MouseClickEvent:
+		m := ev.Mouse()
+		events = g.move(events, m)
+		events = g.endDrag(events, m)
+		events = append(events, g.click(m, now))
+		g.pressed = &m
+	case input.MouseMotionEvent:
+		m := ev.Mouse()
+		events = g.move(events, m)
+		events = g.drag(events, m)
+	case input.MouseReleaseEvent:
+		m := ev.Mouse()
+		events = g.move(events, m)
+		events = g.endDrag(events, m)
+	case input.MouseWheelEvent:
+		events = g.move(events, ev.Mouse())
+	case input.BlurEvent:
+		if g.hover != "" {
+			events = append(events, HoverLeaveEvent{Region: g.hover, Mouse: g.mouse})
+			g.hover = ""
+		}
+	}
+	return events
+}
+
+// click counts a button press as part of a double or triple click when it
+// comes soon enough after the previous one, close enough and with the same
+// button.
+func (g *Recognizer) click(m input.Mouse, now time.Time) ClickEvent {
+	if g.clicks > 0 && m.Button == g.lastClick.Button &&
+		now.Sub(g.lastAt) <= g.interval && distance(m, g.lastClick) <= g.distance {
+		g.clicks++
+	} else {
+		g.clicks = 1
+	}
+	g.lastClick, g.lastAt = m, now
+	return ClickEvent{Mouse: m, Count: g.clicks}
+}
+
+// drag starts or continues a drag on a motion with a button held.
+func (g *Recognizer) drag(events []input.Event, m input.Mouse) []input.Event {
+	if g.pressed == nil || m.Button == input.MouseNone {
+		return events
+	}
+	origin := *g.pressed
+	switch {
+	case g.dragging:
+		return append(events, DragMoveEvent{Origin: origin, Mouse: m})
+	case distance(m, origin) >= g.threshold:
+		// A drag isn't part of a double click.
+		g.dragging = true
+		g.clicks = 0
+		return append(events, DragStartEvent{Origin: origin, Mouse: m})
+	}
+	return events
+}
+
+// endDrag ends the drag in progress, if any, and forgets the pressed
+// button.
+func (g *Recognizer) endDrag(events []input.Event, m input.Mouse) []input.Event {
+	if g.dragging {
+		events = append(events, DragEndEvent{Origin: *g.pressed, Mouse: m})
+	}
+	g.pressed = nil
+	g.dragging = false
+	return events
+}
+
+// move updates the hovered region for the mouse position, leaving the
+// previous region before entering the new one.
+func (g *Recognizer) move(events []input.Event, m input.Mouse) []input.Event {
+	g.mouse = m
+	hover := g.regionAt(image.Pt(m.X, m.Y))
+	if hover == g.hover {
+		return events
+	}
+	if g.hover != "" {
+		events = append(events, HoverLeaveEvent{Region: g.hover, Mouse: m})
+	}
+	if hover != "" {
+		events = append(events, HoverEnterEvent{Region: hover, Mouse: m})
+	}
+	g.hover = hover
+	return events
+}
+
+// regionAt returns the ID of the topmost region at p.
+func (g *Recognizer) regionAt(p image.Point) string {
+	for i := len(g.regions) - 1; i >= 0; i-- {
+		if p.In(g.regions[i].Rect) {
+			return g.regions[i].ID
+		}
+	}
+	return ""
+}
+
+// distance returns the distance between two mouse positions in cells,
+// counting diagonal moves as one cell.
+func distance(a, b input.Mouse) int {
+	return max(abs(a.X-b.X), abs(a.Y-b.Y))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
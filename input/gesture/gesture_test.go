@@ -0,0 +1,155 @@
+package gesture
+
+import (
+	"image"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/input"
+)
+
+func mouse(x, y int, button input.MouseButton) input.Mouse {
+	return input.Mouse{X: x, Y: y, Button: button}
+}
+
+func TestRecognizer_Clicks(t *testing.T) {
+	g := New()
+	now := time.Unix(0, 0)
+	click := func(after time.Duration, m input.Mouse) int {
+		t.Helper()
+		now = now.Add(after)
+		var count int
+		for _, ev := range g.Handle(input.MouseClickEvent(m), now) {
+			if c, ok := ev.(ClickEvent); ok {
+				count = c.Count
+			}
+		}
+		g.Handle(input.MouseReleaseEvent(m), now)
+		return count
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		mouse input.Mouse
+		want  int
+	}{
+		{"single", 0, mouse(5, 5, input.MouseLeft), 1},
+		{"double", 100 * time.Millisecond, mouse(5, 5, input.MouseLeft), 2},
+		{"triple nearby", 100 * time.Millisecond, mouse(6, 4, input.MouseLeft), 3},
+		{"too far", 100 * time.Millisecond, mouse(8, 4, input.MouseLeft), 1},
+		{"other button", 100 * time.Millisecond, mouse(8, 4, input.MouseRight), 1},
+		{"double right", 100 * time.Millisecond, mouse(8, 4, input.MouseRight), 2},
+		{"too late", time.Second, mouse(8, 4, input.MouseRight), 1},
+	}
+	for _, tc := range tests {
+		if got := click(tc.after, tc.mouse); got != tc.want {
+			t.Errorf("%s: got count %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	g.SetClickInterval(2 * time.Second)
+	g.SetClickDistance(0)
+	if got := click(time.Second, mouse(8, 4, input.MouseRight)); got != 2 {
+		t.Errorf("longer interval: got count %d, want 2", got)
+	}
+	if got := click(0, mouse(9, 4, input.MouseRight)); got != 1 {
+		t.Errorf("same cell: got count %d, want 1", got)
+	}
+}
+
+func TestRecognizer_Drag(t *testing.T) {
+	g := New()
+	g.SetDragThreshold(2)
+	now := time.Unix(0, 0)
+	origin := mouse(1, 1, input.MouseLeft)
+
+	steps := []struct {
+		ev   input.Event
+		want []input.Event
+	}{
+		{input.MouseClickEvent(origin), []input.Event{ClickEvent{Mouse: origin, Count: 1}}},
+		{input.MouseMotionEvent(mouse(2, 1, input.MouseLeft)), nil},
+		{input.MouseMotionEvent(mouse(3, 2, input.MouseLeft)), []input.Event{
+			DragStartEvent{Origin: origin, Mouse: mouse(3, 2, input.MouseLeft)},
+		}},
+		{input.MouseMotionEvent(mouse(3, 3, input.MouseLeft)), []input.Event{
+			DragMoveEvent{Origin: origin, Mouse: mouse(3, 3, input.MouseLeft)},
+		}},
+		{input.MouseReleaseEvent(mouse(4, 3, input.MouseNone)), []input.Event{
+			DragEndEvent{Origin: origin, Mouse: mouse(4, 3, input.MouseNone)},
+		}},
+		{input.MouseMotionEvent(mouse(6, 3, input.MouseNone)), nil},
+		// A drag isn't part of a double click.
+		{input.MouseClickEvent(origin), []input.Event{ClickEvent{Mouse: origin, Count: 1}}},
+	}
+	for i, step := range steps {
+		got := g.Handle(step.ev, now)
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: got %#v, want %#v", i, got, step.want)
+		}
+		if i == 3 && !g.Dragging() {
+			t.Errorf("step %d: expected a drag in progress", i)
+		}
+	}
+	if g.Dragging() {
+		t.Error("expected no drag in progress")
+	}
+}
+
+func TestRecognizer_Hover(t *testing.T) {
+	g := New()
+	g.SetRegions(
+		Region{ID: "panel", Rect: image.Rect(0, 0, 10, 5)},
+		Region{ID: "button", Rect: image.Rect(2, 2, 6, 3)},
+	)
+	now := time.Unix(0, 0)
+
+	move := func(x, y int) []string {
+		var got []string
+		for _, ev := range g.Handle(input.MouseMotionEvent(mouse(x, y, input.MouseNone)), now) {
+			switch ev := ev.(type) {
+			case HoverEnterEvent:
+				got = append(got, "enter "+ev.Region)
+			case HoverLeaveEvent:
+				got = append(got, "leave "+ev.Region)
+			}
+		}
+		return got
+	}
+
+	steps := []struct {
+		x, y int
+		want []string
+	}{
+		{20, 20, nil},
+		{1, 1, []string{"enter panel"}},
+		{1, 2, nil},
+		{2, 2, []string{"leave panel", "enter button"}},
+		{5, 2, nil},
+		{6, 2, []string{"leave button", "enter panel"}},
+		{10, 2, []string{"leave panel"}},
+	}
+	for _, step := range steps {
+		if got := move(step.x, step.y); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("(%d,%d): got %q, want %q", step.x, step.y, got, step.want)
+		}
+	}
+
+	// Clicks move the pointer too, and losing focus leaves the region.
+	got := g.Handle(input.MouseClickEvent(mouse(3, 2, input.MouseLeft)), now)
+	if len(got) != 2 || got[0] != (HoverEnterEvent{Region: "button", Mouse: mouse(3, 2, input.MouseLeft)}) {
+		t.Errorf("click: got %#v", got)
+	}
+	if g.Hovered() != "button" {
+		t.Errorf("hovered: got %q", g.Hovered())
+	}
+	got = g.Handle(input.BlurEvent{}, now)
+	if want := []input.Event{HoverLeaveEvent{Region: "button", Mouse: mouse(3, 2, input.MouseLeft)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("blur: got %#v, want %#v", got, want)
+	}
+	if got := g.Handle(input.KeyPressEvent{Code: 'a'}, now); got != nil {
+		t.Errorf("key press: got %#v", got)
+	}
+}
@@ -421,6 +421,23 @@ func (k Key) Keystroke() string {
 	return sb.String()
 }
 
+// KeyCode returns the code of the key with the given name, as written by
+// [Key.Keystroke], such as "enter", "pgup" or "f1". Printable characters
+// aren't named, except for "space".
+func KeyCode(name string) (rune, bool) {
+	code, ok := keyTypeCode[name]
+	return code, ok
+}
+
+// keyTypeCode maps the names of [keyTypeString] back to key codes.
+var keyTypeCode = func() map[string]rune {
+	m := make(map[string]rune, len(keyTypeString))
+	for code, name := range keyTypeString {
+		m[name] = code
+	}
+	return m
+}()
+
 var keyTypeString = map[rune]string{
 	KeyEnter:      "enter",
 	KeyTab:        "tab",
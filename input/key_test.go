@@ -859,6 +859,9 @@ func FuzzParseSequence(f *testing.F) {
 	f.Add("\x1b]11;rgb:0000/0000/0000\x1b\\")   // OSC 11
 	f.Add("\x1bP>|charm terminal(0.1.2)\x1b\\") // DCS (XTVERSION)
 	f.Add("\x1b_Gi=123\x1b\\")                  // APC
+	for _, rec := range readRecordings(f) {
+		f.Add(string(rec.Data))
+	}
 	f.Fuzz(func(t *testing.T, seq string) {
 		n, _ := p.parseSequence([]byte(seq))
 		if n == 0 && seq != "" {
@@ -0,0 +1,210 @@
+package keymap
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// HelpEntry is a line of help text: the keys of a binding and what they do.
+type HelpEntry struct {
+	Keys string
+	Help string
+}
+
+// Help returns the help of the bindings of a mode and the global ones, in
+// the order they were bound. Bindings without help are left out, and so are
+// the keys of global bindings that the mode binds to something else.
+func (k *Keymap) Help(mode string) []HelpEntry {
+	var entries []HelpEntry
+	for _, layer := range layersOf(mode) {
+		for _, b := range k.modes[layer] {
+			if b.Help == "" {
+				continue
+			}
+			var keys []string
+			for _, seq := range b.Keys {
+				if layer != mode && k.binds(mode, seq) {
+					continue
+				}
+				keys = append(keys, seq.String())
+			}
+			if len(keys) > 0 {
+				entries = append(entries, HelpEntry{Keys: strings.Join(keys, ", "), Help: b.Help})
+			}
+		}
+	}
+	return entries
+}
+
+// HelpText returns the help of a mode as lines of keys and help, aligned in
+// two columns.
+func (k *Keymap) HelpText(mode string) string {
+	entries := k.Help(mode)
+	var width int
+	for _, e := range entries {
+		width = max(width, len(e.Keys))
+	}
+
+	var sb strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "%-*s  %s\n", width, e.Keys, e.Help)
+	}
+	return sb.String()
+}
+
+// binds reports whether a mode binds a key sequence.
+func (k *Keymap) binds(mode string, seq Sequence) bool {
+	for _, b := range k.modes[mode] {
+		for _, s := range b.Keys {
+			if slices.Equal(s, seq) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// layersOf returns a mode and the global mode, from the top.
+func layersOf(mode string) []string {
+	if mode == Global {
+		return []string{Global}
+	}
+	return []string{mode, Global}
+}
+
+// ConflictKind is the kind of a [Conflict].
+type ConflictKind int
+
+// Conflict kinds.
+const (
+	// Duplicate means that the same keys are bound to different actions in
+	// a mode. The first binding wins.
+	Duplicate ConflictKind = iota
+
+	// Prefix means that a key sequence starts another one, so it only
+	// matches once the chord times out.
+	Prefix
+
+	// Shadowed means that a mode binds keys of a global binding to another
+	// action, which wins in that mode.
+	Shadowed
+)
+
+// String returns the name of the conflict kind.
+func (c ConflictKind) String() string {
+	switch c {
+	case Prefix:
+		return "prefix"
+	case Shadowed:
+		return "shadowed"
+	default:
+		return "duplicate"
+	}
+}
+
+// Conflict is a pair of bindings that get in the way of each other.
+type Conflict struct {
+	Kind ConflictKind
+
+	// Mode is the mode of the conflict.
+	Mode string
+
+	// Keys and Action are those of the binding that wins, or of the
+	// shorter one for [Prefix] conflicts.
+	Keys   Sequence
+	Action string
+
+	// OtherKeys and OtherAction are those of the other binding.
+	OtherKeys   Sequence
+	OtherAction string
+}
+
+// String describes the conflict.
+func (c Conflict) String() string {
+	var what string
+	switch c.Kind {
+	case Prefix:
+		what = "starts"
+	case Shadowed:
+		what = "shadows global"
+	default:
+		what = "duplicates"
+	}
+	mode := c.Mode
+	if mode == Global {
+		mode = "global"
+	}
+	return fmt.Sprintf("%s: %q (%s) %s %q (%s)", mode, c.Keys, c.Action, what, c.OtherKeys, c.OtherAction)
+}
+
+// boundKeys is a key sequence of a binding.
+type boundKeys struct {
+	keys   Sequence
+	action string
+}
+
+// Conflicts returns the conflicts between the bindings of each mode, and
+// between each mode and the global bindings, sorted by mode.
+func (k *Keymap) Conflicts() []Conflict {
+	modes := make([]string, 0, len(k.modes))
+	for mode := range k.modes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	var conflicts []Conflict
+	global := k.boundKeys(Global)
+	for _, mode := range modes {
+		bound := k.boundKeys(mode)
+		for i, a := range bound {
+			for _, b := range bound[i+1:] {
+				conflicts = appendConflict(conflicts, mode, a, b, Duplicate)
+			}
+			if mode != Global {
+				for _, b := range global {
+					conflicts = appendConflict(conflicts, mode, a, b, Shadowed)
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// boundKeys returns the key sequences bound in a mode.
+func (k *Keymap) boundKeys(mode string) []boundKeys {
+	var bound []boundKeys
+	for _, b := range k.modes[mode] {
+		for _, seq := range b.Keys {
+			bound = append(bound, boundKeys{seq, b.Action})
+		}
+	}
+	return bound
+}
+
+// appendConflict appends the conflict between a and b, of the given kind
+// when they have the same keys.
+func appendConflict(conflicts []Conflict, mode string, a, b boundKeys, kind ConflictKind) []Conflict {
+	switch {
+	case slices.Equal(a.keys, b.keys):
+		if a.action == b.action {
+			return conflicts
+		}
+	case b.keys.hasPrefix(a.keys):
+		kind = Prefix
+	case a.keys.hasPrefix(b.keys):
+		a, b = b, a
+		kind = Prefix
+	default:
+		return conflicts
+	}
+	return append(conflicts, Conflict{
+		Kind:        kind,
+		Mode:        mode,
+		Keys:        a.keys,
+		Action:      a.action,
+		OtherKeys:   b.keys,
+		OtherAction: b.action,
+	})
+}
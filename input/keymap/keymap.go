@@ -0,0 +1,287 @@
+// Package keymap binds key sequences to actions for [input.KeyPressEvent]
+// events: single keystrokes such as "ctrl+s", chords of several keystrokes
+// such as "ctrl+x ctrl+s" or "g g", in modes such as the normal and insert
+// modes of modal editors.
+//
+// Example:
+//
+//	km := keymap.New()
+//	km.Bind(keymap.Global, "quit", "quit", "ctrl+c", "ctrl+x ctrl+c")
+//	km.Bind("normal", "top", "go to the top", "g g")
+//	km.Bind("normal", "insert", "insert text", "i")
+//	km.SetMode("normal")
+//
+//	switch ev := ev.(type) {
+//	case input.KeyPressEvent:
+//	    res := km.Press(ev.Key(), time.Now())
+//	    if res.Flushed != nil {
+//	        run(res.Flushed.Action)
+//	    }
+//	    switch res.Status {
+//	    case keymap.Matched:
+//	        run(res.Action)
+//	    case keymap.Pending:
+//	        showStatus(res.Keys.String() + "-")
+//	    }
+//	}
+package keymap
+
+import (
+	"time"
+
+	"github.com/charmbracelet/x/input"
+)
+
+// Global is the mode of the bindings that apply in every mode, unless the
+// current mode binds the same keys.
+const Global = ""
+
+// DefaultTimeout is the default time allowed between the keystrokes of a
+// chord.
+const DefaultTimeout = time.Second
+
+// Binding binds one or more key sequences to an action.
+type Binding struct {
+	// Action names what the keys do, such as "save".
+	Action string
+
+	// Help describes the action in help text, such as "save the file".
+	Help string
+
+	// Keys are the key sequences of the action.
+	Keys []Sequence
+}
+
+// Status is the outcome of a key press.
+type Status int
+
+// Key press outcomes.
+const (
+	// Unbound means that the keys aren't bound in the current mode, such as
+	// text typed in an insert mode. A chord in progress is dropped.
+	Unbound Status = iota
+
+	// Pending means that the keys are the start of a chord, waiting for the
+	// next keystroke.
+	Pending
+
+	// Matched means that the keys completed a binding.
+	Matched
+)
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Matched:
+		return "matched"
+	default:
+		return "unbound"
+	}
+}
+
+// Result is the outcome of a key press.
+type Result struct {
+	Status Status
+
+	// Action is the action of the matched binding.
+	Action string
+
+	// Keys are the keystrokes of the matched binding or of the pending
+	// chord, or the keystrokes that aren't bound.
+	Keys Sequence
+
+	// Flushed is the binding completed by a chord in progress that the key
+	// press ended, such as "g" with "g g" bound when another key follows it
+	// or comes after the timeout. It happened before the key press.
+	Flushed *Result
+}
+
+// Keymap maps key sequences to actions, per mode. It keeps track of the
+// chord in progress.
+//
+// A Keymap is not safe for concurrent use.
+type Keymap struct {
+	modes   map[string][]Binding
+	mode    string
+	timeout time.Duration
+	pending []input.Key
+	last    time.Time
+}
+
+// New creates an empty keymap in the global mode, with [DefaultTimeout]
+// between the keystrokes of chords.
+func New() *Keymap {
+	return &Keymap{
+		modes:   make(map[string][]Binding),
+		timeout: DefaultTimeout,
+	}
+}
+
+// SetTimeout sets the time allowed between the keystrokes of a chord. Zero
+// means no limit.
+func (k *Keymap) SetTimeout(timeout time.Duration) {
+	k.timeout = timeout
+}
+
+// Bind binds the key sequences to an action in a mode, [Global] for all
+// modes. Key sequences are parsed with [ParseSequence]. Binding keys again
+// doesn't replace their previous binding, see [Keymap.Conflicts].
+func (k *Keymap) Bind(mode, action, help string, keys ...string) error {
+	b := Binding{Action: action, Help: help}
+	for _, spec := range keys {
+		seq, err := ParseSequence(spec)
+		if err != nil {
+			return err
+		}
+		b.Keys = append(b.Keys, seq)
+	}
+	k.Add(mode, b)
+	return nil
+}
+
+// Add adds bindings to a mode, [Global] for all modes.
+func (k *Keymap) Add(mode string, bindings ...Binding) {
+	k.modes[mode] = append(k.modes[mode], bindings...)
+}
+
+// Bindings returns the bindings of a mode, without the global ones.
+func (k *Keymap) Bindings(mode string) []Binding {
+	return k.modes[mode]
+}
+
+// Mode returns the current mode.
+func (k *Keymap) Mode() string {
+	return k.mode
+}
+
+// SetMode switches to a mode, dropping the chord in progress.
+func (k *Keymap) SetMode(mode string) {
+	k.mode = mode
+	k.pending = nil
+}
+
+// Pending returns the keystrokes of the chord in progress.
+func (k *Keymap) Pending() Sequence {
+	return keystrokes(k.pending)
+}
+
+// Deadline returns when the chord in progress times out, for scheduling a
+// call to [Keymap.Expire]. It returns false when no chord is in progress or
+// chords don't time out.
+func (k *Keymap) Deadline() (time.Time, bool) {
+	if len(k.pending) == 0 || k.timeout <= 0 {
+		return time.Time{}, false
+	}
+	return k.last.Add(k.timeout), true
+}
+
+// Press matches a key press at the given time against the bindings of the
+// current mode and the global ones.
+//
+// When the keys complete a binding that is also the start of a longer one,
+// such as "g" with "g g" bound, the result is [Pending] and the shorter
+// binding is matched by [Keymap.Expire] if no other key comes in time. A
+// key that breaks a chord, or comes after it timed out, is matched on its
+// own, and the shorter binding is reported in [Result.Flushed].
+func (k *Keymap) Press(key input.Key, now time.Time) Result {
+	var flushed *Result
+	if len(k.pending) > 0 && k.timeout > 0 && now.Sub(k.last) > k.timeout {
+		flushed = k.flush()
+	}
+	k.last = now
+
+	keys := append(k.pending, key)
+	exact, longer := k.lookup(keys)
+	if exact == nil && !longer && len(k.pending) > 0 {
+		// The key breaks the chord: start over with it.
+		flushed = k.flush()
+		keys = []input.Key{key}
+		exact, longer = k.lookup(keys)
+	}
+
+	switch {
+	case longer:
+		k.pending = keys
+		return Result{Status: Pending, Keys: keystrokes(keys), Flushed: flushed}
+	case exact != nil:
+		k.pending = nil
+		return Result{Status: Matched, Action: exact.Action, Keys: keystrokes(keys), Flushed: flushed}
+	default:
+		k.pending = nil
+		return Result{Status: Unbound, Keys: keystrokes(keys), Flushed: flushed}
+	}
+}
+
+// flush drops the chord in progress and returns the binding it completes,
+// if any.
+func (k *Keymap) flush() *Result {
+	keys := k.pending
+	k.pending = nil
+	if exact, _ := k.lookup(keys); exact != nil {
+		return &Result{Status: Matched, Action: exact.Action, Keys: keystrokes(keys)}
+	}
+	return nil
+}
+
+// Expire ends the chord in progress if it timed out at the given time. The
+// result is [Matched] if the chord is a binding by itself, [Unbound] if it
+// is dropped, and [Pending] if it hasn't timed out.
+func (k *Keymap) Expire(now time.Time) Result {
+	if len(k.pending) == 0 {
+		return Result{}
+	}
+	keys := k.pending
+	if deadline, ok := k.Deadline(); ok && now.Before(deadline) {
+		return Result{Status: Pending, Keys: keystrokes(keys)}
+	}
+
+	k.pending = nil
+	if exact, _ := k.lookup(keys); exact != nil {
+		return Result{Status: Matched, Action: exact.Action, Keys: keystrokes(keys)}
+	}
+	return Result{Status: Unbound, Keys: keystrokes(keys)}
+}
+
+// lookup returns the binding that the keys complete in the current mode,
+// and reports whether they are the start of a longer binding. Bindings of
+// the mode come before global ones, and earlier bindings before later ones.
+func (k *Keymap) lookup(keys []input.Key) (exact *Binding, longer bool) {
+	for _, b := range k.active() {
+		for _, seq := range b.Keys {
+			if !seq.matches(keys) {
+				continue
+			}
+			if len(seq) > len(keys) {
+				longer = true
+			} else if exact == nil {
+				exact = b
+			}
+		}
+	}
+	return exact, longer
+}
+
+// active returns the bindings of the current mode, then the global ones.
+func (k *Keymap) active() []*Binding {
+	var bindings []*Binding
+	for _, mode := range layersOf(k.mode) {
+		for i := range k.modes[mode] {
+			bindings = append(bindings, &k.modes[mode][i])
+		}
+	}
+	return bindings
+}
+
+// keystrokes returns the keystrokes of key presses.
+func keystrokes(keys []input.Key) Sequence {
+	if len(keys) == 0 {
+		return nil
+	}
+	seq := make(Sequence, len(keys))
+	for i, key := range keys {
+		seq[i] = fromKey(key)
+	}
+	return seq
+}
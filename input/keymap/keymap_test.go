@@ -0,0 +1,289 @@
+package keymap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/input"
+)
+
+func TestParseKeystroke(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Keystroke
+		str  string
+	}{
+		{"a", Keystroke{Code: 'a'}, "a"},
+		{"A", Keystroke{Code: 'A'}, "A"},
+		{"?", Keystroke{Code: '?'}, "?"},
+		{"ctrl+s", Keystroke{Code: 's', Mod: input.ModCtrl}, "ctrl+s"},
+		{"shift+alt+up", Keystroke{Code: input.KeyUp, Mod: input.ModAlt | input.ModShift}, "alt+shift+up"},
+		{"Ctrl+Enter", Keystroke{Code: input.KeyEnter, Mod: input.ModCtrl}, "ctrl+enter"},
+		{"escape", Keystroke{Code: input.KeyEscape}, "esc"},
+		{"space", Keystroke{Code: input.KeySpace}, "space"},
+		{"f12", Keystroke{Code: input.KeyF12}, "f12"},
+		{"+", Keystroke{Code: '+'}, "+"},
+		{"ctrl++", Keystroke{Code: '+', Mod: input.ModCtrl}, "ctrl++"},
+		{"é", Keystroke{Code: 'é'}, "é"},
+	}
+	for _, tc := range tests {
+		got, err := ParseKeystroke(tc.spec)
+		if err != nil {
+			t.Errorf("ParseKeystroke(%q): %v", tc.spec, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseKeystroke(%q) = %#v, want %#v", tc.spec, got, tc.want)
+		}
+		if got.String() != tc.str {
+			t.Errorf("ParseKeystroke(%q).String() = %q, want %q", tc.spec, got.String(), tc.str)
+		}
+	}
+
+	for _, spec := range []string{"", "ctrl+", "hyperdrive+a", "ab", "ctrl+foo"} {
+		if _, err := ParseKeystroke(spec); err == nil {
+			t.Errorf("ParseKeystroke(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestParseSequence(t *testing.T) {
+	seq, err := ParseSequence("ctrl+x  ctrl+s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := seq.String(), "ctrl+x ctrl+s"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, spec := range []string{"", "   ", "g nope"} {
+		if _, err := ParseSequence(spec); err == nil {
+			t.Errorf("ParseSequence(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestKeystroke_Matches(t *testing.T) {
+	mustParse := func(spec string) Keystroke {
+		k, err := ParseKeystroke(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		key  input.Key
+		want bool
+	}{
+		{"plain", "a", input.Key{Code: 'a', Text: "a"}, true},
+		{"modifiers", "ctrl+a", input.Key{Code: 'a', Mod: input.ModCtrl}, true},
+		{"missing modifier", "ctrl+a", input.Key{Code: 'a', Text: "a"}, false},
+		{"extra modifier", "a", input.Key{Code: 'a', Mod: input.ModAlt}, false},
+		{"caps lock", "ctrl+a", input.Key{Code: 'a', Mod: input.ModCtrl | input.ModCapsLock}, true},
+		{"base layout", "ctrl+c", input.Key{Code: 'с', BaseCode: 'c', Mod: input.ModCtrl}, true},
+		{"shifted kitty", "?", input.Key{Code: '/', ShiftedCode: '?', Mod: input.ModShift}, true},
+		{"shifted legacy", "?", input.Key{Code: '?', Text: "?"}, true},
+		{"upper case", "A", input.Key{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: input.ModShift}, true},
+		{"shift letter", "shift+a", input.Key{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: input.ModShift}, true},
+		{"shifted text", "ctrl+?", input.Key{Code: '/', Text: "?", Mod: input.ModCtrl | input.ModShift}, true},
+		{"other key", "a", input.Key{Code: 'b', Text: "b"}, false},
+	}
+	for _, tc := range tests {
+		if got := mustParse(tc.spec).Matches(tc.key); got != tc.want {
+			t.Errorf("%s: %q matches %#v = %v, want %v", tc.name, tc.spec, tc.key, got, tc.want)
+		}
+	}
+}
+
+func key(code rune, mod input.KeyMod) input.Key {
+	return input.Key{Code: code, Mod: mod}
+}
+
+func newTestKeymap(t *testing.T) *Keymap {
+	t.Helper()
+	km := New()
+	binds := []struct {
+		mode, action, help string
+		keys               []string
+	}{
+		{Global, "quit", "quit", []string{"ctrl+c", "ctrl+x ctrl+c"}},
+		{Global, "save", "save the file", []string{"ctrl+x ctrl+s"}},
+		{Global, "help", "", []string{"f1"}},
+		{"normal", "insert", "insert text", []string{"i"}},
+		{"normal", "top", "go to the top", []string{"g g"}},
+		{"normal", "next", "next match", []string{"g"}},
+		{"normal", "close", "close the buffer", []string{"ctrl+c"}},
+		{"insert", "normal", "back to normal mode", []string{"esc"}},
+	}
+	for _, b := range binds {
+		if err := km.Bind(b.mode, b.action, b.help, b.keys...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return km
+}
+
+func TestKeymap_Press(t *testing.T) {
+	km := newTestKeymap(t)
+	now := time.Unix(0, 0)
+	press := func(k input.Key) Result {
+		now = now.Add(100 * time.Millisecond)
+		return km.Press(k, now)
+	}
+	expect := func(got Result, status Status, action, keys string) {
+		t.Helper()
+		if got.Status != status || got.Action != action || got.Keys.String() != keys {
+			t.Errorf("got %v %q %q, want %v %q %q", got.Status, got.Action, got.Keys, status, action, keys)
+		}
+	}
+
+	expect(press(key('c', input.ModCtrl)), Matched, "quit", "ctrl+c")
+	expect(press(key('x', input.ModCtrl)), Pending, "", "ctrl+x")
+	if got := km.Pending().String(); got != "ctrl+x" {
+		t.Errorf("pending: got %q", got)
+	}
+	expect(press(key('s', input.ModCtrl)), Matched, "save", "ctrl+x ctrl+s")
+
+	// A key that breaks a chord is matched on its own.
+	expect(press(key('x', input.ModCtrl)), Pending, "", "ctrl+x")
+	expect(press(key('c', input.ModCtrl)), Matched, "quit", "ctrl+x ctrl+c")
+	expect(press(key('x', input.ModCtrl)), Pending, "", "ctrl+x")
+	expect(press(key('q', 0)), Unbound, "", "q")
+	if len(km.Pending()) != 0 {
+		t.Errorf("expected no pending chord, got %q", km.Pending())
+	}
+
+	// Chords time out.
+	expect(press(key('x', input.ModCtrl)), Pending, "", "ctrl+x")
+	now = now.Add(2 * time.Second)
+	expect(press(key('s', input.ModCtrl)), Unbound, "", "ctrl+s")
+
+	// Modes come before the global bindings.
+	km.SetMode("normal")
+	expect(press(key('c', input.ModCtrl)), Matched, "close", "ctrl+c")
+	expect(press(key('x', input.ModCtrl)), Pending, "", "ctrl+x")
+	expect(press(key('s', input.ModCtrl)), Matched, "save", "ctrl+x ctrl+s")
+	expect(press(key('i', 0)), Matched, "insert", "i")
+	km.SetMode("insert")
+	expect(press(key('i', 0)), Unbound, "", "i")
+	expect(press(key(input.KeyEscape, 0)), Matched, "normal", "esc")
+}
+
+func TestKeymap_Expire(t *testing.T) {
+	km := newTestKeymap(t)
+	km.SetMode("normal")
+	now := time.Unix(0, 0)
+
+	// "g" is both a binding and the start of "g g".
+	if got := km.Press(key('g', 0), now); got.Status != Pending {
+		t.Fatalf("expected a pending chord, got %v", got.Status)
+	}
+	if got := km.Press(key('g', 0), now.Add(time.Millisecond)); got.Status != Matched || got.Action != "top" {
+		t.Errorf("g g: got %v %q", got.Status, got.Action)
+	}
+
+	km.Press(key('g', 0), now)
+	deadline, ok := km.Deadline()
+	if !ok || !deadline.Equal(now.Add(DefaultTimeout)) {
+		t.Errorf("deadline: got %v %v", deadline, ok)
+	}
+	if got := km.Expire(now.Add(DefaultTimeout / 2)); got.Status != Pending {
+		t.Errorf("before the deadline: got %v", got.Status)
+	}
+	if got := km.Expire(deadline); got.Status != Matched || got.Action != "next" {
+		t.Errorf("after the deadline: got %v %q", got.Status, got.Action)
+	}
+	if _, ok := km.Deadline(); ok {
+		t.Error("expected no deadline without a pending chord")
+	}
+
+	km.Press(key('x', input.ModCtrl), now)
+	if got := km.Expire(deadline); got.Status != Unbound || got.Keys.String() != "ctrl+x" {
+		t.Errorf("dropped chord: got %v %q", got.Status, got.Keys)
+	}
+}
+
+func TestKeymap_Flush(t *testing.T) {
+	km := newTestKeymap(t)
+	km.SetMode("normal")
+	now := time.Unix(0, 0)
+
+	// A key that breaks the chord reports the binding of the chord.
+	km.Press(key('g', 0), now)
+	got := km.Press(key('j', 0), now.Add(time.Millisecond))
+	if got.Status != Unbound || got.Keys.String() != "j" {
+		t.Errorf("g j: got %v %q", got.Status, got.Keys)
+	}
+	if got.Flushed == nil || got.Flushed.Status != Matched || got.Flushed.Action != "next" || got.Flushed.Keys.String() != "g" {
+		t.Errorf("g j: got flushed %+v", got.Flushed)
+	}
+
+	km.Press(key('g', 0), now)
+	got = km.Press(key('i', 0), now.Add(time.Millisecond))
+	if got.Status != Matched || got.Action != "insert" || got.Flushed == nil || got.Flushed.Action != "next" {
+		t.Errorf("g i: got %v %q, flushed %+v", got.Status, got.Action, got.Flushed)
+	}
+
+	// So does a key after the deadline when Expire wasn't called.
+	km.Press(key('g', 0), now)
+	got = km.Press(key('g', 0), now.Add(2*DefaultTimeout))
+	if got.Status != Pending || got.Keys.String() != "g" {
+		t.Errorf("g after the deadline: got %v %q", got.Status, got.Keys)
+	}
+	if got.Flushed == nil || got.Flushed.Action != "next" {
+		t.Errorf("g after the deadline: got flushed %+v", got.Flushed)
+	}
+
+	// Chords that aren't bindings by themselves are dropped.
+	km.Press(key('x', input.ModCtrl), now)
+	if got := km.Press(key('j', 0), now.Add(time.Millisecond)); got.Flushed != nil {
+		t.Errorf("ctrl+x j: got flushed %+v", got.Flushed)
+	}
+}
+
+func TestKeymap_Help(t *testing.T) {
+	km := newTestKeymap(t)
+
+	want := "" +
+		"i              insert text\n" +
+		"g g            go to the top\n" +
+		"g              next match\n" +
+		"ctrl+c         close the buffer\n" +
+		"ctrl+x ctrl+c  quit\n" +
+		"ctrl+x ctrl+s  save the file\n"
+	if got := km.HelpText("normal"); got != want {
+		t.Errorf("help:\n%s\nwant:\n%s", got, want)
+	}
+
+	entries := km.Help(Global)
+	if len(entries) != 2 || entries[0] != (HelpEntry{"ctrl+c, ctrl+x ctrl+c", "quit"}) {
+		t.Errorf("global help: got %#v", entries)
+	}
+}
+
+func TestKeymap_Conflicts(t *testing.T) {
+	km := newTestKeymap(t)
+	if err := km.Bind("insert", "indent", "", "esc"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range km.Conflicts() {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`insert: "esc" (normal) duplicates "esc" (indent)`,
+		`normal: "g" (next) starts "g g" (top)`,
+		`normal: "ctrl+c" (close) shadows global "ctrl+c" (quit)`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("conflict %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/input"
+)
+
+// lockMods are the lock states of [input.KeyMod], which bindings ignore.
+const lockMods = input.ModCapsLock | input.ModNumLock | input.ModScrollLock
+
+// modNames maps the modifier names of binding specs to modifiers.
+var modNames = map[string]input.KeyMod{
+	"ctrl":  input.ModCtrl,
+	"alt":   input.ModAlt,
+	"shift": input.ModShift,
+	"meta":  input.ModMeta,
+	"hyper": input.ModHyper,
+	"super": input.ModSuper,
+}
+
+// keyAliases are names of keys accepted in binding specs besides the names
+// of [input.Key.Keystroke].
+var keyAliases = map[string]rune{
+	"escape":   input.KeyEscape,
+	"return":   input.KeyEnter,
+	"pageup":   input.KeyPgUp,
+	"pagedown": input.KeyPgDown,
+}
+
+// Keystroke is a key pressed with modifiers, such as ctrl+s.
+type Keystroke struct {
+	Code rune
+	Mod  input.KeyMod
+}
+
+// ParseKeystroke parses a keystroke such as "ctrl+s", "alt+shift+up", "?"
+// or "ctrl++". Modifiers are ctrl, alt, shift, meta, hyper and super, in any
+// order, and keys are printable characters or the names used by
+// [input.Key.Keystroke], such as "enter", "space" or "f1".
+func ParseKeystroke(s string) (Keystroke, error) {
+	if s == "" {
+		return Keystroke{}, fmt.Errorf("empty keystroke")
+	}
+
+	// The last "+" separates the key, unless the key is "+" itself.
+	mods, name := "", s
+	if i := strings.LastIndex(s[:len(s)-1], "+"); i >= 0 {
+		mods, name = s[:i], s[i+1:]
+	}
+
+	var k Keystroke
+	if mods != "" {
+		for _, mod := range strings.Split(mods, "+") {
+			m, ok := modNames[strings.ToLower(mod)]
+			if !ok {
+				return Keystroke{}, fmt.Errorf("unknown modifier %q in %q", mod, s)
+			}
+			k.Mod |= m
+		}
+	}
+
+	if code, ok := input.KeyCode(strings.ToLower(name)); ok {
+		k.Code = code
+	} else if code, ok := keyAliases[strings.ToLower(name)]; ok {
+		k.Code = code
+	} else if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		k.Code = r
+	} else {
+		return Keystroke{}, fmt.Errorf("unknown key %q in %q", name, s)
+	}
+	return k, nil
+}
+
+// String returns the keystroke as written by [input.Key.Keystroke], such as
+// "ctrl+alt+a".
+func (k Keystroke) String() string {
+	return input.Key{Code: k.Code, Mod: k.Mod}.Keystroke()
+}
+
+// Matches reports whether a key press is the keystroke. Besides the code of
+// the key, it matches the key of the US layout reported by the kitty
+// keyboard protocol, so that ctrl+c works on layouts without a c key, and the
+// shifted key, so that "?" matches shift+/. Lock modifiers are ignored.
+func (k Keystroke) Matches(key input.Key) bool {
+	mod := key.Mod &^ lockMods
+	if mod == k.Mod && (key.Code == k.Code || (key.BaseCode != 0 && key.BaseCode == k.Code)) {
+		return true
+	}
+	if !mod.Contains(input.ModShift) || mod&^input.ModShift != k.Mod {
+		return false
+	}
+	if key.ShiftedCode != 0 {
+		return key.ShiftedCode == k.Code
+	}
+	r, size := utf8.DecodeRuneInString(key.Text)
+	return size > 0 && size == len(key.Text) && r == k.Code
+}
+
+// fromKey returns the keystroke of a key press.
+func fromKey(key input.Key) Keystroke {
+	return Keystroke{Code: key.Code, Mod: key.Mod &^ lockMods}
+}
+
+// Sequence is a series of keystrokes pressed one after the other, such as
+// "ctrl+x ctrl+s". Most are a single keystroke.
+type Sequence []Keystroke
+
+// ParseSequence parses keystrokes separated by spaces, such as
+// "ctrl+x ctrl+s" or "g g". See [ParseKeystroke].
+func ParseSequence(s string) (Sequence, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	seq := make(Sequence, len(fields))
+	for i, field := range fields {
+		k, err := ParseKeystroke(field)
+		if err != nil {
+			return nil, err
+		}
+		seq[i] = k
+	}
+	return seq, nil
+}
+
+// String returns the keystrokes of the sequence separated by spaces.
+func (s Sequence) String() string {
+	parts := make([]string, len(s))
+	for i, k := range s {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// matches reports whether the key presses are the start of the sequence, or
+// the whole sequence.
+func (s Sequence) matches(keys []input.Key) bool {
+	if len(keys) > len(s) {
+		return false
+	}
+	for i, key := range keys {
+		if !s[i].Matches(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPrefix reports whether p is the start of s, or s itself.
+func (s Sequence) hasPrefix(p Sequence) bool {
+	if len(p) > len(s) {
+		return false
+	}
+	for i := range p {
+		if s[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
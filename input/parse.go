@@ -184,6 +184,98 @@ func (p *Parser) parseSequence(buf []byte) (n int, Event Event) {
 	}
 }
 
+// incompleteSequence reports whether buf starts with a sequence that more
+// input could complete or change: a lone ESC that might introduce a
+// sequence or an Alt-modified key, an unterminated control sequence or
+// string, or a partial UTF-8 rune. The parser would report such a prefix as
+// an unknown event or a different key.
+func incompleteSequence(buf []byte) bool {
+	if len(buf) == 0 {
+		return false
+	}
+
+	switch buf[0] {
+	case ansi.ESC:
+		if len(buf) == 1 {
+			return true
+		}
+		switch buf[1] {
+		case '[':
+			return incompleteCsi(buf[2:])
+		case 'O':
+			return incompleteSs3(buf[2:])
+		case ']':
+			return incompleteString(buf[2:], true)
+		case 'P', '_', '^', 'X':
+			return incompleteString(buf[2:], false)
+		default:
+			// Alt-modified key or sequence
+			return incompleteSequence(buf[1:])
+		}
+	case ansi.CSI:
+		return incompleteCsi(buf[1:])
+	case ansi.SS3:
+		return incompleteSs3(buf[1:])
+	case ansi.OSC:
+		return incompleteString(buf[1:], true)
+	case ansi.DCS, ansi.APC, ansi.PM, ansi.SOS:
+		return incompleteString(buf[1:], false)
+	}
+
+	return !utf8.FullRune(buf)
+}
+
+// incompleteCsi reports whether b, the bytes following a CSI introducer, is
+// missing its final byte, or the 3 bytes of an X10 mouse event.
+func incompleteCsi(b []byte) bool {
+	// Parameter and intermediate bytes
+	var i int
+	for i < len(b) && b[i] >= 0x20 && b[i] <= 0x3F {
+		i++
+	}
+	if i >= len(b) {
+		return true
+	}
+
+	prefixed := i > 0 && b[0] >= '<' && b[0] <= '?'
+	intermediate := i > 0 && b[i-1] <= 0x2F
+	if b[i] == 'M' && !prefixed && !intermediate {
+		// X10 mouse: CSI M Cb Cx Cy
+		return len(b) < i+4
+	}
+	return false
+}
+
+// incompleteSs3 reports whether b, the bytes following a SS3 introducer, is
+// missing its GL character.
+func incompleteSs3(b []byte) bool {
+	// Modifier
+	var i int
+	for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	return i >= len(b)
+}
+
+// incompleteString reports whether b, the bytes following the introducer of
+// an OSC, DCS, APC, PM or SOS sequence, is missing its string terminator.
+func incompleteString(b []byte, bel bool) bool {
+	for i, c := range b {
+		switch c {
+		case ansi.BEL:
+			if bel {
+				return false
+			}
+		case ansi.ST, ansi.CAN, ansi.SUB:
+			return false
+		case ansi.ESC:
+			// ESC \ or a cancelled sequence
+			return i == len(b)-1
+		}
+	}
+	return true
+}
+
 func (p *Parser) parseCsi(b []byte) (int, Event) {
 	if len(b) == 2 && b[0] == ansi.ESC {
 		// short cut if this is an alt+[ key
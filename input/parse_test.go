@@ -45,3 +45,45 @@ func BenchmarkParseSequence(b *testing.B) {
 		p.parseSequence(input)
 	}
 }
+
+func TestIncompleteSequence(t *testing.T) {
+	tests := []struct {
+		seq  string
+		want bool
+	}{
+		{"", false},
+		{"a", false},
+		{"\x1b", true},
+		{"\x1ba", false},
+		{"\x1b\x1b", true},
+		{"\x1b\x1b[", true},
+		{"\x1b\x1b[A", false},
+		{"\x1b[", true},
+		{"\x1b[1;5", true},
+		{"\x1b[1;5A", false},
+		{"\x1b[?1049;2$", true},
+		{"\x1b[M", true},
+		{"\x1b[M !", true},
+		{"\x1b[M !!", false},
+		{"\x1b[<0;1;1M", false},
+		{"\x1bO", true},
+		{"\x1bOP", false},
+		{"\x1b]11;rgb:0000/0000/0000", true},
+		{"\x1b]11;rgb:0000/0000/0000\x07", false},
+		{"\x1b]52;c;YQ==\x1b", true},
+		{"\x1b]52;c;YQ==\x1b\\", false},
+		{"\x1bP>|xterm", true},
+		{"\x1bP>|xterm\x1b\\", false},
+		{"\x9b1;5", true},
+		{"\xc3", true},
+		{"\xc3\xa9", false},
+		{"\xf0\x9f\x98", true},
+		{"\xfe", false},
+	}
+
+	for _, tc := range tests {
+		if got := incompleteSequence([]byte(tc.seq)); got != tc.want {
+			t.Errorf("incompleteSequence(%q) = %v, want %v", tc.seq, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a chunk of raw terminal input and when it was read, relative to
+// the start of the recording.
+type Record struct {
+	At   time.Duration
+	Data []byte
+}
+
+// Recorder writes the raw input read by a [Reader] to a recording, one
+// record per line: the time since the start of the recording and the bytes
+// read as a quoted Go string, such as:
+//
+//	1.203s "\x1b[A"
+//
+// Recordings can be read back with [ReadRecording], replayed with a
+// [Player], and decoded with [DecodeRecording].
+//
+// Example:
+//
+//	f, _ := os.Create("input.rec")
+//	defer f.Close()
+//	r, _ := input.NewReader(os.Stdin, os.Getenv("TERM"), 0)
+//	r.SetRecorder(input.NewRecorder(f))
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+}
+
+// NewRecorder returns a recorder that writes records to w. The recording
+// starts now.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, start: time.Now()}
+}
+
+// Write records p as read now. It implements [io.Writer], so that a
+// recorder can be used with [io.TeeReader] to record any input source.
+func (r *Recorder) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, r.err
+	}
+	at := time.Since(r.start)
+	if _, err := fmt.Fprintf(r.w, "%s %s\n", at, strconv.Quote(string(p))); err != nil {
+		r.err = err
+		return 0, err //nolint:wrapcheck
+	}
+	return len(p), nil
+}
+
+// Err returns the first error writing the recording, if any. A [Reader]
+// doesn't report recording errors so that they don't get in the way of the
+// input.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ReadRecording reads the records of a recording written by a [Recorder].
+// Empty lines and lines starting with "#" are ignored.
+func ReadRecording(r io.Reader) ([]Record, error) {
+	var records []Record
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		at, data, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing input data", line)
+		}
+		d, err := time.ParseDuration(at)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(records) > 0 && d < records[len(records)-1].At {
+			return nil, fmt.Errorf("line %d: record at %s is out of order", line, d)
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(data))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid input data %s: %w", line, data, err)
+		}
+		records = append(records, Record{At: d, Data: []byte(s)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return records, nil
+}
+
+// Player replays the records of a recording as an [io.Reader], to be read
+// by a [Reader] as if it was typed in the terminal:
+//
+//	records, _ := input.ReadRecording(f)
+//	r, _ := input.NewReader(input.NewPlayer(records), os.Getenv("TERM"), 0)
+//
+// Each read returns at most one record, once it is due.
+type Player struct {
+	records []Record
+	speed   float64
+	start   time.Time
+	next    int
+	rest    []byte
+}
+
+// NewPlayer returns a player that replays the records at their original
+// pace. The replay starts on the first read.
+func NewPlayer(records []Record) *Player {
+	return &Player{records: records, speed: 1}
+}
+
+// SetSpeed sets the pace of the replay: 1 is the original pace, 2 twice as
+// fast, and 0 or less replays the records without waiting.
+//
+// Replaying faster changes when a [Reader] gives up waiting for the rest of
+// an escape sequence, see [Reader.SetEscTimeout]. Use [DecodeRecording] to
+// decode a recording as it was read, regardless of the pace.
+func (p *Player) SetSpeed(speed float64) {
+	p.speed = speed
+}
+
+// Read waits for the next record to be due and reads it. It returns
+// [io.EOF] after the last record.
+func (p *Player) Read(b []byte) (int, error) {
+	if len(p.rest) == 0 {
+		if p.next >= len(p.records) {
+			return 0, io.EOF
+		}
+		rec := p.records[p.next]
+		p.next++
+		if p.start.IsZero() {
+			p.start = time.Now()
+		}
+		if p.speed > 0 {
+			due := p.start.Add(time.Duration(float64(rec.At) / p.speed))
+			time.Sleep(time.Until(due))
+		}
+		p.rest = rec.Data
+	}
+
+	n := copy(b, p.rest)
+	p.rest = p.rest[n:]
+	return n, nil
+}
+
+// RecordedEvent is an event decoded from a recording, with the time of the
+// record that completed it.
+type RecordedEvent struct {
+	At    time.Duration
+	Event Event
+}
+
+// String returns the time and the event, such as
+// "1.203s KeyPressEvent{ctrl+c}".
+func (e RecordedEvent) String() string {
+	return e.At.String() + " " + FormatEvent(e.Event)
+}
+
+// DecodeRecording decodes the events of a recording the way a [Reader]
+// with the given terminal type and flags read them, including sequences
+// split across reads and the escape timeout, without replaying it in real
+// time. See [NewReader] for the terminal type and flags.
+func DecodeRecording(records []Record, termType string, flags int) []RecordedEvent {
+	d := &Reader{
+		table:      buildKeysTable(flags, termType),
+		term:       termType,
+		escTimeout: DefaultEscTimeout,
+	}
+	d.parser.flags = flags
+
+	var events []RecordedEvent
+	add := func(at time.Duration, evs []Event) {
+		for _, ev := range evs {
+			events = append(events, RecordedEvent{At: at, Event: ev})
+		}
+	}
+	for i, rec := range records {
+		d.pending = append(d.pending, rec.Data...)
+		add(rec.At, d.parseEvents(false))
+
+		// The reader flushes what is left when the next read doesn't come
+		// within the escape timeout.
+		if len(d.pending) > 0 && (i == len(records)-1 || records[i+1].At-rec.At > d.escTimeout) {
+			add(rec.At+d.escTimeout, d.parseEvents(true))
+		}
+	}
+	return events
+}
+
+// FormatEvent returns a human-readable representation of an event: its type
+// and its string representation, such as "KeyPressEvent{ctrl+c}" or
+// "MouseClickEvent{left}".
+func FormatEvent(ev Event) string {
+	name := fmt.Sprintf("%T", ev)
+	name = name[strings.LastIndex(name, ".")+1:]
+	if s, ok := ev.(fmt.Stringer); ok {
+		return name + "{" + s.String() + "}"
+	}
+	switch v := reflect.ValueOf(ev); v.Kind() {
+	case reflect.Struct:
+		return fmt.Sprintf("%s%+v", name, ev)
+	case reflect.String:
+		return fmt.Sprintf("%s{%q}", name, v.String())
+	default:
+		return fmt.Sprintf("%s{%v}", name, ev)
+	}
+}
@@ -0,0 +1,194 @@
+package input
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	const input = "\x1b[Aé"
+	var buf bytes.Buffer
+	drv, err := NewReader(iotest.OneByteReader(strings.NewReader(input)), "dumb", 0)
+	if err != nil {
+		t.Fatalf("could not create driver: %v", err)
+	}
+	rec := NewRecorder(&buf)
+	drv.SetRecorder(rec)
+	for {
+		if _, err := drv.ReadEvents(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected input error: %v", err)
+		}
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("could not read the recording: %v\n%s", err, buf.String())
+	}
+	if len(records) != len(input) {
+		t.Fatalf("expected a record per byte, got %d records", len(records))
+	}
+	var data []byte
+	for i, r := range records {
+		data = append(data, r.Data...)
+		if i > 0 && r.At < records[i-1].At {
+			t.Errorf("record %d: out of order", i)
+		}
+	}
+	if string(data) != input {
+		t.Errorf("got %q, want %q", data, input)
+	}
+}
+
+func TestReadRecording(t *testing.T) {
+	records, err := ReadRecording(strings.NewReader("# comment\n\n0s \"\\x1b\"\n1.5ms \"[A\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{{0, []byte("\x1b")}, {1500 * time.Microsecond, []byte("[A")}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %#v, want %#v", records, want)
+	}
+
+	for _, rec := range []string{"1s", "soon \"a\"", "1s a", "2s \"a\"\n1s \"b\""} {
+		if _, err := ReadRecording(strings.NewReader(rec)); err == nil {
+			t.Errorf("expected an error for %q", rec)
+		}
+	}
+}
+
+func TestDecodeRecording(t *testing.T) {
+	records := []Record{
+		{0, []byte("\x1b")},
+		{10 * time.Millisecond, []byte("[A")},
+		{20 * time.Millisecond, []byte("\x1b")},
+		{200 * time.Millisecond, []byte("x\x1b")},
+		{210 * time.Millisecond, []byte("y")},
+		{300 * time.Millisecond, []byte("\x1b[<0;1;1M")},
+	}
+	var got []string
+	for _, ev := range DecodeRecording(records, "dumb", 0) {
+		got = append(got, ev.String())
+	}
+	want := []string{
+		"10ms KeyPressEvent{up}",
+		"70ms KeyPressEvent{esc}",
+		"200ms KeyPressEvent{x}",
+		"210ms KeyPressEvent{alt+y}",
+		"300ms MouseClickEvent{left}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	records := []Record{
+		{0, []byte("a")},
+		{time.Second, []byte("\x1b[B")},
+	}
+
+	player := NewPlayer(records)
+	player.SetSpeed(100)
+	start := time.Now()
+	got := readAllEvents(t, player)
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("expected the replay to take 10ms, took %s", elapsed)
+	}
+	want := []Event{KeyPressEvent{Code: 'a', Text: "a"}, KeyPressEvent{Code: KeyDown}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Reads smaller than a record get the rest on the next read.
+	player = NewPlayer(records)
+	player.SetSpeed(0)
+	data, err := io.ReadAll(iotest.OneByteReader(player))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\x1b[B" {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	tests := []struct {
+		ev   Event
+		want string
+	}{
+		{KeyPressEvent{Code: 'c', Mod: ModCtrl}, "KeyPressEvent{ctrl+c}"},
+		{WindowSizeEvent{80, 24}, "WindowSizeEvent{Width:80 Height:24}"},
+		{PasteEvent("hi"), `PasteEvent{"hi"}`},
+		{FocusEvent{}, "FocusEvent{}"},
+		{UnknownEvent("\x1b[?"), `UnknownEvent{"\x1b[?"}`},
+		{KittyEnhancementsEvent(3), "KittyEnhancementsEvent{3}"},
+	}
+	for _, tc := range tests {
+		if got := FormatEvent(tc.ev); got != tc.want {
+			t.Errorf("FormatEvent(%#v) = %q, want %q", tc.ev, got, tc.want)
+		}
+	}
+}
+
+// readRecordings returns the records of the recordings in testdata.
+func readRecordings(tb testing.TB) []Record {
+	tb.Helper()
+	files, err := os.ReadDir("testdata")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var records []Record
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".rec") {
+			continue
+		}
+		f, err := os.Open("testdata/" + file.Name())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		recs, err := ReadRecording(f)
+		f.Close() //nolint:errcheck
+		if err != nil {
+			tb.Fatalf("%s: %v", file.Name(), err)
+		}
+		records = append(records, recs...)
+	}
+	return records
+}
+
+func TestDecodeRecording_Session(t *testing.T) {
+	var got []string
+	for _, ev := range DecodeRecording(readRecordings(t), "xterm-256color", 0) {
+		got = append(got, ev.String())
+	}
+	want := []string{
+		"0s BackgroundColorEvent{#1e1e2e}",
+		"412.5ms KeyPressEvent{h}",
+		"530.1ms KeyPressEvent{i}",
+		"1.2s KeyPressEvent{up}",
+		"1.352s KeyPressEvent{alt+b}",
+		"2.15s KeyPressEvent{esc}",
+		"2.9s PasteStartEvent{}",
+		`2.9s PasteEvent{"hello, été"}`,
+		"2.9s PasteEndEvent{}",
+		"3.4s MouseClickEvent{left}",
+		"3.52s MouseReleaseEvent{left}",
+		"4s BlurEvent{}",
+		"4.8s FocusEvent{}",
+		"5.1s KeyPressEvent{ctrl+c}",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,345 @@
+package pony
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// DefaultFPS is the frame rate of a new Clock.
+const DefaultFPS = 60
+
+// Clock is the frame clock of animations: transitions declared with the
+// transition attribute and spinners. It is ticked by the application, and
+// reports when nothing is animating so that the application can stop
+// ticking, and redrawing.
+//
+// Transitions animate the attributes of elements with an ID from their
+// previous value to the new one when they change between renders:
+//
+//	<box id="panel" width="{{ .PanelWidth }}" transition="width 200ms ease-out">
+//
+// Integers, such as sizes and offsets, percentages and colors can be
+// animated; colors are interpolated in the OkLab color space. Other values
+// change at once.
+//
+// With Bubble Tea, tick the clock while it isn't idle:
+//
+//	type frameMsg time.Time
+//
+//	func (m model) frame() tea.Cmd {
+//	    return tea.Tick(m.clock.Interval(), func(t time.Time) tea.Msg {
+//	        return frameMsg(t)
+//	    })
+//	}
+//
+//	// In Update
+//	case frameMsg:
+//	    m.clock.Tick(time.Time(msg))
+//	    if m.clock.Idle() {
+//	        m.ticking = false
+//	        return m, nil
+//	    }
+//	    return m, m.frame()
+//	default:
+//	    // Changes may start transitions
+//	    if !m.ticking {
+//	        m.ticking = true
+//	        cmd = m.frame()
+//	    }
+//
+// A Clock can drive several templates. Transitions are kept per template: an
+// element that leaves the markup of a template loses its transitions on the
+// next render of that template only.
+//
+// A Clock is not safe for concurrent use.
+type Clock struct {
+	interval time.Duration
+	now      time.Time
+	epoch    time.Time
+	scenes   map[any]*clockScene
+}
+
+// clockScene is the animation state of one template driven by a clock.
+type clockScene struct {
+	clock       *Clock
+	transitions map[string]*transition
+	running     bool // transitions running in the last render
+	spinning    bool // spinners shown in the last render
+}
+
+// transition is the state of an animated attribute of an element.
+type transition struct {
+	spec    transitionSpec
+	from    string
+	to      string
+	shown   string // value of the last render
+	start   time.Time
+	pending bool // waiting for the next tick to start
+	seen    bool // the element was in the last render
+}
+
+// transitionSpec is a property of a transition attribute, such as
+// "width 200ms ease-out".
+type transitionSpec struct {
+	property string
+	duration time.Duration
+	easing   string
+}
+
+// NewClock creates a clock ticking at DefaultFPS.
+func NewClock() *Clock {
+	return &Clock{
+		interval: time.Second / DefaultFPS,
+		scenes:   make(map[any]*clockScene),
+	}
+}
+
+// FPS sets the frame rate and returns the clock for chaining.
+func (c *Clock) FPS(fps int) *Clock {
+	if fps > 0 {
+		c.interval = time.Second / time.Duration(fps)
+	}
+	return c
+}
+
+// Interval returns the time between frames.
+func (c *Clock) Interval() time.Duration {
+	return c.interval
+}
+
+// Tick advances the clock to now. Transitions that started since the last
+// tick start from now.
+func (c *Clock) Tick(now time.Time) {
+	if c.epoch.IsZero() {
+		c.epoch = now
+	}
+	c.now = now
+	for _, s := range c.scenes {
+		for _, t := range s.transitions {
+			if t.pending {
+				t.start = now
+				t.pending = false
+			}
+		}
+	}
+}
+
+// Now returns the time of the last tick.
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+// Idle reports whether nothing was animating in the last render of each
+// template: no transition was running and no spinner was shown.
+func (c *Clock) Idle() bool {
+	for _, s := range c.scenes {
+		if s.running || s.spinning {
+			return false
+		}
+	}
+	return true
+}
+
+// elapsed returns the time since the first tick.
+func (c *Clock) elapsed() time.Duration {
+	return c.now.Sub(c.epoch)
+}
+
+// scene returns the animation state of the template owner. It is nil for a
+// nil clock.
+func (c *Clock) scene(owner any) *clockScene {
+	if c == nil {
+		return nil
+	}
+	s, ok := c.scenes[owner]
+	if !ok {
+		s = &clockScene{clock: c, transitions: make(map[string]*transition)}
+		c.scenes[owner] = s
+	}
+	return s
+}
+
+// animating reports whether transitions were running in the last render, so
+// the markup must be styled again. It is false for a nil scene.
+func (s *clockScene) animating() bool {
+	return s != nil && s.running
+}
+
+// bind advances the spinners of an element tree to the current time.
+func (s *clockScene) bind(elem Element) {
+	s.spinning = false
+	s.bindSpinners(elem)
+}
+
+func (s *clockScene) bindSpinners(elem Element) {
+	if sp, ok := elem.(*Spinner); ok {
+		sp.Advance(s.clock.elapsed())
+		s.spinning = true
+	}
+	for _, child := range elem.Children() {
+		if child != nil {
+			s.bindSpinners(child)
+		}
+	}
+}
+
+// apply sets the attributes of the nodes with transitions to their value at
+// the current time, starting transitions for the attributes that changed.
+// Transitions of elements no longer in the tree are dropped.
+func (s *clockScene) apply(root *node) {
+	for _, t := range s.transitions {
+		t.seen = false
+	}
+	s.running = false
+	s.applyNode(root)
+	for key, t := range s.transitions {
+		if !t.seen {
+			delete(s.transitions, key)
+		}
+	}
+}
+
+func (s *clockScene) applyNode(n *node) {
+	props := n.Props()
+	if id := props.Get("id"); id != "" && props.Has("transition") {
+		specs, _ := parseTransitions(props.Get("transition"))
+		for _, spec := range specs {
+			if !props.Has(spec.property) {
+				continue
+			}
+			value := s.value(id+"\x00"+spec.property, spec, props.Get(spec.property))
+			n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: spec.property}, Value: value})
+		}
+	}
+	for _, child := range n.Children {
+		s.applyNode(child)
+	}
+}
+
+// value returns the value of an animated attribute whose target is to.
+func (s *clockScene) value(key string, spec transitionSpec, to string) string {
+	t, ok := s.transitions[key]
+	if !ok {
+		// Elements appear with their value.
+		t = &transition{from: to, to: to, shown: to}
+		s.transitions[key] = t
+	}
+	t.seen = true
+	t.spec = spec
+	if to != t.to {
+		t.from, t.to = t.shown, to
+		t.pending = true
+	}
+	if t.from == t.to {
+		return t.to
+	}
+
+	var progress float64
+	if !t.pending {
+		progress = 1
+		if elapsed := s.clock.now.Sub(t.start); elapsed < spec.duration {
+			progress = float64(elapsed) / float64(spec.duration)
+		}
+	}
+	if progress >= 1 {
+		t.from = t.to
+		t.shown = t.to
+		return t.to
+	}
+
+	s.running = true
+	t.shown = interpolate(spec.property, t.from, t.to, ease(spec.easing, progress))
+	return t.shown
+}
+
+// parseTransitions parses a transition attribute: a comma-separated list of
+// properties with a duration and an optional easing, such as
+// "width 200ms ease-out, background-color 1s".
+func parseTransitions(s string) ([]transitionSpec, error) {
+	var specs []transitionSpec
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid transition %q, want a property, a duration and an optional easing", strings.TrimSpace(part))
+		}
+		duration, err := time.ParseDuration(fields[1])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("invalid transition duration %q", fields[1])
+		}
+		spec := transitionSpec{property: fields[0], duration: duration, easing: EasingLinear}
+		if len(fields) == 3 {
+			if _, ok := easings[fields[2]]; !ok {
+				return nil, fmt.Errorf("invalid easing %q", fields[2])
+			}
+			spec.easing = fields[2]
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// easings are the easing functions of transitions by name.
+var easings = map[string]func(float64) float64{
+	EasingLinear: func(t float64) float64 { return t },
+	EasingEase:   easeInOut,
+	EasingIn:     func(t float64) float64 { return t * t * t },
+	EasingOut: func(t float64) float64 {
+		t = 1 - t
+		return 1 - t*t*t
+	},
+	EasingInOut: easeInOut,
+}
+
+func easeInOut(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = -2*t + 2
+	return 1 - t*t*t/2
+}
+
+// ease applies the named easing to the progress t of a transition.
+func ease(name string, t float64) float64 {
+	if fn, ok := easings[name]; ok {
+		return fn(t)
+	}
+	return t
+}
+
+// interpolate returns the value of an attribute at the eased progress t
+// between from and to: colors are blended in OkLab, integers and percentages
+// are rounded to the nearest, and other values change at once.
+func interpolate(property, from, to string, t float64) string {
+	if strings.HasSuffix(property, "color") || property == "backdrop" {
+		c1, err1 := parseColor(from)
+		c2, err2 := parseColor(to)
+		if err1 != nil || err2 != nil {
+			return to
+		}
+		cf1, _ := colorful.MakeColor(c1)
+		cf2, _ := colorful.MakeColor(c2)
+		return cf1.BlendOkLab(cf2, t).Clamped().Hex()
+	}
+
+	from, fromPercent := strings.CutSuffix(from, "%")
+	to, toPercent := strings.CutSuffix(to, "%")
+	a, err1 := strconv.Atoi(strings.TrimSpace(from))
+	b, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || fromPercent != toPercent {
+		if toPercent {
+			return to + "%"
+		}
+		return to
+	}
+	value := strconv.Itoa(int(math.Round(float64(a) + float64(b-a)*t)))
+	if toPercent {
+		value += "%"
+	}
+	return value
+}
@@ -0,0 +1,225 @@
+package pony
+
+import (
+	"testing"
+	"time"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+type animateData struct {
+	Width int
+	Color string
+}
+
+const animateMarkup = `<vstack>
+	<box id="panel" width="{{ .Width }}" height="1" foreground-color="{{ .Color }}" transition="width 100ms, foreground-color 100ms ease-in-out">
+		<text>Panel</text>
+	</box>
+</vstack>`
+
+func TestClock_Transition(t *testing.T) {
+	clock := NewClock()
+	tmpl := MustParse[animateData](animateMarkup).WithClock(clock)
+	start := time.Unix(0, 0)
+
+	width := func(data animateData) int {
+		t.Helper()
+		_, bm := tmpl.RenderWithBounds(data, nil, 40, 5)
+		bounds, _ := bm.GetBounds("panel")
+		return bounds.Dx()
+	}
+
+	clock.Tick(start)
+	if got := width(animateData{10, "white"}); got != 10 {
+		t.Errorf("first render: got width %d, want 10", got)
+	}
+	if !clock.Idle() {
+		t.Error("expected the clock to be idle after the first render")
+	}
+
+	// The transition starts on the next tick.
+	if got := width(animateData{20, "white"}); got != 10 {
+		t.Errorf("changed: got width %d, want 10", got)
+	}
+	if clock.Idle() {
+		t.Error("expected a running transition")
+	}
+	clock.Tick(start.Add(time.Second))
+	if got := width(animateData{20, "white"}); got != 10 {
+		t.Errorf("first frame: got width %d, want 10", got)
+	}
+
+	clock.Tick(start.Add(time.Second + 50*time.Millisecond))
+	if got := width(animateData{20, "white"}); got != 15 {
+		t.Errorf("halfway: got width %d, want 15", got)
+	}
+
+	// Changing the target again starts from the current value.
+	if got := width(animateData{5, "white"}); got != 15 {
+		t.Errorf("reversed: got width %d, want 15", got)
+	}
+	clock.Tick(start.Add(2 * time.Second))
+	clock.Tick(start.Add(2*time.Second + 50*time.Millisecond))
+	if got := width(animateData{5, "white"}); got != 10 {
+		t.Errorf("reversed halfway: got width %d, want 10", got)
+	}
+
+	clock.Tick(start.Add(3 * time.Second))
+	if got := width(animateData{5, "white"}); got != 5 {
+		t.Errorf("done: got width %d, want 5", got)
+	}
+	if !clock.Idle() {
+		t.Error("expected the clock to be idle after the transition")
+	}
+}
+
+func TestClock_SharedByTemplates(t *testing.T) {
+	clock := NewClock()
+	tmpl := MustParse[animateData](animateMarkup).WithClock(clock)
+	other := MustParse[struct{}](`<text>Other</text>`).WithClock(clock)
+	start := time.Unix(0, 0)
+
+	width := func(data animateData) int {
+		t.Helper()
+		_, bm := tmpl.RenderWithBounds(data, nil, 40, 5)
+		bounds, _ := bm.GetBounds("panel")
+		return bounds.Dx()
+	}
+
+	clock.Tick(start)
+	width(animateData{10, "white"})
+	width(animateData{20, "white"})
+	clock.Tick(start.Add(time.Second))
+	clock.Tick(start.Add(time.Second + 50*time.Millisecond))
+
+	// Rendering another template keeps the running transition.
+	other.Render(struct{}{}, 20, 1)
+	if clock.Idle() {
+		t.Error("expected the transition of the first template to keep the clock running")
+	}
+	if got := width(animateData{20, "white"}); got != 15 {
+		t.Errorf("halfway: got width %d, want 15", got)
+	}
+}
+
+func TestClock_Retained(t *testing.T) {
+	clock, retainedClock := NewClock(), NewClock()
+	tmpl := MustParse[animateData](animateMarkup).WithClock(clock)
+	renderer := NewRenderer(MustParse[animateData](animateMarkup).WithClock(retainedClock))
+	start := time.Unix(0, 0)
+
+	frames := []struct {
+		data animateData
+		at   time.Duration
+	}{
+		{animateData{10, "#000000"}, 0},
+		{animateData{30, "#ffffff"}, 0},
+		{animateData{30, "#ffffff"}, 10 * time.Millisecond},
+		{animateData{30, "#ffffff"}, 40 * time.Millisecond},
+		{animateData{30, "#ffffff"}, 80 * time.Millisecond},
+		{animateData{30, "#ffffff"}, 200 * time.Millisecond},
+		{animateData{30, "#ffffff"}, 300 * time.Millisecond},
+	}
+	var previous string
+	for i, frame := range frames {
+		clock.Tick(start.Add(frame.at))
+		retainedClock.Tick(start.Add(frame.at))
+		got, _ := renderer.Render(frame.data, nil, nil, nil, 40, 3)
+		want, _ := tmpl.RenderWithBounds(frame.data, nil, 40, 3)
+		if got.Render() != want.Render() {
+			t.Errorf("frame %d: retained frame differs\ngot:\n%s\nwant:\n%s", i, got.Render(), want.Render())
+		}
+		if i == 3 && want.Render() == previous {
+			t.Errorf("frame %d: expected the transition to change the frame", i)
+		}
+		previous = want.Render()
+	}
+	if !retainedClock.Idle() {
+		t.Error("expected the clock to be idle after the transition")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		property, from, to string
+		t                  float64
+		want               string
+	}{
+		{"width", "10", "20", 0.5, "15"},
+		{"width", "10", "20", 0.26, "13"},
+		{"width", "20%", "60%", 0.5, "40%"},
+		{"width", "20", "auto", 0.5, "auto"},
+		{"x", "-4", "4", 0.25, "-2"},
+		{"foreground-color", "#ff0000", "#0000ff", 0, "#ff0000"},
+		{"foreground-color", "#ff0000", "#0000ff", 1, "#0000ff"},
+		{"foreground-color", "red", "nope", 0.5, "nope"},
+	}
+	for _, tt := range tests {
+		if got := interpolate(tt.property, tt.from, tt.to, tt.t); got != tt.want {
+			t.Errorf("interpolate(%q, %q, %q, %v) = %q, want %q", tt.property, tt.from, tt.to, tt.t, got, tt.want)
+		}
+	}
+
+	// Colors are blended in a perceptual space rather than in RGB.
+	if got := interpolate("background-color", "#000000", "#ffffff", 0.5); got == "#808080" || got == "#7f7f7f" {
+		t.Errorf("expected a perceptual blend, got %q", got)
+	}
+}
+
+func TestParseTransitions(t *testing.T) {
+	specs, err := parseTransitions("width 200ms ease-out, foreground-color 1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []transitionSpec{
+		{"width", 200 * time.Millisecond, EasingOut},
+		{"foreground-color", time.Second, EasingLinear},
+	}
+	if len(specs) != len(want) || specs[0] != want[0] || specs[1] != want[1] {
+		t.Errorf("got %+v, want %+v", specs, want)
+	}
+
+	for _, s := range []string{"width", "width fast", "width 1s bounce", "width 1s ease x"} {
+		if _, err := parseTransitions(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if err := Validate(`<box id="a" transition="width 1s bounce" />`); err == nil {
+		t.Error("expected a validation error for an invalid easing")
+	}
+}
+
+func TestSpinner_Clock(t *testing.T) {
+	clock := NewClock()
+	tmpl := MustParse[struct{}](`<hstack spacing="1"><spinner type="line" interval="100ms" /><text>Loading</text></hstack>`).WithClock(clock)
+	renderer := NewRenderer(tmpl)
+	start := time.Unix(0, 0)
+
+	for i, want := range []string{"| Loading", "/ Loading", "- Loading", "\\ Loading", "| Loading"} {
+		clock.Tick(start.Add(time.Duration(i) * 100 * time.Millisecond))
+		scr, _ := renderer.Render(struct{}{}, nil, nil, nil, 20, 1)
+		if got := screenLines(&scr)[0]; got != want {
+			t.Errorf("frame %d: got %q, want %q", i, got, want)
+		}
+	}
+	if clock.Idle() {
+		t.Error("expected the clock not to be idle while a spinner is shown")
+	}
+
+	if got := tmpl.Render(struct{}{}, 20, 1); got != "| Loading" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSpinner_FewerFrames(t *testing.T) {
+	spinner := NewSpinner(SpinnerDots)
+	spinner.Advance(900 * time.Millisecond)
+	spinner.Frames("a", "b")
+
+	scr := uv.NewScreenBuffer(1, 1)
+	spinner.Draw(scr, uv.Rect(0, 0, 1, 1))
+	if got := screenLines(&scr)[0]; got != "b" {
+		t.Errorf("got %q, want %q", got, "b")
+	}
+}
@@ -12,12 +12,24 @@ import (
 type BoundsMap struct {
 	elements   map[string]elementBounds
 	byPosition []elementBounds // ordered for z-index (last = on top)
+	focusables []elementBounds // focusable elements in tree order
+	focused    string
+	cursor     *uv.Position
+	layers     []layerBounds // overlays, from the bottom; layer 0 is the base
 }
 
 type elementBounds struct {
 	id     string
 	elem   Element
 	bounds uv.Rectangle
+	parent int // index in byPosition, -1 for roots
+	layer  int
+}
+
+// layerBounds is the area of an overlay layer.
+type layerBounds struct {
+	bounds uv.Rectangle
+	modal  bool
 }
 
 // NewBoundsMap creates a new empty bounds map.
@@ -30,13 +42,34 @@ func NewBoundsMap() *BoundsMap {
 // Register records an element and its rendered bounds.
 // This should be called during the render pass.
 func (bm *BoundsMap) Register(elem Element, bounds uv.Rectangle) {
+	bm.register(elem, bounds, -1, 0)
+}
+
+// register records an element under the parent at the given index, in a
+// layer, and returns the index of the element.
+func (bm *BoundsMap) register(elem Element, bounds uv.Rectangle, parent, layer int) int {
 	eb := elementBounds{
 		id:     elem.ID(),
 		elem:   elem,
 		bounds: bounds,
+		parent: parent,
+		layer:  layer,
 	}
 	bm.elements[elem.ID()] = eb
 	bm.byPosition = append(bm.byPosition, eb)
+
+	if f, ok := elem.(Focusable); ok && f.IsFocusable() && hasExplicitID(eb.id) {
+		bm.focusables = append(bm.focusables, eb)
+		if f.IsFocused() {
+			bm.focused = eb.id
+			if c, ok := elem.(cursorElement); ok {
+				if pos, ok := c.CursorPosition(); ok {
+					bm.cursor = &pos
+				}
+			}
+		}
+	}
+	return len(bm.byPosition) - 1
 }
 
 // HitTest returns the top-most element at the given screen coordinates.
@@ -56,15 +89,19 @@ func (bm *BoundsMap) Register(elem Element, bounds uv.Rectangle) {
 //	    return vstack
 //	}
 //
+// Only the elements of the top-most layer at that position are hit, so
+// clicks on a popover or a dialog don't fall through to the elements beneath.
+//
 // Returns nil if no element is found at that position.
 func (bm *BoundsMap) HitTest(x, y int) Element {
 	var bestMatch Element
 	var bestMatchHasExplicitID bool
 
 	// Search from end (last drawn = on top)
+	layer := bm.layerAt(x, y)
 	for i := len(bm.byPosition) - 1; i >= 0; i-- {
 		eb := bm.byPosition[i]
-		if pointInRect(x, y, eb.bounds) {
+		if eb.layer == layer && pointInRect(x, y, eb.bounds) {
 			// Check if this element has an explicit ID (not auto-generated)
 			hasExplicitID := !strings.HasPrefix(eb.id, "elem_")
 
@@ -103,14 +140,17 @@ func (bm *BoundsMap) HitTest(x, y int) Element {
 //	    }
 //	}
 //
+// Like HitTest, only the top-most layer at that position is hit.
+//
 // Returns empty slice if no elements are found at that position.
 func (bm *BoundsMap) HitTestAll(x, y int) []Element {
 	var hits []Element
 
 	// Search from end (last drawn = on top)
+	layer := bm.layerAt(x, y)
 	for i := len(bm.byPosition) - 1; i >= 0; i-- {
 		eb := bm.byPosition[i]
-		if pointInRect(x, y, eb.bounds) {
+		if eb.layer == layer && pointInRect(x, y, eb.bounds) {
 			hits = append(hits, eb.elem)
 		}
 	}
@@ -187,6 +227,29 @@ type ElementWithBounds struct {
 	Bounds  uv.Rectangle
 }
 
+// layerAt returns the top-most layer at the given screen coordinates: the
+// last overlay covering them, or blocking everything beneath as a modal, or
+// 0 for the base.
+func (bm *BoundsMap) layerAt(x, y int) int {
+	for i := len(bm.layers) - 1; i >= 0; i-- {
+		if l := bm.layers[i]; l.modal || pointInRect(x, y, l.bounds) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// modalLayer returns the top-most modal layer, or 0 if there is none.
+// Focus stays in that layer and the ones above it.
+func (bm *BoundsMap) modalLayer() int {
+	for i := len(bm.layers) - 1; i >= 0; i-- {
+		if bm.layers[i].modal {
+			return i + 1
+		}
+	}
+	return 0
+}
+
 // pointInRect checks if a point is inside a rectangle.
 func pointInRect(x, y int, rect uv.Rectangle) bool {
 	return x >= rect.Min.X && x < rect.Max.X &&
@@ -196,8 +259,15 @@ func pointInRect(x, y int, rect uv.Rectangle) bool {
 // BaseElement provides common functionality for all elements.
 // Elements should embed this to get ID and bounds tracking.
 type BaseElement struct {
-	id     string
-	bounds uv.Rectangle
+	id        string
+	bounds    uv.Rectangle
+	focusable bool
+	focused   bool
+	tabIndex  int
+	hovered   bool
+	active    bool
+	handlers  map[EventType]string
+	cache     *retainedState
 }
 
 // ID returns the element's identifier.
@@ -227,11 +297,26 @@ func (b *BaseElement) SetBounds(bounds uv.Rectangle) {
 
 // walkAndRegister recursively walks an element tree and registers all elements.
 func walkAndRegister(elem Element, bm *BoundsMap) {
-	bm.Register(elem, elem.Bounds())
+	registerTree(elem, bm, -1, 0)
+}
 
+// registerTree registers an element and its descendants, keeping track of
+// their parents for event bubbling and of the overlay layers of Layers.
+func registerTree(elem Element, bm *BoundsMap, parent, layer int) {
+	index := bm.register(elem, elem.Bounds(), parent, layer)
+
+	layers, _ := elem.(*Layers)
 	for _, child := range elem.Children() {
-		if child != nil {
-			walkAndRegister(child, bm)
+		if child == nil {
+			continue
+		}
+		childLayer := layer
+		if layers != nil {
+			if o, ok := child.(OverlayElement); ok && layers.layerOf(child) > 0 {
+				bm.layers = append(bm.layers, layerBounds{bounds: child.Bounds(), modal: o.Modal()})
+				childLayer = len(bm.layers)
+			}
 		}
+		registerTree(child, bm, index, childLayer)
 	}
 }
@@ -173,7 +173,7 @@ func (b *Box) Draw(scr uv.Screen, area uv.Rectangle) {
 
 	// Draw child if present
 	if b.child != nil {
-		b.child.Draw(scr, area)
+		drawElement(b.child, scr, area)
 	}
 }
 
@@ -224,7 +224,7 @@ func (b *Box) Layout(constraints Constraints) Size {
 
 	var childSize Size
 	if b.child != nil {
-		childSize = b.child.Layout(childConstraints)
+		childSize = layoutElement(b.child, childConstraints)
 	}
 
 	totalSize := Size{
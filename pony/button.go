@@ -11,21 +11,28 @@ type Button struct {
 	style       uv.Style
 	hoverStyle  uv.Style
 	activeStyle uv.Style
+	focusStyle  uv.Style
 	border      string
 	padding     int
 	width       SizeConstraint
 	height      SizeConstraint
 }
 
-var _ Element = (*Button)(nil)
+var (
+	_ Element   = (*Button)(nil)
+	_ Focusable = (*Button)(nil)
+)
 
 // NewButton creates a new button element.
+// Buttons are focusable by default.
 func NewButton(text string) *Button {
-	return &Button{
+	b := &Button{
 		text:    text,
 		border:  BorderRounded,
 		padding: 1,
 	}
+	b.SetFocusable(true)
+	return b
 }
 
 // Style sets the button style and returns the button for chaining.
@@ -46,6 +53,13 @@ func (b *Button) ActiveStyle(style uv.Style) *Button {
 	return b
 }
 
+// FocusStyle sets the style used while the button has focus and returns the
+// button for chaining.
+func (b *Button) FocusStyle(style uv.Style) *Button {
+	b.focusStyle = style
+	return b
+}
+
 // Border sets the border type and returns the button for chaining.
 func (b *Button) Border(border string) *Button {
 	b.border = border
@@ -74,17 +88,27 @@ func (b *Button) Height(height SizeConstraint) *Button {
 func (b *Button) Draw(scr uv.Screen, area uv.Rectangle) {
 	b.SetBounds(area)
 
+	style := b.style
+	switch {
+	case b.IsActive() && !b.activeStyle.IsZero():
+		style = b.activeStyle
+	case b.IsHovered() && !b.hoverStyle.IsZero():
+		style = b.hoverStyle
+	case b.IsFocused() && !b.focusStyle.IsZero():
+		style = b.focusStyle
+	}
+
 	// Create text element
 	textElem := NewText(b.text).Alignment(AlignmentCenter)
-	if !b.style.IsZero() {
+	if !style.IsZero() {
 		// Apply style to text content
-		if b.style.Fg != nil {
-			textElem = textElem.ForegroundColor(b.style.Fg)
+		if style.Fg != nil {
+			textElem = textElem.ForegroundColor(style.Fg)
 		}
-		if b.style.Attrs&uv.AttrBold != 0 {
+		if style.Attrs&uv.AttrBold != 0 {
 			textElem = textElem.Bold()
 		}
-		if b.style.Attrs&uv.AttrItalic != 0 {
+		if style.Attrs&uv.AttrItalic != 0 {
 			textElem = textElem.Italic()
 		}
 	}
@@ -94,8 +118,8 @@ func (b *Button) Draw(scr uv.Screen, area uv.Rectangle) {
 		Border(b.border).
 		Padding(b.padding)
 
-	if !b.style.IsZero() && b.style.Fg != nil {
-		box = box.BorderColor(b.style.Fg)
+	if !style.IsZero() && style.Fg != nil {
+		box = box.BorderColor(style.Fg)
 	}
 
 	box.Draw(scr, area)
@@ -149,15 +173,19 @@ func NewButtonFromProps(props Props, children []Element) Element {
 
 	btn := NewButton(text)
 
-	// Parse foreground color for button text/border
-	if fgColor := props.Get("foreground-color"); fgColor != "" {
-		if c, err := parseColor(fgColor); err == nil {
-			style := uv.Style{Fg: c}
-			if props.Get("font-weight") == FontWeightBold {
-				style.Attrs |= uv.AttrBold
-			}
-			btn = btn.Style(style)
-		}
+	// Parse foreground color for button text/border, and the styles of the
+	// hover:, active: and focus: attributes
+	if style, ok := buttonStyleFromProps(props, ""); ok {
+		btn = btn.Style(style)
+	}
+	if style, ok := buttonStyleFromProps(props, hoverPrefix+":"); ok {
+		btn = btn.HoverStyle(style)
+	}
+	if style, ok := buttonStyleFromProps(props, activePrefix+":"); ok {
+		btn = btn.ActiveStyle(style)
+	}
+	if style, ok := buttonStyleFromProps(props, focusPrefix+":"); ok {
+		btn = btn.FocusStyle(style)
 	}
 
 	if border := props.Get("border"); border != "" {
@@ -179,6 +207,30 @@ func NewButtonFromProps(props Props, children []Element) Element {
 	return btn
 }
 
+// buttonStyleFromProps builds a button style from the foreground-color and
+// font-weight props with the given prefix. It reports false if no color is
+// set.
+func buttonStyleFromProps(props Props, prefix string) (uv.Style, bool) {
+	fgColor := props.Get(prefix + "foreground-color")
+	if fgColor == "" {
+		return uv.Style{}, false
+	}
+	c, err := parseColor(fgColor)
+	if err != nil {
+		return uv.Style{}, false
+	}
+	style := uv.Style{Fg: c}
+	if props.Get(prefix+"font-weight") == FontWeightBold {
+		style.Attrs |= uv.AttrBold
+	}
+	return style, true
+}
+
 func init() {
 	Register("button", NewButtonFromProps)
+	RegisterSchema("button", &Schema{Attrs: append([]Attr{
+		{Name: "text"},
+		{Name: "border", Type: AttrEnum, Values: borders},
+		{Name: "padding", Type: AttrInt},
+	}, sizeAttrs...)})
 }
@@ -0,0 +1,135 @@
+// Package main provides the pony command, which checks pony templates in CI.
+//
+// Usage:
+//
+//	pony lint [-allow name,...] [path...]
+//
+// lint validates the .pony files given, and the .pony files found in the
+// given directories and their subdirectories, against the schemas of the
+// built-in elements and components. Paths default to the current directory.
+// Problems are reported one per line as file:line:column: message.
+//
+// Components registered by applications aren't known to the command; list
+// them with -allow to accept them with any attribute.
+//
+// The exit code is 0 if the templates are valid, 1 if problems were found,
+// and 2 on failure.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/pony"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitFailure  = 2
+)
+
+// templateExt is the extension of pony template files.
+const templateExt = ".pony"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "lint" {
+		fmt.Fprintln(stderr, "usage: pony lint [-allow name,...] [path...]")
+		return exitFailure
+	}
+
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	allow := flags.String("allow", "", "comma-separated `names` of components registered by the application")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitFailure
+	}
+
+	var allowed []string
+	for _, name := range strings.Split(*allow, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed = append(allowed, name)
+		}
+	}
+	opts := []pony.ValidateOption{pony.AllowComponents(allowed...)}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := templateFiles(paths)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	code := exitOK
+	for _, file := range files {
+		problems, err := lint(file, opts...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		for _, p := range problems {
+			fmt.Fprintf(stdout, "%s:%s\n", file, p)
+		}
+		if len(problems) > 0 {
+			code = exitProblems
+		}
+	}
+	return code
+}
+
+// lint validates a template file and returns its problems.
+func lint(file string, opts ...pony.ValidateOption) (pony.ValidationErrors, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var problems pony.ValidationErrors
+	if err := pony.Validate(string(data), opts...); !errors.As(err, &problems) && err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return problems, nil
+}
+
+// templateFiles returns the template files of paths, walking directories.
+// Files given explicitly are kept whatever their extension.
+func templateFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(p) == templateExt {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("ok.pony", `<vstack><text font-weight="bold">{{ .Title }}</text></vstack>`)
+	bad := write("views/bad.pony", "<vstack>\n\t<card title=\"x\" />\n\t<text wrap=\"maybe\">x</text>\n</vstack>")
+	write("notes.txt", "<nope/>")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"lint", dir}, &stdout, &stderr); code != exitProblems {
+		t.Fatalf("exit code = %d, want %d; stderr: %s", code, exitProblems, stderr.String())
+	}
+	want := bad + ":2:2: unknown element <card>\n" +
+		bad + ":3:8: wrap: invalid boolean \"maybe\"\n"
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	// Components of the application are accepted with -allow.
+	stdout.Reset()
+	write("views/bad.pony", "<vstack>\n\t<card title=\"x\" />\n</vstack>")
+	if code := run([]string{"lint", "-allow", "card,modal", dir}, &stdout, &stderr); code != exitOK {
+		t.Errorf("exit code = %d, want %d; output: %s", code, exitOK, stdout.String())
+	}
+
+	if code := run([]string{"lint", filepath.Join(dir, "missing")}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("exit code = %d for a missing path, want %d", code, exitFailure)
+	}
+	if code := run(nil, &stdout, &stderr); code != exitFailure {
+		t.Errorf("exit code = %d without a command, want %d", code, exitFailure)
+	}
+}
@@ -162,4 +162,12 @@ func (p *ProgressView) Children() []Element {
 func init() {
 	Register("badge", NewBadge)
 	Register("progressview", NewProgressView)
+
+	RegisterSchema("badge", &Schema{Attrs: []Attr{{Name: "text"}}})
+	RegisterSchema("progressview", &Schema{Attrs: []Attr{
+		{Name: "value", Type: AttrInt},
+		{Name: "max", Type: AttrInt},
+		{Name: "width", Type: AttrSize},
+		{Name: "char"},
+	}})
 }
@@ -17,14 +17,41 @@ const (
 	AlignmentTrailing = "trailing" // Horizontal: right side
 	AlignmentTop      = "top"      // Vertical: top
 	AlignmentBottom   = "bottom"   // Vertical: bottom
+	AlignmentStretch  = "stretch"  // Horizontal and vertical: fill the space
+)
+
+// Popover placements, the side of the anchor the popover goes on.
+const (
+	PlacementTop    = "top"
+	PlacementBottom = "bottom"
+	PlacementLeft   = "left"
+	PlacementRight  = "right"
+)
+
+// Transition easings.
+const (
+	EasingLinear = "linear"      // Constant speed
+	EasingEase   = "ease"        // Same as ease-in-out
+	EasingIn     = "ease-in"     // Starts slow
+	EasingOut    = "ease-out"    // Ends slow
+	EasingInOut  = "ease-in-out" // Starts and ends slow
+)
+
+// Spinner types, the frames of the spinner element.
+const (
+	SpinnerDots   = "dots"
+	SpinnerLine   = "line"
+	SpinnerCircle = "circle"
+	SpinnerPulse  = "pulse"
 )
 
 // Size constraint units.
 const (
-	UnitAuto    = "auto"
-	UnitMin     = "min"
-	UnitMax     = "max"
-	UnitPercent = "%"
+	UnitAuto     = "auto"
+	UnitMin      = "min"
+	UnitMax      = "max"
+	UnitPercent  = "%"
+	UnitFraction = "fr" // Grid tracks: share of the remaining space
 )
 
 // Underline styles (matching UV).
@@ -71,7 +71,7 @@ func (v *VStack) calculateChildSizes(constraints Constraints) []Size {
 				MaxHeight: constraints.MaxHeight - fixedHeight,
 			}
 
-			size := child.Layout(childConstraints)
+			size := layoutElement(child, childConstraints)
 			childSizes[i] = size
 			fixedHeight += size.Height
 		}
@@ -99,7 +99,7 @@ func (v *VStack) calculateChildSizes(constraints Constraints) []Size {
 						MaxHeight: flexHeight,
 					}
 
-					childSizes[i] = child.Layout(childConstraints)
+					childSizes[i] = layoutElement(child, childConstraints)
 				}
 			}
 		}
@@ -157,7 +157,7 @@ func (v *VStack) Draw(scr uv.Screen, area uv.Rectangle) {
 		// Clip to parent bounds
 		childArea = childArea.Intersect(area)
 
-		child.Draw(scr, childArea)
+		drawElement(child, scr, childArea)
 
 		y += childSize.Height
 		if i < len(v.items)-1 {
@@ -277,7 +277,7 @@ func (h *HStack) calculateChildSizes(constraints Constraints) []Size {
 				MaxHeight: constraints.MaxHeight,
 			}
 
-			size := child.Layout(childConstraints)
+			size := layoutElement(child, childConstraints)
 			childSizes[i] = size
 			fixedWidth += size.Width
 		}
@@ -305,7 +305,7 @@ func (h *HStack) calculateChildSizes(constraints Constraints) []Size {
 						MaxHeight: constraints.MaxHeight,
 					}
 
-					childSizes[i] = child.Layout(childConstraints)
+					childSizes[i] = layoutElement(child, childConstraints)
 				}
 			}
 		}
@@ -363,7 +363,7 @@ func (h *HStack) Draw(scr uv.Screen, area uv.Rectangle) {
 		// Clip to parent bounds
 		childArea = childArea.Intersect(area)
 
-		child.Draw(scr, childArea)
+		drawElement(child, scr, childArea)
 
 		x += childSize.Width
 		if i < len(h.items)-1 {
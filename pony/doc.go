@@ -38,10 +38,17 @@
 //   - hstack: Horizontal stack container with spacing and alignment
 //   - text: Text content with styling and alignment
 //   - box: Container with borders and padding
+//   - grid: Rows and columns sized by fixed, percent, fr and auto tracks,
+//     with spans and named areas
+//   - dialog: Modal overlay centered over the screen
+//   - popover: Overlay placed next to an anchor element
 //   - scrollview: Scrollable viewport with scrollbars
+//   - list, table, tree: Rows of data with a selected row, drawn only
+//     where visible so they stay fast inside a scrollview
 //   - divider: Horizontal or vertical separator line
 //   - spacer: Flexible or fixed empty space
 //   - slot: Placeholder for dynamic content
+//   - spinner: Activity indicator moving with the template's clock
 //
 // # Styling
 //
@@ -56,6 +63,15 @@
 //	    Bold().
 //	    Italic()
 //
+// Stylesheets set attributes by selector, in a <style> block or with
+// Template.WithStylesheet, and support classes, IDs, descendants and the
+// :focus, :hover and :active pseudo-classes:
+//
+//	<style>
+//	    .sidebar text { foreground-color: gray; }
+//	    button:hover { foreground-color: yellow; }
+//	</style>
+//
 // # Custom Components
 //
 // Register custom components with the component registry:
@@ -70,6 +86,13 @@
 //
 //	<card><text>Content</text></card>
 //
+// # Validation
+//
+// Validate and ParseStrict report unknown elements, unknown attributes and
+// invalid values with their line and column, which rendering silently
+// replaces with defaults. Register a Schema for custom components with
+// RegisterSchema. The pony lint command in cmd/pony checks template files.
+//
 // # Stateful Components
 //
 // Use slots for stateful components that manage their own state:
@@ -98,6 +121,63 @@
 //	}
 //	output := tmpl.RenderWithSlots(data, slots, width, height)
 //
+// # Focus
+//
+// Elements become focusable with the focusable or tab-index attributes, and
+// focus: attributes apply only while the element has focus:
+//
+//	<box id="name" focusable="true" border-color="gray" focus:border-color="cyan">
+//
+// A FocusManager tracks the focused element and moves it with Tab, Shift+Tab
+// and the arrow keys:
+//
+//	m.focus.HandleKey(msg.String())
+//	scr, boundsMap := tmpl.RenderWithFocus(data, slots, m.focus, width, height)
+//
+// # Text Input
+//
+// The input and textarea elements edit text. Their state is kept in an
+// InputStore, keyed by element ID, and the bounds map reports where to put
+// the terminal cursor:
+//
+//	<input id="email" placeholder="you@example.com" />
+//
+//	m.inputs.HandleKey(m.focus.Focused(), uv.KeyPressEvent(msg))
+//	scr, boundsMap := tmpl.RenderWithInputs(data, slots, m.focus, m.inputs, width, height)
+//	pos, ok := boundsMap.Cursor()
+//
+// # Retained Mode
+//
+// A Renderer renders a template while keeping the previous frame, so that
+// only the elements that changed are rebuilt, laid out and drawn:
+//
+//	r := pony.NewRenderer(tmpl)
+//	scr, boundsMap := r.Render(data, slots, m.focus, m.inputs, width, height)
+//
+// # Animations
+//
+// A Clock set with Template.WithClock animates spinners and the attributes
+// named in transition attributes, such as transition="width 200ms ease-out".
+// Tick it with the frame time while it isn't idle:
+//
+//	m.clock.Tick(time.Time(msg))
+//	if !m.clock.Idle() {
+//	    cmd = m.frame()
+//	}
+//
+// # Events
+//
+// Elements name handlers with on-click, on-scroll and on-key attributes. A
+// Dispatcher routes events to the element under the pointer or the focused
+// element and bubbles them up through its parents, returning the results of
+// the handlers, such as tea.Cmd values:
+//
+//	events := pony.NewDispatcher(map[string]pony.HandlerFunc[tea.Cmd]{
+//	    "save": func(e *pony.Event) tea.Cmd { return saveCmd },
+//	})
+//	events.Update(boundsMap)
+//	cmd := tea.Batch(events.Click(msg.X, msg.Y)...)
+//
 // # Bubble Tea Integration
 //
 //	type model struct {
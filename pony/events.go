@@ -0,0 +1,234 @@
+package pony
+
+import "strings"
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Event types. Elements declare handlers for them in markup with on-
+// attributes, such as on-click="save".
+const (
+	EventClick  EventType = "click"  // Mouse click at X, Y
+	EventScroll EventType = "scroll" // Mouse wheel at X, Y, by Delta lines
+	EventKey    EventType = "key"    // Key press on the focused element
+)
+
+// handlerPrefix is the prefix of the attributes that declare handlers.
+const handlerPrefix = "on-"
+
+// Event is an input event routed to an element. It starts at the target,
+// the top-most element under the pointer or the focused element, and bubbles
+// up through its parents until a handler stops it.
+type Event struct {
+	Type EventType
+
+	// Target is the element the event was routed to.
+	Target Element
+
+	// Current is the element whose handler is running.
+	Current Element
+
+	// X and Y are the screen coordinates of mouse events.
+	X, Y int
+
+	// Delta is the number of lines scrolled, negative for up.
+	Delta int
+
+	// Key is the key of key events, in the form of Bubble Tea's key
+	// strings, such as "enter" or "ctrl+s".
+	Key string
+
+	stopped bool
+}
+
+// StopPropagation keeps the event from bubbling up to the parents of the
+// current element.
+func (e *Event) StopPropagation() {
+	e.stopped = true
+}
+
+// Stopped reports whether a handler stopped the event.
+func (e *Event) Stopped() bool {
+	return e.stopped
+}
+
+// SetHandler names the handler of an event type on the element. An empty
+// name removes it.
+func (b *BaseElement) SetHandler(event EventType, name string) {
+	if name == "" {
+		delete(b.handlers, event)
+		return
+	}
+	if b.handlers == nil {
+		b.handlers = make(map[EventType]string)
+	}
+	b.handlers[event] = name
+}
+
+// Handler returns the name of the handler of an event type on the element.
+func (b *BaseElement) Handler(event EventType) string {
+	return b.handlers[event]
+}
+
+// namedHandlers is implemented by elements with named handlers, which
+// BaseElement provides.
+type namedHandlers interface {
+	Handler(event EventType) string
+}
+
+// EventHandler is implemented by elements that handle events themselves,
+// such as stateful components. HandleEvent runs before the named handler of
+// the element, and returns a result like a HandlerFunc.
+type EventHandler[C any] interface {
+	HandleEvent(e *Event) C
+}
+
+// HandlerFunc handles an event. The result, typically a tea.Cmd, is returned
+// by the Dispatcher.
+type HandlerFunc[C any] func(e *Event) C
+
+// Dispatcher routes events to the handlers of the elements of the last
+// render. Mouse events go to the top-most element under the pointer and key
+// events to the focused element, or to the root when nothing has focus.
+// Events then bubble up through the parents, running each handler on the
+// way, until one calls StopPropagation.
+//
+// C is the result of the handlers. With Bubble Tea, use tea.Cmd and batch
+// the results:
+//
+//	m.events = pony.NewDispatcher(map[string]pony.HandlerFunc[tea.Cmd]{
+//	    "save": func(e *pony.Event) tea.Cmd { return saveCmd },
+//	    "quit": func(e *pony.Event) tea.Cmd { return tea.Quit },
+//	})
+//
+//	// In Update
+//	case tea.MouseClickMsg:
+//	    return m, tea.Batch(m.events.Click(msg.X, msg.Y)...)
+//
+//	// In View
+//	scr, boundsMap := m.template.RenderWithBounds(data, slots, m.width, m.height)
+//	m.events.Update(boundsMap)
+type Dispatcher[C any] struct {
+	handlers map[string]HandlerFunc[C]
+	bounds   *BoundsMap
+}
+
+// NewDispatcher creates a dispatcher with the given named handlers.
+func NewDispatcher[C any](handlers map[string]HandlerFunc[C]) *Dispatcher[C] {
+	d := &Dispatcher[C]{handlers: make(map[string]HandlerFunc[C], len(handlers))}
+	for name, fn := range handlers {
+		d.handlers[name] = fn
+	}
+	return d
+}
+
+// Handle adds a named handler and returns the dispatcher for chaining.
+func (d *Dispatcher[C]) Handle(name string, fn HandlerFunc[C]) *Dispatcher[C] {
+	d.handlers[name] = fn
+	return d
+}
+
+// Update records the elements of a render. Events are routed using the
+// bounds of the last render.
+func (d *Dispatcher[C]) Update(bm *BoundsMap) {
+	d.bounds = bm
+}
+
+// Click dispatches a click at the given screen coordinates and returns the
+// results of the handlers that ran.
+func (d *Dispatcher[C]) Click(x, y int) []C {
+	return d.Dispatch(&Event{Type: EventClick, X: x, Y: y})
+}
+
+// Scroll dispatches a mouse wheel event at the given screen coordinates and
+// returns the results of the handlers that ran.
+func (d *Dispatcher[C]) Scroll(x, y, delta int) []C {
+	return d.Dispatch(&Event{Type: EventScroll, X: x, Y: y, Delta: delta})
+}
+
+// Key dispatches a key press to the focused element and returns the results
+// of the handlers that ran.
+func (d *Dispatcher[C]) Key(key string) []C {
+	return d.Dispatch(&Event{Type: EventKey, Key: key})
+}
+
+// Dispatch routes an event and returns the results of the handlers that
+// ran, in bubbling order. Key events go to the focused element and other
+// events to the element at X, Y. Target and Current are set by Dispatch.
+func (d *Dispatcher[C]) Dispatch(e *Event) []C {
+	if d.bounds == nil {
+		return nil
+	}
+
+	var index int
+	if e.Type == EventKey {
+		index = d.bounds.indexOf(d.bounds.focused)
+	} else {
+		index = d.bounds.topAt(e.X, e.Y)
+	}
+	if index < 0 {
+		return nil
+	}
+	e.Target = d.bounds.byPosition[index].elem
+
+	var results []C
+	for ; index >= 0 && !e.stopped; index = d.bounds.byPosition[index].parent {
+		e.Current = d.bounds.byPosition[index].elem
+		if h, ok := e.Current.(EventHandler[C]); ok {
+			results = append(results, h.HandleEvent(e))
+			if e.stopped {
+				break
+			}
+		}
+		if named, ok := e.Current.(namedHandlers); ok {
+			if fn, ok := d.handlers[named.Handler(e.Type)]; ok {
+				results = append(results, fn(e))
+			}
+		}
+	}
+	return results
+}
+
+// topAt returns the index of the top-most element at the given screen
+// coordinates, or -1.
+func (bm *BoundsMap) topAt(x, y int) int {
+	layer := bm.layerAt(x, y)
+	for i := len(bm.byPosition) - 1; i >= 0; i-- {
+		if eb := bm.byPosition[i]; eb.layer == layer && pointInRect(x, y, eb.bounds) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOf returns the index of the element with the given ID, the root if
+// the ID is empty, or -1.
+func (bm *BoundsMap) indexOf(id string) int {
+	if id == "" {
+		if len(bm.byPosition) == 0 {
+			return -1
+		}
+		return 0
+	}
+	for i := len(bm.byPosition) - 1; i >= 0; i-- {
+		if bm.byPosition[i].id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// setHandlers sets the handlers declared with on- attributes.
+func setHandlers(elem Element, props Props) {
+	setter, ok := elem.(interface {
+		SetHandler(event EventType, name string)
+	})
+	if !ok {
+		return
+	}
+	for key, name := range props {
+		if event, ok := strings.CutPrefix(key, handlerPrefix); ok {
+			setter.SetHandler(EventType(event), name)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package pony
+
+import (
+	"slices"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+const eventsMarkup = `<vstack id="root" on-key="shortcut">
+	<text id="label">Label</text>
+	<box id="card" border="rounded" on-click="select">
+		<button id="save" text="Save" on-click="save" />
+	</box>
+</vstack>`
+
+func TestDispatcher_Bubbling(t *testing.T) {
+	var stop bool
+	handled := func(name string) HandlerFunc[string] {
+		return func(e *Event) string {
+			if stop {
+				e.StopPropagation()
+			}
+			return name + ":" + e.Current.ID()
+		}
+	}
+	events := NewDispatcher(map[string]HandlerFunc[string]{
+		"save":   handled("save"),
+		"select": handled("select"),
+	})
+
+	tmpl := MustParse[struct{}](eventsMarkup)
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 20, 6)
+	events.Update(bm)
+
+	bounds, _ := bm.GetBounds("save")
+	got := events.Click(bounds.Min.X, bounds.Min.Y)
+	if want := []string{"save:save", "select:card"}; !slices.Equal(got, want) {
+		t.Errorf("click on the button: got %q, want %q", got, want)
+	}
+
+	// The border of the card is outside the button.
+	bounds, _ = bm.GetBounds("card")
+	if got, want := events.Click(bounds.Min.X, bounds.Min.Y), []string{"select:card"}; !slices.Equal(got, want) {
+		t.Errorf("click on the card: got %q, want %q", got, want)
+	}
+
+	stop = true
+	bounds, _ = bm.GetBounds("save")
+	if got, want := events.Click(bounds.Min.X, bounds.Min.Y), []string{"save:save"}; !slices.Equal(got, want) {
+		t.Errorf("stopped click: got %q, want %q", got, want)
+	}
+
+	bounds, _ = bm.GetBounds("label")
+	if got := events.Click(bounds.Min.X, bounds.Min.Y); len(got) != 0 {
+		t.Errorf("expected no handler for the label, got %q", got)
+	}
+}
+
+func TestDispatcher_Key(t *testing.T) {
+	var keys []string
+	events := NewDispatcher(map[string]HandlerFunc[bool]{
+		"shortcut": func(e *Event) bool {
+			keys = append(keys, e.Key+"@"+e.Target.ID())
+			return true
+		},
+	})
+
+	tmpl := MustParse[struct{}](eventsMarkup)
+	focus := NewFocusManager()
+	focus.Focus("save")
+	_, bm := tmpl.RenderWithFocus(struct{}{}, nil, focus, 20, 6)
+	events.Update(bm)
+
+	// Keys go to the focused element and bubble up to the root.
+	events.Key("ctrl+s")
+	focus.Blur()
+	_, bm = tmpl.RenderWithFocus(struct{}{}, nil, focus, 20, 6)
+	events.Update(bm)
+	events.Key("q")
+
+	if want := []string{"ctrl+s@save", "q@root"}; !slices.Equal(keys, want) {
+		t.Errorf("got %q, want %q", keys, want)
+	}
+}
+
+// counter handles its own clicks.
+type counter struct {
+	Text
+	clicks int
+}
+
+func (c *counter) HandleEvent(e *Event) string {
+	if e.Type != EventClick {
+		return ""
+	}
+	c.clicks++
+	e.StopPropagation()
+	return "counted"
+}
+
+func TestDispatcher_EventHandler(t *testing.T) {
+	c := &counter{Text: *NewText("0")}
+	c.SetHandler(EventClick, "never")
+	root := NewVStack(c)
+	root.SetHandler(EventClick, "never")
+
+	events := NewDispatcher(map[string]HandlerFunc[string]{
+		"never": func(e *Event) string {
+			t.Errorf("unexpected handler on %s", e.Current.ID())
+			return ""
+		},
+	})
+	events.Update(renderTree(root, 10, 2))
+
+	if got := events.Click(0, 0); !slices.Equal(got, []string{"counted"}) || c.clicks != 1 {
+		t.Errorf("got %q after %d clicks", got, c.clicks)
+	}
+	if got := events.Scroll(0, 0, 1); len(got) != 1 || got[0] != "" {
+		t.Errorf("expected the scroll to reach the handler only, got %q", got)
+	}
+}
+
+// renderTree draws an element tree and returns its bounds.
+func renderTree(elem Element, width, height int) *BoundsMap {
+	scr := uv.NewScreenBuffer(width, height)
+	elem.Layout(Constraints{MaxWidth: width, MaxHeight: height})
+	elem.Draw(scr, scr.Bounds())
+	bm := NewBoundsMap()
+	walkAndRegister(elem, bm)
+	return bm
+}
@@ -28,13 +28,13 @@ func main() {
 	<vstack spacing="0">
 		<text font-weight="bold">Text Alignment:</text>
 		<box border="normal">
-			<text alignment="left">Left aligned text</text>
+			<text alignment="leading">Left aligned text</text>
 		</box>
 		<box border="normal">
 			<text alignment="center">Center aligned text</text>
 		</box>
 		<box border="normal">
-			<text alignment="right">Right aligned text</text>
+			<text alignment="trailing">Right aligned text</text>
 		</box>
 	</vstack>
 
@@ -58,7 +58,7 @@ func main() {
 	<vstack spacing="0">
 		<text font-weight="bold">VStack Alignment (children):</text>
 		<box border="normal">
-			<vstack alignment="left">
+			<vstack alignment="leading">
 				<text>Left</text>
 				<text>Aligned</text>
 			</vstack>
@@ -70,7 +70,7 @@ func main() {
 			</vstack>
 		</box>
 		<box border="normal">
-			<vstack alignment="right">
+			<vstack alignment="trailing">
 				<text>Right</text>
 				<text>Aligned</text>
 			</vstack>
@@ -88,7 +88,7 @@ func main() {
 			</hstack>
 		</box>
 		<box border="normal" height="5">
-			<hstack spacing="2" alignment="middle">
+			<hstack spacing="2" alignment="center">
 				<text>Middle</text>
 				<text>Aligned</text>
 			</hstack>
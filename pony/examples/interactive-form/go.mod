@@ -10,9 +10,10 @@ require (
 	github.com/charmbracelet/x/pony v0.0.0-00010101000000-000000000000
 )
 
+require github.com/charmbracelet/ultraviolet v0.0.0-20251120225753-26363bddd922
+
 require (
 	github.com/charmbracelet/colorprofile v0.3.3 // indirect
-	github.com/charmbracelet/ultraviolet v0.0.0-20251120225753-26363bddd922 // indirect
 	github.com/charmbracelet/x/ansi v0.11.1 // indirect
 	github.com/charmbracelet/x/term v0.2.2 // indirect
 	github.com/charmbracelet/x/termios v0.1.1 // indirect
@@ -6,105 +6,10 @@ import (
 	"strings"
 
 	tea "charm.land/bubbletea/v2"
+	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/charmbracelet/x/pony"
 )
 
-// Input is a stateful text input component
-type Input struct {
-	pony.BaseElement
-	label       string
-	value       string
-	placeholder string
-	focused     bool
-	cursorPos   int
-}
-
-func NewInput(label, placeholder string) *Input {
-	return &Input{
-		label:       label,
-		placeholder: placeholder,
-		focused:     false,
-	}
-}
-
-func (i *Input) Update(msg tea.Msg) {
-	if !i.focused {
-		return
-	}
-
-	if key, ok := msg.(tea.KeyPressMsg); ok {
-		switch key.String() {
-		case "backspace":
-			if len(i.value) > 0 && i.cursorPos > 0 {
-				i.value = i.value[:i.cursorPos-1] + i.value[i.cursorPos:]
-				i.cursorPos--
-			}
-		case "left":
-			if i.cursorPos > 0 {
-				i.cursorPos--
-			}
-		case "right":
-			if i.cursorPos < len(i.value) {
-				i.cursorPos++
-			}
-		case "home":
-			i.cursorPos = 0
-		case "end":
-			i.cursorPos = len(i.value)
-		default:
-			// Handle printable characters
-			if len(key.String()) == 1 {
-				i.value = i.value[:i.cursorPos] + key.String() + i.value[i.cursorPos:]
-				i.cursorPos++
-			}
-		}
-	}
-}
-
-func (i *Input) Render() pony.Element {
-	displayText := i.value
-	if displayText == "" {
-		displayText = i.placeholder
-	}
-
-	// Add cursor if focused
-	if i.focused && i.cursorPos <= len(displayText) {
-		if i.cursorPos == len(displayText) {
-			displayText += "│"
-		} else {
-			displayText = displayText[:i.cursorPos] + "│" + displayText[i.cursorPos:]
-		}
-	}
-
-	borderColor := pony.RGB(100, 100, 100)
-	textColor := pony.RGB(255, 255, 255)
-	if i.focused {
-		borderColor = pony.Hex("#00FFFF")
-		textColor = pony.Hex("#00FFFF")
-	} else if i.value == "" {
-		textColor = pony.RGB(128, 128, 128)
-	}
-
-	// Create the VStack and set the input's ID on it
-	// so clicks anywhere in the input will register as clicking this input
-	vstack := pony.NewVStack(
-		pony.NewText(i.label).Bold(),
-		pony.NewBox(
-			pony.NewText(displayText).ForegroundColor(textColor),
-		).Border("rounded").
-			BorderColor(borderColor).
-			Padding(1).
-			Width(pony.NewFixedConstraint(50)),
-	)
-	vstack.SetID(i.ID())  // Set the input's ID on the rendered element
-	
-	return vstack
-}
-
-func (i *Input) Value() string   { return i.value }
-func (i *Input) SetFocus(f bool) { i.focused = f }
-func (i *Input) IsFocused() bool { return i.focused }
-
 // ButtonBar is a component that renders action buttons
 type ButtonBar struct {
 	pony.BaseElement
@@ -123,13 +28,15 @@ func NewButtonBar(showSubmit, showClear, showQuit bool) *ButtonBar {
 
 func (b *ButtonBar) Render() pony.Element {
 	buttons := []pony.Element{}
+	focusStyle := pony.NewStyle().Fg(pony.Hex("#00FFFF")).Bold().Build()
 
 	if b.showSubmit {
 		submitBtn := pony.NewButton("Submit")
 		submitBtn.SetID("submit-btn")
 		submitBtn = submitBtn.Border("rounded").
 			Padding(1).
-			Style(pony.NewStyle().Fg(pony.Hex("#00FF00")).Bold().Build())
+			Style(pony.NewStyle().Fg(pony.Hex("#00FF00")).Bold().Build()).
+			FocusStyle(focusStyle)
 		buttons = append(buttons, submitBtn)
 	}
 
@@ -138,7 +45,8 @@ func (b *ButtonBar) Render() pony.Element {
 		clearBtn.SetID("clear-btn")
 		clearBtn = clearBtn.Border("rounded").
 			Padding(1).
-			Style(pony.NewStyle().Fg(pony.Hex("#FFFF00")).Build())
+			Style(pony.NewStyle().Fg(pony.Hex("#FFFF00")).Build()).
+			FocusStyle(focusStyle)
 		buttons = append(buttons, clearBtn)
 	}
 
@@ -147,7 +55,8 @@ func (b *ButtonBar) Render() pony.Element {
 		quitBtn.SetID("quit-btn")
 		quitBtn = quitBtn.Border("rounded").
 			Padding(1).
-			Style(pony.NewStyle().Fg(pony.Hex("#FF0000")).Build())
+			Style(pony.NewStyle().Fg(pony.Hex("#FF0000")).Build()).
+			FocusStyle(focusStyle)
 		buttons = append(buttons, quitBtn)
 	}
 
@@ -165,15 +74,16 @@ const tmpl = `
 
 	<vstack spacing="1">
 		<text font-weight="bold" foreground-color="cyan">User Registration Form</text>
-		<text foreground-color="gray" font-style="italic">Click inputs to focus, type to fill, click buttons to submit</text>
+		<text foreground-color="gray" font-style="italic">Tab or click to focus, type to fill, enter to submit</text>
 	</vstack>
 
 	<divider foreground-color="gray" />
 
-	<vstack spacing="2">
-		<slot name="name-input" />
-		<slot name="email-input" />
-		<slot name="username-input" />
+	<vstack spacing="1">
+		{{ template "field" (field "name-input" "Full Name:" "Enter your full name..." .FocusedInput) }}
+		{{ template "field" (field "email-input" "Email Address:" "your.email@example.com" .FocusedInput) }}
+		{{ template "field" (field "username-input" "Username:" "Choose a username..." .FocusedInput) }}
+		{{ template "field" (field "password-input" "Password:" "" .FocusedInput) }}
 	</vstack>
 
 	<divider foreground-color="gray" />
@@ -198,6 +108,7 @@ const tmpl = `
 			<text>Name: {{ .Name }}</text>
 			<text>Email: {{ .Email }}</text>
 			<text>Username: {{ .Username }}</text>
+			<text>Password: {{ .Password }}</text>
 		</vstack>
 	</box>
 	{{ end }}
@@ -207,12 +118,33 @@ const tmpl = `
 	<text foreground-color="gray" font-style="italic">Focused: {{ .FocusedInput }}</text>
 	<text foreground-color="gray" font-style="italic">Hover: {{ .HoveredElement }}</text>
 </vstack>
+
+{{ define "field" }}
+<vstack>
+	<text font-weight="bold">{{ .Label }}</text>
+	<box border="rounded" border-color="{{ if .Focused }}#00FFFF{{ else }}#646464{{ end }}" padding="1" width="50">
+		<input id="{{ .ID }}" placeholder="{{ .Placeholder }}" width="max"{{ if eq .ID "password-input" }} type="password"{{ end }} />
+	</box>
+</vstack>
+{{ end }}
 `
 
+// Field is the data of the field template.
+type Field struct {
+	ID          string
+	Label       string
+	Placeholder string
+	Focused     bool
+}
+
+// fields lists the IDs of the form inputs.
+var fields = []string{"name-input", "email-input", "username-input", "password-input"}
+
 type ViewData struct {
 	Name           string
 	Email          string
 	Username       string
+	Password       string
 	FocusedInput   string
 	HoveredElement string
 	ShowStatus     bool
@@ -223,9 +155,8 @@ type ViewData struct {
 
 type model struct {
 	template       *pony.Template[ViewData]
-	nameInput      *Input
-	emailInput     *Input
-	usernameInput  *Input
+	focus          *pony.FocusManager
+	inputs         *pony.InputStore
 	buttonBar      *ButtonBar
 	width          int
 	height         int
@@ -237,32 +168,30 @@ type model struct {
 	submittedName  string
 	submittedEmail string
 	submittedUser  string
+	submittedPass  string
 }
 
 func initialModel() model {
-	nameInput := NewInput("Full Name:", "Enter your full name...")
-	nameInput.SetID("name-input")
-
-	emailInput := NewInput("Email Address:", "your.email@example.com")
-	emailInput.SetID("email-input")
-
-	usernameInput := NewInput("Username:", "Choose a username...")
-	usernameInput.SetID("username-input")
-
 	// Create button bar component
 	buttonBar := NewButtonBar(true, true, true)
 
 	// Focus the first input by default
-	nameInput.SetFocus(true)
+	focus := pony.NewFocusManager()
+	focus.Focus("name-input")
+
+	funcs := map[string]any{
+		"field": func(id, label, placeholder, focused string) Field {
+			return Field{ID: id, Label: label, Placeholder: placeholder, Focused: id == focused}
+		},
+	}
 
 	return model{
-		template:      pony.MustParse[ViewData](tmpl),
-		nameInput:     nameInput,
-		emailInput:    emailInput,
-		usernameInput: usernameInput,
-		buttonBar:     buttonBar,
-		width:         80,
-		height:        30,
+		template:  pony.MustParseWithFuncs[ViewData](tmpl, funcs),
+		focus:     focus,
+		inputs:    pony.NewInputStore(),
+		buttonBar: buttonBar,
+		width:     80,
+		height:    30,
 	}
 }
 
@@ -285,46 +214,52 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
 
-		case "tab":
-			// Cycle focus through inputs
-			if m.nameInput.IsFocused() {
-				m.nameInput.SetFocus(false)
-				m.emailInput.SetFocus(true)
-			} else if m.emailInput.IsFocused() {
-				m.emailInput.SetFocus(false)
-				m.usernameInput.SetFocus(true)
-			} else if m.usernameInput.IsFocused() {
-				m.usernameInput.SetFocus(false)
-				m.nameInput.SetFocus(true)
+		case "enter":
+			// Press the focused button, or move on to the next field
+			switch id := m.focus.Focused(); id {
+			case "submit-btn", "clear-btn", "quit-btn":
+				return m.Update(buttonClickMsg(id))
+			default:
+				m.focus.Next()
 			}
 
 		default:
-			// Route to focused input
-			m.nameInput.Update(msg)
-			m.emailInput.Update(msg)
-			m.usernameInput.Update(msg)
+			// Let the focused input edit its text, and use the remaining
+			// keys to move focus through inputs and buttons
+			if !m.inputs.HandleKey(m.focus.Focused(), uv.KeyPressEvent(msg)) {
+				m.focus.HandleKey(msg.String())
+			}
+		}
+
+	case tea.PasteMsg:
+		if state := m.inputs.State(m.focus.Focused()); state != nil {
+			state.Insert(msg.Content)
 		}
 
 	case buttonClickMsg:
 		switch msg {
 		case "submit-btn":
 			// Validate and submit
-			if m.nameInput.Value() == "" {
+			name := m.inputs.Value("name-input")
+			email := m.inputs.Value("email-input")
+			username := m.inputs.Value("username-input")
+			password := m.inputs.Value("password-input")
+			if name == "" {
 				m.showStatus = true
 				m.statusMessage = "❌ Please enter your name"
 				m.statusColor = "red"
 				m.showData = false
-			} else if m.emailInput.Value() == "" {
+			} else if email == "" {
 				m.showStatus = true
 				m.statusMessage = "❌ Please enter your email"
 				m.statusColor = "red"
 				m.showData = false
-			} else if m.usernameInput.Value() == "" {
+			} else if username == "" {
 				m.showStatus = true
 				m.statusMessage = "❌ Please choose a username"
 				m.statusColor = "red"
 				m.showData = false
-			} else if !strings.Contains(m.emailInput.Value(), "@") {
+			} else if !strings.Contains(email, "@") {
 				m.showStatus = true
 				m.statusMessage = "❌ Please enter a valid email address"
 				m.statusColor = "red"
@@ -335,19 +270,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.statusMessage = "✅ Form submitted successfully!"
 				m.statusColor = "green"
 				m.showData = true
-				m.submittedName = m.nameInput.Value()
-				m.submittedEmail = m.emailInput.Value()
-				m.submittedUser = m.usernameInput.Value()
+				m.submittedName = name
+				m.submittedEmail = email
+				m.submittedUser = username
+				m.submittedPass = strings.Repeat("*", len([]rune(password)))
 			}
 
 		case "clear-btn":
 			// Clear all inputs
-			m.nameInput.value = ""
-			m.nameInput.cursorPos = 0
-			m.emailInput.value = ""
-			m.emailInput.cursorPos = 0
-			m.usernameInput.value = ""
-			m.usernameInput.cursorPos = 0
+			for _, id := range fields {
+				m.inputs.SetValue(id, "")
+			}
 			m.showStatus = true
 			m.statusMessage = "🗑️  Form cleared"
 			m.statusColor = "yellow"
@@ -356,11 +289,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "quit-btn":
 			return m, tea.Quit
 
-		case "name-input", "email-input", "username-input":
+		case "name-input", "email-input", "username-input", "password-input":
 			// Focus the clicked input
-			m.nameInput.SetFocus(msg == "name-input")
-			m.emailInput.SetFocus(msg == "email-input")
-			m.usernameInput.SetFocus(msg == "username-input")
+			m.focus.Focus(string(msg))
 		}
 
 	case hoverMsg:
@@ -372,19 +303,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m model) View() tea.View {
 	// Prepare data
-	focusedInput := "none"
-	if m.nameInput.IsFocused() {
-		focusedInput = "name"
-	} else if m.emailInput.IsFocused() {
-		focusedInput = "email"
-	} else if m.usernameInput.IsFocused() {
-		focusedInput = "username"
+	focusedInput := m.focus.Focused()
+	if focusedInput == "" {
+		focusedInput = "none"
 	}
 
 	data := ViewData{
-		Name:           m.nameInput.Value(),
-		Email:          m.emailInput.Value(),
-		Username:       m.usernameInput.Value(),
+		Name:           m.submittedName,
+		Email:          m.submittedEmail,
+		Username:       m.submittedUser,
+		Password:       m.submittedPass,
 		FocusedInput:   focusedInput,
 		HoveredElement: m.hoveredElement,
 		ShowStatus:     m.showStatus,
@@ -395,16 +323,17 @@ func (m model) View() tea.View {
 
 	// Fill slots with stateful components
 	slots := map[string]pony.Element{
-		"name-input":     m.nameInput.Render(),
-		"email-input":    m.emailInput.Render(),
-		"username-input": m.usernameInput.Render(),
-		"button-bar":     m.buttonBar.Render(),
+		"button-bar": m.buttonBar.Render(),
 	}
 
-	// Render with bounds
-	scr, boundsMap := m.template.RenderWithBounds(data, slots, m.width, m.height)
+	// Render with bounds, marking the focused element and keeping the
+	// text of the inputs in the input store
+	scr, boundsMap := m.template.RenderWithInputs(data, slots, m.focus, m.inputs, m.width, m.height)
 
 	view := tea.NewView(scr.Render())
+	if pos, ok := boundsMap.Cursor(); ok {
+		view.Cursor = tea.NewCursor(pos.X, pos.Y)
+	}
 	view.AltScreen = true
 	view.MouseMode = tea.MouseModeAllMotion
 
@@ -47,7 +47,7 @@ func (f *Flex) Draw(scr uv.Screen, area uv.Rectangle) {
 	f.SetBounds(area)
 
 	if f.child != nil {
-		f.child.Draw(scr, area)
+		drawElement(f.child, scr, area)
 	}
 }
 
@@ -63,10 +63,10 @@ func (f *Flex) Layout(constraints Constraints) Size {
 		flexConstraints := constraints
 		flexConstraints.MinWidth = min(f.basis, constraints.MaxWidth)
 		flexConstraints.MinHeight = min(f.basis, constraints.MaxHeight)
-		return f.child.Layout(flexConstraints)
+		return layoutElement(f.child, flexConstraints)
 	}
 
-	return f.child.Layout(constraints)
+	return layoutElement(f.child, constraints)
 }
 
 // Children returns the child element.
@@ -0,0 +1,441 @@
+package pony
+
+import (
+	"slices"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Focusable is implemented by elements that can receive keyboard focus.
+// BaseElement provides its methods, so any element can be made focusable with
+// SetFocusable or SetTabIndex, or with the focusable and tab-index attributes
+// in markup. Only focusable elements with an explicit ID take part in focus
+// navigation, since generated IDs change between renders.
+type Focusable interface {
+	Element
+
+	// IsFocusable reports whether the element can receive focus.
+	IsFocusable() bool
+
+	// TabIndex returns the element's position in the tab order.
+	TabIndex() int
+
+	// IsFocused reports whether the element has focus.
+	IsFocused() bool
+
+	// SetFocused records whether the element has focus.
+	SetFocused(focused bool)
+}
+
+// SetFocusable sets whether the element can receive keyboard focus.
+func (b *BaseElement) SetFocusable(focusable bool) {
+	b.focusable = focusable
+}
+
+// IsFocusable reports whether the element can receive keyboard focus.
+func (b *BaseElement) IsFocusable() bool {
+	return b.focusable
+}
+
+// SetTabIndex sets the element's position in the tab order and makes it
+// focusable. Elements with a positive index come first, in increasing order,
+// followed by elements with index zero in tree order. Elements with a
+// negative index can be focused by clicking or programmatically, but are
+// skipped by Tab.
+func (b *BaseElement) SetTabIndex(index int) {
+	b.tabIndex = index
+	b.focusable = true
+}
+
+// TabIndex returns the element's position in the tab order.
+func (b *BaseElement) TabIndex() int {
+	return b.tabIndex
+}
+
+// SetFocused records whether the element has focus.
+// FocusManager calls it on every focusable element before drawing.
+func (b *BaseElement) SetFocused(focused bool) {
+	b.focused = focused
+}
+
+// IsFocused reports whether the element has focus.
+// Elements can check it in Draw to render a focus style.
+func (b *BaseElement) IsFocused() bool {
+	return b.focused
+}
+
+// SetHovered records whether the pointer is over the element.
+// FocusManager calls it on every element before drawing.
+func (b *BaseElement) SetHovered(hovered bool) {
+	b.hovered = hovered
+}
+
+// IsHovered reports whether the pointer is over the element.
+func (b *BaseElement) IsHovered() bool {
+	return b.hovered
+}
+
+// SetActive records whether the element is being pressed.
+// FocusManager calls it on every element before drawing.
+func (b *BaseElement) SetActive(active bool) {
+	b.active = active
+}
+
+// IsActive reports whether the element is being pressed.
+func (b *BaseElement) IsActive() bool {
+	return b.active
+}
+
+// pointerElement is implemented by elements that track the pointer state,
+// which BaseElement provides.
+type pointerElement interface {
+	SetHovered(hovered bool)
+	SetActive(active bool)
+}
+
+// FocusedID returns the ID of the focused element, or an empty string if no
+// element has focus.
+func (bm *BoundsMap) FocusedID() string {
+	return bm.focused
+}
+
+// FocusOrder returns the IDs of the focusable elements in tab order.
+// Elements with a negative tab index are left out, and so are the elements
+// beneath an open dialog.
+func (bm *BoundsMap) FocusOrder() []string {
+	var positive, zero []elementBounds
+	for _, eb := range bm.reachable() {
+		switch index := eb.elem.(Focusable).TabIndex(); {
+		case index > 0:
+			positive = append(positive, eb)
+		case index == 0:
+			zero = append(zero, eb)
+		}
+	}
+	slices.SortStableFunc(positive, func(a, b elementBounds) int {
+		return a.elem.(Focusable).TabIndex() - b.elem.(Focusable).TabIndex()
+	})
+
+	order := make([]string, 0, len(positive)+len(zero))
+	for _, eb := range append(positive, zero...) {
+		order = append(order, eb.id)
+	}
+	return order
+}
+
+// reachable returns the focusable elements that can get focus: those of the
+// top-most modal layer and above when a dialog is open, all of them
+// otherwise.
+func (bm *BoundsMap) reachable() []elementBounds {
+	modal := bm.modalLayer()
+	if modal == 0 {
+		return bm.focusables
+	}
+	var reachable []elementBounds
+	for _, eb := range bm.focusables {
+		if eb.layer >= modal {
+			reachable = append(reachable, eb)
+		}
+	}
+	return reachable
+}
+
+// focusable returns the bounds of the focusable element with the given ID.
+func (bm *BoundsMap) focusable(id string) (elementBounds, bool) {
+	for _, eb := range bm.reachable() {
+		if eb.id == id {
+			return eb, true
+		}
+	}
+	return elementBounds{}, false
+}
+
+// Direction is a direction for spatial focus navigation.
+type Direction int
+
+// Directions for spatial focus navigation.
+const (
+	DirectionUp Direction = iota
+	DirectionDown
+	DirectionLeft
+	DirectionRight
+)
+
+// FocusManager tracks the focused element across renders and moves focus in
+// response to keys. Pass it to Template.RenderWithFocus, which marks the
+// focused element before drawing and updates the tab order afterwards.
+//
+// It also tracks the element under the pointer and the element being
+// pressed, which get their hover: and active: attributes, or the :hover and
+// :active rules of a stylesheet, and the hover and active styles of buttons.
+//
+// Example:
+//
+//	case tea.KeyPressMsg:
+//	    if m.focus.HandleKey(msg.String()) {
+//	        return m, nil
+//	    }
+//
+//	// In View
+//	scr, boundsMap := m.template.RenderWithFocus(data, slots, m.focus, m.width, m.height)
+type FocusManager struct {
+	focused string
+	hovered string
+	active  string
+	bounds  *BoundsMap
+}
+
+// NewFocusManager creates a focus manager with nothing focused.
+func NewFocusManager() *FocusManager {
+	return &FocusManager{}
+}
+
+// Focused returns the ID of the focused element, or an empty string.
+func (fm *FocusManager) Focused() string {
+	return fm.focused
+}
+
+// IsFocused reports whether the element with the given ID has focus.
+// Stateful components can use it to style themselves before rendering.
+func (fm *FocusManager) IsFocused(id string) bool {
+	return id != "" && fm.focused == id
+}
+
+// Focus moves focus to the element with the given ID. Before the first
+// render any ID is accepted; afterwards the element must have been rendered
+// as focusable. It reports whether focus moved.
+func (fm *FocusManager) Focus(id string) bool {
+	if fm.bounds != nil {
+		if _, ok := fm.bounds.focusable(id); !ok {
+			return false
+		}
+	}
+	fm.focused = id
+	return true
+}
+
+// Blur removes focus from the focused element.
+func (fm *FocusManager) Blur() {
+	fm.focused = ""
+}
+
+// Next moves focus to the next element in tab order, wrapping around, and
+// returns its ID.
+func (fm *FocusManager) Next() string {
+	return fm.cycle(1)
+}
+
+// Prev moves focus to the previous element in tab order, wrapping around, and
+// returns its ID.
+func (fm *FocusManager) Prev() string {
+	return fm.cycle(-1)
+}
+
+// cycle moves focus by delta positions in tab order.
+func (fm *FocusManager) cycle(delta int) string {
+	if fm.bounds == nil {
+		return fm.focused
+	}
+	order := fm.bounds.FocusOrder()
+	if len(order) == 0 {
+		return fm.focused
+	}
+
+	i := slices.Index(order, fm.focused)
+	switch {
+	case i < 0 && delta > 0:
+		i = 0
+	case i < 0:
+		i = len(order) - 1
+	default:
+		i = (i + delta + len(order)) % len(order)
+	}
+	fm.focused = order[i]
+	return fm.focused
+}
+
+// Move moves focus to the nearest focusable element in the given direction,
+// using the bounds of the last render, and returns the ID of the focused
+// element. Focus stays put if there is no element in that direction. If
+// nothing has focus, the first element in tab order is focused.
+func (fm *FocusManager) Move(dir Direction) string {
+	if fm.bounds == nil {
+		return fm.focused
+	}
+	current, ok := fm.bounds.focusable(fm.focused)
+	if !ok {
+		return fm.Next()
+	}
+
+	best, bestScore := "", -1
+	for _, eb := range fm.bounds.reachable() {
+		if eb.id == current.id {
+			continue
+		}
+		score, ok := spatialScore(current.bounds, eb.bounds, dir)
+		if ok && (bestScore < 0 || score < bestScore) {
+			best, bestScore = eb.id, score
+		}
+	}
+	if best != "" {
+		fm.focused = best
+	}
+	return fm.focused
+}
+
+// FocusAt focuses the top-most focusable element at the given screen
+// coordinates, for focusing elements on click. It reports whether an element
+// was found.
+func (fm *FocusManager) FocusAt(x, y int) bool {
+	if fm.bounds == nil {
+		return false
+	}
+	for _, elem := range fm.bounds.HitTestAll(x, y) {
+		if _, ok := fm.bounds.focusable(elem.ID()); ok {
+			fm.focused = elem.ID()
+			return true
+		}
+	}
+	return false
+}
+
+// Hovered returns the ID of the element under the pointer, or an empty
+// string.
+func (fm *FocusManager) Hovered() string {
+	return fm.hovered
+}
+
+// Hover marks the element with the given ID as being under the pointer.
+// An empty ID clears it.
+func (fm *FocusManager) Hover(id string) {
+	fm.hovered = id
+}
+
+// HoverAt marks the top-most element with an ID at the given screen
+// coordinates as being under the pointer, using the bounds of the last
+// render, for mouse motion events. It reports whether the hovered element
+// changed.
+func (fm *FocusManager) HoverAt(x, y int) bool {
+	before := fm.hovered
+	fm.hovered = fm.idAt(x, y)
+	return fm.hovered != before
+}
+
+// Active returns the ID of the element being pressed, or an empty string.
+func (fm *FocusManager) Active() string {
+	return fm.active
+}
+
+// Press marks the element with the given ID as being pressed.
+func (fm *FocusManager) Press(id string) {
+	fm.active = id
+}
+
+// PressAt marks the top-most element with an ID at the given screen
+// coordinates as being pressed, for mouse button presses, and returns its
+// ID.
+func (fm *FocusManager) PressAt(x, y int) string {
+	fm.active = fm.idAt(x, y)
+	return fm.active
+}
+
+// Release clears the pressed element, for mouse button releases.
+func (fm *FocusManager) Release() {
+	fm.active = ""
+}
+
+// idAt returns the ID of the top-most element with an explicit ID at the
+// given screen coordinates.
+func (fm *FocusManager) idAt(x, y int) string {
+	if fm.bounds == nil {
+		return ""
+	}
+	if elem := fm.bounds.HitTest(x, y); elem != nil && hasExplicitID(elem.ID()) {
+		return elem.ID()
+	}
+	return ""
+}
+
+// HandleKey moves focus for Tab, Shift+Tab and the arrow keys, given in the
+// form of Bubble Tea's key strings, such as "shift+tab". It reports whether
+// the key moved focus. Elements that use the arrow keys themselves, like
+// text inputs, should get the key first.
+func (fm *FocusManager) HandleKey(key string) bool {
+	before := fm.focused
+	switch key {
+	case "tab":
+		fm.Next()
+	case "shift+tab":
+		fm.Prev()
+	case "up":
+		fm.Move(DirectionUp)
+	case "down":
+		fm.Move(DirectionDown)
+	case "left":
+		fm.Move(DirectionLeft)
+	case "right":
+		fm.Move(DirectionRight)
+	default:
+		return false
+	}
+	return fm.focused != before
+}
+
+// Update records the focusable elements of a render. Focus is dropped if the
+// focused element is no longer rendered. RenderWithFocus calls it, so it is
+// only needed for element trees rendered by other means.
+func (fm *FocusManager) Update(bm *BoundsMap) {
+	fm.bounds = bm
+	if _, ok := bm.focusable(fm.focused); !ok {
+		fm.focused = ""
+	}
+	bm.focused = fm.focused
+}
+
+// apply marks the focused, hovered and pressed elements of a tree before
+// drawing.
+func (fm *FocusManager) apply(elem Element) {
+	if f, ok := elem.(Focusable); ok && f.IsFocusable() {
+		f.SetFocused(fm.IsFocused(elem.ID()))
+	}
+	if p, ok := elem.(pointerElement); ok {
+		id := elem.ID()
+		p.SetHovered(fm.hovered != "" && fm.hovered == id)
+		p.SetActive(fm.active != "" && fm.active == id)
+	}
+	for _, child := range elem.Children() {
+		if child != nil {
+			fm.apply(child)
+		}
+	}
+}
+
+// spatialScore rates how close to is when moving from from in the given
+// direction; lower is closer. It reports false if to isn't in that direction.
+// Misalignment of the centers across the direction of movement counts
+// double, so that elements in the same row or column are preferred.
+func spatialScore(from, to uv.Rectangle, dir Direction) (int, bool) {
+	// Centers are compared in half cells to stay in integers.
+	var gap, offset int
+	switch dir {
+	case DirectionUp:
+		gap, offset = from.Min.Y-to.Max.Y, (from.Min.X+from.Max.X)-(to.Min.X+to.Max.X)
+	case DirectionDown:
+		gap, offset = to.Min.Y-from.Max.Y, (from.Min.X+from.Max.X)-(to.Min.X+to.Max.X)
+	case DirectionLeft:
+		gap, offset = from.Min.X-to.Max.X, (from.Min.Y+from.Max.Y)-(to.Min.Y+to.Max.Y)
+	case DirectionRight:
+		gap, offset = to.Min.X-from.Max.X, (from.Min.Y+from.Max.Y)-(to.Min.Y+to.Max.Y)
+	}
+	if gap < 0 {
+		return 0, false
+	}
+	return 2*gap + 2*max(offset, -offset), true
+}
+
+// hasExplicitID reports whether an ID was set explicitly rather than
+// generated by BaseElement.
+func hasExplicitID(id string) bool {
+	return !strings.HasPrefix(id, "elem_")
+}
@@ -0,0 +1,175 @@
+package pony
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const focusGrid = `
+<vstack>
+	<hstack spacing="2">
+		<box id="a" focusable="true" border="normal" focus:border="double"><text>A</text></box>
+		<box id="b" focusable="true" border="normal"><text>B</text></box>
+	</hstack>
+	<hstack spacing="2">
+		<box id="c" focusable="true" border="normal"><text>C</text></box>
+		<box id="d" tab-index="1" border="normal"><text>D</text></box>
+	</hstack>
+	<box id="e" tab-index="-1" border="normal"><text>E</text></box>
+	<box focusable="true"><text>no id</text></box>
+</vstack>
+`
+
+func TestFocusOrder(t *testing.T) {
+	tmpl := MustParse[struct{}](focusGrid)
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 40, 20)
+
+	// Positive tab indexes come first, negative ones and generated IDs are
+	// left out.
+	if got, want := bm.FocusOrder(), []string{"d", "a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("FocusOrder() = %v, want %v", got, want)
+	}
+	if bm.FocusedID() != "" {
+		t.Errorf("expected nothing focused, got %q", bm.FocusedID())
+	}
+}
+
+func TestFocusManager_Tab(t *testing.T) {
+	tmpl := MustParse[struct{}](focusGrid)
+	fm := NewFocusManager()
+	render := func() *BoundsMap {
+		_, bm := tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 20)
+		return bm
+	}
+	render()
+
+	var got []string
+	for range 5 {
+		fm.HandleKey("tab")
+		got = append(got, fm.Focused())
+	}
+	if want := []string{"d", "a", "b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("tab order = %v, want %v", got, want)
+	}
+
+	if !fm.HandleKey("shift+tab") || fm.Focused() != "c" {
+		t.Errorf("expected shift+tab to focus c, got %q", fm.Focused())
+	}
+	if fm.HandleKey("enter") {
+		t.Error("expected enter not to move focus")
+	}
+
+	// Elements with a negative tab index can still be focused directly.
+	if !fm.Focus("e") {
+		t.Error("expected e to be focusable")
+	}
+	if fm.Focus("missing") || fm.Focused() != "e" {
+		t.Errorf("expected unknown IDs to be rejected, focused %q", fm.Focused())
+	}
+
+	if bm := render(); bm.FocusedID() != "e" {
+		t.Errorf("expected the bounds map to report e, got %q", bm.FocusedID())
+	}
+}
+
+func TestFocusManager_Move(t *testing.T) {
+	tmpl := MustParse[struct{}](focusGrid)
+	fm := NewFocusManager()
+	tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 20)
+
+	// Without focus, moving focuses the first element in tab order.
+	if got := fm.Move(DirectionRight); got != "d" {
+		t.Fatalf("expected d, got %q", got)
+	}
+
+	steps := []struct {
+		key  string
+		want string
+	}{
+		{"left", "c"},
+		{"up", "a"},
+		{"right", "b"},
+		{"down", "d"},
+		{"down", "e"},
+		{"up", "c"},
+		{"right", "d"},
+		{"right", "d"}, // nothing to the right
+	}
+	for _, step := range steps {
+		fm.HandleKey(step.key)
+		if fm.Focused() != step.want {
+			t.Fatalf("after %s: focused %q, want %q", step.key, fm.Focused(), step.want)
+		}
+	}
+}
+
+func TestFocusManager_Styling(t *testing.T) {
+	tmpl := MustParse[struct{}](focusGrid)
+	fm := NewFocusManager()
+
+	scr, _ := tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 20)
+	if strings.Contains(scr.Render(), "═") {
+		t.Fatal("expected no double border without focus")
+	}
+
+	fm.Focus("a")
+	scr, bm := tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 20)
+	if !strings.Contains(scr.Render(), "═") {
+		t.Error("expected the focus:border attribute to apply to the focused box")
+	}
+	elem, _ := bm.GetByID("a")
+	if !elem.(Focusable).IsFocused() {
+		t.Error("expected the focused element to be marked")
+	}
+	if elem, _ := bm.GetByID("b"); elem.(Focusable).IsFocused() {
+		t.Error("expected other elements not to be marked")
+	}
+}
+
+func TestFocusManager_SlotsAndClicks(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<vstack>
+	<slot name="input" />
+	<box id="other" focusable="true"><text>Other</text></box>
+</vstack>
+`)
+
+	input := NewVStack(NewText("Label"), NewText("Value"))
+	input.SetID("input")
+	input.SetFocusable(true)
+	slots := map[string]Element{"input": input}
+
+	fm := NewFocusManager()
+	fm.Focus("input")
+	_, bm := tmpl.RenderWithFocus(struct{}{}, slots, fm, 20, 10)
+	if !input.IsFocused() || bm.FocusedID() != "input" {
+		t.Fatalf("expected the slot element to be focused, got %q", bm.FocusedID())
+	}
+
+	// Clicking a child of a focusable element focuses the element.
+	bounds, _ := bm.GetBounds("other")
+	if !fm.FocusAt(bounds.Min.X, bounds.Min.Y) || fm.Focused() != "other" {
+		t.Errorf("expected click to focus other, got %q", fm.Focused())
+	}
+
+	// Focus is dropped once the element is no longer rendered.
+	fm.Focus("input")
+	tmpl.RenderWithFocus(struct{}{}, nil, fm, 20, 10)
+	if fm.Focused() != "" {
+		t.Errorf("expected focus to be dropped, got %q", fm.Focused())
+	}
+}
+
+func TestButtonFocusable(t *testing.T) {
+	btn := NewButton("OK")
+	if !btn.IsFocusable() {
+		t.Error("expected buttons to be focusable by default")
+	}
+
+	tmpl := MustParse[struct{}](`<hstack><button id="ok" text="OK" /><button id="no" text="No" focusable="false" /></hstack>`)
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 30, 5)
+	if got := bm.FocusOrder(); !slices.Equal(got, []string{"ok"}) {
+		t.Errorf("FocusOrder() = %v, want [ok]", got)
+	}
+}
@@ -7,6 +7,7 @@ require (
 	github.com/charmbracelet/x/ansi v0.11.1
 	github.com/charmbracelet/x/exp/golden v0.0.0-20251118172736-77d017256798
 	github.com/lucasb-eyer/go-colorful v1.3.0
+	github.com/rivo/uniseg v0.4.7
 	golang.org/x/text v0.31.0
 )
 
@@ -21,7 +22,6 @@ require (
 	github.com/clipperhouse/uax29/v2 v2.3.0 // indirect
 	github.com/mattn/go-runewidth v0.0.19 // indirect
 	github.com/muesli/cancelreader v0.2.2 // indirect
-	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/sync v0.18.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
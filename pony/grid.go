@@ -0,0 +1,662 @@
+package pony
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// GridTrack is the size of a column or row of a grid: a fixed number of
+// cells, a percentage of the grid size, a fraction of the space left by the
+// other tracks, or auto to fit the content.
+type GridTrack struct {
+	value int
+	kind  trackKind
+}
+
+// trackKind is the unit of a grid track. The zero value is auto, so that the
+// zero GridTrack fits its content.
+type trackKind int
+
+const (
+	trackAuto trackKind = iota
+	trackFixed
+	trackPercent
+	trackFraction
+)
+
+// NewFixedTrack creates a track of a fixed size.
+func NewFixedTrack(size int) GridTrack {
+	return GridTrack{value: size, kind: trackFixed}
+}
+
+// NewPercentTrack creates a track sized as a percentage of the grid.
+func NewPercentTrack(percent int) GridTrack {
+	return GridTrack{value: percent, kind: trackPercent}
+}
+
+// NewFractionTrack creates a track that takes fr parts of the space left by
+// the other tracks.
+func NewFractionTrack(fr int) GridTrack {
+	return GridTrack{value: fr, kind: trackFraction}
+}
+
+// NewAutoTrack creates a track that fits its content.
+func NewAutoTrack() GridTrack {
+	return GridTrack{kind: trackAuto}
+}
+
+// ParseGridTracks parses a track list such as "20 1fr 2fr auto 25%".
+// repeat(n, tracks) repeats tracks n times.
+func ParseGridTracks(s string) ([]GridTrack, error) {
+	var tracks []GridTrack
+	s = strings.TrimSpace(s)
+	for s != "" {
+		if rest, ok := strings.CutPrefix(s, "repeat("); ok {
+			end := strings.IndexByte(rest, ')')
+			if end < 0 {
+				return nil, fmt.Errorf("grid: unclosed repeat in %q", s)
+			}
+			countStr, list, ok := strings.Cut(rest[:end], ",")
+			count, err := strconv.Atoi(strings.TrimSpace(countStr))
+			if !ok || err != nil || count < 0 {
+				return nil, fmt.Errorf("grid: invalid repeat %q", s[:len("repeat(")+end+1])
+			}
+			repeated, err := ParseGridTracks(list)
+			if err != nil {
+				return nil, err
+			}
+			for range count {
+				tracks = append(tracks, repeated...)
+			}
+			s = strings.TrimSpace(rest[end+1:])
+			continue
+		}
+
+		field, rest, _ := strings.Cut(s, " ")
+		track, err := parseGridTrack(field)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, track)
+		s = strings.TrimSpace(rest)
+	}
+	return tracks, nil
+}
+
+// parseGridTrack parses a single track size.
+func parseGridTrack(s string) (GridTrack, error) {
+	if s == UnitAuto {
+		return NewAutoTrack(), nil
+	}
+	for _, u := range []struct {
+		unit string
+		kind trackKind
+	}{{UnitFraction, trackFraction}, {UnitPercent, trackPercent}} {
+		if v, ok := strings.CutSuffix(s, u.unit); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return GridTrack{}, fmt.Errorf("grid: invalid track size %q", s)
+			}
+			return GridTrack{value: n, kind: u.kind}, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return GridTrack{}, fmt.Errorf("grid: invalid track size %q", s)
+	}
+	return NewFixedTrack(n), nil
+}
+
+// String returns the track size as written in markup.
+func (t GridTrack) String() string {
+	switch t.kind {
+	case trackFixed:
+		return strconv.Itoa(t.value)
+	case trackPercent:
+		return strconv.Itoa(t.value) + UnitPercent
+	case trackFraction:
+		return strconv.Itoa(t.value) + UnitFraction
+	default:
+		return UnitAuto
+	}
+}
+
+// isFraction reports whether the track takes a share of the remaining space.
+func (t GridTrack) isFraction() bool {
+	return t.kind == trackFraction && t.value > 0
+}
+
+// isAuto reports whether the track fits its content, like a 0fr track.
+func (t GridTrack) isAuto() bool {
+	return t.kind == trackAuto || (t.kind == trackFraction && t.value == 0)
+}
+
+// Grid represents a two-dimensional layout container. Columns and rows are
+// sized by track lists mixing fixed sizes, percentages, fractions of the
+// remaining space (fr) and auto tracks that fit their content. Children are
+// placed in the next free cell, row by row, unless they are wrapped in a
+// GridItem with an explicit position, a span or a named area. Rows are added
+// as needed.
+//
+// Fractions fill the space available to the grid, so a grid with fr tracks
+// fills its parent in that direction. Without space limits, such as inside a
+// ScrollView, fr tracks fit their content like auto tracks.
+//
+// Example:
+//
+//	<grid columns="20 1fr" rows="auto 1fr" gap="1" areas="header header, nav main">
+//	    <box area="header">Dashboard</box>
+//	    <box area="nav">Menu</box>
+//	    <box area="main">Content</box>
+//	</grid>
+type Grid struct {
+	BaseElement
+	items        []Element
+	columns      []GridTrack
+	rows         []GridTrack
+	columnGap    int
+	rowGap       int
+	areas        [][]string
+	justifyItems string
+	alignItems   string
+	width        SizeConstraint
+	height       SizeConstraint
+}
+
+var _ Element = (*Grid)(nil)
+
+// NewGrid creates a grid with a single auto column. Children stretch to
+// fill their cells.
+func NewGrid(children ...Element) *Grid {
+	return &Grid{
+		items:        children,
+		justifyItems: AlignmentStretch,
+		alignItems:   AlignmentStretch,
+	}
+}
+
+// Columns sets the column tracks and returns the grid for chaining.
+func (g *Grid) Columns(tracks ...GridTrack) *Grid {
+	g.columns = tracks
+	return g
+}
+
+// Rows sets the row tracks and returns the grid for chaining. Rows past the
+// tracks are auto.
+func (g *Grid) Rows(tracks ...GridTrack) *Grid {
+	g.rows = tracks
+	return g
+}
+
+// Gap sets the space between rows and between columns and returns the grid
+// for chaining.
+func (g *Grid) Gap(rowGap, columnGap int) *Grid {
+	g.rowGap = rowGap
+	g.columnGap = columnGap
+	return g
+}
+
+// Areas names the cells of the grid, one string per row with a name per
+// column, such as "header header" and "nav main", and returns the grid for
+// chaining. A "." leaves a cell unnamed. Items placed in an area span the
+// cells with its name.
+func (g *Grid) Areas(rows ...string) *Grid {
+	g.areas = nil
+	for _, row := range rows {
+		g.areas = append(g.areas, strings.Fields(row))
+	}
+	return g
+}
+
+// JustifyItems sets the default horizontal alignment of children in their
+// cells and returns the grid for chaining: AlignmentStretch (the default),
+// AlignmentLeading, AlignmentCenter or AlignmentTrailing.
+func (g *Grid) JustifyItems(alignment string) *Grid {
+	g.justifyItems = alignment
+	return g
+}
+
+// AlignItems sets the default vertical alignment of children in their cells
+// and returns the grid for chaining: AlignmentStretch (the default),
+// AlignmentTop, AlignmentCenter or AlignmentBottom.
+func (g *Grid) AlignItems(alignment string) *Grid {
+	g.alignItems = alignment
+	return g
+}
+
+// Width sets the width constraint and returns the grid for chaining.
+func (g *Grid) Width(width SizeConstraint) *Grid {
+	g.width = width
+	return g
+}
+
+// Height sets the height constraint and returns the grid for chaining.
+func (g *Grid) Height(height SizeConstraint) *Grid {
+	g.height = height
+	return g
+}
+
+// gridCell is a child placed in the grid, with 0-based tracks.
+type gridCell struct {
+	elem     Element
+	col, row int
+	colSpan  int
+	rowSpan  int
+	justify  string
+	align    string
+}
+
+// Layout calculates the grid size: the sum of its tracks and gaps.
+func (g *Grid) Layout(constraints Constraints) Size {
+	width, height := constraints.MaxWidth, constraints.MaxHeight
+	if !g.width.IsAuto() {
+		width = g.width.Apply(constraints.MaxWidth, constraints.MaxWidth)
+	}
+	if !g.height.IsAuto() {
+		height = g.height.Apply(constraints.MaxHeight, constraints.MaxHeight)
+	}
+
+	_, cols, rows := g.resolve(width, height)
+	size := Size{
+		Width:  trackSpan(cols, 0, len(cols), g.columnGap),
+		Height: trackSpan(rows, 0, len(rows), g.rowGap),
+	}
+	if !g.width.IsAuto() {
+		size.Width = width
+	}
+	if !g.height.IsAuto() {
+		size.Height = height
+	}
+	return constraints.Constrain(size)
+}
+
+// Draw renders the children in their cells.
+func (g *Grid) Draw(scr uv.Screen, area uv.Rectangle) {
+	g.SetBounds(area)
+
+	cells, cols, rows := g.resolve(area.Dx(), area.Dy())
+	for _, c := range cells {
+		cell := uv.Rect(
+			area.Min.X+trackOffset(cols, c.col, g.columnGap),
+			area.Min.Y+trackOffset(rows, c.row, g.rowGap),
+			trackSpan(cols, c.col, c.colSpan, g.columnGap),
+			trackSpan(rows, c.row, c.rowSpan, g.rowGap),
+		)
+
+		childArea := cell
+		if c.justify != AlignmentStretch || c.align != AlignmentStretch {
+			size := layoutElement(c.elem, Constraints{MaxWidth: cell.Dx(), MaxHeight: cell.Dy()})
+			if c.justify != AlignmentStretch {
+				childArea.Min.X = cell.Min.X + alignOffset(c.justify, cell.Dx(), size.Width)
+				childArea.Max.X = childArea.Min.X + size.Width
+			}
+			if c.align != AlignmentStretch {
+				childArea.Min.Y = cell.Min.Y + alignOffset(c.align, cell.Dy(), size.Height)
+				childArea.Max.Y = childArea.Min.Y + size.Height
+			}
+		}
+		drawElement(c.elem, scr, childArea.Intersect(area))
+	}
+}
+
+// alignOffset returns the offset of content of the given size in space.
+func alignOffset(alignment string, space, size int) int {
+	switch alignment {
+	case AlignmentCenter:
+		return max(0, (space-size)/2)
+	case AlignmentTrailing, AlignmentBottom:
+		return max(0, space-size)
+	default:
+		return 0
+	}
+}
+
+// trackOffset returns the position of a track from the start of the grid.
+func trackOffset(sizes []int, index, gap int) int {
+	offset := 0
+	for _, size := range sizes[:index] {
+		offset += size + gap
+	}
+	return offset
+}
+
+// trackSpan returns the size of span tracks from start, with the gaps
+// between them.
+func trackSpan(sizes []int, start, span, gap int) int {
+	if span == 0 {
+		return 0
+	}
+	total := gap * (span - 1)
+	for _, size := range sizes[start : start+span] {
+		total += size
+	}
+	return total
+}
+
+// resolve places the children and sizes the tracks for a grid of the given
+// size.
+func (g *Grid) resolve(width, height int) ([]gridCell, []int, []int) {
+	cells, ncols, nrows := g.place()
+
+	cols := g.sizeTracks(g.columns, ncols, width, g.columnGap, cells, func(c *gridCell) (int, int, int) {
+		size := layoutElement(c.elem, Constraints{MaxWidth: width, MaxHeight: height})
+		return c.col, c.colSpan, size.Width
+	})
+	rows := g.sizeTracks(g.rows, nrows, height, g.rowGap, cells, func(c *gridCell) (int, int, int) {
+		w := trackSpan(cols, c.col, c.colSpan, g.columnGap)
+		size := layoutElement(c.elem, Constraints{MaxWidth: w, MaxHeight: height})
+		return c.row, c.rowSpan, size.Height
+	})
+	return cells, cols, rows
+}
+
+// sizeTracks sizes count tracks sharing available cells. measure returns the
+// first track, the span and the size wanted by a cell along the axis.
+func (g *Grid) sizeTracks(tracks []GridTrack, count, available, gap int, cells []gridCell, measure func(c *gridCell) (start, span, size int)) []int {
+	track := func(i int) GridTrack {
+		if i < len(tracks) {
+			return tracks[i]
+		}
+		return NewAutoTrack()
+	}
+	bounded := available < unboundedSize
+	space := max(0, available-gap*max(0, count-1))
+
+	// Fractions of unknown space fit their content.
+	fitsContent := func(t GridTrack) bool {
+		return t.isAuto() || (t.isFraction() && !bounded)
+	}
+
+	sizes := make([]int, count)
+	for i := range sizes {
+		t := track(i)
+		switch {
+		case t.kind == trackPercent:
+			if bounded {
+				sizes[i] = space * t.value / 100
+			}
+		case t.kind == trackFixed:
+			sizes[i] = t.value
+		}
+	}
+
+	// Content tracks fit the cells spanning only them, then grow for the
+	// cells spanning several tracks.
+	type want struct{ start, span, size int }
+	var spanning []want
+	for i := range cells {
+		start, span, size := measure(&cells[i])
+		if span > 1 {
+			spanning = append(spanning, want{start, span, size})
+			continue
+		}
+		if fitsContent(track(start)) {
+			sizes[start] = max(sizes[start], size)
+		}
+	}
+	for _, w := range spanning {
+		deficit := w.size - trackSpan(sizes, w.start, w.span, gap)
+		if deficit <= 0 {
+			continue
+		}
+		for i := w.start + w.span - 1; i >= w.start; i-- {
+			if fitsContent(track(i)) {
+				sizes[i] += deficit
+				break
+			}
+		}
+	}
+
+	if !bounded {
+		return sizes
+	}
+	totalFr, used := 0, 0
+	for i, size := range sizes {
+		if t := track(i); t.isFraction() {
+			totalFr += t.value
+		} else {
+			used += size
+		}
+	}
+	if totalFr == 0 {
+		return sizes
+	}
+	remaining := max(0, space-used)
+	left := remaining
+	for i := range sizes {
+		if t := track(i); t.isFraction() {
+			sizes[i] = remaining * t.value / totalFr
+			left -= sizes[i]
+		}
+	}
+	// Hand out the cells lost to rounding to the first fractions.
+	for i := 0; left > 0 && i < count; i++ {
+		if track(i).isFraction() {
+			sizes[i]++
+			left--
+		}
+	}
+	return sizes
+}
+
+// place assigns the children to cells and returns them with the number of
+// columns and rows. Children with an area or an explicit position are placed
+// first, then the others fill the free cells row by row.
+func (g *Grid) place() ([]gridCell, int, int) {
+	ncols := max(1, len(g.columns))
+	for _, row := range g.areas {
+		ncols = max(ncols, len(row))
+	}
+	nrows := max(len(g.rows), len(g.areas))
+
+	occupied := map[[2]int]bool{}
+	fits := func(col, row, colSpan, rowSpan int) bool {
+		if col+colSpan > ncols {
+			return false
+		}
+		for r := row; r < row+rowSpan; r++ {
+			for c := col; c < col+colSpan; c++ {
+				if occupied[[2]int{c, r}] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	occupy := func(c *gridCell) {
+		for r := c.row; r < c.row+c.rowSpan; r++ {
+			for col := c.col; col < c.col+c.colSpan; col++ {
+				occupied[[2]int{col, r}] = true
+			}
+		}
+		nrows = max(nrows, c.row+c.rowSpan)
+	}
+
+	cells := make([]gridCell, len(g.items))
+	var pending []int
+	for i, elem := range g.items {
+		c := gridCell{elem: elem, col: -1, row: -1, colSpan: 1, rowSpan: 1, justify: g.justifyItems, align: g.alignItems}
+		if item, ok := elem.(*GridItem); ok {
+			c.col, c.row = item.column-1, item.row-1
+			c.colSpan, c.rowSpan = max(1, item.columnSpan), max(1, item.rowSpan)
+			if item.justifySelf != "" {
+				c.justify = item.justifySelf
+			}
+			if item.alignSelf != "" {
+				c.align = item.alignSelf
+			}
+			if item.area != "" {
+				if col, row, colSpan, rowSpan, ok := g.area(item.area); ok {
+					c.col, c.row, c.colSpan, c.rowSpan = col, row, colSpan, rowSpan
+				}
+			}
+		}
+		c.colSpan = min(c.colSpan, ncols)
+		c.col = min(c.col, ncols-c.colSpan)
+		cells[i] = c
+
+		if c.col >= 0 && c.row >= 0 {
+			occupy(&cells[i])
+		} else {
+			pending = append(pending, i)
+		}
+	}
+
+	// Auto placement, in a single pass so that the order of the children
+	// is kept.
+	cursorCol, cursorRow := 0, 0
+	for _, i := range pending {
+		c := &cells[i]
+		switch {
+		case c.col >= 0:
+			// Fixed column: the first row from the cursor where it fits.
+			row := cursorRow
+			for !fits(c.col, row, c.colSpan, c.rowSpan) {
+				row++
+			}
+			c.row = row
+		case c.row >= 0:
+			// Fixed row: the first column where it fits.
+			col := 0
+			for col+c.colSpan <= ncols && !fits(col, c.row, c.colSpan, c.rowSpan) {
+				col++
+			}
+			c.col = min(col, ncols-c.colSpan)
+		default:
+			for !fits(cursorCol, cursorRow, c.colSpan, c.rowSpan) {
+				cursorCol++
+				if cursorCol+c.colSpan > ncols {
+					cursorCol = 0
+					cursorRow++
+				}
+			}
+			c.col, c.row = cursorCol, cursorRow
+		}
+		occupy(c)
+	}
+	return cells, ncols, nrows
+}
+
+// area returns the cells covered by a named area.
+func (g *Grid) area(name string) (col, row, colSpan, rowSpan int, ok bool) {
+	minCol, minRow, maxCol, maxRow := -1, -1, -1, -1
+	for r, names := range g.areas {
+		for c, n := range names {
+			if n != name {
+				continue
+			}
+			if minCol < 0 {
+				minCol, minRow, maxCol, maxRow = c, r, c, r
+				continue
+			}
+			minCol, minRow = min(minCol, c), min(minRow, r)
+			maxCol, maxRow = max(maxCol, c), max(maxRow, r)
+		}
+	}
+	if minCol < 0 {
+		return 0, 0, 0, 0, false
+	}
+	return minCol, minRow, maxCol - minCol + 1, maxRow - minRow + 1, true
+}
+
+// Children returns the grid children.
+func (g *Grid) Children() []Element {
+	return g.items
+}
+
+// GridItem places an element in a Grid: at a column and row, spanning
+// several tracks, or in a named area, with its own alignment in the cell.
+type GridItem struct {
+	BaseElement
+	child       Element
+	column      int // 1-based, 0 for auto placement
+	row         int // 1-based, 0 for auto placement
+	columnSpan  int
+	rowSpan     int
+	area        string
+	justifySelf string
+	alignSelf   string
+}
+
+var _ Element = (*GridItem)(nil)
+
+// NewGridItem wraps an element for placing it in a grid.
+func NewGridItem(child Element) *GridItem {
+	return &GridItem{child: child, columnSpan: 1, rowSpan: 1}
+}
+
+// Column sets the column of the item, starting at 1, and returns the item
+// for chaining.
+func (gi *GridItem) Column(column int) *GridItem {
+	gi.column = column
+	return gi
+}
+
+// Row sets the row of the item, starting at 1, and returns the item for
+// chaining.
+func (gi *GridItem) Row(row int) *GridItem {
+	gi.row = row
+	return gi
+}
+
+// ColumnSpan sets the number of columns the item covers and returns the
+// item for chaining.
+func (gi *GridItem) ColumnSpan(span int) *GridItem {
+	gi.columnSpan = span
+	return gi
+}
+
+// RowSpan sets the number of rows the item covers and returns the item for
+// chaining.
+func (gi *GridItem) RowSpan(span int) *GridItem {
+	gi.rowSpan = span
+	return gi
+}
+
+// Area places the item in a named area of the grid and returns the item for
+// chaining.
+func (gi *GridItem) Area(name string) *GridItem {
+	gi.area = name
+	return gi
+}
+
+// JustifySelf sets the horizontal alignment of the item in its cell and
+// returns the item for chaining.
+func (gi *GridItem) JustifySelf(alignment string) *GridItem {
+	gi.justifySelf = alignment
+	return gi
+}
+
+// AlignSelf sets the vertical alignment of the item in its cell and returns
+// the item for chaining.
+func (gi *GridItem) AlignSelf(alignment string) *GridItem {
+	gi.alignSelf = alignment
+	return gi
+}
+
+// Draw renders the child.
+func (gi *GridItem) Draw(scr uv.Screen, area uv.Rectangle) {
+	gi.SetBounds(area)
+	if gi.child != nil {
+		drawElement(gi.child, scr, area)
+	}
+}
+
+// Layout calculates the child size.
+func (gi *GridItem) Layout(constraints Constraints) Size {
+	if gi.child == nil {
+		return Size{}
+	}
+	return layoutElement(gi.child, constraints)
+}
+
+// Children returns the child element.
+func (gi *GridItem) Children() []Element {
+	if gi.child == nil {
+		return nil
+	}
+	return []Element{gi.child}
+}
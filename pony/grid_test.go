@@ -0,0 +1,160 @@
+package pony
+
+import (
+	"fmt"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestParseGridTracks(t *testing.T) {
+	tracks, err := ParseGridTracks("20 1fr 2fr auto 25% repeat(2, 1fr 3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[20 1fr 2fr auto 25% 1fr 3 1fr 3]"
+	if got := fmt.Sprint(tracks); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for _, s := range []string{"1px", "-1", "repeat(2, 1fr", "repeat(x, 1fr)"} {
+		if _, err := ParseGridTracks(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestGrid_Tracks(t *testing.T) {
+	grid := NewGrid(NewText("a"), NewText("long text"), NewText("b"), NewText("c"), NewText("d")).
+		Columns(NewFixedTrack(5), NewAutoTrack(), NewPercentTrack(10), NewFractionTrack(1), NewFractionTrack(2)).
+		Gap(0, 1)
+
+	_, cols, rows := grid.resolve(50, 10)
+	// 50 cells less 4 gaps: 5 fixed, 9 for the text, 4 for 10%, and the
+	// remaining 28 shared 1:2 by the fractions.
+	if got, want := fmt.Sprint(cols), "[5 9 4 10 18]"; got != want {
+		t.Errorf("columns = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(rows), "[1]"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+
+	size := grid.Layout(Constraints{MaxWidth: 50, MaxHeight: 10})
+	if size.Width != 50 || size.Height != 1 {
+		t.Errorf("expected the fractions to fill the width, got %+v", size)
+	}
+
+	// Without a width limit, fractions fit their content.
+	_, cols, _ = grid.resolve(unboundedSize, 10)
+	if got, want := fmt.Sprint(cols), "[5 9 0 1 1]"; got != want {
+		t.Errorf("unbounded columns = %s, want %s", got, want)
+	}
+}
+
+func TestGrid_ZeroTrack(t *testing.T) {
+	tracks, err := ParseGridTracks("0 1fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(tracks), "[0 1fr]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	grid := NewGrid(NewText("hidden"), NewText("shown")).Columns(tracks...)
+	_, cols, _ := grid.resolve(20, 1)
+	if got, want := fmt.Sprint(cols), "[0 20]"; got != want {
+		t.Errorf("columns = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(GridTrack{}); got != "auto" {
+		t.Errorf("expected the zero track to be auto, got %s", got)
+	}
+}
+
+func TestGrid_Placement(t *testing.T) {
+	grid := NewGrid(
+		NewGridItem(NewText("wide")).ColumnSpan(2),
+		NewText("a"),
+		NewGridItem(NewText("tall")).Column(3).Row(2).RowSpan(2),
+		NewText("b"),
+		NewText("c"),
+		NewText("d"),
+	).Columns(NewFixedTrack(4), NewFixedTrack(4), NewFixedTrack(4))
+
+	cells, ncols, nrows := grid.place()
+	if ncols != 3 || nrows != 3 {
+		t.Fatalf("expected 3x3 tracks, got %dx%d", ncols, nrows)
+	}
+	want := [][2]int{{0, 0}, {2, 0}, {2, 1}, {0, 1}, {1, 1}, {0, 2}}
+	for i, c := range cells {
+		if got := [2]int{c.col, c.row}; got != want[i] {
+			t.Errorf("item %d placed at %v, want %v", i, got, want[i])
+		}
+	}
+
+	scr := uv.NewScreenBuffer(12, 3)
+	grid.Draw(scr, uv.Rect(0, 0, 12, 3))
+	lines := []string{
+		"wide    a",
+		"b   c   tall",
+		"d",
+	}
+	if got := screenLines(&scr); !equalLines(got, lines) {
+		t.Errorf("got %q, want %q", got, lines)
+	}
+}
+
+func TestGrid_AreasAndAlignment(t *testing.T) {
+	grid := NewGrid(
+		NewGridItem(NewText("Main")).Area("main").JustifySelf(AlignmentCenter).AlignSelf(AlignmentCenter),
+		NewGridItem(NewText("Head")).Area("header").JustifySelf(AlignmentTrailing),
+		NewGridItem(NewText("Nav")).Area("nav").AlignSelf(AlignmentBottom),
+	).
+		Columns(NewFixedTrack(6), NewFractionTrack(1)).
+		Rows(NewAutoTrack(), NewFractionTrack(1)).
+		Areas("header header", "nav main")
+
+	scr := uv.NewScreenBuffer(20, 5)
+	grid.Draw(scr, uv.Rect(0, 0, 20, 5))
+	want := []string{
+		"                Head",
+		"",
+		"           Main",
+		"",
+		"Nav",
+	}
+	if got := screenLines(&scr); !equalLines(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGrid_Markup(t *testing.T) {
+	tmpl := MustParse[struct{}](`<grid id="layout" columns="10 1fr" rows="auto 1fr" gap="1" areas="header header, nav main">
+	<text id="header" area="header">Header</text>
+	<box id="nav" area="nav" border="rounded"><text>Nav</text></box>
+	<text id="main" area="main" justify-self="trailing">Main</text>
+</grid>`)
+	scr, bm := tmpl.RenderWithBounds(struct{}{}, nil, 30, 8)
+
+	if _, ok := bm.GetByID("layout"); !ok {
+		t.Fatal("grid not rendered")
+	}
+	tests := []struct {
+		id   string
+		want uv.Rectangle
+	}{
+		{"header", uv.Rect(0, 0, 30, 1)},
+		{"nav", uv.Rect(0, 2, 10, 6)},
+		{"main", uv.Rect(26, 2, 4, 6)},
+	}
+	for _, tt := range tests {
+		if got, ok := bm.GetBounds(tt.id); !ok || got != tt.want {
+			t.Errorf("%s bounds = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+	if elem := bm.HitTest(0, 4); elem == nil || elem.ID() != "nav" {
+		t.Errorf("expected a click in the nav cell to hit the box, got %v", elem)
+	}
+	if got := screenLines(&scr)[7]; got != "╰────────╯" {
+		t.Errorf("expected the stretched box to fill its cell, got %q", got)
+	}
+}
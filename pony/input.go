@@ -0,0 +1,427 @@
+package pony
+
+import (
+	"image/color"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Default sizes of inputs and textareas, in cells.
+const (
+	defaultInputWidth     = 20
+	defaultTextAreaHeight = 3
+)
+
+// Input is an editable text field: a single-line input, or a multiline
+// textarea that wraps its lines. Single-line inputs scroll horizontally to
+// keep the caret in view, textareas scroll vertically.
+//
+// The text, caret and selection live in an [InputState], which outlives the
+// element. In markup, <input> and <textarea> elements with an ID get their
+// state from the [InputStore] passed to Template.RenderWithInputs, so typing
+// into them persists between renders. Inputs are focusable by default, and
+// the focused input reports its caret through [BoundsMap.Cursor].
+//
+// Example:
+//
+//	<input id="email" placeholder="you@example.com" width="30" />
+//	<input id="password" type="password" />
+//	<textarea id="bio" height="5" />
+type Input struct {
+	BaseElement
+	state            *InputState
+	value            string
+	placeholder      string
+	mask             string
+	multiline        bool
+	style            uv.Style
+	placeholderStyle uv.Style
+	width            SizeConstraint
+	height           SizeConstraint
+
+	caret    uv.Position
+	hasCaret bool
+}
+
+var (
+	_ Element   = (*Input)(nil)
+	_ Focusable = (*Input)(nil)
+)
+
+// NewInput creates a single-line text input.
+// Inputs are focusable by default.
+func NewInput() *Input {
+	i := &Input{placeholderStyle: uv.Style{Attrs: uv.AttrFaint}}
+	i.SetFocusable(true)
+	return i
+}
+
+// NewTextArea creates a multiline text input.
+// Textareas are focusable by default.
+func NewTextArea() *Input {
+	i := NewInput()
+	i.multiline = true
+	return i
+}
+
+// State sets the editing state and returns the input for chaining.
+// Without one, the input creates its own state from its initial value when
+// it is first drawn.
+func (i *Input) State(state *InputState) *Input {
+	i.state = state
+	return i
+}
+
+// EditState returns the editing state of the input, or nil if it has none
+// yet.
+func (i *Input) EditState() *InputState {
+	return i.state
+}
+
+// Value sets the initial value, used when the input creates its state, and
+// returns the input for chaining.
+func (i *Input) Value(value string) *Input {
+	i.value = value
+	return i
+}
+
+// Placeholder sets the text shown while the input is empty and returns the
+// input for chaining.
+func (i *Input) Placeholder(placeholder string) *Input {
+	i.placeholder = placeholder
+	return i
+}
+
+// Mask sets a character drawn in place of every character of the value, as
+// in password fields, and returns the input for chaining.
+func (i *Input) Mask(mask string) *Input {
+	i.mask = mask
+	return i
+}
+
+// Style sets the text style and returns the input for chaining.
+func (i *Input) Style(style uv.Style) *Input {
+	i.style = style
+	return i
+}
+
+// PlaceholderStyle sets the placeholder style and returns the input for
+// chaining. The default is faint text.
+func (i *Input) PlaceholderStyle(style uv.Style) *Input {
+	i.placeholderStyle = style
+	return i
+}
+
+// ForegroundColor sets the text color and returns the input for chaining.
+func (i *Input) ForegroundColor(c color.Color) *Input {
+	i.style.Fg = c
+	return i
+}
+
+// BackgroundColor sets the background color and returns the input for
+// chaining.
+func (i *Input) BackgroundColor(c color.Color) *Input {
+	i.style.Bg = c
+	return i
+}
+
+// Width sets the width constraint and returns the input for chaining.
+func (i *Input) Width(width SizeConstraint) *Input {
+	i.width = width
+	return i
+}
+
+// Height sets the height constraint of a textarea and returns the input for
+// chaining. Single-line inputs are always one row high.
+func (i *Input) Height(height SizeConstraint) *Input {
+	i.height = height
+	return i
+}
+
+// CursorPosition returns the screen position of the caret from the last
+// draw. It reports false if the input hasn't been drawn.
+func (i *Input) CursorPosition() (uv.Position, bool) {
+	return i.caret, i.hasCaret
+}
+
+// Layout calculates the input size. Inputs are 20 cells wide unless a width
+// is set, and textareas are 3 rows high unless a height is set.
+func (i *Input) Layout(constraints Constraints) Size {
+	width := defaultInputWidth
+	if !i.width.IsAuto() {
+		width = i.width.Apply(constraints.MaxWidth, width)
+	}
+	height := 1
+	if i.multiline {
+		height = defaultTextAreaHeight
+		if !i.height.IsAuto() {
+			height = i.height.Apply(constraints.MaxHeight, height)
+		}
+	}
+	return constraints.Constrain(Size{Width: width, Height: height})
+}
+
+// Draw renders the visible part of the value, the selection in reverse video
+// and the placeholder if the value is empty, and records the caret position.
+func (i *Input) Draw(scr uv.Screen, area uv.Rectangle) {
+	i.SetBounds(area)
+	i.hasCaret = false
+	if area.Dx() <= 0 || area.Dy() <= 0 {
+		return
+	}
+	if i.state == nil {
+		i.state = i.newState()
+	}
+	s := i.state
+
+	blank := &uv.Cell{Content: " ", Width: 1, Style: i.style}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			scr.SetCell(x, y, blank)
+		}
+	}
+
+	if s.value == "" {
+		if i.placeholder != "" {
+			styled := uv.NewStyledString(i.placeholderStyle.Styled(i.placeholder))
+			styled.Wrap = i.multiline
+			styled.Draw(scr, area)
+		}
+		s.scrollX, s.scrollY = 0, 0
+		i.caret, i.hasCaret = area.Min, true
+		return
+	}
+
+	wrapWidth := 0
+	if i.multiline {
+		wrapWidth = area.Dx()
+	}
+	s.width = wrapWidth
+	rows := layoutInput(s.value, i.mask, wrapWidth)
+	caretRow, caretCol := caretCell(rows, s.caret)
+
+	// Scroll just enough to keep the caret in view, and no further than
+	// needed to show the end of the text.
+	if i.multiline {
+		caretCol = min(caretCol, area.Dx()-1)
+		s.scrollX = 0
+		s.scrollY = scrollToShow(s.scrollY, caretRow, area.Dy(), len(rows))
+	} else {
+		s.scrollX = scrollToShow(s.scrollX, caretCol, area.Dx(), rowWidth(rows[0])+1)
+		s.scrollY = 0
+	}
+
+	selStart, selEnd := s.Selection()
+	selected := i.style
+	selected.Attrs |= uv.AttrReverse
+	for y := 0; y < area.Dy() && s.scrollY+y < len(rows); y++ {
+		for _, g := range rows[s.scrollY+y].cells {
+			x := g.col - s.scrollX
+			if x < 0 || x+g.width > area.Dx() || g.width == 0 {
+				continue
+			}
+			cell := &uv.Cell{Content: g.text, Width: g.width, Style: i.style}
+			if g.offset >= selStart && g.offset < selEnd {
+				cell.Style = selected
+			}
+			scr.SetCell(area.Min.X+x, area.Min.Y+y, cell)
+		}
+	}
+
+	i.caret = uv.Pos(area.Min.X+caretCol-s.scrollX, area.Min.Y+caretRow-s.scrollY)
+	i.hasCaret = true
+}
+
+// Children returns nil for inputs.
+func (i *Input) Children() []Element {
+	return nil
+}
+
+// newState creates a state from the initial value of the input.
+func (i *Input) newState() *InputState {
+	if i.multiline {
+		return NewTextAreaState(i.value)
+	}
+	return NewInputState(i.value)
+}
+
+// scrollToShow returns the scroll offset that keeps pos visible in a view of
+// the given size over content of the given total size, moving the current
+// offset as little as possible.
+func scrollToShow(offset, pos, size, total int) int {
+	if pos < offset {
+		offset = pos
+	}
+	if pos >= offset+size {
+		offset = pos - size + 1
+	}
+	return max(0, min(offset, total-size))
+}
+
+// rowWidth returns the width of a row in cells.
+func rowWidth(row inputRow) int {
+	if len(row.cells) == 0 {
+		return 0
+	}
+	last := row.cells[len(row.cells)-1]
+	return last.col + last.width
+}
+
+// cursorElement is implemented by elements that place the terminal cursor
+// while focused.
+type cursorElement interface {
+	CursorPosition() (uv.Position, bool)
+}
+
+// Cursor returns the position at which to show the terminal cursor: the
+// caret of the focused input, if any.
+//
+// Example:
+//
+//	scr, boundsMap := m.template.RenderWithInputs(data, nil, m.focus, m.inputs, m.width, m.height)
+//	view := tea.NewView(scr.Render())
+//	if pos, ok := boundsMap.Cursor(); ok {
+//	    view.Cursor = tea.NewCursor(pos.X, pos.Y)
+//	}
+func (bm *BoundsMap) Cursor() (uv.Position, bool) {
+	if bm.cursor == nil {
+		return uv.Position{}, false
+	}
+	return *bm.cursor, true
+}
+
+// InputStore keeps the editing state of inputs and textareas between
+// renders, keyed by element ID. Pass it to Template.RenderWithInputs, and
+// route key presses to the focused input with HandleKey.
+//
+// Example:
+//
+//	case tea.KeyPressMsg:
+//	    if m.inputs.HandleKey(m.focus.Focused(), uv.KeyPressEvent(msg)) {
+//	        return m, nil
+//	    }
+//	    m.focus.HandleKey(msg.String())
+type InputStore struct {
+	states map[string]*InputState
+}
+
+// NewInputStore creates an empty input store.
+func NewInputStore() *InputStore {
+	return &InputStore{states: make(map[string]*InputState)}
+}
+
+// State returns the state of the input with the given ID, or nil if no such
+// input has been rendered or set.
+func (s *InputStore) State(id string) *InputState {
+	return s.states[id]
+}
+
+// Value returns the value of the input with the given ID.
+func (s *InputStore) Value(id string) string {
+	if state, ok := s.states[id]; ok {
+		return state.Value()
+	}
+	return ""
+}
+
+// SetValue sets the value of the input with the given ID. The state is
+// created if the input hasn't been rendered yet, so its value attribute is
+// ignored.
+func (s *InputStore) SetValue(id, value string) {
+	if state, ok := s.states[id]; ok {
+		state.SetValue(value)
+		return
+	}
+	// Keep newlines until the input is bound, in case it is a textarea.
+	s.states[id] = NewTextAreaState(value)
+}
+
+// HandleKey passes a key event to the input with the given ID and reports
+// whether the input handled it. It returns false if there is no such input.
+func (s *InputStore) HandleKey(id string, ev uv.KeyEvent) bool {
+	state, ok := s.states[id]
+	if !ok {
+		return false
+	}
+	return state.HandleKey(ev)
+}
+
+// bind gives the inputs with an explicit ID in an element tree their state
+// from the store, creating it from the initial value on first render.
+func (s *InputStore) bind(elem Element) {
+	if i, ok := elem.(*Input); ok && i.state == nil && hasExplicitID(i.ID()) {
+		state, ok := s.states[i.ID()]
+		if !ok {
+			state = i.newState()
+			s.states[i.ID()] = state
+		}
+		if state.multiline != i.multiline {
+			state.multiline = i.multiline
+			state.SetValue(state.value)
+		}
+		i.state = state
+	}
+	for _, child := range elem.Children() {
+		if child != nil {
+			s.bind(child)
+		}
+	}
+}
+
+// NewInputFromProps creates an input from props (for parser).
+func NewInputFromProps(props Props, _ []Element) Element {
+	return inputFromProps(NewInput(), props)
+}
+
+// NewTextAreaFromProps creates a textarea from props (for parser).
+func NewTextAreaFromProps(props Props, _ []Element) Element {
+	return inputFromProps(NewTextArea(), props)
+}
+
+// inputFromProps applies the attributes shared by inputs and textareas.
+func inputFromProps(i *Input, props Props) *Input {
+	if props.Has("value") {
+		i.Value(props.Get("value"))
+	}
+	if placeholder := props.Get("placeholder"); placeholder != "" {
+		i.Placeholder(placeholder)
+	}
+	if props.Get("type") == "password" {
+		i.Mask("*")
+	}
+	if mask := props.Get("mask"); mask != "" {
+		i.Mask(mask)
+	}
+	if fgColor := props.Get("foreground-color"); fgColor != "" {
+		if c, err := parseColor(fgColor); err == nil {
+			i.ForegroundColor(c)
+		}
+	}
+	if bgColor := props.Get("background-color"); bgColor != "" {
+		if c, err := parseColor(bgColor); err == nil {
+			i.BackgroundColor(c)
+		}
+	}
+	if width := props.Get("width"); width != "" {
+		i.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		i.Height(parseSizeConstraint(height))
+	}
+	return i
+}
+
+func init() {
+	Register("input", NewInputFromProps)
+	Register("textarea", NewTextAreaFromProps)
+
+	schema := &Schema{Attrs: append([]Attr{
+		{Name: "value"},
+		{Name: "placeholder"},
+		{Name: "type", Type: AttrEnum, Values: []string{"text", "password"}},
+		{Name: "mask"},
+		{Name: "background-color", Type: AttrColor},
+	}, sizeAttrs...)}
+	RegisterSchema("input", schema)
+	RegisterSchema("textarea", schema)
+}
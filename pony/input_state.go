@@ -0,0 +1,487 @@
+package pony
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/rivo/uniseg"
+)
+
+// InputState is the editing state of an input or textarea: its value, the
+// caret and the selection. Elements are rebuilt on every render, so the
+// state is kept outside of them, usually in an [InputStore] keyed by element
+// ID.
+//
+// Offsets are byte offsets into the value. They always fall on grapheme
+// cluster boundaries, so the caret never ends up inside a character made of
+// several code points, such as an emoji with a skin tone.
+type InputState struct {
+	value     string
+	caret     int
+	anchor    int // other end of the selection, equal to caret if none
+	multiline bool
+
+	// Scroll positions and wrap width of the last draw, kept so that the
+	// view only scrolls when the caret leaves it, and so that up and down
+	// move by wrapped rows.
+	scrollX int
+	scrollY int
+	width   int
+}
+
+// NewInputState creates a single-line editing state with the caret at the
+// end of the value.
+func NewInputState(value string) *InputState {
+	s := &InputState{}
+	s.SetValue(value)
+	return s
+}
+
+// NewTextAreaState creates a multiline editing state with the caret at the
+// end of the value.
+func NewTextAreaState(value string) *InputState {
+	s := &InputState{multiline: true}
+	s.SetValue(value)
+	return s
+}
+
+// Value returns the text being edited.
+func (s *InputState) Value() string {
+	return s.value
+}
+
+// SetValue replaces the text, moves the caret to its end and clears the
+// selection. Newlines are replaced by spaces in single-line states.
+func (s *InputState) SetValue(value string) {
+	s.value = sanitizeInput(value, s.multiline)
+	s.caret = len(s.value)
+	s.anchor = s.caret
+}
+
+// IsMultiline reports whether the state belongs to a textarea.
+func (s *InputState) IsMultiline() bool {
+	return s.multiline
+}
+
+// Caret returns the byte offset of the caret.
+func (s *InputState) Caret() int {
+	return s.caret
+}
+
+// SetCaret moves the caret to the grapheme boundary at or before the given
+// byte offset and clears the selection.
+func (s *InputState) SetCaret(offset int) {
+	s.caret = s.snap(offset)
+	s.anchor = s.caret
+}
+
+// Selection returns the byte range of the selected text. Start and end are
+// equal if nothing is selected.
+func (s *InputState) Selection() (start, end int) {
+	return min(s.anchor, s.caret), max(s.anchor, s.caret)
+}
+
+// SelectedText returns the selected text.
+func (s *InputState) SelectedText() string {
+	start, end := s.Selection()
+	return s.value[start:end]
+}
+
+// Select selects the text between two byte offsets, with the caret at end.
+func (s *InputState) Select(start, end int) {
+	s.anchor = s.snap(start)
+	s.caret = s.snap(end)
+}
+
+// SelectAll selects the whole text.
+func (s *InputState) SelectAll() {
+	s.Select(0, len(s.value))
+}
+
+// Insert replaces the selection with text, or inserts it at the caret, and
+// moves the caret after it. Use it for pasted text as well as typed text.
+func (s *InputState) Insert(text string) {
+	text = sanitizeInput(text, s.multiline)
+	start, end := s.Selection()
+	s.value = s.value[:start] + text + s.value[end:]
+	s.caret = start + len(text)
+	s.anchor = s.caret
+}
+
+// DeleteBackward deletes the selection, or the grapheme before the caret.
+func (s *InputState) DeleteBackward() {
+	s.deleteTo(prevGrapheme(s.value, s.caret))
+}
+
+// DeleteForward deletes the selection, or the grapheme after the caret.
+func (s *InputState) DeleteForward() {
+	s.deleteTo(nextGrapheme(s.value, s.caret))
+}
+
+// DeleteWordBackward deletes the selection, or the word before the caret.
+func (s *InputState) DeleteWordBackward() {
+	s.deleteTo(prevWord(s.value, s.caret))
+}
+
+// DeleteWordForward deletes the selection, or the word after the caret.
+func (s *InputState) DeleteWordForward() {
+	s.deleteTo(nextWord(s.value, s.caret))
+}
+
+// deleteTo deletes the selection if there is one, and the text between the
+// caret and offset otherwise.
+func (s *InputState) deleteTo(offset int) {
+	start, end := s.Selection()
+	if start == end {
+		start, end = min(s.caret, offset), max(s.caret, offset)
+	}
+	s.value = s.value[:start] + s.value[end:]
+	s.caret = start
+	s.anchor = start
+}
+
+// move moves the caret to offset, extending the selection if extend is set
+// and clearing it otherwise.
+func (s *InputState) move(offset int, extend bool) {
+	s.caret = offset
+	if !extend {
+		s.anchor = offset
+	}
+}
+
+// HandleKey edits the text in response to a key press and reports whether
+// the key was handled. Key releases and keys without a meaning for the input,
+// like Tab, are not handled, so they can be used for focus navigation. Enter
+// inserts a newline in textareas and is left to the caller in single-line
+// inputs, to submit a form for instance.
+//
+// Supported keys are the arrow keys, Home, End, Backspace and Delete, with
+// Shift to select and Ctrl or Alt to move or delete by word, as well as the
+// common readline bindings such as ctrl+a, ctrl+e, ctrl+w, ctrl+u and ctrl+k.
+func (s *InputState) HandleKey(ev uv.KeyEvent) bool {
+	if _, ok := ev.(uv.KeyReleaseEvent); ok {
+		return false
+	}
+	k := ev.Key()
+
+	start, end := s.Selection()
+	switch {
+	case k.MatchString("left", "ctrl+b"):
+		if start != end {
+			s.move(start, false)
+		} else {
+			s.move(prevGrapheme(s.value, s.caret), false)
+		}
+	case k.MatchString("right", "ctrl+f"):
+		if start != end {
+			s.move(end, false)
+		} else {
+			s.move(nextGrapheme(s.value, s.caret), false)
+		}
+	case k.MatchString("shift+left"):
+		s.move(prevGrapheme(s.value, s.caret), true)
+	case k.MatchString("shift+right"):
+		s.move(nextGrapheme(s.value, s.caret), true)
+	case k.MatchString("ctrl+left", "alt+left", "alt+b"):
+		s.move(prevWord(s.value, s.caret), false)
+	case k.MatchString("ctrl+right", "alt+right", "alt+f"):
+		s.move(nextWord(s.value, s.caret), false)
+	case k.MatchString("ctrl+shift+left", "alt+shift+left"):
+		s.move(prevWord(s.value, s.caret), true)
+	case k.MatchString("ctrl+shift+right", "alt+shift+right"):
+		s.move(nextWord(s.value, s.caret), true)
+	case k.MatchString("home", "ctrl+a"):
+		s.move(s.lineStart(), false)
+	case k.MatchString("end", "ctrl+e"):
+		s.move(s.lineEnd(), false)
+	case k.MatchString("shift+home"):
+		s.move(s.lineStart(), true)
+	case k.MatchString("shift+end"):
+		s.move(s.lineEnd(), true)
+	case k.MatchString("ctrl+home"):
+		s.move(0, false)
+	case k.MatchString("ctrl+end"):
+		s.move(len(s.value), false)
+	case s.multiline && k.MatchString("up", "shift+up"):
+		s.move(s.verticalMove(-1), k.Mod&uv.ModShift != 0)
+	case s.multiline && k.MatchString("down", "shift+down"):
+		s.move(s.verticalMove(1), k.Mod&uv.ModShift != 0)
+	case k.MatchString("backspace", "ctrl+h", "shift+backspace"):
+		s.DeleteBackward()
+	case k.MatchString("delete", "ctrl+d"):
+		s.DeleteForward()
+	case k.MatchString("alt+backspace", "ctrl+backspace", "ctrl+w"):
+		s.DeleteWordBackward()
+	case k.MatchString("alt+delete", "ctrl+delete", "alt+d"):
+		s.DeleteWordForward()
+	case k.MatchString("ctrl+u"):
+		s.deleteTo(s.lineStart())
+	case k.MatchString("ctrl+k"):
+		s.deleteTo(s.lineEnd())
+	case k.MatchString("enter"):
+		if !s.multiline {
+			return false
+		}
+		s.Insert("\n")
+	case k.Text != "" && k.Mod&^(uv.ModShift|uv.ModCapsLock|uv.ModNumLock) == 0:
+		s.Insert(k.Text)
+	default:
+		return false
+	}
+	return true
+}
+
+// lineStart returns the offset of the start of the caret's line.
+func (s *InputState) lineStart() int {
+	if !s.multiline {
+		return 0
+	}
+	return strings.LastIndexByte(s.value[:s.caret], '\n') + 1
+}
+
+// lineEnd returns the offset of the end of the caret's line.
+func (s *InputState) lineEnd() int {
+	if !s.multiline {
+		return len(s.value)
+	}
+	if i := strings.IndexByte(s.value[s.caret:], '\n'); i >= 0 {
+		return s.caret + i
+	}
+	return len(s.value)
+}
+
+// verticalMove returns the offset one row above (delta -1) or below
+// (delta 1) the caret, in the rows of the last draw, keeping the column as
+// far as possible. Moving past the first or last row goes to the start or
+// end of the text.
+func (s *InputState) verticalMove(delta int) int {
+	rows := layoutInput(s.value, "", s.width)
+	row, col := caretCell(rows, s.caret)
+	target := row + delta
+	switch {
+	case target < 0:
+		return 0
+	case target >= len(rows):
+		return len(s.value)
+	}
+
+	r := rows[target]
+	offset := r.start
+	for _, g := range r.cells {
+		if g.col+g.width > col {
+			break
+		}
+		offset = g.offset + len(g.text)
+	}
+	// The end of a wrapped row is the start of the next one.
+	if !r.last && offset == r.end && len(r.cells) > 0 {
+		offset = r.cells[len(r.cells)-1].offset
+	}
+	return offset
+}
+
+// snap clamps offset to the value and moves it back to a grapheme boundary.
+func (s *InputState) snap(offset int) int {
+	offset = max(0, min(offset, len(s.value)))
+	boundary := 0
+	for _, g := range splitGraphemes(s.value) {
+		if g.offset > offset {
+			break
+		}
+		boundary = g.offset
+	}
+	if offset == len(s.value) {
+		return offset
+	}
+	return boundary
+}
+
+// sanitizeInput drops control characters from text, except newlines in
+// multiline states, which are normalized to "\n". Newlines in single-line
+// states and tabs become spaces.
+func sanitizeInput(text string, multiline bool) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r':
+			if multiline {
+				return '\n'
+			}
+			return ' '
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// inputGrapheme is a grapheme cluster of an input's value.
+type inputGrapheme struct {
+	text   string
+	offset int // byte offset in the value
+	width  int // width in cells
+	col    int // column in its row, set by layoutInput
+}
+
+// splitGraphemes splits s into grapheme clusters.
+func splitGraphemes(s string) []inputGrapheme {
+	var graphemes []inputGrapheme
+	state := -1
+	offset := 0
+	for s != "" {
+		var cluster string
+		var width int
+		cluster, s, width, state = uniseg.FirstGraphemeClusterInString(s, state)
+		graphemes = append(graphemes, inputGrapheme{text: cluster, offset: offset, width: width})
+		offset += len(cluster)
+	}
+	return graphemes
+}
+
+// prevGrapheme returns the offset of the grapheme before offset.
+func prevGrapheme(s string, offset int) int {
+	prev := 0
+	for _, g := range splitGraphemes(s[:offset]) {
+		prev = g.offset
+	}
+	return prev
+}
+
+// nextGrapheme returns the offset of the grapheme after offset.
+func nextGrapheme(s string, offset int) int {
+	if offset >= len(s) {
+		return len(s)
+	}
+	cluster, _, _, _ := uniseg.FirstGraphemeClusterInString(s[offset:], -1)
+	return offset + len(cluster)
+}
+
+// prevWord returns the offset of the start of the word before offset,
+// skipping any spaces and punctuation in between.
+func prevWord(s string, offset int) int {
+	graphemes := splitGraphemes(s[:offset])
+	i := len(graphemes)
+	for i > 0 && !isWordGrapheme(graphemes[i-1].text) {
+		i--
+	}
+	for i > 0 && isWordGrapheme(graphemes[i-1].text) {
+		i--
+	}
+	if i == len(graphemes) {
+		return offset
+	}
+	return graphemes[i].offset
+}
+
+// nextWord returns the offset of the end of the word after offset, skipping
+// any spaces and punctuation in between.
+func nextWord(s string, offset int) int {
+	graphemes := splitGraphemes(s[offset:])
+	i := 0
+	for i < len(graphemes) && !isWordGrapheme(graphemes[i].text) {
+		i++
+	}
+	for i < len(graphemes) && isWordGrapheme(graphemes[i].text) {
+		i++
+	}
+	if i == len(graphemes) {
+		return len(s)
+	}
+	return offset + graphemes[i].offset
+}
+
+// isWordGrapheme reports whether a grapheme cluster is part of a word.
+func isWordGrapheme(g string) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// inputRow is a row of an input as drawn: a whole line of a single-line
+// input, or a wrapped part of a line of a textarea.
+type inputRow struct {
+	start, end int // byte range in the value, without the newline
+	last       bool
+	cells      []inputGrapheme
+}
+
+// layoutInput splits value into rows of at most width cells, breaking lines
+// after spaces where possible. A width of zero or less disables wrapping.
+// If mask is set, every grapheme is drawn as mask.
+func layoutInput(value, mask string, width int) []inputRow {
+	maskWidth := uniseg.StringWidth(mask)
+
+	var rows []inputRow
+	offset := 0
+	for _, line := range strings.Split(value, "\n") {
+		row := inputRow{start: offset}
+		col := 0
+		breakAt := -1 // index of the first cell after the last space
+		for _, g := range splitGraphemes(line) {
+			g.offset += offset
+			if mask != "" {
+				g.text, g.width = mask, maskWidth
+			}
+
+			if width > 0 && col+g.width > width && len(row.cells) > 0 {
+				split := len(row.cells)
+				if breakAt > 0 {
+					split = breakAt
+				}
+				rest := append([]inputGrapheme(nil), row.cells[split:]...)
+				row.cells = row.cells[:split]
+				row.end = g.offset
+				if len(rest) > 0 {
+					row.end = rest[0].offset
+				}
+				rows = append(rows, row)
+
+				row = inputRow{start: row.end}
+				col = 0
+				breakAt = -1
+				for _, r := range rest {
+					r.col = col
+					col += r.width
+					row.cells = append(row.cells, r)
+					if r.text == " " {
+						breakAt = len(row.cells)
+					}
+				}
+			}
+
+			g.col = col
+			col += g.width
+			row.cells = append(row.cells, g)
+			if g.text == " " {
+				breakAt = len(row.cells)
+			}
+		}
+		offset += len(line)
+		row.end = offset
+		row.last = true
+		rows = append(rows, row)
+		offset++ // newline
+	}
+	return rows
+}
+
+// caretCell returns the row and column of the caret in rows.
+func caretCell(rows []inputRow, caret int) (row, col int) {
+	for i, r := range rows {
+		if caret < r.start || caret > r.end || (caret == r.end && !r.last) {
+			continue
+		}
+		col := 0
+		for _, g := range r.cells {
+			if g.offset >= caret {
+				break
+			}
+			col = g.col + g.width
+		}
+		return i, col
+	}
+	return max(0, len(rows)-1), 0
+}
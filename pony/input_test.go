@@ -0,0 +1,253 @@
+package pony
+
+import (
+	"strings"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// press returns a key press event for a key string such as "ctrl+left" or a
+// printable character.
+func press(key string) uv.KeyPressEvent {
+	keys := map[string]uv.Key{
+		"left":            {Code: uv.KeyLeft},
+		"right":           {Code: uv.KeyRight},
+		"up":              {Code: uv.KeyUp},
+		"down":            {Code: uv.KeyDown},
+		"home":            {Code: uv.KeyHome},
+		"end":             {Code: uv.KeyEnd},
+		"enter":           {Code: uv.KeyEnter},
+		"tab":             {Code: uv.KeyTab},
+		"backspace":       {Code: uv.KeyBackspace},
+		"delete":          {Code: uv.KeyDelete},
+		"shift+left":      {Code: uv.KeyLeft, Mod: uv.ModShift},
+		"shift+end":       {Code: uv.KeyEnd, Mod: uv.ModShift},
+		"ctrl+left":       {Code: uv.KeyLeft, Mod: uv.ModCtrl},
+		"ctrl+right":      {Code: uv.KeyRight, Mod: uv.ModCtrl},
+		"ctrl+shift+left": {Code: uv.KeyLeft, Mod: uv.ModCtrl | uv.ModShift},
+		"alt+backspace":   {Code: uv.KeyBackspace, Mod: uv.ModAlt},
+		"ctrl+w":          {Code: 'w', Mod: uv.ModCtrl},
+		"ctrl+k":          {Code: 'k', Mod: uv.ModCtrl},
+	}
+	if k, ok := keys[key]; ok {
+		return uv.KeyPressEvent(k)
+	}
+	r := []rune(key)
+	return uv.KeyPressEvent{Code: r[0], Text: key}
+}
+
+func typeKeys(s *InputState, keys ...string) {
+	for _, key := range keys {
+		s.HandleKey(press(key))
+	}
+}
+
+func TestInputState_Graphemes(t *testing.T) {
+	// A family emoji and an e with a combining accent are single graphemes.
+	s := NewInputState("a👨‍👩‍👧éb")
+
+	typeKeys(s, "left", "left")
+	if want := len("a👨‍👩‍👧"); s.Caret() != want {
+		t.Fatalf("caret = %d, want %d", s.Caret(), want)
+	}
+	typeKeys(s, "backspace")
+	if s.Value() != "aéb" {
+		t.Errorf("value = %q, want the emoji deleted", s.Value())
+	}
+	typeKeys(s, "delete")
+	if s.Value() != "ab" || s.Caret() != 1 {
+		t.Errorf("value = %q, caret %d, want the accented e deleted", s.Value(), s.Caret())
+	}
+
+	s = NewInputState("a👨‍👩‍👧")
+	s.SetCaret(len("a") + 1) // inside the emoji
+	if s.Caret() != 1 {
+		t.Errorf("expected SetCaret to snap to a boundary, got %d", s.Caret())
+	}
+}
+
+func TestInputState_Keys(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		keys  []string
+		want  string
+		caret int
+	}{
+		{"type", "", []string{"h", "i", "!"}, "hi!", 3},
+		{"insert", "ac", []string{"left", "b"}, "abc", 2},
+		{"word left", "foo bar.baz", []string{"ctrl+left", "ctrl+left", "|"}, "foo |bar.baz", 5},
+		{"word right", "foo bar", []string{"home", "ctrl+right", "|"}, "foo| bar", 4},
+		{"delete word", "foo bar  ", []string{"ctrl+w"}, "foo ", 4},
+		{"alt backspace", "foo-bar", []string{"alt+backspace"}, "foo-", 4},
+		{"kill to end", "foo bar", []string{"home", "ctrl+right", "ctrl+k"}, "foo", 3},
+		{"replace selection", "hello world", []string{"ctrl+shift+left", "x"}, "hello x", 7},
+		{"delete selection", "hello", []string{"home", "shift+end", "backspace"}, "", 0},
+		{"collapse selection", "hello", []string{"shift+left", "shift+left", "left", "|"}, "hel|lo", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewInputState(tt.value)
+			typeKeys(s, tt.keys...)
+			if s.Value() != tt.want || s.Caret() != tt.caret {
+				t.Errorf("got %q with caret %d, want %q with caret %d", s.Value(), s.Caret(), tt.want, tt.caret)
+			}
+		})
+	}
+}
+
+func TestInputState_Unhandled(t *testing.T) {
+	s := NewInputState("one")
+	for _, key := range []string{"tab", "enter", "up", "down"} {
+		if s.HandleKey(press(key)) {
+			t.Errorf("expected %s not to be handled by a single-line input", key)
+		}
+	}
+	if s.HandleKey(uv.KeyReleaseEvent{Code: 'x', Text: "x"}) || s.Value() != "one" {
+		t.Error("expected key releases to be ignored")
+	}
+
+	s.Insert("two\nthree")
+	if s.Value() != "onetwo three" {
+		t.Errorf("expected newlines to become spaces, got %q", s.Value())
+	}
+
+	area := NewTextAreaState("one")
+	if !area.HandleKey(press("enter")) || area.Value() != "one\n" {
+		t.Errorf("expected enter to insert a newline, got %q", area.Value())
+	}
+}
+
+func TestInput_Scroll(t *testing.T) {
+	s := NewInputState("abcdefghij")
+	input := NewInput().State(s).Width(NewFixedConstraint(5))
+	draw := func() string {
+		scr := uv.NewScreenBuffer(5, 1)
+		input.Draw(scr, uv.Rect(0, 0, 5, 1))
+		return strings.TrimRight(scr.Render(), " ")
+	}
+
+	// The caret sits after the last character, so the view leaves a cell
+	// for it.
+	if got := draw(); got != "ghij" {
+		t.Errorf("got %q, want the end of the value", got)
+	}
+	if pos, _ := input.CursorPosition(); pos.X != 4 {
+		t.Errorf("caret at %d, want 4", pos.X)
+	}
+
+	// The view only scrolls once the caret leaves it.
+	typeKeys(s, "left", "left", "left")
+	if got := draw(); got != "ghij" {
+		t.Errorf("got %q, want no scrolling", got)
+	}
+	typeKeys(s, "home")
+	if got := draw(); got != "abcde" {
+		t.Errorf("got %q, want the start of the value", got)
+	}
+	if pos, _ := input.CursorPosition(); pos.X != 0 {
+		t.Errorf("caret at %d, want 0", pos.X)
+	}
+}
+
+func TestInput_PlaceholderAndMask(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<vstack>
+	<input id="user" placeholder="Username" />
+	<input id="pass" type="password" value="secret" />
+</vstack>
+`)
+	out := tmpl.Render(struct{}{}, 20, 2)
+	lines := strings.Split(out, "\n")
+	if !strings.Contains(lines[0], "Username") {
+		t.Errorf("expected the placeholder, got %q", lines[0])
+	}
+	if strings.Contains(out, "secret") || !strings.Contains(lines[1], "******") {
+		t.Errorf("expected the password to be masked, got %q", lines[1])
+	}
+}
+
+func TestTextArea_Wrap(t *testing.T) {
+	s := NewTextAreaState("the quick brown fox\njumps")
+	area := NewTextArea().State(s)
+	scr := uv.NewScreenBuffer(10, 3)
+	area.Draw(scr, uv.Rect(0, 0, 10, 3))
+
+	// Lines break after spaces.
+	var got []string
+	for _, line := range strings.Split(scr.Render(), "\n") {
+		got = append(got, strings.TrimRight(line, " \r"))
+	}
+	if want := []string{"the quick", "brown fox", "jumps"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got rows %q, want %q", got, want)
+	}
+	if pos, _ := area.CursorPosition(); pos != uv.Pos(5, 2) {
+		t.Errorf("caret at %v, want (5,2)", pos)
+	}
+
+	// Up and down move by wrapped rows, keeping the column.
+	typeKeys(s, "up")
+	if s.Caret() != len("the quick brown") {
+		t.Errorf("caret = %d after up, want %d", s.Caret(), len("the quick brown"))
+	}
+	typeKeys(s, "up")
+	if s.Caret() != len("the q") {
+		t.Errorf("caret = %d after up, want %d", s.Caret(), len("the q"))
+	}
+	typeKeys(s, "up")
+	if s.Caret() != 0 {
+		t.Errorf("expected up on the first row to go to the start, got %d", s.Caret())
+	}
+}
+
+func TestInputStore(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<vstack>
+	<text>Name</text>
+	<input id="name" value="Al" />
+	<textarea id="bio" width="10" />
+</vstack>
+`)
+	inputs := NewInputStore()
+	fm := NewFocusManager()
+	fm.Focus("name")
+
+	_, bm := tmpl.RenderWithInputs(struct{}{}, nil, fm, inputs, 20, 10)
+	if inputs.Value("name") != "Al" {
+		t.Fatalf("expected the value attribute to seed the state, got %q", inputs.Value("name"))
+	}
+	if pos, ok := bm.Cursor(); !ok || pos != uv.Pos(2, 1) {
+		t.Errorf("cursor = %v, %v, want (2,1)", pos, ok)
+	}
+
+	// Edits persist across renders, even though the value attribute is
+	// still set.
+	for _, key := range []string{"e", "x"} {
+		if !inputs.HandleKey("name", press(key)) {
+			t.Errorf("expected %q to be handled", key)
+		}
+	}
+	scr, bm := tmpl.RenderWithInputs(struct{}{}, nil, fm, inputs, 20, 10)
+	if !strings.Contains(scr.Render(), "Alex") || inputs.Value("name") != "Alex" {
+		t.Errorf("expected the edit to persist, got %q", inputs.Value("name"))
+	}
+	if pos, _ := bm.Cursor(); pos != uv.Pos(4, 1) {
+		t.Errorf("cursor = %v, want (4,1)", pos)
+	}
+
+	// Values set before the first render survive binding, with newlines
+	// in textareas only.
+	inputs.SetValue("bio", "line one\nline two")
+	fm.Blur()
+	_, bm = tmpl.RenderWithInputs(struct{}{}, nil, fm, inputs, 20, 10)
+	if inputs.Value("bio") != "line one\nline two" {
+		t.Errorf("got bio %q", inputs.Value("bio"))
+	}
+	if _, ok := bm.Cursor(); ok {
+		t.Error("expected no cursor without a focused input")
+	}
+	if inputs.HandleKey("missing", press("x")) {
+		t.Error("expected unknown inputs not to handle keys")
+	}
+}
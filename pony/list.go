@@ -0,0 +1,181 @@
+package pony
+
+import (
+	"image/color"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ListData provides the items of a list on demand, so that long lists don't
+// have to be built up front.
+type ListData interface {
+	// Len returns the number of items.
+	Len() int
+	// Item returns the text of the item at index i.
+	Item(i int) string
+}
+
+// ListItems is a ListData backed by a slice.
+type ListItems []string
+
+// Len returns the number of items.
+func (l ListItems) Len() int { return len(l) }
+
+// Item returns the item at index i.
+func (l ListItems) Item(i int) string { return l[i] }
+
+// List represents a list of single-line items with an optional selected
+// item. Only the items in view are drawn, so lists with many items are
+// cheap, see Virtualized. Lists fill the available width unless a width is
+// set. When a list is drawn in less height than it needs outside of a
+// ScrollView, it shows the rows around the selected item.
+//
+// Example:
+//
+//	<list id="files" selected="1" marker="> ">
+//	    <item>main.go</item>
+//	    <item>go.mod</item>
+//	</list>
+type List struct {
+	BaseElement
+	data          ListData
+	selected      int
+	marker        string
+	style         uv.Style
+	selectedStyle uv.Style
+	width         SizeConstraint
+	height        SizeConstraint
+	rows          rowViewport
+}
+
+var (
+	_ Element     = (*List)(nil)
+	_ Virtualized = (*List)(nil)
+)
+
+// NewList creates a list of items.
+func NewList(items ...string) *List {
+	return NewListFromData(ListItems(items))
+}
+
+// NewListFromData creates a list that gets its items from data.
+// Nothing is selected, and the selected item is drawn in reverse video.
+func NewListFromData(data ListData) *List {
+	return &List{
+		data:          data,
+		selected:      -1,
+		selectedStyle: uv.Style{Attrs: uv.AttrReverse},
+	}
+}
+
+// Selected sets the index of the selected item, or -1 for none, and returns
+// the list for chaining.
+func (l *List) Selected(index int) *List {
+	l.selected = index
+	return l
+}
+
+// SelectedIndex returns the index of the selected item, or -1.
+func (l *List) SelectedIndex() int {
+	return l.selected
+}
+
+// Len returns the number of items.
+func (l *List) Len() int {
+	if l.data == nil {
+		return 0
+	}
+	return l.data.Len()
+}
+
+// Marker sets a prefix drawn before the selected item, such as "> ", and
+// returns the list for chaining. Other items are indented to match.
+func (l *List) Marker(marker string) *List {
+	l.marker = marker
+	return l
+}
+
+// Style sets the style of the items and returns the list for chaining.
+func (l *List) Style(style uv.Style) *List {
+	l.style = style
+	return l
+}
+
+// SelectedStyle sets the style of the selected item and returns the list for
+// chaining.
+func (l *List) SelectedStyle(style uv.Style) *List {
+	l.selectedStyle = style
+	return l
+}
+
+// ForegroundColor sets the text color and returns the list for chaining.
+func (l *List) ForegroundColor(c color.Color) *List {
+	l.style.Fg = c
+	return l
+}
+
+// Width sets the width constraint and returns the list for chaining.
+func (l *List) Width(width SizeConstraint) *List {
+	l.width = width
+	return l
+}
+
+// Height sets the height constraint and returns the list for chaining.
+func (l *List) Height(height SizeConstraint) *List {
+	l.height = height
+	return l
+}
+
+// RowAt returns the index of the item at the given screen coordinates, from
+// the last draw, for handling clicks.
+func (l *List) RowAt(x, y int) (int, bool) {
+	return l.rows.rowAt(x, y)
+}
+
+// Layout calculates the list size: one row per item.
+func (l *List) Layout(constraints Constraints) Size {
+	natural := 0
+	if n := l.Len(); n > 0 {
+		// Measuring every item would defeat virtualization, so only the
+		// first screenful is measured for the natural width.
+		for i := range min(n, 100) {
+			natural = max(natural, ansi.StringWidth(l.data.Item(i)))
+		}
+		natural += ansi.StringWidth(l.marker)
+	}
+	return layoutRows(constraints, l.width, l.height, natural, l.Len())
+}
+
+// Draw renders the list, scrolled to show the selected item if it doesn't
+// fit.
+func (l *List) Draw(scr uv.Screen, area uv.Rectangle) {
+	l.DrawViewport(scr, area, 0, firstVisibleRow(l.selected, area.Dy(), l.Len()))
+}
+
+// DrawViewport renders the items visible in area when scrolled by offsetX
+// columns and offsetY rows.
+func (l *List) DrawViewport(scr uv.Screen, area uv.Rectangle, offsetX, offsetY int) {
+	l.SetBounds(area)
+	count := l.Len()
+	l.rows = rowViewport{area: area, first: offsetY, count: count}
+
+	indent := fitCell("", ansi.StringWidth(l.marker), AlignmentLeading)
+	for y := 0; y < area.Dy(); y++ {
+		i := offsetY + y
+		if i >= count {
+			break
+		}
+
+		prefix, style := indent, l.style
+		if i == l.selected {
+			prefix, style = l.marker, l.selectedStyle
+		}
+		drawRow(scr, area, area.Min.Y+y, prefix+l.data.Item(i), style, offsetX)
+	}
+}
+
+// Children returns nil for lists.
+func (l *List) Children() []Element {
+	return nil
+}
@@ -0,0 +1,84 @@
+package pony
+
+import (
+	"strings"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestList(t *testing.T) {
+	list := NewList("one", "two", "three", "four", "five").Selected(3).Marker("> ")
+	scr := uv.NewScreenBuffer(10, 3)
+	size := list.Layout(Constraints{MaxWidth: 10, MaxHeight: 3})
+	if size.Width != 10 || size.Height != 3 {
+		t.Fatalf("expected the list to fill the width and be clipped to 3 rows, got %+v", size)
+	}
+	list.Draw(scr, uv.Rect(0, 0, 10, 3))
+
+	// The rows scroll to show the selected item.
+	want := []string{"  two", "  three", "> four"}
+	if got := screenLines(&scr); !equalLines(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := scr.CellAt(9, 2).Style.Attrs; got&uv.AttrReverse == 0 {
+		t.Error("expected the selected style to cover the whole row")
+	}
+
+	if row, ok := list.RowAt(0, 0); !ok || row != 1 {
+		t.Errorf("RowAt(0, 0) = %d, %v, want 1, true", row, ok)
+	}
+	if _, ok := list.RowAt(0, 3); ok {
+		t.Error("expected no row outside the list")
+	}
+}
+
+func TestList_Markup(t *testing.T) {
+	tmpl := MustParse[[]string](`
+<list id="files" selected="1" selected-foreground-color="red">
+	{{ range . }}<item>{{ . }}</item>{{ end }}
+</list>`)
+	scr, bm := tmpl.RenderWithBounds([]string{"main.go", "go.mod"}, nil, 20, 5)
+
+	elem, ok := bm.GetByID("files")
+	if !ok {
+		t.Fatal("list not rendered")
+	}
+	list := elem.(*List)
+	if list.Len() != 2 || list.SelectedIndex() != 1 {
+		t.Errorf("unexpected list %d items, selected %d", list.Len(), list.SelectedIndex())
+	}
+	if got := scr.CellAt(0, 1).Style.Fg; got != mustColor(t, "red") {
+		t.Errorf("expected the selected item to be red, got %v", got)
+	}
+	if got := screenLines(&scr)[:2]; !equalLines(got, []string{"main.go", "go.mod"}) {
+		t.Errorf("unexpected items %q", got)
+	}
+}
+
+// screenLines returns the text of the lines of a screen, without trailing
+// spaces.
+func screenLines(scr *uv.ScreenBuffer) []string {
+	lines := make([]string, scr.Height())
+	for y := range lines {
+		lines[y] = strings.TrimRight(scr.Line(y).String(), " ")
+	}
+	return lines
+}
+
+func equalLines(got, want []string) bool {
+	if len(got) < len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	for _, line := range got[len(want):] {
+		if line != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,501 @@
+package pony
+
+import (
+	"encoding/xml"
+	"image/color"
+	"slices"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/ultraviolet/screen"
+)
+
+// OverlayElement is implemented by elements drawn in a layer above the rest of the
+// UI, like Dialog and Popover. In markup they can be declared anywhere; they
+// are taken out of the flow of their parent and drawn after the rest of the
+// tree, in document order or by z-index.
+type OverlayElement interface {
+	Element
+
+	// Place returns the area of the overlay on a screen, given the bounds
+	// of the elements drawn beneath it by ID. It reports false if the
+	// overlay shouldn't be drawn, such as a popover whose anchor isn't.
+	Place(scr uv.Rectangle, bounds func(id string) (uv.Rectangle, bool)) (uv.Rectangle, bool)
+
+	// Modal reports whether the overlay blocks the layers beneath it:
+	// clicks don't reach them, and focus stays in the modal layers.
+	Modal() bool
+}
+
+// Dialog is a modal overlay centered on the screen over a dimmed backdrop.
+// Clicks on the backdrop hit the dialog itself, so that the application can
+// close it, and never reach the elements beneath.
+//
+// Example:
+//
+//	<dialog id="confirm">
+//	    <box border="rounded" padding="1">
+//	        <text>Discard changes?</text>
+//	    </box>
+//	</dialog>
+type Dialog struct {
+	BaseElement
+	child    Element
+	dim      bool
+	backdrop color.Color
+	width    SizeConstraint
+	height   SizeConstraint
+}
+
+var _ OverlayElement = (*Dialog)(nil)
+
+// NewDialog creates a dialog with a dimmed backdrop.
+func NewDialog(child Element) *Dialog {
+	return &Dialog{child: child, dim: true}
+}
+
+// Dim sets whether the content beneath the dialog is dimmed and returns the
+// dialog for chaining.
+func (d *Dialog) Dim(dim bool) *Dialog {
+	d.dim = dim
+	return d
+}
+
+// Backdrop sets the background color of the backdrop and returns the dialog
+// for chaining.
+func (d *Dialog) Backdrop(c color.Color) *Dialog {
+	d.backdrop = c
+	return d
+}
+
+// Width sets the width of the dialog content and returns the dialog for
+// chaining.
+func (d *Dialog) Width(width SizeConstraint) *Dialog {
+	d.width = width
+	return d
+}
+
+// Height sets the height of the dialog content and returns the dialog for
+// chaining.
+func (d *Dialog) Height(height SizeConstraint) *Dialog {
+	d.height = height
+	return d
+}
+
+// Place returns the whole screen, which the backdrop covers.
+func (d *Dialog) Place(scr uv.Rectangle, _ func(string) (uv.Rectangle, bool)) (uv.Rectangle, bool) {
+	return scr, true
+}
+
+// Modal reports true: dialogs block the UI beneath them.
+func (d *Dialog) Modal() bool {
+	return true
+}
+
+// Layout returns the whole available space, which the backdrop covers.
+func (d *Dialog) Layout(constraints Constraints) Size {
+	return Size{Width: constraints.MaxWidth, Height: constraints.MaxHeight}
+}
+
+// Draw dims the area and draws the content centered on it.
+func (d *Dialog) Draw(scr uv.Screen, area uv.Rectangle) {
+	d.SetBounds(area)
+
+	if d.dim || d.backdrop != nil {
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				c := scr.CellAt(x, y)
+				if c == nil || isWidePlaceholder(c) {
+					continue
+				}
+				dimmed := *c
+				if d.dim {
+					dimmed.Style.Attrs |= uv.AttrFaint
+				}
+				if d.backdrop != nil {
+					dimmed.Style.Bg = d.backdrop
+				}
+				scr.SetCell(x, y, &dimmed)
+			}
+		}
+	}
+
+	if d.child == nil {
+		return
+	}
+	size := d.contentSize(area)
+	content := uv.CenterRect(area, size.Width, size.Height)
+	screen.ClearArea(scr, content)
+	drawElement(d.child, scr, content)
+}
+
+// contentSize returns the size of the content in an area.
+func (d *Dialog) contentSize(area uv.Rectangle) Size {
+	constraints := Constraints{MaxWidth: area.Dx(), MaxHeight: area.Dy()}
+	if !d.width.IsAuto() {
+		constraints.MaxWidth = d.width.Apply(area.Dx(), area.Dx())
+	}
+	if !d.height.IsAuto() {
+		constraints.MaxHeight = d.height.Apply(area.Dy(), area.Dy())
+	}
+	size := layoutElement(d.child, constraints)
+	if !d.width.IsAuto() {
+		size.Width = constraints.MaxWidth
+	}
+	if !d.height.IsAuto() {
+		size.Height = constraints.MaxHeight
+	}
+	return constraints.Constrain(size)
+}
+
+// Children returns the content.
+func (d *Dialog) Children() []Element {
+	if d.child == nil {
+		return nil
+	}
+	return []Element{d.child}
+}
+
+// Popover is an overlay placed next to an anchor element, identified by its
+// ID, such as a menu under a button or a tooltip. It is placed below the
+// anchor by default and flips to the other side when it would go off the
+// screen, then shifts to stay on it. Popovers aren't modal: the rest of the
+// UI can still be clicked.
+//
+// Example:
+//
+//	<button id="file" text="File" />
+//	<popover anchor="file" placement="bottom">
+//	    <box border="normal"><text>Open</text></box>
+//	</popover>
+type Popover struct {
+	BaseElement
+	child     Element
+	anchor    string
+	placement string
+	offset    int
+	width     SizeConstraint
+	height    SizeConstraint
+}
+
+var _ OverlayElement = (*Popover)(nil)
+
+// NewPopover creates a popover anchored to the element with the given ID,
+// placed below it.
+func NewPopover(child Element, anchor string) *Popover {
+	return &Popover{child: child, anchor: anchor, placement: PlacementBottom}
+}
+
+// Placement sets the side of the anchor the popover goes on and returns the
+// popover for chaining: PlacementBottom, PlacementTop, PlacementLeft or
+// PlacementRight.
+func (p *Popover) Placement(placement string) *Popover {
+	p.placement = placement
+	return p
+}
+
+// Offset sets the distance between the anchor and the popover and returns
+// the popover for chaining.
+func (p *Popover) Offset(offset int) *Popover {
+	p.offset = offset
+	return p
+}
+
+// Width sets the width constraint and returns the popover for chaining.
+func (p *Popover) Width(width SizeConstraint) *Popover {
+	p.width = width
+	return p
+}
+
+// Height sets the height constraint and returns the popover for chaining.
+func (p *Popover) Height(height SizeConstraint) *Popover {
+	p.height = height
+	return p
+}
+
+// Modal reports false: popovers let the UI beneath them be clicked.
+func (p *Popover) Modal() bool {
+	return false
+}
+
+// Place returns the area of the popover next to its anchor. The popover
+// flips to the opposite side of the anchor if it doesn't fit on its side
+// but fits better on the other, and is shifted along the side to stay on
+// the screen.
+func (p *Popover) Place(scr uv.Rectangle, bounds func(string) (uv.Rectangle, bool)) (uv.Rectangle, bool) {
+	anchor, ok := bounds(p.anchor)
+	if !ok || anchor.Empty() {
+		return uv.Rectangle{}, false
+	}
+	size := p.Layout(Constraints{MaxWidth: scr.Dx(), MaxHeight: scr.Dy()})
+	w, h := size.Width, size.Height
+
+	var x, y int
+	switch p.placement {
+	case PlacementLeft, PlacementRight:
+		right := anchor.Max.X + p.offset
+		left := anchor.Min.X - p.offset - w
+		spaceRight := scr.Max.X - right
+		spaceLeft := anchor.Min.X - p.offset - scr.Min.X
+		x = right
+		if p.placement == PlacementLeft {
+			x = left
+			if spaceLeft < w && spaceRight > spaceLeft {
+				x = right
+			}
+		} else if spaceRight < w && spaceLeft > spaceRight {
+			x = left
+		}
+		y = anchor.Min.Y
+	default:
+		below := anchor.Max.Y + p.offset
+		above := anchor.Min.Y - p.offset - h
+		spaceBelow := scr.Max.Y - below
+		spaceAbove := anchor.Min.Y - p.offset - scr.Min.Y
+		y = below
+		if p.placement == PlacementTop {
+			y = above
+			if spaceAbove < h && spaceBelow > spaceAbove {
+				y = below
+			}
+		} else if spaceBelow < h && spaceAbove > spaceBelow {
+			y = above
+		}
+		x = anchor.Min.X
+	}
+
+	// Shift to stay on the screen.
+	x = max(scr.Min.X, min(x, scr.Max.X-w))
+	y = max(scr.Min.Y, min(y, scr.Max.Y-h))
+	return uv.Rect(x, y, w, h).Intersect(scr), true
+}
+
+// Layout calculates the size of the content.
+func (p *Popover) Layout(constraints Constraints) Size {
+	if p.child == nil {
+		return Size{}
+	}
+	if !p.width.IsAuto() {
+		constraints.MaxWidth = p.width.Apply(constraints.MaxWidth, constraints.MaxWidth)
+	}
+	if !p.height.IsAuto() {
+		constraints.MaxHeight = p.height.Apply(constraints.MaxHeight, constraints.MaxHeight)
+	}
+	size := layoutElement(p.child, constraints)
+	if !p.width.IsAuto() {
+		size.Width = constraints.MaxWidth
+	}
+	if !p.height.IsAuto() {
+		size.Height = constraints.MaxHeight
+	}
+	return constraints.Constrain(size)
+}
+
+// Draw clears the area and draws the content in it.
+func (p *Popover) Draw(scr uv.Screen, area uv.Rectangle) {
+	p.SetBounds(area)
+	if p.child == nil {
+		return
+	}
+	screen.ClearArea(scr, area)
+	drawElement(p.child, scr, area)
+}
+
+// Children returns the content.
+func (p *Popover) Children() []Element {
+	if p.child == nil {
+		return nil
+	}
+	return []Element{p.child}
+}
+
+// Layers draws a base element and the overlays above it, each in its own
+// layer of the BoundsMap. Templates build it for markup with <dialog> or
+// <popover> elements; build one directly for element trees made in Go.
+type Layers struct {
+	BaseElement
+	base     Element
+	overlays []OverlayElement
+}
+
+var _ Element = (*Layers)(nil)
+
+// NewLayers creates layers with overlays drawn above base, in order.
+func NewLayers(base Element, overlays ...OverlayElement) *Layers {
+	return &Layers{base: base, overlays: overlays}
+}
+
+// Layout returns the whole available space when there are overlays, which
+// are placed on the screen, and the size of the base otherwise.
+func (l *Layers) Layout(constraints Constraints) Size {
+	var size Size
+	if l.base != nil {
+		size = layoutElement(l.base, constraints)
+	}
+	if len(l.overlays) > 0 {
+		size = Size{Width: constraints.MaxWidth, Height: constraints.MaxHeight}
+	}
+	return size
+}
+
+// Draw draws the base, then each overlay where it is placed.
+func (l *Layers) Draw(scr uv.Screen, area uv.Rectangle) {
+	l.SetBounds(area)
+	if l.base != nil {
+		size := layoutElement(l.base, Constraints{MaxWidth: area.Dx(), MaxHeight: area.Dy()})
+		drawElement(l.base, scr, uv.Rect(area.Min.X, area.Min.Y, size.Width, size.Height))
+	}
+
+	// Overlays can be anchored to the base and to the overlays beneath.
+	bounds := func(id string) (uv.Rectangle, bool) {
+		for _, elem := range l.Children() {
+			if found := findElement(elem, id); found != nil {
+				return found.Bounds(), true
+			}
+		}
+		return uv.Rectangle{}, false
+	}
+	for _, o := range l.overlays {
+		placed, ok := o.Place(area, bounds)
+		if !ok {
+			// Not drawn, so it can't be hit.
+			o.SetBounds(uv.Rectangle{})
+			continue
+		}
+		o.Draw(scr, placed)
+	}
+}
+
+// Children returns the base followed by the overlays.
+func (l *Layers) Children() []Element {
+	children := make([]Element, 0, len(l.overlays)+1)
+	if l.base != nil {
+		children = append(children, l.base)
+	}
+	for _, o := range l.overlays {
+		children = append(children, o)
+	}
+	return children
+}
+
+// layerOf returns the layer of a child: 0 for the base, and 1 and up for
+// the overlays in order.
+func (l *Layers) layerOf(child Element) int {
+	for i, o := range l.overlays {
+		if Element(o) == child {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// findElement returns the element with the given ID in a tree, or nil.
+func findElement(elem Element, id string) Element {
+	if elem == nil {
+		return nil
+	}
+	if elem.ID() == id {
+		return elem
+	}
+	for _, child := range elem.Children() {
+		if found := findElement(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// layersTag is the tag of the node built around markup with overlays. It
+// can't be written in markup.
+const layersTag = "#layers"
+
+// extractOverlays takes the <dialog> and <popover> nodes out of the tree
+// and returns a layers node with the tree followed by the overlays, sorted
+// by z-index, or the tree itself if it has none.
+func (n *node) extractOverlays() *node {
+	var overlays []*node
+	var walk func(n *node)
+	walk = func(n *node) {
+		children := n.Children[:0]
+		for _, child := range n.Children {
+			switch child.XMLName.Local {
+			case "dialog", "popover":
+				overlays = append(overlays, child)
+			default:
+				children = append(children, child)
+			}
+			walk(child)
+		}
+		n.Children = children
+	}
+	walk(n)
+	if len(overlays) == 0 {
+		return n
+	}
+
+	slices.SortStableFunc(overlays, func(a, b *node) int {
+		return parseIntAttr(a.Props(), "z-index", 0) - parseIntAttr(b.Props(), "z-index", 0)
+	})
+	return &node{
+		XMLName:  xml.Name{Local: layersTag},
+		Children: append([]*node{n}, overlays...),
+	}
+}
+
+// toLayers converts the layers node to a Layers element.
+func (n *node) toLayers() Element {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	var overlays []OverlayElement
+	for _, child := range n.Children[1:] {
+		if o, ok := child.toElement().(OverlayElement); ok {
+			overlays = append(overlays, o)
+		}
+	}
+	return NewLayers(n.Children[0].toElement(), overlays...)
+}
+
+// toDialog converts node to Dialog element.
+func (n *node) toDialog(props Props) Element {
+	var child Element
+	if children := n.childElements(); len(children) == 1 {
+		child = children[0]
+	} else if len(children) > 1 {
+		child = NewVStack(children...)
+	}
+
+	dialog := NewDialog(child).Dim(parseBoolAttr(props, "dim", true))
+	if bg := props.Get("backdrop"); bg != "" {
+		if c, err := parseColor(bg); err == nil {
+			dialog = dialog.Backdrop(c)
+		}
+	}
+	if width := props.Get("width"); width != "" {
+		dialog = dialog.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		dialog = dialog.Height(parseSizeConstraint(height))
+	}
+	return dialog
+}
+
+// toPopover converts node to Popover element.
+func (n *node) toPopover(props Props) Element {
+	var child Element
+	if children := n.childElements(); len(children) == 1 {
+		child = children[0]
+	} else if len(children) > 1 {
+		child = NewVStack(children...)
+	}
+
+	popover := NewPopover(child, props.Get("anchor")).
+		Placement(props.GetOr("placement", PlacementBottom)).
+		Offset(parseIntAttr(props, "offset", 0))
+	if width := props.Get("width"); width != "" {
+		popover = popover.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		popover = popover.Height(parseSizeConstraint(height))
+	}
+	return popover
+}
@@ -0,0 +1,130 @@
+package pony
+
+import (
+	"slices"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+type overlayData struct {
+	Open bool
+}
+
+const overlayMarkup = `<vstack id="root">
+	<button id="menu" text="Menu" />
+	<button id="base" text="Base" />
+	<popover id="tip" anchor="menu">
+		<text>Tip</text>
+	</popover>
+	{{ if .Open }}
+	<dialog id="confirm" width="10" height="3">
+		<button id="ok" text="OK" />
+	</dialog>
+	{{ end }}
+</vstack>`
+
+func TestDialog_Centered(t *testing.T) {
+	tmpl := MustParse[overlayData](overlayMarkup)
+	_, bm := tmpl.RenderWithBounds(overlayData{Open: true}, nil, 20, 9)
+
+	bounds, ok := bm.GetBounds("confirm")
+	if !ok {
+		t.Fatal("dialog not registered")
+	}
+	if want := uv.Rect(0, 0, 20, 9); bounds != want {
+		t.Errorf("dialog bounds: got %v, want %v", bounds, want)
+	}
+	okBounds, _ := bm.GetBounds("ok")
+	if okBounds.Min.X != 5 || okBounds.Min.Y != 3 {
+		t.Errorf("dialog content at %v, want it centered at (5,3)", okBounds.Min)
+	}
+
+	// Clicks outside the dialog content don't reach the elements beneath.
+	base, _ := bm.GetBounds("base")
+	if hit := bm.HitTest(base.Min.X, base.Min.Y); hit == nil || hit.ID() != "confirm" {
+		t.Errorf("click on the backdrop hit %v, want the dialog", hit)
+	}
+	if hit := bm.HitTest(okBounds.Min.X, okBounds.Min.Y); hit == nil || hit.ID() != "ok" {
+		t.Errorf("click in the dialog hit %v, want ok", hit)
+	}
+
+	// Focus is trapped in the dialog.
+	if got, want := bm.FocusOrder(), []string{"ok"}; !slices.Equal(got, want) {
+		t.Errorf("focus order: got %q, want %q", got, want)
+	}
+
+	_, bm = tmpl.RenderWithBounds(overlayData{}, nil, 20, 9)
+	if got, want := bm.FocusOrder(), []string{"menu", "base"}; !slices.Equal(got, want) {
+		t.Errorf("focus order without dialog: got %q, want %q", got, want)
+	}
+}
+
+func TestDialog_Dim(t *testing.T) {
+	scr := uv.NewScreenBuffer(12, 5)
+	layers := NewLayers(NewText("under"), NewDialog(NewText("hi")).Width(NewFixedConstraint(4)).Height(NewFixedConstraint(1)))
+	layers.Layout(Constraints{MaxWidth: 12, MaxHeight: 5})
+	layers.Draw(scr, scr.Bounds())
+
+	if c := scr.CellAt(0, 0); c == nil || c.Content != "u" || c.Style.Attrs&uv.AttrFaint == 0 {
+		t.Errorf("expected dimmed base content, got %+v", c)
+	}
+	if c := scr.CellAt(4, 2); c == nil || c.Content != "h" || c.Style.Attrs&uv.AttrFaint != 0 {
+		t.Errorf("expected undimmed dialog content, got %+v", c)
+	}
+}
+
+func TestPopover_Placement(t *testing.T) {
+	tmpl := MustParse[overlayData](overlayMarkup)
+	_, bm := tmpl.RenderWithBounds(overlayData{}, nil, 20, 9)
+
+	menu, _ := bm.GetBounds("menu")
+	tip, ok := bm.GetBounds("tip")
+	if !ok {
+		t.Fatal("popover not registered")
+	}
+	if tip.Min.Y != menu.Max.Y || tip.Min.X != menu.Min.X {
+		t.Errorf("popover at %v, want it below the anchor %v", tip, menu)
+	}
+
+	// The popover covers the button beneath it, which can't be clicked.
+	if hit := bm.HitTest(tip.Min.X, tip.Min.Y); hit == nil || hit.ID() != "tip" {
+		t.Errorf("click on the popover hit %v, want tip", hit)
+	}
+	if hit := bm.HitTest(menu.Min.X, menu.Min.Y); hit == nil || hit.ID() != "menu" {
+		t.Errorf("click on the anchor hit %v, want menu", hit)
+	}
+}
+
+func TestPopover_Flip(t *testing.T) {
+	anchor := NewText("anchor")
+	anchor.SetID("anchor")
+	base := NewVStack(NewSpacer(), anchor)
+	popover := NewPopover(NewText("tip"), "anchor")
+
+	scr := uv.NewScreenBuffer(10, 4)
+	layers := NewLayers(base, popover)
+	layers.Layout(Constraints{MaxWidth: 10, MaxHeight: 4})
+	layers.Draw(scr, scr.Bounds())
+
+	// There is no room below the anchor on the last line.
+	if got, want := popover.Bounds(), uv.Rect(0, 2, 3, 1); got != want {
+		t.Errorf("flipped popover: got %v, want %v", got, want)
+	}
+	if got := screenLines(&scr)[2]; got != "tip" {
+		t.Errorf("line 2: got %q, want %q", got, "tip")
+	}
+}
+
+func TestLayers_Retained(t *testing.T) {
+	tmpl := MustParse[overlayData](overlayMarkup)
+	renderer := NewRenderer(tmpl)
+
+	for _, data := range []overlayData{{}, {Open: true}, {Open: true}, {}} {
+		got, _ := renderer.Render(data, nil, nil, nil, 20, 9)
+		want, _ := tmpl.RenderWithBounds(data, nil, 20, 9)
+		if got.Render() != want.Render() {
+			t.Errorf("open=%v: retained frame differs\ngot:\n%s\nwant:\n%s", data.Open, got.Render(), want.Render())
+		}
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"image/color"
 	"io"
 	"strings"
+	"time"
+
+	uv "github.com/charmbracelet/ultraviolet"
 )
 
 // node represents a parsed XML element.
@@ -14,17 +17,86 @@ type node struct {
 	Attrs    []xml.Attr `xml:",any,attr"`
 	Content  string     `xml:",chardata"`
 	Children []*node    `xml:",any"`
+
+	// State of retained mode, see Renderer.
+	retain   bool    // build the element for reuse by the next frame
+	volatile bool    // the node contains state from outside the markup
+	noLayer  bool    // the element must not cache its drawing
+	hash     uint64  // hash of the node and its descendants
+	elem     Element // element built for the node, or kept from the previous frame
 }
 
-// Props converts XML attributes to Props map.
+// Prefixes of attributes that only apply while the element is in a state,
+// such as focus:border-color. They correspond to the :focus, :hover and
+// :active pseudo-classes of stylesheets.
+const (
+	focusPrefix  = "focus"
+	hoverPrefix  = "hover"
+	activePrefix = "active"
+)
+
+// Props converts XML attributes to Props map. Prefixed attributes are kept
+// under their full name, such as "hover:foreground-color".
 func (n *node) Props() Props {
 	props := make(Props)
 	for _, attr := range n.Attrs {
+		if attr.Name.Space != "" {
+			props[attr.Name.Space+":"+attr.Name.Local] = attr.Value
+			continue
+		}
 		props[attr.Name.Local] = attr.Value
 	}
 	return props
 }
 
+// applyPseudo finds the node with the given ID and makes its attributes with
+// the given prefix override the others. It reports whether the node was
+// found.
+func (n *node) applyPseudo(prefix, id string) bool {
+	if id == "" {
+		return false
+	}
+	if n.Props().Get("id") == id {
+		var stateAttrs []xml.Attr
+		for _, attr := range n.Attrs {
+			if attr.Name.Space == prefix {
+				stateAttrs = append(stateAttrs, xml.Attr{Name: xml.Name{Local: attr.Name.Local}, Value: attr.Value})
+			}
+		}
+		n.Attrs = append(n.Attrs, stateAttrs...)
+		return true
+	}
+
+	for _, child := range n.Children {
+		if child.applyPseudo(prefix, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritedProperties are the text attributes that elements take from their
+// parent unless they set them, as in CSS.
+var inheritedProperties = []string{"foreground-color", "font-weight", "font-style", "text-decoration"}
+
+// inherit copies the inherited properties of parent to the node and its
+// descendants that don't set them.
+func (n *node) inherit(parent Props) {
+	if n.XMLName.Local == "" {
+		return
+	}
+	props := n.Props()
+	for _, name := range inheritedProperties {
+		if !props.Has(name) && parent.Has(name) {
+			n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: parent.Get(name)})
+			props[name] = parent.Get(name)
+		}
+	}
+	for _, child := range n.Children {
+		child.inherit(props)
+	}
+}
+
 // parse parses XML markup into a node tree.
 func parse(markup string) (*node, error) {
 	// Wrap in root element if not already wrapped
@@ -61,6 +133,10 @@ func (n *node) toElement() Element {
 		return nil
 	}
 
+	if elem, ok := n.retained(); ok {
+		return elem
+	}
+
 	// Get the tag name
 	tagName := n.XMLName.Local
 	props := n.Props()
@@ -85,16 +161,32 @@ func (n *node) toElement() Element {
 			elem = n.toBox(props)
 		case "spacer":
 			elem = n.toSpacer(props)
+		case "grid":
+			elem = n.toGrid(props)
 		case "flex":
 			elem = n.toFlex(props)
+		case "dialog":
+			elem = n.toDialog(props)
+		case "popover":
+			elem = n.toPopover(props)
+		case layersTag:
+			elem = n.toLayers()
 		case "positioned":
 			elem = n.toPositioned(props)
 		case "divider":
 			elem = n.toDivider(props)
+		case "spinner":
+			elem = n.toSpinner(props)
 		case "slot":
 			elem = n.toSlot(props)
 		case "scrollview":
 			elem = n.toScrollView(props)
+		case "list":
+			elem = n.toList(props)
+		case "table":
+			elem = n.toTable(props)
+		case "tree":
+			elem = n.toTree(props)
 		case "":
 			// Anonymous text node (no tag, just content)
 			content := strings.TrimSpace(n.Content)
@@ -116,6 +208,26 @@ func (n *node) toElement() Element {
 		}
 	}
 
+	// Make focusable if requested
+	if elem != nil && (props.Has("focusable") || props.Has("tab-index")) {
+		if setter, ok := elem.(interface {
+			SetFocusable(bool)
+			SetTabIndex(int)
+		}); ok {
+			if props.Has("tab-index") {
+				setter.SetTabIndex(parseIntAttr(props, "tab-index", 0))
+			}
+			if props.Has("focusable") {
+				setter.SetFocusable(parseBoolAttr(props, "focusable", false))
+			}
+		}
+	}
+
+	if elem != nil {
+		setHandlers(elem, props)
+	}
+
+	n.keep(elem)
 	return elem
 }
 
@@ -210,6 +322,80 @@ func (n *node) toZStack(props Props) Element {
 	return zstack
 }
 
+// toGrid converts node to Grid element. Children with placement attributes
+// are wrapped in a GridItem.
+func (n *node) toGrid(props Props) Element {
+	var items []Element
+	for _, child := range n.Children {
+		if child.XMLName.Local == "" && strings.TrimSpace(child.Content) != "" {
+			items = append(items, NewText(strings.TrimSpace(child.Content)))
+			continue
+		}
+
+		elem := child.toElement()
+		if elem == nil {
+			continue
+		}
+		items = append(items, child.toGridItem(elem))
+	}
+
+	grid := NewGrid(items...)
+	if tracks, err := ParseGridTracks(props.Get("columns")); err == nil && len(tracks) > 0 {
+		grid = grid.Columns(tracks...)
+	}
+	if tracks, err := ParseGridTracks(props.Get("rows")); err == nil && len(tracks) > 0 {
+		grid = grid.Rows(tracks...)
+	}
+
+	gap := parseIntAttr(props, "gap", 0)
+	rowGap := parseIntAttr(props, "row-gap", gap)
+	columnGap := parseIntAttr(props, "column-gap", gap)
+	grid = grid.Gap(rowGap, columnGap)
+
+	if areas := props.Get("areas"); areas != "" {
+		grid = grid.Areas(strings.Split(areas, ",")...)
+	}
+	if justify := props.Get("justify-items"); justify != "" {
+		grid = grid.JustifyItems(justify)
+	}
+	if align := props.Get("align-items"); align != "" {
+		grid = grid.AlignItems(align)
+	}
+
+	width := parseSizeConstraint(props.Get("width"))
+	height := parseSizeConstraint(props.Get("height"))
+	if !width.IsAuto() {
+		grid = grid.Width(width)
+	}
+	if !height.IsAuto() {
+		grid = grid.Height(height)
+	}
+
+	return grid
+}
+
+// toGridItem wraps elem in a GridItem when the node has grid placement
+// attributes.
+func (n *node) toGridItem(elem Element) Element {
+	props := n.Props()
+	placed := false
+	for _, attr := range []string{"col", "row", "col-span", "row-span", "area", "justify-self", "align-self"} {
+		placed = placed || props.Has(attr)
+	}
+	if !placed {
+		return elem
+	}
+
+	return NewGridItem(elem).
+		Column(parseIntAttr(props, "col", 0)).
+		Row(parseIntAttr(props, "row", 0)).
+		ColumnSpan(parseIntAttr(props, "col-span", 1)).
+		RowSpan(parseIntAttr(props, "row-span", 1)).
+		Area(props.Get("area")).
+		JustifySelf(props.Get("justify-self")).
+		AlignSelf(props.Get("align-self"))
+}
+
 // toText converts node to Text element.
 func (n *node) toText(props Props) Element {
 	// Collect text from content and children
@@ -431,6 +617,22 @@ func (n *node) toDivider(props Props) Element {
 	return divider
 }
 
+// toSpinner converts node to Spinner element.
+func (n *node) toSpinner(props Props) Element {
+	spinner := NewSpinner(props.GetOr("type", SpinnerDots))
+	if interval := props.Get("interval"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil {
+			spinner = spinner.Interval(d)
+		}
+	}
+	if fgColor := props.Get("foreground-color"); fgColor != "" {
+		if c, err := parseColor(fgColor); err == nil {
+			spinner = spinner.ForegroundColor(c)
+		}
+	}
+	return spinner
+}
+
 // toSlot converts node to Slot element.
 func (n *node) toSlot(props Props) Element {
 	name := props.Get("name")
@@ -495,6 +697,129 @@ func (n *node) toScrollView(props Props) Element {
 	return scrollView
 }
 
+// toList converts node to List element, with an item per <item> child.
+func (n *node) toList(props Props) Element {
+	var items []string
+	for _, child := range n.Children {
+		if child.XMLName.Local == "item" {
+			items = append(items, child.text())
+		}
+	}
+
+	list := NewList(items...).
+		Selected(parseIntAttr(props, "selected", -1)).
+		Marker(props.Get("marker")).
+		Style(rowStyleFromProps(props, "", uv.Style{})).
+		SelectedStyle(rowStyleFromProps(props, "selected-", uv.Style{Attrs: uv.AttrReverse}))
+	if width := props.Get("width"); width != "" {
+		list.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		list.Height(parseSizeConstraint(height))
+	}
+	return list
+}
+
+// toTable converts node to Table element, with a column per <column> child
+// and a row per <row> child of <cell> elements.
+func (n *node) toTable(props Props) Element {
+	var columns []TableColumn
+	var rows [][]string
+	for _, child := range n.Children {
+		switch child.XMLName.Local {
+		case "column":
+			colProps := child.Props()
+			title := colProps.Get("title")
+			if title == "" {
+				title = child.text()
+			}
+			columns = append(columns, TableColumn{
+				Title:     title,
+				Width:     parseSizeConstraint(colProps.Get("width")),
+				Alignment: colProps.Get("alignment"),
+			})
+		case "row":
+			var cells []string
+			for _, cell := range child.Children {
+				if cell.XMLName.Local == "cell" {
+					cells = append(cells, cell.text())
+				}
+			}
+			rows = append(rows, cells)
+		}
+	}
+
+	table := NewTable(columns...).
+		Rows(rows...).
+		Header(parseBoolAttr(props, "header", true)).
+		Spacing(parseIntAttr(props, "spacing", 1)).
+		Selected(parseIntAttr(props, "selected", -1)).
+		Style(rowStyleFromProps(props, "", uv.Style{})).
+		HeaderStyle(rowStyleFromProps(props, "header-", uv.Style{Attrs: uv.AttrBold})).
+		SelectedStyle(rowStyleFromProps(props, "selected-", uv.Style{Attrs: uv.AttrReverse}))
+	if width := props.Get("width"); width != "" {
+		table.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		table.Height(parseSizeConstraint(height))
+	}
+	return table
+}
+
+// toTree converts node to Tree element, with nested <node> children.
+func (n *node) toTree(props Props) Element {
+	tree := NewTree(n.treeNodes()...).
+		Selected(parseIntAttr(props, "selected", -1)).
+		Style(rowStyleFromProps(props, "", uv.Style{})).
+		SelectedStyle(rowStyleFromProps(props, "selected-", uv.Style{Attrs: uv.AttrReverse}))
+	if width := props.Get("width"); width != "" {
+		tree.Width(parseSizeConstraint(width))
+	}
+	if height := props.Get("height"); height != "" {
+		tree.Height(parseSizeConstraint(height))
+	}
+	return tree
+}
+
+// treeNodes converts the <node> children of n to tree nodes.
+func (n *node) treeNodes() []*TreeNode {
+	var nodes []*TreeNode
+	for _, child := range n.Children {
+		if child.XMLName.Local != "node" {
+			continue
+		}
+		props := child.Props()
+		label := props.Get("label")
+		if label == "" {
+			label = child.text()
+		}
+		nodes = append(nodes, &TreeNode{
+			ID:       props.Get("id"),
+			Label:    label,
+			Expanded: parseBoolAttr(props, "expanded", false),
+			Children: child.treeNodes(),
+		})
+	}
+	return nodes
+}
+
+// text returns the text content of the node and its anonymous text
+// children, trimmed.
+func (n *node) text() string {
+	var parts []string
+	if content := strings.TrimSpace(n.Content); content != "" {
+		parts = append(parts, content)
+	}
+	for _, child := range n.Children {
+		if child.XMLName.Local == "" {
+			if content := strings.TrimSpace(child.Content); content != "" {
+				parts = append(parts, content)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 // Helper functions for parsing attributes
 
 func parseIntAttr(props Props, key string, defaultValue int) int {
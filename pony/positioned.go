@@ -83,7 +83,7 @@ func (p *Positioned) Draw(scr uv.Screen, area uv.Rectangle) {
 		constraints.MaxHeight = height
 	}
 
-	childSize := p.child.Layout(constraints)
+	childSize := layoutElement(p.child, constraints)
 
 	// Calculate position based on positioning constraints
 	var childArea uv.Rectangle
@@ -117,7 +117,7 @@ func (p *Positioned) Draw(scr uv.Screen, area uv.Rectangle) {
 	// Ensure the area is within parent bounds
 	childArea = childArea.Intersect(area)
 
-	p.child.Draw(scr, childArea)
+	drawElement(p.child, scr, childArea)
 }
 
 // Layout calculates the positioned element size.
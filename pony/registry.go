@@ -10,6 +10,7 @@ type ComponentFactory func(props Props, children []Element) Element
 
 var (
 	registry   = make(map[string]ComponentFactory)
+	schemas    = make(map[string]*Schema)
 	registryMu sync.RWMutex
 )
 
@@ -30,11 +31,29 @@ func Register(name string, factory ComponentFactory) {
 	registry[name] = factory
 }
 
+// RegisterSchema describes the markup of a registered component, so that
+// Validate and ParseStrict check its attributes and children.
+//
+// Example:
+//
+//	pony.RegisterSchema("badge", &pony.Schema{
+//	    Attrs: []pony.Attr{
+//	        {Name: "text"},
+//	        {Name: "variant", Type: pony.AttrEnum, Values: []string{"info", "warning"}},
+//	    },
+//	})
+func RegisterSchema(name string, schema *Schema) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	schemas[name] = schema
+}
+
 // Unregister removes a registered component.
 func Unregister(name string) {
 	registryMu.Lock()
 	defer registryMu.Unlock()
 	delete(registry, name)
+	delete(schemas, name)
 }
 
 // GetComponent retrieves a component factory by name.
@@ -64,4 +83,5 @@ func ClearRegistry() {
 	registryMu.Lock()
 	defer registryMu.Unlock()
 	registry = make(map[string]ComponentFactory)
+	schemas = make(map[string]*Schema)
 }
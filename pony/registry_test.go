@@ -1,6 +1,7 @@
 package pony
 
 import (
+	"maps"
 	"testing"
 
 	"github.com/charmbracelet/x/exp/golden"
@@ -143,6 +144,16 @@ func TestCustomComponentWithChildren(t *testing.T) {
 
 // Test ClearRegistry.
 func TestClearRegistry(t *testing.T) {
+	// Restore the built-in components and their schemas afterwards.
+	registryMu.RLock()
+	savedRegistry, savedSchemas := maps.Clone(registry), maps.Clone(schemas)
+	registryMu.RUnlock()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		registry, schemas = savedRegistry, savedSchemas
+	})
+
 	Register("test-clear", func(Props, []Element) Element {
 		return NewText("test")
 	})
@@ -156,10 +167,6 @@ func TestClearRegistry(t *testing.T) {
 	if _, ok := GetComponent("test-clear"); ok {
 		t.Error("Component should be cleared")
 	}
-
-	// Re-register built-in components since we cleared everything
-	Register("badge", NewBadge)
-	Register("progressview", NewProgressView)
 }
 
 // Test component Children methods.
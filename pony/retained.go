@@ -0,0 +1,442 @@
+package pony
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Renderer renders a template in retained mode, for UIs that re-render
+// often. Template.Render rebuilds, lays out and draws every element on each
+// frame; a Renderer keeps the previous frame and does only the work that
+// changed:
+//
+//   - When the template produces the same markup as the previous frame, the
+//     parsed and styled markup is reused instead of being parsed again.
+//   - Otherwise the new markup is compared with the previous one, matching
+//     elements by ID or by position among their siblings, and unchanged
+//     elements are kept with their layout and their drawing.
+//   - The frame is drawn into a screen buffer kept across frames, whose
+//     touched lines are the cells that changed, see Dirty.
+//
+// Slots, inputs, textareas and spinners, and the elements containing them,
+// are rebuilt on every frame since their state lives outside of the markup.
+// So is the whole tree while transitions of the template's clock run.
+// Elements of a retained frame must not be modified, as the changes may not
+// show on the next frame; change the data instead.
+//
+// A Renderer is not safe for concurrent use.
+type Renderer[T any] struct {
+	tmpl    *Template[T]
+	markup  string
+	state   pseudoState
+	root    *node
+	screen  uv.ScreenBuffer
+	scratch uv.ScreenBuffer
+	elem    Element
+	size    Size
+	bounds  *BoundsMap
+}
+
+// pseudoState is the focused, hovered and pressed element of a frame.
+type pseudoState struct {
+	focused string
+	hovered string
+	active  string
+}
+
+// NewRenderer creates a retained mode renderer for a template.
+func NewRenderer[T any](tmpl *Template[T]) *Renderer[T] {
+	return &Renderer[T]{tmpl: tmpl}
+}
+
+// Render renders a frame like Template.RenderWithInputs. Slots, focus and
+// inputs may be nil.
+//
+// The returned screen buffer is reused by the next frame. Its touched lines
+// cover the cells that changed since the previous frame, or every line after
+// a resize.
+func (r *Renderer[T]) Render(data T, slots map[string]Element, focus *FocusManager, inputs *InputStore, width, height int) (uv.ScreenBuffer, *BoundsMap) {
+	markup, err := r.tmpl.execute(data)
+	if err != nil {
+		r.Invalidate()
+		return uv.NewScreenBuffer(width, 1), NewBoundsMap()
+	}
+
+	var state pseudoState
+	if focus != nil {
+		state = pseudoState{focus.Focused(), focus.Hovered(), focus.Active()}
+	}
+	root := r.root
+	if root == nil || markup != r.markup || state != r.state || r.tmpl.clock.scene(r.tmpl).animating() {
+		root, err = r.tmpl.prepare(markup, focus)
+		if err != nil {
+			r.Invalidate()
+			return uv.NewScreenBuffer(width, 1), NewBoundsMap()
+		}
+		root.fingerprint(state, false)
+		root.reuse(r.root)
+		r.root, r.markup, r.state = root, markup, state
+	}
+
+	elem := root.toElement()
+	if elem == nil {
+		r.Invalidate()
+		return uv.NewScreenBuffer(width, height), NewBoundsMap()
+	}
+	bindElement(elem, slots, focus, inputs, r.tmpl.clock.scene(r.tmpl))
+
+	size := layoutElement(elem, Constraints{MaxWidth: width, MaxHeight: height})
+	size.Width = min(size.Width, width)
+	size.Height = min(size.Height, height)
+
+	if elem == r.elem && size == r.size && retainedStateOf(elem) != nil {
+		// Nothing changed: the screen and the bounds are those of the
+		// previous frame.
+		clear(r.screen.Touched)
+	} else {
+		r.draw(elem, size)
+		r.bounds = NewBoundsMap()
+		walkAndRegister(elem, r.bounds)
+		r.elem, r.size = elem, size
+	}
+	if focus != nil {
+		focus.Update(r.bounds)
+	}
+	return r.screen, r.bounds
+}
+
+// Dirty returns the areas of the screen that changed in the last frame, one
+// per run of consecutive lines changed over the same columns.
+func (r *Renderer[T]) Dirty() []uv.Rectangle {
+	if r.screen.Buffer == nil {
+		return nil
+	}
+
+	var dirty []uv.Rectangle
+	for y, line := range r.screen.Touched {
+		if line == nil {
+			continue
+		}
+		rect := uv.Rect(line.FirstCell, y, line.LastCell-line.FirstCell, 1)
+		if n := len(dirty); n > 0 {
+			last := &dirty[n-1]
+			if last.Max.Y == y && last.Min.X == rect.Min.X && last.Max.X == rect.Max.X {
+				last.Max.Y++
+				continue
+			}
+		}
+		dirty = append(dirty, rect)
+	}
+	return dirty
+}
+
+// Invalidate drops the previous frame, so that the next one is built and
+// drawn from scratch.
+func (r *Renderer[T]) Invalidate() {
+	r.root = nil
+	r.markup = ""
+	r.state = pseudoState{}
+	r.screen = uv.ScreenBuffer{}
+	r.elem = nil
+}
+
+// draw draws the element into a scratch buffer, then copies the cells that
+// changed to the screen so that they are marked as touched.
+func (r *Renderer[T]) draw(elem Element, size Size) {
+	if r.scratch.Buffer == nil || r.scratch.Width() != size.Width || r.scratch.Height() != size.Height {
+		r.scratch = uv.NewScreenBuffer(size.Width, size.Height)
+	} else {
+		fillBuffer(r.scratch.Buffer, uv.EmptyCell)
+	}
+	drawElement(elem, r.scratch, uv.Rect(0, 0, size.Width, size.Height))
+
+	resized := r.screen.Buffer == nil || r.screen.Width() != size.Width || r.screen.Height() != size.Height
+	if resized {
+		r.screen = uv.NewScreenBuffer(size.Width, size.Height)
+	}
+	clear(r.screen.Touched)
+	for y := range size.Height {
+		for x := range size.Width {
+			c := r.scratch.CellAt(x, y)
+			if isWidePlaceholder(c) {
+				// Set along with the wide cell before it.
+				continue
+			}
+			if !c.Equal(r.screen.CellAt(x, y)) {
+				r.screen.SetCell(x, y, c)
+			}
+		}
+		if resized {
+			r.screen.TouchLine(0, y, size.Width)
+		}
+	}
+}
+
+// fillBuffer sets every cell of buf to c, without marking them as touched.
+func fillBuffer(buf *uv.Buffer, c uv.Cell) {
+	for _, line := range buf.Lines {
+		for x := range line {
+			line[x] = c
+		}
+	}
+}
+
+// isWidePlaceholder reports whether c is one of the empty cells following a
+// wide cell.
+func isWidePlaceholder(c *uv.Cell) bool {
+	return c.Width == 0 && c.Content == ""
+}
+
+// fingerprint hashes the tag, attributes and content of every node of the
+// tree, along with whether it is focused, hovered or pressed, and marks the
+// nodes as built in retained mode. Slots and inputs, and the nodes
+// containing them, are volatile. Nodes inside a scroll view don't cache
+// their drawing, as the scroll view moves their bounds after drawing them.
+func (n *node) fingerprint(state pseudoState, inScrollView bool) {
+	n.retain = true
+	n.noLayer = inScrollView
+	switch n.XMLName.Local {
+	case "slot", "input", "textarea", "spinner":
+		n.volatile = true
+	case "dialog", "popover", layersTag:
+		// Overlays draw over what is beneath them.
+		n.noLayer = true
+	}
+
+	h := fnv.New64a()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(n.XMLName.Local)
+	for _, attr := range n.Attrs {
+		write(attr.Name.Space)
+		write(attr.Name.Local)
+		write(attr.Value)
+	}
+	write(n.Content)
+
+	props := n.Props()
+	if props.Has("id") {
+		id := props.Get("id")
+		for _, target := range []string{state.focused, state.hovered, state.active} {
+			write(strconv.FormatBool(id == target))
+		}
+	}
+
+	var sum [16]byte
+	for _, child := range n.Children {
+		child.fingerprint(state, inScrollView || n.XMLName.Local == "scrollview")
+		n.volatile = n.volatile || child.volatile
+		h.Write(strconv.AppendUint(sum[:0], child.hash, 16))
+	}
+	n.hash = h.Sum64()
+}
+
+// reuse takes the elements of the nodes of the previous frame that haven't
+// changed. Children are matched by ID, or by position among the siblings of
+// the same tag without an ID.
+func (n *node) reuse(old *node) {
+	if old == nil || old.XMLName != n.XMLName {
+		return
+	}
+	if old.hash == n.hash && !n.volatile && old.elem != nil {
+		n.elem = old.elem
+		return
+	}
+
+	// The node changed since the last frame, so it is likely to change
+	// again: drawing it into a layer would only add a copy to every frame.
+	n.noLayer = true
+
+	previous := make(map[string]*node, len(old.Children))
+	for i, key := range childKeys(old.Children) {
+		previous[key] = old.Children[i]
+	}
+	for i, key := range childKeys(n.Children) {
+		if match, ok := previous[key]; ok {
+			// Each element can only be reused once.
+			delete(previous, key)
+			n.Children[i].reuse(match)
+		}
+	}
+}
+
+// childKeys returns the keys matching children across frames.
+func childKeys(children []*node) []string {
+	keys := make([]string, len(children))
+	counts := make(map[string]int)
+	for i, child := range children {
+		if id := child.Props().Get("id"); id != "" {
+			keys[i] = "#" + id
+			continue
+		}
+		tag := child.XMLName.Local
+		keys[i] = tag + ":" + strconv.Itoa(counts[tag])
+		counts[tag]++
+	}
+	return keys
+}
+
+// retained returns the element kept for the node from the previous frame,
+// if any.
+func (n *node) retained() (Element, bool) {
+	if !n.retain || n.volatile || n.elem == nil {
+		return nil, false
+	}
+	return n.elem, true
+}
+
+// keep records the element built for the node in retained mode, so that the
+// next frame can reuse it, and gives it a cache for its layout and drawing.
+func (n *node) keep(elem Element) {
+	if !n.retain || elem == nil {
+		return
+	}
+	n.elem = elem
+	if n.volatile {
+		return
+	}
+	if r, ok := elem.(retainer); ok {
+		r.setRetained(&retainedState{layer: !n.noLayer})
+	}
+}
+
+// retainer is implemented by elements that can cache their layout and
+// drawing across frames in retained mode, through BaseElement.
+type retainer interface {
+	retained() *retainedState
+	setRetained(state *retainedState)
+}
+
+// retainedState caches the layout and the drawing of an element.
+type retainedState struct {
+	layouts []cachedLayout
+	layer   bool
+	drawing *layer
+}
+
+// cachedLayout is the size of an element for some constraints.
+type cachedLayout struct {
+	constraints Constraints
+	size        Size
+}
+
+// maxCachedLayouts is the number of layouts cached per element; containers
+// lay out their children a few times with different constraints.
+const maxCachedLayouts = 4
+
+// retained returns the cache of the element, or nil outside of retained mode.
+func (b *BaseElement) retained() *retainedState {
+	return b.cache
+}
+
+// setRetained sets the cache of the element.
+func (b *BaseElement) setRetained(state *retainedState) {
+	b.cache = state
+}
+
+// retainedStateOf returns the cache of an element, or nil.
+func retainedStateOf(elem Element) *retainedState {
+	if r, ok := elem.(retainer); ok {
+		return r.retained()
+	}
+	return nil
+}
+
+// layoutElement lays out an element, or returns its cached size for the same
+// constraints in retained mode. Containers lay out their children with it.
+func layoutElement(elem Element, constraints Constraints) Size {
+	st := retainedStateOf(elem)
+	if st == nil {
+		return elem.Layout(constraints)
+	}
+	if _, ok := elem.(Virtualized); ok {
+		// Cheap to lay out, and they keep state from their last layout.
+		return elem.Layout(constraints)
+	}
+
+	for _, l := range st.layouts {
+		if l.constraints == constraints {
+			return l.size
+		}
+	}
+	size := elem.Layout(constraints)
+	if len(st.layouts) == maxCachedLayouts {
+		st.layouts = st.layouts[1:]
+	}
+	st.layouts = append(st.layouts, cachedLayout{constraints, size})
+	return size
+}
+
+// drawElement draws an element. In retained mode, the element is drawn into
+// a layer that is kept and composited again as long as the element is drawn
+// in the same area. Containers draw their children with it.
+func drawElement(elem Element, scr uv.Screen, area uv.Rectangle) {
+	st := retainedStateOf(elem)
+	if st == nil || !st.layer {
+		elem.Draw(scr, area)
+		return
+	}
+	if st.drawing == nil || st.drawing.area != area {
+		st.drawing = newLayer(area, scr.WidthMethod())
+		elem.Draw(st.drawing, area)
+	}
+	st.drawing.composite(scr)
+}
+
+// transparentCell marks the cells of a layer that weren't drawn.
+var transparentCell = uv.Cell{Content: "\x00", Width: 1}
+
+// layer is an off-screen buffer covering an area of the screen, in screen
+// coordinates. Cells that aren't drawn let what is beneath show through
+// when the layer is composited.
+type layer struct {
+	area   uv.Rectangle
+	buf    *uv.Buffer
+	method uv.WidthMethod
+}
+
+var _ uv.Screen = (*layer)(nil)
+
+// newLayer creates a transparent layer.
+func newLayer(area uv.Rectangle, method uv.WidthMethod) *layer {
+	buf := uv.NewBuffer(area.Dx(), area.Dy())
+	fillBuffer(buf, transparentCell)
+	return &layer{area: area, buf: buf, method: method}
+}
+
+// Bounds returns the area of the layer.
+func (l *layer) Bounds() uv.Rectangle {
+	return l.area
+}
+
+// CellAt returns the cell at the given screen position.
+func (l *layer) CellAt(x, y int) *uv.Cell {
+	return l.buf.CellAt(x-l.area.Min.X, y-l.area.Min.Y)
+}
+
+// SetCell sets the cell at the given screen position.
+func (l *layer) SetCell(x, y int, c *uv.Cell) {
+	l.buf.SetCell(x-l.area.Min.X, y-l.area.Min.Y, c)
+}
+
+// WidthMethod returns the width method of the screen the layer is drawn on.
+func (l *layer) WidthMethod() uv.WidthMethod {
+	return l.method
+}
+
+// composite draws the drawn cells of the layer onto scr.
+func (l *layer) composite(scr uv.Screen) {
+	for y := range l.area.Dy() {
+		for x := range l.area.Dx() {
+			c := l.buf.CellAt(x, y)
+			if c.Content == transparentCell.Content || isWidePlaceholder(c) {
+				continue
+			}
+			scr.SetCell(l.area.Min.X+x, l.area.Min.Y+y, c)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package pony
+
+import (
+	"fmt"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+type retainedData struct {
+	Title      string
+	Items      []string
+	Background string
+}
+
+const retainedMarkup = `<vstack>
+	<box id="header" border="rounded"><text>{{ .Title }}</text></box>
+	<vstack id="items">{{ range .Items }}<text>{{ . }}</text>{{ end }}</vstack>
+	<hstack spacing="1">
+		<button id="ok" text="OK" focus:foreground-color="cyan" />
+		<button id="cancel" text="Cancel" focus:foreground-color="cyan" />
+	</hstack>
+	<zstack>
+		<text>{{ .Background }}</text>
+		<positioned x="4" y="0"><text>TOP</text></positioned>
+	</zstack>
+	<text id="footer">Footer</text>
+</vstack>`
+
+func TestRenderer_MatchesTemplate(t *testing.T) {
+	tmpl := MustParse[retainedData](retainedMarkup)
+	renderer := NewRenderer(tmpl)
+	retainedFocus, focus := NewFocusManager(), NewFocusManager()
+
+	frames := []struct {
+		name   string
+		data   retainedData
+		focus  string
+		width  int
+		height int
+	}{
+		{"first", retainedData{"Title", []string{"one", "two", "three"}, "background"}, "", 30, 12},
+		{"same", retainedData{"Title", []string{"one", "two", "three"}, "background"}, "", 30, 12},
+		{"title", retainedData{"Longer title", []string{"one", "two", "three"}, "background"}, "", 30, 12},
+		{"removed item", retainedData{"Longer title", []string{"one", "three"}, "background"}, "", 30, 12},
+		{"beneath overlay", retainedData{"Longer title", []string{"one", "three"}, "under"}, "", 30, 12},
+		{"focus", retainedData{"Longer title", []string{"one", "three"}, "under"}, "cancel", 30, 12},
+		{"resize", retainedData{"Longer title", []string{"one", "three"}, "under"}, "cancel", 20, 10},
+		{"back", retainedData{"Title", []string{"one", "two", "three"}, "background"}, "", 30, 12},
+	}
+	for _, frame := range frames {
+		retainedFocus.Focus(frame.focus)
+		focus.Focus(frame.focus)
+		got, _ := renderer.Render(frame.data, nil, retainedFocus, nil, frame.width, frame.height)
+		want, _ := tmpl.RenderWithFocus(frame.data, nil, focus, frame.width, frame.height)
+		if got.Render() != want.Render() {
+			t.Errorf("%s: retained frame differs\ngot:\n%s\nwant:\n%s", frame.name, got.Render(), want.Render())
+		}
+	}
+}
+
+func TestRenderer_Reuse(t *testing.T) {
+	renderer := NewRenderer(MustParse[retainedData](retainedMarkup))
+	data := retainedData{"Title", []string{"one", "two", "three"}, "background"}
+
+	_, bm := renderer.Render(data, nil, nil, nil, 30, 14)
+	header, _ := bm.GetByID("header")
+	footer, _ := bm.GetByID("footer")
+
+	data.Items = []string{"one", "2", "three"}
+	_, bm = renderer.Render(data, nil, nil, nil, 30, 14)
+	if got, _ := bm.GetByID("header"); got != header {
+		t.Error("expected the unchanged header to be reused")
+	}
+	if got, _ := bm.GetByID("footer"); got != footer {
+		t.Error("expected the unchanged footer to be reused")
+	}
+	if bounds, _ := bm.GetBounds("footer"); bounds.Min.Y != 12 {
+		t.Errorf("expected the footer bounds to be kept, got %v", bounds)
+	}
+
+	// Only the changed item is redrawn.
+	want := []uv.Rectangle{uv.Rect(0, 4, 3, 1)}
+	if got := renderer.Dirty(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Dirty() = %v, want %v", got, want)
+	}
+
+	_, _ = renderer.Render(data, nil, nil, nil, 30, 14)
+	if got := renderer.Dirty(); len(got) != 0 {
+		t.Errorf("expected nothing to redraw, got %v", got)
+	}
+
+	renderer.Invalidate()
+	_, bm = renderer.Render(data, nil, nil, nil, 30, 14)
+	if got, _ := bm.GetByID("footer"); got == footer {
+		t.Error("expected invalidate to rebuild every element")
+	}
+	if got := renderer.Dirty(); len(got) != 1 || got[0] != uv.Rect(0, 0, 17, 13) {
+		t.Errorf("expected the whole screen to be dirty, got %v", got)
+	}
+}
+
+func TestRenderer_Slots(t *testing.T) {
+	tmpl := MustParse[struct{}](`<vstack><text id="title">Counter</text><slot name="count" /></vstack>`)
+	renderer := NewRenderer(tmpl)
+
+	for i := range 3 {
+		slots := map[string]Element{"count": NewText(fmt.Sprint(i))}
+		scr, _ := renderer.Render(struct{}{}, slots, nil, nil, 20, 5)
+		if got := screenLines(&scr); got[1] != fmt.Sprint(i) {
+			t.Errorf("frame %d: expected the slot to be redrawn, got %q", i, got)
+		}
+	}
+}
+
+// benchmarkData returns the data of a large UI with a counter.
+func benchmarkData(frame int) retainedData {
+	items := make([]string, 200)
+	for i := range items {
+		items[i] = fmt.Sprintf("Item %d", i)
+	}
+	return retainedData{Title: fmt.Sprintf("Frame %d", frame), Items: items, Background: "background"}
+}
+
+func BenchmarkTemplate_Render(b *testing.B) {
+	tmpl := MustParse[retainedData](retainedMarkup)
+	for i := 0; b.Loop(); i++ {
+		tmpl.RenderWithBounds(benchmarkData(i), nil, 120, 220)
+	}
+}
+
+func BenchmarkRenderer_Render(b *testing.B) {
+	renderer := NewRenderer(MustParse[retainedData](retainedMarkup))
+	for i := 0; b.Loop(); i++ {
+		renderer.Render(benchmarkData(i), nil, nil, nil, 120, 220)
+	}
+}
+
+func BenchmarkRenderer_Unchanged(b *testing.B) {
+	renderer := NewRenderer(MustParse[retainedData](retainedMarkup))
+	data := benchmarkData(0)
+	for b.Loop() {
+		renderer.Render(data, nil, nil, nil, 120, 220)
+	}
+}
@@ -0,0 +1,661 @@
+package pony
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	tparse "text/template/parse"
+	"unicode/utf8"
+)
+
+// AttrType is the type of the value of an attribute.
+type AttrType int
+
+// Attribute types.
+const (
+	AttrString     AttrType = iota // Any text
+	AttrInt                        // Integer, such as "2"
+	AttrBool                       // true, false, 1, 0, yes or no
+	AttrColor                      // Named, ANSI, hex or rgb() color
+	AttrSize                       // Size constraint: auto, min, max, "20" or "50%"
+	AttrEnum                       // One of the values of the attribute
+	AttrTracks                     // Grid track list, such as "20 1fr auto"
+	AttrTransition                 // Transitions, such as "width 200ms ease-out"
+)
+
+// Attr describes an attribute of an element.
+type Attr struct {
+	Name   string
+	Type   AttrType
+	Values []string // allowed values of AttrEnum attributes
+}
+
+// Schema describes the markup of an element for validation: the attributes
+// it accepts, and the tags of its children.
+type Schema struct {
+	// Attrs are the attributes of the element, besides the ones every
+	// element accepts: id, class, focusable, tab-index, the inherited text
+	// attributes and the on- handlers.
+	Attrs []Attr
+
+	// ChildAttrs are attributes the element gives to its children, such as
+	// the placement attributes of grid children.
+	ChildAttrs []Attr
+
+	// Children are the only tags allowed as children, with their schema.
+	// When nil, any element is allowed.
+	Children map[string]*Schema
+}
+
+// attr returns the attribute with the given name.
+func (s *Schema) attr(name string) (Attr, bool) {
+	for _, a := range s.Attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attr{}, false
+}
+
+// commonAttrs are the attributes of every element.
+var commonAttrs = []Attr{
+	{Name: "id"},
+	{Name: "class"},
+	{Name: "focusable", Type: AttrBool},
+	{Name: "tab-index", Type: AttrInt},
+	{Name: "foreground-color", Type: AttrColor},
+	{Name: "font-weight", Type: AttrEnum, Values: []string{FontWeightBold}},
+	{Name: "font-style", Type: AttrEnum, Values: []string{FontStyleItalic}},
+	{Name: "text-decoration", Type: AttrEnum, Values: []string{DecorationUnderline, DecorationStrikethrough}},
+	{Name: "transition", Type: AttrTransition},
+}
+
+var (
+	horizontalAlignments = []string{AlignmentLeading, AlignmentCenter, AlignmentTrailing}
+	verticalAlignments   = []string{AlignmentTop, AlignmentCenter, AlignmentBottom}
+	borders              = []string{BorderNone, BorderNormal, BorderRounded, BorderThick, BorderDouble, BorderHidden}
+)
+
+// sizeAttrs are the width and height attributes.
+var sizeAttrs = []Attr{
+	{Name: "width", Type: AttrSize},
+	{Name: "height", Type: AttrSize},
+}
+
+// rowStyleAttrs returns the attributes read by rowStyleFromProps for each
+// prefix.
+func rowStyleAttrs(prefixes ...string) []Attr {
+	var attrs []Attr
+	for _, prefix := range prefixes {
+		attrs = append(attrs,
+			Attr{Name: prefix + "foreground-color", Type: AttrColor},
+			Attr{Name: prefix + "background-color", Type: AttrColor},
+			Attr{Name: prefix + "font-weight", Type: AttrEnum, Values: []string{FontWeightBold}},
+			Attr{Name: prefix + "font-style", Type: AttrEnum, Values: []string{FontStyleItalic}},
+		)
+	}
+	return attrs
+}
+
+// treeNodeSchema is the schema of the <node> children of trees.
+var treeNodeSchema = &Schema{
+	Attrs: []Attr{{Name: "label"}, {Name: "expanded", Type: AttrBool}},
+}
+
+func init() {
+	treeNodeSchema.Children = map[string]*Schema{"node": treeNodeSchema}
+}
+
+// builtinSchemas are the schemas of the built-in elements.
+var builtinSchemas = map[string]*Schema{
+	"vstack": {Attrs: append([]Attr{
+		{Name: "spacing", Type: AttrInt},
+		{Name: "alignment", Type: AttrEnum, Values: horizontalAlignments},
+	}, sizeAttrs...)},
+	"hstack": {Attrs: append([]Attr{
+		{Name: "spacing", Type: AttrInt},
+		{Name: "alignment", Type: AttrEnum, Values: verticalAlignments},
+	}, sizeAttrs...)},
+	"zstack": {Attrs: append([]Attr{
+		{Name: "alignment", Type: AttrEnum, Values: horizontalAlignments},
+		{Name: "vertical-alignment", Type: AttrEnum, Values: verticalAlignments},
+	}, sizeAttrs...)},
+	"grid": {
+		Attrs: append([]Attr{
+			{Name: "columns", Type: AttrTracks},
+			{Name: "rows", Type: AttrTracks},
+			{Name: "gap", Type: AttrInt},
+			{Name: "row-gap", Type: AttrInt},
+			{Name: "column-gap", Type: AttrInt},
+			{Name: "areas"},
+			{Name: "justify-items", Type: AttrEnum, Values: append(horizontalAlignments, AlignmentStretch)},
+			{Name: "align-items", Type: AttrEnum, Values: append(verticalAlignments, AlignmentStretch)},
+		}, sizeAttrs...),
+		ChildAttrs: []Attr{
+			{Name: "col", Type: AttrInt},
+			{Name: "row", Type: AttrInt},
+			{Name: "col-span", Type: AttrInt},
+			{Name: "row-span", Type: AttrInt},
+			{Name: "area"},
+			{Name: "justify-self", Type: AttrEnum, Values: append(horizontalAlignments, AlignmentStretch)},
+			{Name: "align-self", Type: AttrEnum, Values: append(verticalAlignments, AlignmentStretch)},
+		},
+	},
+	"text": {Attrs: []Attr{
+		{Name: "background-color", Type: AttrColor},
+		{Name: "wrap", Type: AttrBool},
+		{Name: "alignment", Type: AttrEnum, Values: horizontalAlignments},
+	}},
+	"box": {Attrs: append([]Attr{
+		{Name: "border", Type: AttrEnum, Values: borders},
+		{Name: "border-color", Type: AttrColor},
+		{Name: "padding", Type: AttrInt},
+		{Name: "margin", Type: AttrInt},
+		{Name: "margin-top", Type: AttrInt},
+		{Name: "margin-right", Type: AttrInt},
+		{Name: "margin-bottom", Type: AttrInt},
+		{Name: "margin-left", Type: AttrInt},
+	}, sizeAttrs...)},
+	"spacer": {Attrs: []Attr{{Name: "size", Type: AttrInt}}},
+	"flex": {Attrs: []Attr{
+		{Name: "grow", Type: AttrInt},
+		{Name: "shrink", Type: AttrInt},
+		{Name: "basis", Type: AttrInt},
+	}},
+	"positioned": {Attrs: append([]Attr{
+		{Name: "x", Type: AttrInt},
+		{Name: "y", Type: AttrInt},
+		{Name: "right", Type: AttrInt},
+		{Name: "bottom", Type: AttrInt},
+	}, sizeAttrs...)},
+	"divider": {Attrs: []Attr{
+		{Name: "vertical", Type: AttrBool},
+		{Name: "char"},
+	}},
+	"slot": {Attrs: []Attr{{Name: "name"}}},
+	"scrollview": {Attrs: append([]Attr{
+		{Name: "offset-x", Type: AttrInt},
+		{Name: "offset-y", Type: AttrInt},
+		{Name: "scrollbar", Type: AttrBool},
+		{Name: "vertical", Type: AttrBool},
+		{Name: "horizontal", Type: AttrBool},
+		{Name: "scrollbar-color", Type: AttrColor},
+	}, sizeAttrs...)},
+	"list": {
+		Attrs: append(append([]Attr{
+			{Name: "selected", Type: AttrInt},
+			{Name: "marker"},
+		}, sizeAttrs...), rowStyleAttrs("", "selected-")...),
+		Children: map[string]*Schema{"item": {}},
+	},
+	"table": {
+		Attrs: append(append([]Attr{
+			{Name: "header", Type: AttrBool},
+			{Name: "spacing", Type: AttrInt},
+			{Name: "selected", Type: AttrInt},
+		}, sizeAttrs...), rowStyleAttrs("", "header-", "selected-")...),
+		Children: map[string]*Schema{
+			"column": {Attrs: []Attr{
+				{Name: "title"},
+				{Name: "width", Type: AttrSize},
+				{Name: "alignment", Type: AttrEnum, Values: horizontalAlignments},
+			}},
+			"row": {Children: map[string]*Schema{"cell": {}}},
+		},
+	},
+	"tree": {
+		Attrs: append(append([]Attr{
+			{Name: "selected", Type: AttrInt},
+		}, sizeAttrs...), rowStyleAttrs("", "selected-")...),
+		Children: map[string]*Schema{"node": treeNodeSchema},
+	},
+	"dialog": {Attrs: append([]Attr{
+		{Name: "dim", Type: AttrBool},
+		{Name: "backdrop", Type: AttrColor},
+		{Name: "z-index", Type: AttrInt},
+	}, sizeAttrs...)},
+	"popover": {Attrs: append([]Attr{
+		{Name: "anchor"},
+		{Name: "placement", Type: AttrEnum, Values: []string{PlacementBottom, PlacementTop, PlacementLeft, PlacementRight}},
+		{Name: "offset", Type: AttrInt},
+		{Name: "z-index", Type: AttrInt},
+	}, sizeAttrs...)},
+	"spinner": {Attrs: []Attr{
+		{Name: "type", Type: AttrEnum, Values: []string{SpinnerDots, SpinnerLine, SpinnerCircle, SpinnerPulse}},
+		{Name: "interval"},
+	}},
+	"style": {Children: map[string]*Schema{}},
+}
+
+// LookupSchema returns the schema of a registered component or of a
+// built-in element. It reports false for unknown tags and for components
+// registered without a schema, which accept any attribute.
+func LookupSchema(name string) (*Schema, bool) {
+	if _, ok := GetComponent(name); ok {
+		registryMu.RLock()
+		defer registryMu.RUnlock()
+		s, ok := schemas[name]
+		return s, ok
+	}
+	s, ok := builtinSchemas[name]
+	return s, ok
+}
+
+// ValidationError is a problem found in markup, at a 1-based line and
+// column. The column counts characters; it is 0 when unknown.
+type ValidationError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors are the problems found in markup, in order.
+type ValidationErrors []*ValidationError
+
+// Error implements error, with a problem per line.
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks markup against the schemas of the built-in elements and
+// registered components. It reports unknown elements, unknown attributes
+// and invalid values, which rendering silently replaces with defaults. The
+// error is a ValidationErrors, or nil if the markup is valid.
+//
+// The syntax of template actions is checked, without checking that their
+// functions exist. Attribute values that contain actions are skipped, since
+// they are only known when rendering.
+func Validate(markup string, opts ...ValidateOption) error {
+	v := &validator{src: maskActions(markup)}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if err := checkActions(markup); err != nil {
+		v.errs = append(v.errs, err)
+	}
+	v.run()
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// ValidateOption configures Validate.
+type ValidateOption func(*validator)
+
+// AllowComponents makes Validate accept the named elements with any
+// attribute, as if they were registered components without a schema. It is
+// meant for tools that check templates without the application's registry.
+func AllowComponents(names ...string) ValidateOption {
+	return func(v *validator) {
+		if v.allowed == nil {
+			v.allowed = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			v.allowed[name] = true
+		}
+	}
+}
+
+// ParseStrict parses pony markup like Parse, and validates it. Problems are
+// returned as ValidationErrors.
+func ParseStrict[T any](markup string) (*Template[T], error) {
+	t, err := Parse[T](markup)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(markup); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkActions checks the syntax of the template actions of markup.
+func checkActions(markup string) *ValidationError {
+	tree := tparse.New("pony")
+	tree.Mode = tparse.SkipFuncCheck
+	_, err := tree.Parse(markup, "", "", map[string]*tparse.Tree{})
+	if err == nil {
+		return nil
+	}
+
+	// Errors have the form "template: pony:3: unexpected {{end}}".
+	msg := strings.TrimPrefix(err.Error(), "template: pony:")
+	lineStr, rest, _ := strings.Cut(msg, ": ")
+	line, convErr := strconv.Atoi(lineStr)
+	if convErr != nil {
+		return &ValidationError{Line: 1, Message: err.Error()}
+	}
+	return &ValidationError{Line: line, Message: rest}
+}
+
+// maskActions blanks out the template actions of markup, keeping the
+// offsets and lines of the rest. Each action becomes "{", spaces and "}",
+// which marks attribute values set by actions. Actions between the
+// attributes of a tag, such as conditional attributes, become spaces only,
+// so that the attributes of every branch are validated.
+func maskActions(markup string) string {
+	src := []byte(markup)
+	var inTag bool
+	var quote byte
+	for i := 0; i < len(markup); {
+		if strings.HasPrefix(markup[i:], "{{") {
+			end := strings.Index(markup[i+2:], "}}")
+			if end < 0 {
+				end = len(markup)
+			} else {
+				end += i + 4
+			}
+			for j := i; j < end; j++ {
+				if src[j] != '\n' {
+					src[j] = ' '
+				}
+			}
+			if !inTag || quote != 0 {
+				src[i] = '{'
+				src[end-1] = '}'
+			}
+			i = end
+			continue
+		}
+
+		switch c := markup[i]; {
+		case !inTag:
+			inTag = c == '<'
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			inTag = false
+		}
+		i++
+	}
+	return string(src)
+}
+
+// validator walks the tokens of markup and collects errors.
+type validator struct {
+	src     string
+	errs    ValidationErrors
+	allowed map[string]bool // elements accepted with any attribute
+}
+
+// frame is an open element during validation.
+type frame struct {
+	tag    string
+	schema *Schema // nil if any attribute is allowed
+}
+
+func (v *validator) run() {
+	decoder := xml.NewDecoder(strings.NewReader(v.src))
+	decoder.Strict = false
+
+	var stack []frame
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				v.syntaxError(syntaxErr, offset, int(decoder.InputOffset()))
+			} else {
+				v.addAt(offset, err.Error())
+			}
+			return
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			end := int(decoder.InputOffset())
+			var parent *frame
+			if len(stack) > 0 {
+				parent = &stack[len(stack)-1]
+			}
+			stack = append(stack, v.element(tok, parent, offset, end))
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 && stack[len(stack)-1].tag == "style" {
+				if _, err := ParseStylesheet(string(tok)); err != nil {
+					v.addAt(offset, err.Error())
+				}
+			}
+		}
+	}
+}
+
+// element validates a start tag spanning src[start:end] and returns its
+// frame.
+func (v *validator) element(tok xml.StartElement, parent *frame, start, end int) frame {
+	tag := tok.Name.Local
+	f := frame{tag: tag}
+
+	var childAttrs []Attr
+	switch {
+	case parent != nil && parent.schema != nil && parent.schema.Children != nil:
+		schema, ok := parent.schema.Children[tag]
+		if !ok {
+			v.addAt(start, fmt.Sprintf("<%s> is not allowed in <%s>", tag, parent.tag))
+			return f
+		}
+		f.schema = schema
+	default:
+		schema, ok := LookupSchema(tag)
+		if !ok {
+			if _, registered := GetComponent(tag); !registered && !v.allowed[tag] {
+				v.addAt(start, fmt.Sprintf("unknown element <%s>", tag))
+			}
+			return f // any attribute
+		}
+		f.schema = schema
+		if parent != nil && parent.schema != nil {
+			childAttrs = parent.schema.ChildAttrs
+		}
+	}
+
+	// Elements of parents with restricted children don't take the common
+	// attributes, except for tree nodes, which have an ID.
+	common := commonAttrs
+	if parent != nil && parent.schema != nil && parent.schema.Children != nil {
+		common = commonAttrs[:1]
+	}
+
+	for _, a := range tok.Attr {
+		name := a.Name.Local
+		switch a.Name.Space {
+		case "", focusPrefix, hoverPrefix, activePrefix:
+		default:
+			v.addAt(v.attrOffset(start, end, a.Name), fmt.Sprintf("unknown attribute prefix %q on <%s>", a.Name.Space, tag))
+			continue
+		}
+		if strings.HasPrefix(name, handlerPrefix) {
+			continue
+		}
+
+		spec, ok := f.schema.attr(name)
+		if !ok {
+			spec, ok = findAttr(common, name)
+		}
+		if !ok {
+			spec, ok = findAttr(childAttrs, name)
+		}
+		if !ok {
+			msg := fmt.Sprintf("unknown attribute %q on <%s>", name, tag)
+			if suggestion := v.suggest(name, f.schema, common, childAttrs); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			v.addAt(v.attrOffset(start, end, a.Name), msg)
+			continue
+		}
+		if strings.Contains(a.Value, "{") {
+			continue // set by a template action
+		}
+		if msg := checkValue(spec, a.Value); msg != "" {
+			v.addAt(v.attrOffset(start, end, a.Name), fmt.Sprintf("%s: %s", name, msg))
+		}
+	}
+	return f
+}
+
+// findAttr returns the attribute with the given name.
+func findAttr(attrs []Attr, name string) (Attr, bool) {
+	i := slices.IndexFunc(attrs, func(a Attr) bool { return a.Name == name })
+	if i < 0 {
+		return Attr{}, false
+	}
+	return attrs[i], true
+}
+
+// suggest returns the known attribute closest to a misspelled name, or an
+// empty string if none is close.
+func (v *validator) suggest(name string, schema *Schema, lists ...[]Attr) string {
+	best, bestDist := "", 3
+	for _, attrs := range append(lists, schema.Attrs) {
+		for _, a := range attrs {
+			if d := editDistance(name, a.Name); d < bestDist {
+				best, bestDist = a.Name, d
+			}
+		}
+	}
+	return best
+}
+
+// checkValue returns why value isn't valid for the attribute, or an empty
+// string.
+func checkValue(spec Attr, value string) string {
+	switch spec.Type {
+	case AttrInt:
+		if _, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			return fmt.Sprintf("invalid integer %q", value)
+		}
+	case AttrBool:
+		switch value {
+		case "true", "false", "1", "0", "yes", "no":
+		default:
+			return fmt.Sprintf("invalid boolean %q", value)
+		}
+	case AttrColor:
+		if _, err := parseColor(value); err != nil {
+			return fmt.Sprintf("invalid color %q", value)
+		}
+	case AttrSize:
+		if !validSize(value) {
+			return fmt.Sprintf("invalid size %q, want auto, min, max, a number or a percentage", value)
+		}
+	case AttrEnum:
+		if !slices.Contains(spec.Values, value) {
+			return fmt.Sprintf("invalid value %q, want one of %s", value, strings.Join(spec.Values, ", "))
+		}
+	case AttrTracks:
+		if _, err := ParseGridTracks(value); err != nil {
+			return err.Error()
+		}
+	case AttrTransition:
+		if _, err := parseTransitions(value); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// validSize reports whether s is a size constraint that parseSizeConstraint
+// understands, rather than one it replaces with auto.
+func validSize(s string) bool {
+	s = strings.TrimSpace(s)
+	switch s {
+	case UnitAuto, UnitMin, UnitMax:
+		return true
+	}
+	_, err := strconv.Atoi(strings.TrimSuffix(s, UnitPercent))
+	return err == nil
+}
+
+// attrOffset returns the offset of an attribute in the tag at
+// src[start:end], or start if it isn't found.
+func (v *validator) attrOffset(start, end int, name xml.Name) int {
+	full := name.Local
+	if name.Space != "" {
+		full = name.Space + ":" + full
+	}
+	tag := v.src[start:end]
+	for i := 0; ; {
+		j := strings.Index(tag[i:], full)
+		if j < 0 {
+			return start
+		}
+		j += i
+		before, after := tag[j-1], strings.TrimLeft(tag[j+len(full):], " \t\r\n")
+		if (before == ' ' || before == '\t' || before == '\n' || before == '\r') && strings.HasPrefix(after, "=") {
+			return start + j
+		}
+		i = j + len(full)
+	}
+}
+
+// syntaxError records an XML syntax error. The decoder only reports its
+// line, so the error is placed at the start of the token that failed, or
+// where the decoder stopped, whichever is on that line.
+func (v *validator) syntaxError(err *xml.SyntaxError, start, stop int) {
+	for _, offset := range []int{start, stop} {
+		offset = min(offset, len(v.src))
+		if strings.Count(v.src[:offset], "\n")+1 == err.Line {
+			v.addAt(offset, err.Msg)
+			return
+		}
+	}
+	v.errs = append(v.errs, &ValidationError{Line: err.Line, Message: err.Msg})
+}
+
+// addAt records an error at an offset of the source.
+func (v *validator) addAt(offset int, msg string) {
+	offset = min(offset, len(v.src))
+	line := strings.Count(v.src[:offset], "\n") + 1
+	lineStart := strings.LastIndexByte(v.src[:offset], '\n') + 1
+	column := utf8.RuneCountInString(v.src[lineStart:offset]) + 1
+	v.errs = append(v.errs, &ValidationError{Line: line, Column: column, Message: msg})
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,141 @@
+package pony
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	markup := `<vstack spacing="1">
+	<text foregound-color="red">Hello</text>
+	<box width="20px" border="dashed">
+		<text foreground-color="notacolor" hover:font-weight="heavy">x</text>
+	</box>
+	<widget />
+	<list selected="one"><item>a</item><entry>b</entry></list>
+</vstack>`
+
+	err := Validate(markup)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []string{
+		`2:8: unknown attribute "foregound-color" on <text>, did you mean "foreground-color"?`,
+		`3:7: width: invalid size "20px", want auto, min, max, a number or a percentage`,
+		`3:20: border: invalid value "dashed", want one of none, normal, rounded, thick, double, hidden`,
+		`4:9: foreground-color: invalid color "notacolor"`,
+		`4:38: font-weight: invalid value "heavy", want one of bold`,
+		`6:2: unknown element <widget>`,
+		`7:8: selected: invalid integer "one"`,
+		`7:37: <entry> is not allowed in <list>`,
+	}
+	if got := strings.Split(err.Error(), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	valid := []string{
+		retainedMarkup,
+		eventsMarkup,
+		`<grid columns="20 repeat(2, 1fr)" gap="1"><text col-span="2" justify-self="center">x</text></grid>`,
+		`<table header="false"><column title="A" width="50%" /><row><cell>1</cell></row></table>`,
+		`<tree><node label="a" expanded="yes"><node id="b">b</node></node></tree>`,
+		`<vstack><style>.title { foreground-color: red; }</style><text class="title">x</text></vstack>`,
+		`<input id="name" placeholder="Name" type="password" focus:background-color="#333" />`,
+	}
+	for _, markup := range valid {
+		if err := Validate(markup); err != nil {
+			t.Errorf("unexpected errors for %s:\n%v", markup, err)
+		}
+	}
+}
+
+func TestValidate_Templates(t *testing.T) {
+	// Values set by actions are only known when rendering.
+	markup := `<vstack>
+	{{ range .Items }}<text foreground-color="{{ .Color }}">{{ .Name }}</text>{{ end }}
+	<box padding="{{ .Padding }}" margin="x" />
+</vstack>`
+	err := Validate(markup)
+	if err == nil || err.Error() != `3:32: margin: invalid integer "x"` {
+		t.Errorf("unexpected errors %v", err)
+	}
+
+	err = Validate("<vstack>\n{{ if .Show }}\n<text>x</text>\n</vstack>")
+	if err == nil || !strings.HasPrefix(err.Error(), "4: unexpected EOF") {
+		t.Errorf("expected the unclosed action to be reported, got %v", err)
+	}
+
+	// Actions between attributes add attributes conditionally.
+	markup = `<vstack>
+	<input id="{{ .ID }}" width="max"{{ if eq .ID "password" }} type="password"{{ end }} />
+	<input{{ if .Secret }} type="secret"{{ end }} />
+</vstack>`
+	err = Validate(markup)
+	if err == nil || err.Error() != `3:25: type: invalid value "secret", want one of text, password` {
+		t.Errorf("unexpected errors %v", err)
+	}
+}
+
+func TestValidate_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		markup string
+		want   string
+	}{
+		{"<vstack>\n <box <text/></vstack>", "2:2: expected attribute name in element"},
+		{"<vstack>\n  <text>x</txet>\n</vstack>", "2:17: unexpected end element </txet>"},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.markup); err == nil || err.Error() != tt.want {
+			t.Errorf("Validate(%q) = %v, want %s", tt.markup, err, tt.want)
+		}
+	}
+}
+
+func TestValidate_RegisteredSchema(t *testing.T) {
+	Register("status", func(props Props, _ []Element) Element { return NewText(props.Get("text")) })
+	Register("anything", func(Props, []Element) Element { return NewText("") })
+	defer Unregister("status")
+	defer Unregister("anything")
+	RegisterSchema("status", &Schema{Attrs: []Attr{
+		{Name: "text"},
+		{Name: "level", Type: AttrEnum, Values: []string{"info", "error"}},
+	}})
+
+	if err := Validate(`<vstack><status level="info" text="ok" /><anything foo="bar" /></vstack>`); err != nil {
+		t.Errorf("unexpected errors %v", err)
+	}
+	err := Validate(`<status level="fatal" />`)
+	if err == nil || err.Error() != `1:9: level: invalid value "fatal", want one of info, error` {
+		t.Errorf("unexpected errors %v", err)
+	}
+}
+
+func TestValidateAllowComponents(t *testing.T) {
+	markup := `<vstack><card title="x" /></vstack>`
+	if err := Validate(markup); err == nil {
+		t.Fatal("expected an error for an unknown element")
+	}
+	if err := Validate(markup, AllowComponents("card")); err != nil {
+		t.Errorf("unexpected errors %v", err)
+	}
+	if _, ok := GetComponent("card"); ok {
+		t.Error("AllowComponents registered a component")
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	if _, err := ParseStrict[struct{}](`<text alignment="middle">x</text>`); err == nil {
+		t.Error("expected an error for an invalid alignment")
+	}
+	tmpl, err := ParseStrict[struct{}](`<text alignment="center">x</text>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(tmpl.Render(struct{}{}, 3, 1)); got != "x" {
+		t.Errorf("unexpected render %q", got)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 // ScrollView represents a scrollable container.
 // Content can be larger than the viewport and will be clipped.
+// A Virtualized child, such as a list or a table, only draws the rows in
+// view; see Virtualized.
 type ScrollView struct {
 	BaseElement
 	child Element
@@ -137,6 +139,12 @@ func (s *ScrollView) Draw(scr uv.Screen, area uv.Rectangle) {
 		}
 	}
 
+	// Virtualized content draws only what is in view, straight to the screen.
+	if v, ok := s.child.(Virtualized); ok {
+		s.drawVirtualized(scr, area, v, viewportWidth, viewportHeight, scrollbarWidth, scrollbarHeight)
+		return
+	}
+
 	// Layout child with unbounded constraints to get full content size
 	contentConstraints := Constraints{
 		MinWidth:  0,
@@ -144,12 +152,12 @@ func (s *ScrollView) Draw(scr uv.Screen, area uv.Rectangle) {
 		MinHeight: 0,
 		MaxHeight: 1 << 30,
 	}
-	contentSize := s.child.Layout(contentConstraints)
+	contentSize := layoutElement(s.child, contentConstraints)
 
 	// Create a buffer for the full content
 	contentBuffer := uv.NewScreenBuffer(contentSize.Width, contentSize.Height)
 	contentArea := uv.Rect(0, 0, contentSize.Width, contentSize.Height)
-	s.child.Draw(contentBuffer, contentArea)
+	drawElement(s.child, contentBuffer, contentArea)
 
 	// Adjust child bounds to screen coordinates (accounting for viewport position and scroll offset)
 	s.adjustChildBounds(s.child, area.Min.X-s.offsetX, area.Min.Y-s.offsetY)
@@ -184,6 +192,31 @@ func (s *ScrollView) Draw(scr uv.Screen, area uv.Rectangle) {
 	}
 }
 
+// drawVirtualized draws virtualized content into the viewport. The content
+// fills the viewport width unless the view scrolls horizontally.
+func (s *ScrollView) drawVirtualized(scr uv.Screen, area uv.Rectangle, v Virtualized, viewportWidth, viewportHeight, scrollbarWidth, scrollbarHeight int) {
+	maxWidth := max(0, viewportWidth)
+	if s.horizontal {
+		maxWidth = unboundedSize
+	}
+	contentSize := v.Layout(Constraints{
+		MaxWidth:  maxWidth,
+		MaxHeight: unboundedSize,
+	})
+
+	viewport := uv.Rect(area.Min.X, area.Min.Y, max(0, viewportWidth), max(0, viewportHeight))
+	v.DrawViewport(scr, viewport, s.offsetX, s.offsetY)
+
+	if s.showScrollbar {
+		if s.vertical {
+			s.drawVerticalScrollbar(scr, area, contentSize.Height, viewportHeight, scrollbarWidth)
+		}
+		if s.horizontal {
+			s.drawHorizontalScrollbar(scr, area, contentSize.Width, viewportWidth, scrollbarHeight)
+		}
+	}
+}
+
 // drawVerticalScrollbar draws a vertical scrollbar.
 func (s *ScrollView) drawVerticalScrollbar(scr uv.Screen, area uv.Rectangle, contentHeight, viewportHeight, scrollbarWidth int) {
 	if contentHeight <= viewportHeight {
@@ -0,0 +1,99 @@
+package pony
+
+import (
+	"image/color"
+	"time"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// spinnerFrames are the frames of the spinner types.
+var spinnerFrames = map[string][]string{
+	SpinnerDots:   {"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
+	SpinnerLine:   {"|", "/", "-", "\\"},
+	SpinnerCircle: {"◐", "◓", "◑", "◒"},
+	SpinnerPulse:  {"█", "▓", "▒", "░", "▒", "▓"},
+}
+
+// defaultSpinnerInterval is the time each frame of a spinner is shown.
+const defaultSpinnerInterval = 100 * time.Millisecond
+
+// Spinner is a one-line activity indicator cycling through frames. In
+// templates, spinners follow the Clock of the template; otherwise call
+// Advance before drawing.
+type Spinner struct {
+	BaseElement
+	frames   []string
+	interval time.Duration
+	frame    int
+	color    color.Color
+}
+
+var _ Element = (*Spinner)(nil)
+
+// NewSpinner creates a spinner of the given type, such as SpinnerDots, which
+// is also the default for unknown types.
+func NewSpinner(kind string) *Spinner {
+	frames, ok := spinnerFrames[kind]
+	if !ok {
+		frames = spinnerFrames[SpinnerDots]
+	}
+	return &Spinner{frames: frames, interval: defaultSpinnerInterval}
+}
+
+// Frames sets the frames and returns the spinner for chaining. The current
+// frame wraps around if the new frames are fewer.
+func (s *Spinner) Frames(frames ...string) *Spinner {
+	if len(frames) > 0 {
+		s.frames = frames
+		s.frame %= len(frames)
+	}
+	return s
+}
+
+// Interval sets the time each frame is shown and returns the spinner for
+// chaining.
+func (s *Spinner) Interval(interval time.Duration) *Spinner {
+	if interval > 0 {
+		s.interval = interval
+	}
+	return s
+}
+
+// ForegroundColor sets the color and returns the spinner for chaining.
+func (s *Spinner) ForegroundColor(c color.Color) *Spinner {
+	s.color = c
+	return s
+}
+
+// Advance shows the frame at the given time since the spinner started.
+func (s *Spinner) Advance(elapsed time.Duration) {
+	s.frame = int(elapsed/s.interval) % len(s.frames)
+}
+
+// Layout calculates the spinner size, the width of its widest frame.
+func (s *Spinner) Layout(constraints Constraints) Size {
+	width := 0
+	for _, frame := range s.frames {
+		width = max(width, ansi.StringWidth(frame))
+	}
+	return constraints.Constrain(Size{Width: width, Height: 1})
+}
+
+// Draw renders the current frame to the screen.
+func (s *Spinner) Draw(scr uv.Screen, area uv.Rectangle) {
+	s.SetBounds(area)
+
+	frame := s.frames[s.frame%len(s.frames)]
+	if s.color != nil {
+		style := uv.Style{Fg: s.color}
+		frame = style.Styled(frame)
+	}
+	uv.NewStyledString(frame).Draw(scr, area)
+}
+
+// Children returns nil for spinners.
+func (s *Spinner) Children() []Element {
+	return nil
+}
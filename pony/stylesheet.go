@@ -0,0 +1,372 @@
+package pony
+
+import (
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Stylesheet holds CSS-like rules that set attributes on the elements they
+// select, so that themes don't have to repeat the same attributes on every
+// tag. Properties are attribute names, such as foreground-color or border.
+//
+// Selectors combine a tag name, an #id and .classes, matched against the
+// class attribute, and can be chained to select descendants. The :focus,
+// :hover and :active pseudo-classes apply while the element is in that
+// state, see FocusManager.
+//
+//	text.title { foreground-color: cyan; font-weight: bold; }
+//	.sidebar text { font-style: italic; }
+//	button:hover, #save:focus { foreground-color: green; }
+//
+// More specific rules win, counting IDs first, then classes and
+// pseudo-classes, then tag names; later rules win among equally specific
+// ones. Attributes set in markup win over the stylesheet. The text
+// properties foreground-color, font-weight, font-style and text-decoration
+// are inherited by child elements.
+//
+// Stylesheets can be attached to a template with Template.WithStylesheet or
+// written in a <style> block in the markup. They apply to the elements of
+// the markup, not to slot elements.
+type Stylesheet struct {
+	rules []styleRule
+}
+
+// styleRule is a selector with its declarations.
+type styleRule struct {
+	selector     selector
+	declarations []styleDeclaration
+}
+
+// styleDeclaration sets a property to a value.
+type styleDeclaration struct {
+	property string
+	value    string
+}
+
+// selector matches a node by itself and its ancestors.
+type selector struct {
+	compounds []compoundSelector // ancestors first, the selected node last
+	pseudo    string
+}
+
+// compoundSelector matches a single node.
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// ParseStylesheet parses CSS-like rules into a stylesheet.
+func ParseStylesheet(css string) (*Stylesheet, error) {
+	css = stripComments(css)
+
+	ss := &Stylesheet{}
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			if rest := strings.TrimSpace(css); rest != "" {
+				return nil, fmt.Errorf("stylesheet: unexpected %q", rest)
+			}
+			return ss, nil
+		}
+		end := strings.IndexByte(css[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("stylesheet: unclosed block after %q", strings.TrimSpace(css[:open]))
+		}
+		end += open
+
+		declarations, err := parseDeclarations(css[open+1 : end])
+		if err != nil {
+			return nil, err
+		}
+		for _, sel := range strings.Split(css[:open], ",") {
+			s, err := parseSelector(strings.TrimSpace(sel))
+			if err != nil {
+				return nil, err
+			}
+			ss.rules = append(ss.rules, styleRule{selector: s, declarations: declarations})
+		}
+		css = css[end+1:]
+	}
+}
+
+// MustParseStylesheet parses a stylesheet and panics on error.
+func MustParseStylesheet(css string) *Stylesheet {
+	ss, err := ParseStylesheet(css)
+	if err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+// stripComments removes /* */ comments.
+func stripComments(css string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return b.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
+
+// parseDeclarations parses the property: value pairs of a block.
+func parseDeclarations(block string) ([]styleDeclaration, error) {
+	var declarations []styleDeclaration
+	for _, decl := range strings.Split(block, ";") {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+		property, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			return nil, fmt.Errorf("stylesheet: invalid declaration %q", decl)
+		}
+		declarations = append(declarations, styleDeclaration{
+			property: strings.ToLower(strings.TrimSpace(property)),
+			value:    strings.TrimSpace(value),
+		})
+	}
+	return declarations, nil
+}
+
+// parseSelector parses a selector such as "vstack .card #save:hover".
+func parseSelector(s string) (selector, error) {
+	parts := strings.Fields(s)
+	if len(parts) == 0 {
+		return selector{}, fmt.Errorf("stylesheet: empty selector")
+	}
+
+	var sel selector
+	for i, part := range parts {
+		compound, pseudo, err := parseCompound(part)
+		if err != nil {
+			return selector{}, err
+		}
+		if pseudo != "" && i < len(parts)-1 {
+			return selector{}, fmt.Errorf("stylesheet: pseudo-class must come last in %q", s)
+		}
+		sel.compounds = append(sel.compounds, compound)
+		sel.pseudo = pseudo
+	}
+	return sel, nil
+}
+
+// parseCompound parses a selector without spaces, such as "text.title".
+func parseCompound(s string) (compoundSelector, string, error) {
+	var c compoundSelector
+	var pseudo string
+
+	ident := func() string {
+		end := 0
+		for end < len(s) && isIdentByte(s[end]) {
+			end++
+		}
+		name := s[:end]
+		s = s[end:]
+		return name
+	}
+
+	if strings.HasPrefix(s, "*") {
+		s = s[1:]
+	} else {
+		c.tag = ident()
+	}
+	for s != "" {
+		kind := s[0]
+		s = s[1:]
+		name := ident()
+		if name == "" {
+			return c, "", fmt.Errorf("stylesheet: invalid selector near %q", string(kind)+s)
+		}
+		switch kind {
+		case '#':
+			c.id = name
+		case '.':
+			c.classes = append(c.classes, name)
+		case ':':
+			switch name {
+			case focusPrefix, hoverPrefix, activePrefix:
+				pseudo = name
+			default:
+				return c, "", fmt.Errorf("stylesheet: unsupported pseudo-class :%s", name)
+			}
+		default:
+			return c, "", fmt.Errorf("stylesheet: invalid selector near %q", string(kind)+name+s)
+		}
+	}
+	return c, pseudo, nil
+}
+
+// isIdentByte reports whether b can be part of a tag name, ID or class.
+func isIdentByte(b byte) bool {
+	return b == '-' || b == '_' || b >= 0x80 ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// specificity returns the number of IDs, of classes and pseudo-classes, and
+// of tag names in the selector.
+func (s selector) specificity() [3]int {
+	var spec [3]int
+	for _, c := range s.compounds {
+		if c.id != "" {
+			spec[0]++
+		}
+		spec[1] += len(c.classes)
+		if c.tag != "" {
+			spec[2]++
+		}
+	}
+	if s.pseudo != "" {
+		spec[1]++
+	}
+	return spec
+}
+
+// matches reports whether the selector matches the last node of path, given
+// its ancestors in the rest of path.
+func (s selector) matches(path []*node) bool {
+	last := len(s.compounds) - 1
+	if !s.compounds[last].matches(path[len(path)-1]) {
+		return false
+	}
+
+	// Match the remaining compounds against ancestors, nearest first.
+	ancestors := path[:len(path)-1]
+	for i := last - 1; i >= 0; i-- {
+		found := false
+		for len(ancestors) > 0 {
+			n := ancestors[len(ancestors)-1]
+			ancestors = ancestors[:len(ancestors)-1]
+			if s.compounds[i].matches(n) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the compound selector matches a node.
+func (c compoundSelector) matches(n *node) bool {
+	if c.tag != "" && c.tag != n.XMLName.Local {
+		return false
+	}
+	props := n.Props()
+	if c.id != "" && props.Get("id") != c.id {
+		return false
+	}
+	classes := strings.Fields(props.Get("class"))
+	for _, class := range c.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply sets the attributes declared by the matching rules on every node of
+// the tree that doesn't set them already. Declarations of rules with a
+// pseudo-class are set as prefixed attributes, like focus:border-color.
+func (ss *Stylesheet) apply(root *node) {
+	if ss == nil || len(ss.rules) == 0 {
+		return
+	}
+	ss.applyPath([]*node{root})
+}
+
+// applyPath applies the stylesheet to the last node of path and its
+// descendants.
+func (ss *Stylesheet) applyPath(path []*node) {
+	n := path[len(path)-1]
+	if n.XMLName.Local == "" {
+		return
+	}
+
+	var matched []styleRule
+	for _, rule := range ss.rules {
+		if rule.selector.matches(path) {
+			matched = append(matched, rule)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b styleRule) int {
+		sa, sb := a.selector.specificity(), b.selector.specificity()
+		return slices.Compare(sa[:], sb[:])
+	})
+
+	// Later rules override earlier ones, and markup overrides both.
+	var attrs []xml.Attr
+	index := make(map[xml.Name]int)
+	for _, rule := range matched {
+		for _, decl := range rule.declarations {
+			name := xml.Name{Space: rule.selector.pseudo, Local: decl.property}
+			if i, ok := index[name]; ok {
+				attrs[i].Value = decl.value
+				continue
+			}
+			index[name] = len(attrs)
+			attrs = append(attrs, xml.Attr{Name: name, Value: decl.value})
+		}
+	}
+	for _, attr := range n.Attrs {
+		delete(index, attr.Name)
+	}
+	for _, attr := range attrs {
+		if _, ok := index[attr.Name]; ok {
+			n.Attrs = append(n.Attrs, attr)
+		}
+	}
+
+	for _, child := range n.Children {
+		ss.applyPath(append(path, child))
+	}
+}
+
+// extractStyles removes the <style> blocks from the tree and returns a
+// stylesheet with the rules of base followed by theirs.
+func (n *node) extractStyles(base *Stylesheet) (*Stylesheet, error) {
+	ss := &Stylesheet{}
+	if base != nil {
+		ss.rules = append(ss.rules, base.rules...)
+	}
+
+	var walk func(n *node) error
+	walk = func(n *node) error {
+		children := n.Children[:0]
+		for _, child := range n.Children {
+			if child.XMLName.Local != "style" {
+				children = append(children, child)
+				continue
+			}
+			block, err := ParseStylesheet(child.Content)
+			if err != nil {
+				return err
+			}
+			ss.rules = append(ss.rules, block.rules...)
+		}
+		n.Children = children
+
+		for _, child := range n.Children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(n); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
@@ -0,0 +1,226 @@
+package pony
+
+import (
+	"strings"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestParseStylesheet(t *testing.T) {
+	ss, err := ParseStylesheet(`
+/* Theme */
+text.title, #save:hover {
+	foreground-color: cyan;
+	font-weight: bold
+}
+vstack * .item { font-style: italic; }
+`)
+	if err != nil {
+		t.Fatalf("ParseStylesheet failed: %v", err)
+	}
+	if len(ss.rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(ss.rules))
+	}
+
+	tests := []struct {
+		rule   int
+		spec   [3]int
+		pseudo string
+	}{
+		{0, [3]int{0, 1, 1}, ""},
+		{1, [3]int{1, 1, 0}, hoverPrefix},
+		{2, [3]int{0, 1, 1}, ""},
+	}
+	for _, tt := range tests {
+		sel := ss.rules[tt.rule].selector
+		if sel.specificity() != tt.spec || sel.pseudo != tt.pseudo {
+			t.Errorf("rule %d: specificity %v, pseudo %q, want %v, %q", tt.rule, sel.specificity(), sel.pseudo, tt.spec, tt.pseudo)
+		}
+	}
+	if got := ss.rules[0].declarations; len(got) != 2 || got[1] != (styleDeclaration{"font-weight", "bold"}) {
+		t.Errorf("unexpected declarations %+v", got)
+	}
+
+	for _, css := range []string{
+		"text { color: red",
+		"text { color }",
+		"text:visited { color: red }",
+		"text:hover box { color: red }",
+		"text[id] { color: red }",
+		"{ color: red }",
+		"stray",
+	} {
+		if _, err := ParseStylesheet(css); err == nil {
+			t.Errorf("expected an error for %q", css)
+		}
+	}
+}
+
+func TestStylesheet_Cascade(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<vstack class="card">
+	<style>
+		.card text { foreground-color: red; }
+		text.title { foreground-color: green; }
+	</style>
+	<text id="title" class="title big">Title</text>
+	<text id="body">Body</text>
+	<text id="inline" class="title" foreground-color="blue">Inline</text>
+	<box id="box"><text id="nested">Nested</text></box>
+</vstack>
+`).WithStylesheet(MustParseStylesheet(`
+#title { font-weight: bold; }
+text.title { foreground-color: yellow; font-style: italic; }
+text { foreground-color: magenta; }
+`))
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 40, 10)
+
+	style := func(id string) uv.Style {
+		t.Helper()
+		elem, ok := bm.GetByID(id)
+		if !ok {
+			t.Fatalf("element %q not rendered", id)
+		}
+		return elem.(*Text).style
+	}
+
+	// The <style> block comes after the template's stylesheet, so it wins
+	// among equally specific rules, and the more specific rule wins over
+	// the descendant rule.
+	if got := style("title"); got.Fg != mustColor(t, "green") || got.Attrs&uv.AttrBold == 0 || got.Attrs&uv.AttrItalic == 0 {
+		t.Errorf("unexpected title style %+v", got)
+	}
+	if got := style("body"); got.Fg != mustColor(t, "red") {
+		t.Errorf("expected the descendant rule for body, got %+v", got.Fg)
+	}
+	if got := style("inline"); got.Fg != mustColor(t, "blue") {
+		t.Errorf("expected markup to win, got %+v", got.Fg)
+	}
+	if got := style("nested"); got.Fg != mustColor(t, "red") {
+		t.Errorf("expected the descendant rule to match through the box, got %+v", got.Fg)
+	}
+}
+
+func TestStylesheet_Inheritance(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<vstack>
+	<style>
+		.muted { foreground-color: gray; font-style: italic; }
+	</style>
+	<box class="muted" border="rounded">
+		<vstack>
+			<text id="inherited">Inherited</text>
+			<text id="own" foreground-color="red">Own</text>
+		</vstack>
+	</box>
+	<text id="outside">Outside</text>
+</vstack>
+`)
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 40, 10)
+
+	get := func(id string) uv.Style {
+		elem, _ := bm.GetByID(id)
+		return elem.(*Text).style
+	}
+	if got := get("inherited"); got.Fg != mustColor(t, "gray") || got.Attrs&uv.AttrItalic == 0 {
+		t.Errorf("expected text properties to be inherited, got %+v", got)
+	}
+	if got := get("own"); got.Fg != mustColor(t, "red") || got.Attrs&uv.AttrItalic == 0 {
+		t.Errorf("expected own color and inherited italics, got %+v", got)
+	}
+	if got := get("outside"); !got.IsZero() {
+		t.Errorf("expected no style outside the box, got %+v", got)
+	}
+}
+
+func TestStylesheet_PseudoClasses(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<hstack>
+	<box id="card" focusable="true" border="normal"><text>Card</text></box>
+	<button id="ok" text="OK" />
+</hstack>
+`).WithStylesheet(MustParseStylesheet(`
+#card:focus { border: double; }
+#card:hover { foreground-color: cyan; }
+button { foreground-color: white; }
+button:hover { foreground-color: yellow; }
+button:active { foreground-color: red; }
+`))
+
+	fm := NewFocusManager()
+	scr, bm := tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 10)
+	if strings.Contains(scr.Render(), "═") {
+		t.Fatal("expected no double border without focus")
+	}
+	btn, _ := bm.GetByID("ok")
+	if b := btn.(*Button); b.style.Fg != mustColor(t, "white") || b.hoverStyle.Fg != mustColor(t, "yellow") || b.activeStyle.Fg != mustColor(t, "red") {
+		t.Errorf("expected the pseudo-class rules to set the button styles, got %+v", b)
+	}
+
+	fm.Focus("card")
+	scr, bm = tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 10)
+	if !strings.Contains(scr.Render(), "═") {
+		t.Error("expected :focus to apply to the focused box")
+	}
+
+	// Hovering the card colors its text through inheritance.
+	bounds, _ := bm.GetBounds("card")
+	if !fm.HoverAt(bounds.Min.X+1, bounds.Min.Y+1) || fm.Hovered() != "card" {
+		t.Fatalf("expected the card to be hovered, got %q", fm.Hovered())
+	}
+	_, bm = tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 10)
+	card, _ := bm.GetByID("card")
+	if text := card.Children()[0].(*Text); text.style.Fg != mustColor(t, "cyan") {
+		t.Errorf("expected :hover to color the card text, got %+v", text.style.Fg)
+	}
+
+	// Pressing the button marks it active, so it draws its active style.
+	bounds, _ = bm.GetBounds("ok")
+	if id := fm.PressAt(bounds.Min.X+1, bounds.Min.Y+1); id != "ok" {
+		t.Fatalf("expected to press ok, got %q", id)
+	}
+	_, bm = tmpl.RenderWithFocus(struct{}{}, nil, fm, 40, 10)
+	btn, _ = bm.GetByID("ok")
+	if !btn.(*Button).IsActive() {
+		t.Error("expected the button to be active")
+	}
+	fm.Release()
+	if fm.Active() != "" {
+		t.Errorf("expected release to clear the pressed element, got %q", fm.Active())
+	}
+}
+
+func TestButtonHoverStyle(t *testing.T) {
+	btn := NewButton("OK").
+		Style(NewStyle().Fg(Hex("#00FF00")).Build()).
+		HoverStyle(NewStyle().Fg(Hex("#00FFFF")).Build()).
+		ActiveStyle(NewStyle().Fg(Hex("#FF00FF")).Build())
+	btn.SetID("ok")
+
+	draw := func() uv.Style {
+		scr := uv.NewScreenBuffer(10, 5)
+		btn.Draw(scr, uv.Rect(0, 0, 10, 5))
+		return scr.CellAt(0, 0).Style
+	}
+	if got := draw().Fg; got != Hex("#00FF00") {
+		t.Errorf("expected the base style, got %v", got)
+	}
+	btn.SetHovered(true)
+	if got := draw().Fg; got != Hex("#00FFFF") {
+		t.Errorf("expected the hover style, got %v", got)
+	}
+	btn.SetActive(true)
+	if got := draw().Fg; got != Hex("#FF00FF") {
+		t.Errorf("expected the active style, got %v", got)
+	}
+}
+
+func mustColor(t *testing.T, s string) any {
+	t.Helper()
+	c, err := parseColor(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,310 @@
+package pony
+
+import (
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// TableColumn describes a column of a table.
+type TableColumn struct {
+	// Title is shown in the header row.
+	Title string
+
+	// Width is a fixed width, a percentage of the table width, "max" to
+	// share the space left by the other columns, or auto (the zero value)
+	// to fit the title and the first rows, see Table.
+	Width SizeConstraint
+
+	// Alignment is AlignmentLeading (the default), AlignmentCenter or
+	// AlignmentTrailing.
+	Alignment string
+}
+
+// TableData provides the cells of a table on demand, so that large tables
+// don't have to be built up front.
+type TableData interface {
+	// Len returns the number of rows.
+	Len() int
+	// Cell returns the text of the cell at the given row and column.
+	Cell(row, col int) string
+}
+
+// TableRows is a TableData backed by a slice of rows.
+type TableRows [][]string
+
+// Len returns the number of rows.
+func (t TableRows) Len() int { return len(t) }
+
+// Cell returns the text of the cell, or an empty string if the row is short.
+func (t TableRows) Cell(row, col int) string {
+	if col >= len(t[row]) {
+		return ""
+	}
+	return t[row][col]
+}
+
+// Table represents rows of cells in columns, with a header row and an
+// optional selected row. Cells that don't fit their column are truncated.
+// Only the rows in view are drawn, so a table of 100k rows in a ScrollView
+// is as cheap as a small one, see Virtualized. Auto columns are measured
+// once from their title and the first 1000 rows, so they keep their width
+// while scrolling; longer cells further down are truncated. The header
+// stays at the top while scrolling. Tables fill the available width unless
+// a width is set.
+//
+// Example:
+//
+//	<table selected="0">
+//	    <column title="Name" width="50%" />
+//	    <column title="Size" alignment="trailing" />
+//	    <row><cell>main.go</cell><cell>1.2K</cell></row>
+//	</table>
+type Table struct {
+	BaseElement
+	columns       []TableColumn
+	data          TableData
+	header        bool
+	spacing       int
+	selected      int
+	style         uv.Style
+	headerStyle   uv.Style
+	selectedStyle uv.Style
+	width         SizeConstraint
+	height        SizeConstraint
+	contentWidth  int
+	rows          rowViewport
+}
+
+// tableMeasureRows is the number of rows auto columns are measured from.
+const tableMeasureRows = 1000
+
+var (
+	_ Element     = (*Table)(nil)
+	_ Virtualized = (*Table)(nil)
+)
+
+// NewTable creates a table with the given columns and no rows.
+// The header is bold, the selected row is drawn in reverse video and
+// columns are separated by a space.
+func NewTable(columns ...TableColumn) *Table {
+	return &Table{
+		columns:       columns,
+		data:          TableRows(nil),
+		header:        true,
+		spacing:       1,
+		selected:      -1,
+		headerStyle:   uv.Style{Attrs: uv.AttrBold},
+		selectedStyle: uv.Style{Attrs: uv.AttrReverse},
+	}
+}
+
+// Rows sets the rows of the table and returns the table for chaining.
+func (t *Table) Rows(rows ...[]string) *Table {
+	t.data = TableRows(rows)
+	return t
+}
+
+// Data sets the source of the rows and returns the table for chaining.
+func (t *Table) Data(data TableData) *Table {
+	t.data = data
+	return t
+}
+
+// Header sets whether the header row is shown and returns the table for
+// chaining.
+func (t *Table) Header(show bool) *Table {
+	t.header = show
+	return t
+}
+
+// Spacing sets the number of cells between columns and returns the table for
+// chaining.
+func (t *Table) Spacing(spacing int) *Table {
+	t.spacing = spacing
+	return t
+}
+
+// Selected sets the index of the selected row, or -1 for none, and returns
+// the table for chaining.
+func (t *Table) Selected(index int) *Table {
+	t.selected = index
+	return t
+}
+
+// SelectedIndex returns the index of the selected row, or -1.
+func (t *Table) SelectedIndex() int {
+	return t.selected
+}
+
+// Len returns the number of rows, without the header.
+func (t *Table) Len() int {
+	if t.data == nil {
+		return 0
+	}
+	return t.data.Len()
+}
+
+// Style sets the style of the rows and returns the table for chaining.
+func (t *Table) Style(style uv.Style) *Table {
+	t.style = style
+	return t
+}
+
+// HeaderStyle sets the style of the header row and returns the table for
+// chaining.
+func (t *Table) HeaderStyle(style uv.Style) *Table {
+	t.headerStyle = style
+	return t
+}
+
+// SelectedStyle sets the style of the selected row and returns the table
+// for chaining.
+func (t *Table) SelectedStyle(style uv.Style) *Table {
+	t.selectedStyle = style
+	return t
+}
+
+// Width sets the width constraint and returns the table for chaining.
+func (t *Table) Width(width SizeConstraint) *Table {
+	t.width = width
+	return t
+}
+
+// Height sets the height constraint and returns the table for chaining.
+func (t *Table) Height(height SizeConstraint) *Table {
+	t.height = height
+	return t
+}
+
+// RowAt returns the index of the row at the given screen coordinates, from
+// the last draw, for handling clicks. The header is not a row.
+func (t *Table) RowAt(x, y int) (int, bool) {
+	return t.rows.rowAt(x, y)
+}
+
+// headerHeight returns the number of rows taken by the header.
+func (t *Table) headerHeight() int {
+	if t.header {
+		return 1
+	}
+	return 0
+}
+
+// Layout calculates the table size: the header and one row per row of data.
+func (t *Table) Layout(constraints Constraints) Size {
+	// When the width is unbounded, percentages mean nothing, so every
+	// column that isn't fixed is as wide as its title.
+	natural := max(0, len(t.columns)-1) * t.spacing
+	for _, col := range t.columns {
+		if col.Width.IsFixed() && !col.Width.IsAuto() {
+			natural += col.Width.Apply(unboundedSize, 0)
+		} else {
+			natural += ansi.StringWidth(col.Title)
+		}
+	}
+	size := layoutRows(constraints, t.width, t.height, natural, t.headerHeight()+t.Len())
+	t.contentWidth = size.Width
+	return size
+}
+
+// Draw renders the table, scrolled to show the selected row if it doesn't
+// fit.
+func (t *Table) Draw(scr uv.Screen, area uv.Rectangle) {
+	visible := area.Dy() - t.headerHeight()
+	t.DrawViewport(scr, area, 0, firstVisibleRow(t.selected, visible, t.Len()))
+}
+
+// DrawViewport renders the header and the rows visible in area when
+// scrolled by offsetX columns and offsetY rows. The header is drawn at the
+// top of area whatever the offset.
+func (t *Table) DrawViewport(scr uv.Screen, area uv.Rectangle, offsetX, offsetY int) {
+	t.SetBounds(area)
+	count := t.Len()
+	rowsArea := area
+	rowsArea.Min.Y = min(area.Max.Y, area.Min.Y+t.headerHeight())
+	first := max(0, min(offsetY, count))
+	last := min(count, first+rowsArea.Dy())
+	t.rows = rowViewport{area: rowsArea, first: first, count: count}
+
+	// Columns are resolved for the laid out width, which is wider than
+	// area when scrolling horizontally.
+	widths := t.columnWidths(max(area.Dx(), t.contentWidth))
+	line := func(cell func(col int) string) string {
+		var b strings.Builder
+		for i, col := range t.columns {
+			if i > 0 {
+				b.WriteString(strings.Repeat(" ", t.spacing))
+			}
+			b.WriteString(fitCell(cell(i), widths[i], col.Alignment))
+		}
+		return b.String()
+	}
+
+	if t.header && area.Dy() > 0 {
+		title := line(func(col int) string { return t.columns[col].Title })
+		drawRow(scr, area, area.Min.Y, title, t.headerStyle, offsetX)
+	}
+	for row := first; row < last; row++ {
+		style := t.style
+		if row == t.selected {
+			style = t.selectedStyle
+		}
+		text := line(func(col int) string { return t.data.Cell(row, col) })
+		drawRow(scr, rowsArea, rowsArea.Min.Y+row-first, text, style, offsetX)
+	}
+}
+
+// columnWidths resolves the widths of the columns for a table of the given
+// width. Auto columns fit their title and the first tableMeasureRows rows,
+// whatever rows are in view; if they don't fit, they shrink in proportion
+// to their content.
+func (t *Table) columnWidths(width int) []int {
+	measured := min(t.Len(), tableMeasureRows)
+	widths := make([]int, len(t.columns))
+	available := max(0, width-max(0, len(t.columns)-1)*t.spacing)
+
+	remaining := available
+	var auto, flex []int
+	autoWidth := 0
+	for i, col := range t.columns {
+		switch {
+		case col.Width.IsPercent() || (col.Width.IsFixed() && !col.Width.IsAuto()):
+			widths[i] = col.Width.Apply(available, 0)
+			remaining -= widths[i]
+		case col.Width.String() == UnitMax:
+			flex = append(flex, i)
+		default:
+			w := ansi.StringWidth(col.Title)
+			for row := range measured {
+				w = max(w, ansi.StringWidth(t.data.Cell(row, i)))
+			}
+			widths[i] = w
+			autoWidth += w
+			auto = append(auto, i)
+		}
+	}
+	remaining = max(0, remaining)
+
+	if autoWidth > remaining {
+		for _, i := range auto {
+			widths[i] = widths[i] * remaining / autoWidth
+		}
+		remaining = 0
+	} else {
+		remaining -= autoWidth
+	}
+	for n, i := range flex {
+		widths[i] = remaining / len(flex)
+		if n < remaining%len(flex) {
+			widths[i]++
+		}
+	}
+	return widths
+}
+
+// Children returns nil for tables.
+func (t *Table) Children() []Element {
+	return nil
+}
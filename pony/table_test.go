@@ -0,0 +1,184 @@
+package pony
+
+import (
+	"fmt"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestTable_ColumnWidths(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []TableColumn
+		width   int
+		want    []int
+	}{
+		{
+			name:    "auto fits content",
+			columns: []TableColumn{{Title: "Name"}, {Title: "Size"}},
+			width:   30,
+			want:    []int{14, 4},
+		},
+		{
+			name:    "fixed and percent",
+			columns: []TableColumn{{Title: "Name", Width: NewPercentConstraint(50)}, {Title: "Size", Width: NewFixedConstraint(6)}},
+			width:   21,
+			want:    []int{10, 6},
+		},
+		{
+			name:    "max takes the rest",
+			columns: []TableColumn{{Title: "Name", Width: parseSizeConstraint("max")}, {Title: "Size"}},
+			width:   30,
+			want:    []int{25, 4},
+		},
+		{
+			name:    "auto shrinks",
+			columns: []TableColumn{{Title: "Name"}, {Title: "Size", Width: NewFixedConstraint(4)}},
+			width:   10,
+			want:    []int{5, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := NewTable(tt.columns...).Rows(
+				[]string{"main.go", "1K"},
+				[]string{"parser_test.go", "12K"},
+			)
+			got := table.columnWidths(tt.width)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_ColumnWidthsWhileScrolling(t *testing.T) {
+	rows := make(TableRows, 2000)
+	for i := range rows {
+		rows[i] = []string{"x"}
+	}
+	rows[500] = []string{"a long cell"}
+	rows[1500] = []string{"a cell past the measured rows"}
+	table := NewTable(TableColumn{Title: "Name"}).Data(rows)
+
+	// The width is the same whatever rows are in view.
+	for _, offset := range []int{0, 500, 1990} {
+		scr := uv.NewScreenBuffer(40, 5)
+		table.DrawViewport(scr, uv.Rect(0, 0, 40, 5), 0, offset)
+		if got := table.columnWidths(40); got[0] != 11 {
+			t.Errorf("offset %d: got width %d, want 11", offset, got[0])
+		}
+	}
+}
+
+func TestTable_Draw(t *testing.T) {
+	table := NewTable(
+		TableColumn{Title: "Name", Width: NewFixedConstraint(8)},
+		TableColumn{Title: "Size", Width: NewFixedConstraint(5), Alignment: AlignmentTrailing},
+		TableColumn{Title: "Kind", Alignment: AlignmentCenter},
+	).Rows(
+		[]string{"main.go", "1K", "go"},
+		[]string{"stylesheet.go", "12K", "go"},
+	).Selected(1)
+
+	scr := uv.NewScreenBuffer(20, 4)
+	table.Layout(Constraints{MaxWidth: 20, MaxHeight: 4})
+	table.Draw(scr, uv.Rect(0, 0, 20, 4))
+
+	want := []string{
+		"Name      Size Kind",
+		"main.go     1K  go",
+		"stylesh…   12K  go",
+	}
+	if got := screenLines(&scr); !equalLines(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if scr.CellAt(0, 0).Style.Attrs&uv.AttrBold == 0 {
+		t.Error("expected a bold header")
+	}
+	if scr.CellAt(0, 2).Style.Attrs&uv.AttrReverse == 0 {
+		t.Error("expected the selected row in reverse video")
+	}
+	if row, ok := table.RowAt(3, 1); !ok || row != 0 {
+		t.Errorf("RowAt(3, 1) = %d, %v, want 0, true", row, ok)
+	}
+	if _, ok := table.RowAt(3, 0); ok {
+		t.Error("expected the header not to be a row")
+	}
+}
+
+func TestTable_Markup(t *testing.T) {
+	tmpl := MustParse[struct{}](`
+<table id="files" header-foreground-color="cyan" spacing="2">
+	<column title="Name" />
+	<column title="Size" alignment="trailing" />
+	<row><cell>main.go</cell><cell>1K</cell></row>
+</table>`)
+	scr, bm := tmpl.RenderWithBounds(struct{}{}, nil, 20, 4)
+
+	if _, ok := bm.GetByID("files"); !ok {
+		t.Fatal("table not rendered")
+	}
+	want := []string{"Name     Size", "main.go    1K"}
+	if got := screenLines(&scr); !equalLines(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := scr.CellAt(0, 0).Style.Fg; got != mustColor(t, "cyan") {
+		t.Errorf("expected a cyan header, got %v", got)
+	}
+}
+
+// numberedRows is a table of n generated rows.
+type numberedRows int
+
+func (n numberedRows) Len() int { return int(n) }
+
+func (n numberedRows) Cell(row, col int) string {
+	if col == 0 {
+		return fmt.Sprintf("row %d", row)
+	}
+	return fmt.Sprint(row * col)
+}
+
+// countingRows counts the cells it is asked for.
+type countingRows struct {
+	numberedRows
+	cells int
+}
+
+func (c *countingRows) Cell(row, col int) string {
+	c.cells++
+	return c.numberedRows.Cell(row, col)
+}
+
+func TestTable_Virtualized(t *testing.T) {
+	data := &countingRows{numberedRows: 100_000}
+	// Auto columns are measured from the first rows only, so the row labels,
+	// which grow further down, get a fixed width.
+	table := NewTable(TableColumn{Title: "Row", Width: NewFixedConstraint(9)}, TableColumn{Title: "Value", Alignment: AlignmentTrailing}).Data(data)
+	scroll := NewScrollView(table).Offset(0, 50_000).Scrollbar(false)
+
+	scr := uv.NewScreenBuffer(30, 5)
+	scroll.Draw(scr, uv.Rect(0, 0, 30, 5))
+
+	// The header stays at the top, followed by the rows at the offset.
+	lines := screenLines(&scr)
+	if lines[0] != "Row       Value" || lines[1] != "row 50000 50000" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+	if data.cells > tableMeasureRows+20 {
+		t.Errorf("expected only the measured and visible cells to be read, got %d", data.cells)
+	}
+	if row, ok := table.RowAt(0, 4); !ok || row != 50_003 {
+		t.Errorf("RowAt(0, 4) = %d, %v, want 50003, true", row, ok)
+	}
+}
+
+func BenchmarkTable_Virtualized(b *testing.B) {
+	table := NewTable(TableColumn{Title: "Row"}, TableColumn{Title: "Value"}).Data(numberedRows(100_000))
+	scr := uv.NewScreenBuffer(80, 24)
+	for i := 0; b.Loop(); i++ {
+		NewScrollView(table).Offset(0, i%100_000).Draw(scr, uv.Rect(0, 0, 80, 24))
+	}
+}
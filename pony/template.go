@@ -14,9 +14,11 @@ import (
 
 // Template is a type-safe pony template that can be rendered with data of type T.
 type Template[T any] struct {
-	markup   string
-	goTmpl   *template.Template
-	cacheKey string
+	markup     string
+	goTmpl     *template.Template
+	cacheKey   string
+	stylesheet *Stylesheet
+	clock      *Clock
 }
 
 // Parse parses pony markup into a type-safe template.
@@ -63,6 +65,22 @@ func MustParseWithFuncs[T any](markup string, funcs template.FuncMap) *Template[
 	return t
 }
 
+// WithStylesheet sets the stylesheet applied to the markup on every render
+// and returns the template for chaining. Rules in <style> blocks of the
+// markup come after the rules of the stylesheet.
+func (t *Template[T]) WithStylesheet(ss *Stylesheet) *Template[T] {
+	t.stylesheet = ss
+	return t
+}
+
+// WithClock sets the clock that drives the transitions and spinners of the
+// template and returns the template for chaining. Without a clock,
+// attributes change at once and spinners stay on their first frame.
+func (t *Template[T]) WithClock(clock *Clock) *Template[T] {
+	t.clock = clock
+	return t
+}
+
 // Render renders the template with the given data to the specified viewport size.
 func (t *Template[T]) Render(data T, width, height int) string {
 	scr, _ := t.RenderWithBounds(data, nil, width, height)
@@ -73,17 +91,36 @@ func (t *Template[T]) Render(data T, width, height int) string {
 // RenderWithBounds renders the template and returns both the screen buffer and bounds map.
 // The bounds map can be used for mouse hit testing in event handlers.
 func (t *Template[T]) RenderWithBounds(data T, slots map[string]Element, width, height int) (uv.ScreenBuffer, *BoundsMap) {
-	// Execute Go template first
-	var buf bytes.Buffer
-	if err := t.goTmpl.Execute(&buf, data); err != nil {
+	return t.render(data, slots, nil, nil, width, height)
+}
+
+// RenderWithFocus renders the template like RenderWithBounds, with the
+// element focused in the focus manager marked as focused and styled with its
+// focus: attributes. The focus manager is updated with the focusable elements
+// of the render, so it can move focus on the next key press.
+func (t *Template[T]) RenderWithFocus(data T, slots map[string]Element, focus *FocusManager, width, height int) (uv.ScreenBuffer, *BoundsMap) {
+	return t.render(data, slots, focus, nil, width, height)
+}
+
+// RenderWithInputs renders the template like RenderWithFocus, with the
+// <input> and <textarea> elements getting their editing state from the input
+// store, keyed by their ID. States are created from the value attribute on
+// the first render and kept afterwards. The bounds map reports the caret of
+// the focused input through BoundsMap.Cursor. Either focus or inputs may be
+// nil.
+func (t *Template[T]) RenderWithInputs(data T, slots map[string]Element, focus *FocusManager, inputs *InputStore, width, height int) (uv.ScreenBuffer, *BoundsMap) {
+	return t.render(data, slots, focus, inputs, width, height)
+}
+
+// render renders the template, applying focus if a focus manager is given
+// and binding inputs to their state if an input store is given.
+func (t *Template[T]) render(data T, slots map[string]Element, focus *FocusManager, inputs *InputStore, width, height int) (uv.ScreenBuffer, *BoundsMap) {
+	markup, err := t.execute(data)
+	if err != nil {
 		errScreen := uv.NewScreenBuffer(width, 1)
 		return errScreen, NewBoundsMap()
 	}
-
-	processedMarkup := buf.String()
-
-	// Parse the processed markup
-	root, err := parse(processedMarkup)
+	root, err := t.prepare(markup, focus)
 	if err != nil {
 		errScreen := uv.NewScreenBuffer(width, 1)
 		return errScreen, NewBoundsMap()
@@ -95,11 +132,7 @@ func (t *Template[T]) RenderWithBounds(data T, slots map[string]Element, width,
 		emptyScreen := uv.NewScreenBuffer(width, height)
 		return emptyScreen, NewBoundsMap()
 	}
-
-	// Fill slots with provided elements
-	if slots != nil {
-		fillSlots(elem, slots)
-	}
+	bindElement(elem, slots, focus, inputs, t.clock.scene(t))
 
 	// Layout the element
 	constraints := Constraints{
@@ -126,10 +159,65 @@ func (t *Template[T]) RenderWithBounds(data T, slots map[string]Element, width,
 	// Build bounds map
 	boundsMap := NewBoundsMap()
 	walkAndRegister(elem, boundsMap)
+	if focus != nil {
+		focus.Update(boundsMap)
+	}
 
 	return uvBuf, boundsMap
 }
 
+// execute runs the Go template with data and returns the markup.
+func (t *Template[T]) execute(data T) (string, error) {
+	var buf bytes.Buffer
+	if err := t.goTmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// prepare parses markup and applies the stylesheets, then the styles of
+// focused, hovered and pressed elements and the transitions of the clock,
+// then inherits text styles. Dialogs and popovers are moved to layers above
+// the rest of the tree.
+func (t *Template[T]) prepare(markup string, focus *FocusManager) (*node, error) {
+	root, err := parse(markup)
+	if err != nil {
+		return nil, err
+	}
+	styles, err := root.extractStyles(t.stylesheet)
+	if err != nil {
+		return nil, err
+	}
+	styles.apply(root)
+	if focus != nil {
+		root.applyPseudo(focusPrefix, focus.Focused())
+		root.applyPseudo(hoverPrefix, focus.Hovered())
+		root.applyPseudo(activePrefix, focus.Active())
+	}
+	if scene := t.clock.scene(t); scene != nil {
+		scene.apply(root)
+	}
+	root.inherit(nil)
+	return root.extractOverlays(), nil
+}
+
+// bindElement fills the slots of an element tree, binds its inputs to their
+// editing state, marks the focused element and advances its spinners.
+func bindElement(elem Element, slots map[string]Element, focus *FocusManager, inputs *InputStore, scene *clockScene) {
+	if slots != nil {
+		fillSlots(elem, slots)
+	}
+	if inputs != nil {
+		inputs.bind(elem)
+	}
+	if focus != nil {
+		focus.apply(elem)
+	}
+	if scene != nil {
+		scene.bind(elem)
+	}
+}
+
 // RenderWithSlots renders the template with data and slot elements.
 // Slots allow injecting stateful components into the template.
 func (t *Template[T]) RenderWithSlots(data T, slots map[string]Element, width, height int) string {
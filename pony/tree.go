@@ -0,0 +1,266 @@
+package pony
+
+import (
+	"image/color"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// TreeNode is a node of a Tree. Nodes with children can be expanded to
+// show them.
+type TreeNode struct {
+	// ID identifies the node, for keeping track of expanded nodes across
+	// renders.
+	ID string
+
+	// Label is the text shown for the node.
+	Label string
+
+	// Expanded reports whether the children of the node are shown.
+	Expanded bool
+
+	// Children are the child nodes.
+	Children []*TreeNode
+}
+
+// NewTreeNode creates a collapsed node.
+func NewTreeNode(label string, children ...*TreeNode) *TreeNode {
+	return &TreeNode{Label: label, Children: children}
+}
+
+// Toggle expands a collapsed node or collapses an expanded one.
+func (n *TreeNode) Toggle() {
+	n.Expanded = !n.Expanded
+}
+
+// treeRow is a visible row of a tree.
+type treeRow struct {
+	node   *TreeNode
+	prefix string // guides and expansion marker
+}
+
+// Tree represents a hierarchy of nodes drawn with guide lines, where nodes
+// with children can be expanded and collapsed. Rows are selected by index
+// among the visible rows. Only the rows in view are drawn, see Virtualized.
+//
+// Example:
+//
+//	<tree selected="0">
+//	    <node label="src" expanded="true">
+//	        <node label="main.go" />
+//	    </node>
+//	    <node label="README.md" />
+//	</tree>
+type Tree struct {
+	BaseElement
+	roots         []*TreeNode
+	selected      int
+	style         uv.Style
+	selectedStyle uv.Style
+	width         SizeConstraint
+	height        SizeConstraint
+	visible       []treeRow
+	rows          rowViewport
+}
+
+var (
+	_ Element     = (*Tree)(nil)
+	_ Virtualized = (*Tree)(nil)
+)
+
+// NewTree creates a tree with the given root nodes. Nothing is selected,
+// and the selected row is drawn in reverse video.
+func NewTree(roots ...*TreeNode) *Tree {
+	return &Tree{
+		roots:         roots,
+		selected:      -1,
+		selectedStyle: uv.Style{Attrs: uv.AttrReverse},
+	}
+}
+
+// Selected sets the index of the selected row, or -1 for none, and returns
+// the tree for chaining.
+func (t *Tree) Selected(index int) *Tree {
+	t.selected = index
+	return t
+}
+
+// SelectedIndex returns the index of the selected row, or -1.
+func (t *Tree) SelectedIndex() int {
+	return t.selected
+}
+
+// SelectedNode returns the selected node, or nil.
+func (t *Tree) SelectedNode() *TreeNode {
+	return t.Node(t.selected)
+}
+
+// Node returns the node of a visible row, or nil.
+func (t *Tree) Node(row int) *TreeNode {
+	rows := t.flatten()
+	if row < 0 || row >= len(rows) {
+		return nil
+	}
+	return rows[row].node
+}
+
+// Len returns the number of visible rows.
+func (t *Tree) Len() int {
+	return len(t.flatten())
+}
+
+// Style sets the style of the rows and returns the tree for chaining.
+func (t *Tree) Style(style uv.Style) *Tree {
+	t.style = style
+	return t
+}
+
+// SelectedStyle sets the style of the selected row and returns the tree for
+// chaining.
+func (t *Tree) SelectedStyle(style uv.Style) *Tree {
+	t.selectedStyle = style
+	return t
+}
+
+// ForegroundColor sets the text color and returns the tree for chaining.
+func (t *Tree) ForegroundColor(c color.Color) *Tree {
+	t.style.Fg = c
+	return t
+}
+
+// Width sets the width constraint and returns the tree for chaining.
+func (t *Tree) Width(width SizeConstraint) *Tree {
+	t.width = width
+	return t
+}
+
+// Height sets the height constraint and returns the tree for chaining.
+func (t *Tree) Height(height SizeConstraint) *Tree {
+	t.height = height
+	return t
+}
+
+// Toggle expands or collapses the node of a visible row, and returns whether
+// the row had children to show or hide.
+func (t *Tree) Toggle(row int) bool {
+	n := t.Node(row)
+	if n == nil || len(n.Children) == 0 {
+		return false
+	}
+	n.Toggle()
+	t.visible = nil
+	return true
+}
+
+// RowAt returns the index of the visible row at the given screen
+// coordinates, from the last draw, for handling clicks.
+func (t *Tree) RowAt(x, y int) (int, bool) {
+	return t.rows.rowAt(x, y)
+}
+
+// NodeAt returns the node at the given screen coordinates, from the last
+// draw.
+func (t *Tree) NodeAt(x, y int) (*TreeNode, bool) {
+	row, ok := t.RowAt(x, y)
+	if !ok {
+		return nil, false
+	}
+	return t.Node(row), true
+}
+
+// flatten returns the visible rows, walking expanded nodes depth first.
+// The rows are kept until the next layout or Toggle.
+func (t *Tree) flatten() []treeRow {
+	if t.visible != nil {
+		return t.visible
+	}
+
+	rows := []treeRow{}
+	var walk func(nodes []*TreeNode, guides string, nested bool)
+	walk = func(nodes []*TreeNode, guides string, nested bool) {
+		for i, n := range nodes {
+			last := i == len(nodes)-1
+
+			var b strings.Builder
+			b.WriteString(guides)
+			if nested {
+				if last {
+					b.WriteString("└─ ")
+				} else {
+					b.WriteString("├─ ")
+				}
+			}
+			switch {
+			case len(n.Children) == 0:
+				b.WriteString("  ")
+			case n.Expanded:
+				b.WriteString("▾ ")
+			default:
+				b.WriteString("▸ ")
+			}
+			rows = append(rows, treeRow{node: n, prefix: b.String()})
+
+			if n.Expanded {
+				next := guides
+				if nested {
+					if last {
+						next += "   "
+					} else {
+						next += "│  "
+					}
+				}
+				walk(n.Children, next, true)
+			}
+		}
+	}
+	walk(t.roots, "", false)
+
+	t.visible = rows
+	return rows
+}
+
+// Layout calculates the tree size: one row per visible node.
+func (t *Tree) Layout(constraints Constraints) Size {
+	// Nodes may have been expanded or collapsed since the last layout.
+	t.visible = nil
+	rows := t.flatten()
+	natural := 0
+	for _, row := range rows[:min(len(rows), 100)] {
+		natural = max(natural, ansi.StringWidth(row.prefix+row.node.Label))
+	}
+	return layoutRows(constraints, t.width, t.height, natural, len(rows))
+}
+
+// Draw renders the tree, scrolled to show the selected row if it doesn't
+// fit.
+func (t *Tree) Draw(scr uv.Screen, area uv.Rectangle) {
+	t.DrawViewport(scr, area, 0, firstVisibleRow(t.selected, area.Dy(), t.Len()))
+}
+
+// DrawViewport renders the rows visible in area when scrolled by offsetX
+// columns and offsetY rows.
+func (t *Tree) DrawViewport(scr uv.Screen, area uv.Rectangle, offsetX, offsetY int) {
+	t.SetBounds(area)
+	rows := t.flatten()
+	t.rows = rowViewport{area: area, first: offsetY, count: len(rows)}
+
+	for y := 0; y < area.Dy(); y++ {
+		i := offsetY + y
+		if i >= len(rows) {
+			break
+		}
+
+		style := t.style
+		if i == t.selected {
+			style = t.selectedStyle
+		}
+		drawRow(scr, area, area.Min.Y+y, rows[i].prefix+rows[i].node.Label, style, offsetX)
+	}
+}
+
+// Children returns nil for trees.
+func (t *Tree) Children() []Element {
+	return nil
+}
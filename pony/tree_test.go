@@ -0,0 +1,72 @@
+package pony
+
+import (
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestTree(t *testing.T) {
+	src := NewTreeNode("src", NewTreeNode("main.go"), NewTreeNode("util", NewTreeNode("util.go")))
+	tree := NewTree(src, NewTreeNode("README.md"))
+
+	draw := func() []string {
+		scr := uv.NewScreenBuffer(20, 6)
+		tree.Layout(Constraints{MaxWidth: 20, MaxHeight: 6})
+		tree.Draw(scr, uv.Rect(0, 0, 20, 6))
+		return screenLines(&scr)
+	}
+
+	if got, want := draw(), []string{"▸ src", "  README.md"}; !equalLines(got, want) {
+		t.Errorf("collapsed: got %q, want %q", got, want)
+	}
+
+	if !tree.Toggle(0) {
+		t.Fatal("expected src to expand")
+	}
+	if !tree.Toggle(2) {
+		t.Fatal("expected util to expand")
+	}
+	want := []string{
+		"▾ src",
+		"├─   main.go",
+		"└─ ▾ util",
+		"   └─   util.go",
+		"  README.md",
+	}
+	if got := draw(); !equalLines(got, want) {
+		t.Errorf("expanded: got %q, want %q", got, want)
+	}
+	if tree.Toggle(1) {
+		t.Error("expected a leaf not to toggle")
+	}
+
+	tree.Selected(3)
+	draw()
+	if n, ok := tree.NodeAt(5, 3); !ok || n.Label != "util.go" || tree.SelectedNode() != n {
+		t.Errorf("unexpected node at row 3: %+v", n)
+	}
+
+	// Collapsing the node directly shows up at the next layout.
+	src.Toggle()
+	if got := draw(); !equalLines(got, []string{"▸ src", "  README.md"}) {
+		t.Errorf("collapsed again: got %q", got)
+	}
+}
+
+func TestTree_Markup(t *testing.T) {
+	tmpl := MustParse[struct{}](`<tree id="files" selected="1">
+	<node label="src" expanded="true">
+		<node id="main">main.go</node>
+	</node>
+</tree>`)
+	_, bm := tmpl.RenderWithBounds(struct{}{}, nil, 20, 5)
+
+	elem, ok := bm.GetByID("files")
+	if !ok {
+		t.Fatal("tree not rendered")
+	}
+	if n := elem.(*Tree).SelectedNode(); n == nil || n.ID != "main" || n.Label != "main.go" {
+		t.Errorf("unexpected selected node %+v", n)
+	}
+}
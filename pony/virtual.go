@@ -0,0 +1,135 @@
+package pony
+
+import (
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// unboundedSize is the size ScrollView lays out its content with along the
+// directions it scrolls in.
+const unboundedSize = 1 << 30
+
+// Virtualized is implemented by elements made of many rows, like lists,
+// tables and trees, that can draw just the part of their content in view.
+// ScrollView draws them straight into its viewport instead of drawing their
+// whole content off-screen, so that only the visible rows are laid out and
+// drawn. Their Layout must be cheap, and still return the full content size.
+//
+// Only the direct child of a ScrollView is virtualized. An element wrapped
+// in another one, such as a box with a border, is drawn whole off-screen
+// like any other content; put the ScrollView inside the wrapper instead.
+type Virtualized interface {
+	Element
+
+	// DrawViewport draws the content visible in area when scrolled by
+	// offsetX columns and offsetY rows.
+	DrawViewport(scr uv.Screen, area uv.Rectangle, offsetX, offsetY int)
+}
+
+// rowViewport records where the rows of a virtualized element were last
+// drawn, for hit testing.
+type rowViewport struct {
+	area  uv.Rectangle // area of the rows, without headers
+	first int          // index of the first row drawn
+	count int          // number of rows in the element
+}
+
+// rowAt returns the index of the row at the given screen coordinates.
+func (v rowViewport) rowAt(x, y int) (int, bool) {
+	if !pointInRect(x, y, v.area) {
+		return -1, false
+	}
+	row := v.first + y - v.area.Min.Y
+	if row >= v.count {
+		return -1, false
+	}
+	return row, true
+}
+
+// firstVisibleRow returns the first row to draw in a view of height rows so
+// that the selected row is visible.
+func firstVisibleRow(selected, height, count int) int {
+	if selected < height {
+		return 0
+	}
+	return max(0, min(selected-height+1, count-height))
+}
+
+// fitCell pads or truncates s to width cells with the given alignment.
+func fitCell(s string, width int, alignment string) string {
+	if width <= 0 {
+		return ""
+	}
+	w := ansi.StringWidth(s)
+	if w > width {
+		s = ansi.Truncate(s, width, "…")
+		w = ansi.StringWidth(s)
+	}
+
+	pad := width - w
+	switch alignment {
+	case AlignmentCenter:
+		return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+	case AlignmentTrailing:
+		return strings.Repeat(" ", pad) + s
+	default:
+		return s + strings.Repeat(" ", pad)
+	}
+}
+
+// drawRow draws a line of text in the row y of area, scrolled left by
+// offsetX cells. The line is padded to the width of the area so that the
+// style covers the whole row.
+func drawRow(scr uv.Screen, area uv.Rectangle, y int, line string, style uv.Style, offsetX int) {
+	width := area.Dx()
+	if offsetX > 0 {
+		line = ansi.Cut(line, offsetX, offsetX+width)
+	}
+	line = fitCell(line, width, AlignmentLeading)
+	if !style.IsZero() {
+		line = style.Styled(line)
+	}
+	uv.NewStyledString(line).Draw(scr, uv.Rect(area.Min.X, y, width, 1))
+}
+
+// rowStyleFromProps applies the foreground-color, background-color,
+// font-weight and font-style attributes with the given prefix, such as
+// "selected-", to style.
+func rowStyleFromProps(props Props, prefix string, style uv.Style) uv.Style {
+	if fgColor := props.Get(prefix + "foreground-color"); fgColor != "" {
+		if c, err := parseColor(fgColor); err == nil {
+			style.Fg = c
+		}
+	}
+	if bgColor := props.Get(prefix + "background-color"); bgColor != "" {
+		if c, err := parseColor(bgColor); err == nil {
+			style.Bg = c
+		}
+	}
+	if props.Get(prefix+"font-weight") == FontWeightBold {
+		style.Attrs |= uv.AttrBold
+	}
+	if props.Get(prefix+"font-style") == FontStyleItalic {
+		style.Attrs |= uv.AttrItalic
+	}
+	return style
+}
+
+// layoutRows returns the size of a virtualized element with the given
+// number of rows, which fills the available width unless a width is set.
+func layoutRows(constraints Constraints, width, height SizeConstraint, naturalWidth, rows int) Size {
+	w := constraints.MaxWidth
+	if w >= unboundedSize {
+		w = naturalWidth
+	}
+	if !width.IsAuto() {
+		w = width.Apply(constraints.MaxWidth, w)
+	}
+	h := rows
+	if !height.IsAuto() {
+		h = height.Apply(constraints.MaxHeight, h)
+	}
+	return constraints.Constrain(Size{Width: w, Height: h})
+}
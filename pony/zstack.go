@@ -66,14 +66,14 @@ func (z *ZStack) Draw(scr uv.Screen, area uv.Rectangle) {
 
 	childSizes := make([]Size, len(z.items))
 	for i, child := range z.items {
-		childSizes[i] = child.Layout(childConstraints)
+		childSizes[i] = layoutElement(child, childConstraints)
 	}
 
 	// Draw each child in order (later children draw on top)
 	for i, child := range z.items {
 		// Positioned elements handle their own layout and positioning
 		if _, isPositioned := child.(*Positioned); isPositioned {
-			child.Draw(scr, area)
+			drawElement(child, scr, area)
 			continue
 		}
 
@@ -107,7 +107,7 @@ func (z *ZStack) Draw(scr uv.Screen, area uv.Rectangle) {
 			childArea = uv.TopLeftRect(area, childSize.Width, childSize.Height)
 		}
 
-		child.Draw(scr, childArea)
+		drawElement(child, scr, childArea)
 	}
 }
 
@@ -123,7 +123,7 @@ func (z *ZStack) Layout(constraints Constraints) Size {
 
 	// Find maximum dimensions
 	for _, child := range z.items {
-		size := child.Layout(constraints)
+		size := layoutElement(child, constraints)
 		if size.Width > maxWidth {
 			maxWidth = size.Width
 		}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// location is a position in a file as printed by powernap.
+type location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// diagnostic is a diagnostic as printed by powernap.
+type diagnostic struct {
+	location
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Source   string `json:"source,omitempty"`
+	Code     any    `json:"code,omitempty"`
+}
+
+// symbol is a document symbol as printed by powernap.
+type symbol struct {
+	Name     string   `json:"name"`
+	Detail   string   `json:"detail,omitempty"`
+	Kind     string   `json:"kind"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Children []symbol `json:"children,omitempty"`
+}
+
+func allCommands() []command {
+	var includeDeclaration, write, insertSpaces bool
+	var settle time.Duration
+	var tabSize uint
+
+	return []command{
+		{
+			name: "hover",
+			args: "<file:line:col>",
+			help: "Show hover information for the symbol at a position.",
+			run:  runHover,
+		},
+		{
+			name: "definition",
+			args: "<file:line:col>",
+			help: "List the definition locations of the symbol at a position.",
+			run:  runDefinition,
+		},
+		{
+			name: "references",
+			args: "<file:line:col>",
+			help: "List the references to the symbol at a position.",
+			flags: func(fs *flag.FlagSet) {
+				fs.BoolVar(&includeDeclaration, "declaration", true, "include the declaration of the symbol")
+			},
+			run: func(ctx context.Context, s *session, args []string) error {
+				return runReferences(ctx, s, args, includeDeclaration)
+			},
+		},
+		{
+			name: "symbols",
+			args: "<file>",
+			help: "Show the symbol outline of a file.",
+			run:  runSymbols,
+		},
+		{
+			name: "diagnostics",
+			args: "[path...]",
+			help: "Report diagnostics for files and directories, failing on errors.",
+			flags: func(fs *flag.FlagSet) {
+				fs.DurationVar(&settle, "settle", time.Second, "how long the servers must be quiet before reporting")
+			},
+			run: func(ctx context.Context, s *session, args []string) error {
+				return runDiagnostics(ctx, s, args, settle)
+			},
+		},
+		{
+			name: "format",
+			args: "<file>",
+			help: "Format a file, printing the result unless -write is set.",
+			flags: func(fs *flag.FlagSet) {
+				fs.BoolVar(&write, "write", false, "write the result to the file instead of stdout")
+				fs.UintVar(&tabSize, "tab-size", 4, "size of a tab in spaces")
+				fs.BoolVar(&insertSpaces, "insert-spaces", true, "indent with spaces instead of tabs (tabs were the default before this flag)")
+			},
+			run: func(ctx context.Context, s *session, args []string) error {
+				if tabSize == 0 || tabSize > math.MaxUint32 {
+					return fmt.Errorf("invalid tab size %d", tabSize)
+				}
+				return runFormat(ctx, s, args, write, protocol.FormattingOptions{
+					TabSize:            uint32(tabSize),
+					InsertSpaces:       insertSpaces,
+					InsertFinalNewline: true,
+				})
+			},
+		},
+	}
+}
+
+func runHover(ctx context.Context, s *session, args []string) error {
+	client, uri, pos, err := s.target(ctx, args, lsp.MethodTextDocumentHover)
+	if err != nil {
+		return err
+	}
+
+	hover, err := client.RequestHover(ctx, uri, pos)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if hover == nil || strings.TrimSpace(hover.Contents.Value) == "" {
+		return errNoResult
+	}
+
+	if s.opts.json {
+		return s.printJSON(hover)
+	}
+	fmt.Fprintln(s.stdout, strings.TrimSpace(hover.Contents.Value))
+	return nil
+}
+
+func runDefinition(ctx context.Context, s *session, args []string) error {
+	client, uri, pos, err := s.target(ctx, args, lsp.MethodTextDocumentDefinition)
+	if err != nil {
+		return err
+	}
+
+	locations, err := client.RequestDefinition(ctx, uri, pos)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return s.printLocations(locations, client.OffsetEncoding())
+}
+
+func runReferences(ctx context.Context, s *session, args []string, includeDeclaration bool) error {
+	client, uri, pos, err := s.target(ctx, args, lsp.MethodTextDocumentReferences)
+	if err != nil {
+		return err
+	}
+
+	path, _ := protocol.DocumentURI(uri).Path()
+	locations, err := client.FindReferences(ctx, path, int(pos.Line), int(pos.Character), includeDeclaration)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return s.printLocations(locations, client.OffsetEncoding())
+}
+
+func runSymbols(ctx context.Context, s *session, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	client, uri, err := s.document(ctx, path, lsp.MethodTextDocumentDocumentSymbol)
+	if err != nil {
+		return err
+	}
+
+	symbols, err := client.RequestDocumentSymbols(ctx, uri)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if len(symbols) == 0 {
+		return errNoResult
+	}
+
+	content, _ := s.read(path)
+	out := convertSymbols(content, symbols, client.OffsetEncoding())
+	if s.opts.json {
+		return s.printJSON(out)
+	}
+
+	var printTree func(symbols []symbol, depth int)
+	printTree = func(symbols []symbol, depth int) {
+		for _, sym := range symbols {
+			fmt.Fprintf(s.stdout, "%s%s %s %d:%d\n", strings.Repeat("  ", depth), sym.Kind, sym.Name, sym.Line, sym.Column)
+			printTree(sym.Children, depth+1)
+		}
+	}
+	printTree(out, 0)
+	return nil
+}
+
+func runDiagnostics(ctx context.Context, s *session, args []string, settle time.Duration) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	files, err := s.collectFiles(args)
+	if err != nil {
+		return err
+	}
+
+	// Open every file we have a server for, skipping languages whose
+	// servers failed to start.
+	failed := make(map[string]bool)
+	var uris []string
+	for _, path := range files {
+		key := filepath.Ext(path)
+		if failed[key] {
+			continue
+		}
+		clients, err := s.clients(ctx, path)
+		if err != nil {
+			failed[key] = true
+			continue
+		}
+		uri, err := s.open(ctx, clients, path)
+		if err != nil {
+			return err
+		}
+		uris = append(uris, uri)
+	}
+	if len(uris) == 0 {
+		return fmt.Errorf("no language server available for the given files")
+	}
+
+	if err := s.waitForDiagnostics(ctx, uris, settle); err != nil {
+		return err
+	}
+
+	var diags []diagnostic
+	s.mu.Lock()
+	for uri, byClient := range s.diagnostics {
+		path, err := uri.Path()
+		if err != nil {
+			continue
+		}
+		content, _ := s.read(path)
+		for client, list := range byClient {
+			for _, d := range list {
+				line, col := fromPosition(content, d.Range.Start, client.OffsetEncoding())
+				diags = append(diags, diagnostic{
+					location: location{File: s.relative(path), Line: line, Column: col},
+					Severity: severityName(d.Severity),
+					Message:  d.Message,
+					Source:   d.Source,
+					Code:     d.Code,
+				})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(diags, func(a, b diagnostic) int {
+		return compareLocations(a.location, b.location)
+	})
+
+	if s.opts.json {
+		if diags == nil {
+			diags = []diagnostic{}
+		}
+		if err := s.printJSON(diags); err != nil {
+			return err
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprintf(s.stdout, "%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+			if d.Source != "" {
+				fmt.Fprintf(s.stdout, " (%s)", d.Source)
+			}
+			fmt.Fprintln(s.stdout)
+		}
+	}
+
+	if slices.ContainsFunc(diags, func(d diagnostic) bool { return d.Severity == "error" }) {
+		return errDiagnostics
+	}
+	return nil
+}
+
+func runFormat(ctx context.Context, s *session, args []string, write bool, options protocol.FormattingOptions) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	client, uri, err := s.document(ctx, path, lsp.MethodTextDocumentFormatting)
+	if err != nil {
+		return err
+	}
+
+	edits, err := client.RequestFormatting(ctx, uri, options)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	content, err := s.read(path)
+	if err != nil {
+		return err
+	}
+	formatted, err := lsp.ApplyTextEditsWithEncoding(content, edits, client.OffsetEncoding())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	switch {
+	case s.opts.json:
+		if edits == nil {
+			edits = []protocol.TextEdit{}
+		}
+		return s.printJSON(edits)
+	case write:
+		if formatted == content {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		return os.WriteFile(path, []byte(formatted), info.Mode().Perm()) //nolint:wrapcheck
+	default:
+		_, err := fmt.Fprint(s.stdout, formatted)
+		return err //nolint:wrapcheck
+	}
+}
+
+// target resolves a file:line:col argument into a client supporting method,
+// the document URI, and the LSP position.
+func (s *session) target(ctx context.Context, args []string, method string) (*lsp.Client, string, protocol.Position, error) {
+	if len(args) != 1 {
+		return nil, "", protocol.Position{}, errUsage
+	}
+	path, line, col, err := parseTarget(args[0])
+	if err != nil {
+		return nil, "", protocol.Position{}, err
+	}
+
+	client, uri, err := s.document(ctx, path, method)
+	if err != nil {
+		return nil, "", protocol.Position{}, err
+	}
+
+	content, err := s.read(path)
+	if err != nil {
+		return nil, "", protocol.Position{}, err
+	}
+	return client, uri, toPosition(content, line, col, client.OffsetEncoding()), nil
+}
+
+// document opens a file and returns the first client supporting method.
+func (s *session) document(ctx context.Context, path, method string) (*lsp.Client, string, error) {
+	clients, err := s.clients(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	uri, err := s.open(ctx, clients, path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, client := range clients {
+		if client.Supports(method) {
+			return client, uri, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no language server for %s supports %s", s.relative(path), method)
+}
+
+// collectFiles expands the given files and directories into a sorted list of
+// files, skipping hidden directories and dependencies.
+func (s *session) collectFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, arg := range paths {
+		root, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if s.opts.server != "" && !slices.Contains(s.registry.ServersForFile(path), s.opts.server) {
+				return nil
+			}
+			if lsp.DetectLanguage(path) == "" {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// printLocations prints locations with positions in the given encoding,
+// returning errNoResult if there are none.
+func (s *session) printLocations(locations []protocol.Location, encoding lsp.OffsetEncoding) error {
+	if len(locations) == 0 {
+		return errNoResult
+	}
+
+	out := make([]location, 0, len(locations))
+	for _, loc := range locations {
+		path, err := loc.URI.Path()
+		if err != nil {
+			continue
+		}
+		content, _ := s.read(path)
+		line, col := fromPosition(content, loc.Range.Start, encoding)
+		out = append(out, location{File: s.relative(path), Line: line, Column: col})
+	}
+
+	if s.opts.json {
+		return s.printJSON(out)
+	}
+	for _, loc := range out {
+		fmt.Fprintf(s.stdout, "%s:%d:%d\n", loc.File, loc.Line, loc.Column)
+	}
+	return nil
+}
+
+// printJSON prints v as indented JSON.
+func (s *session) printJSON(v any) error {
+	enc := json.NewEncoder(s.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v) //nolint:wrapcheck
+}
+
+// relative returns path relative to the working directory when it is inside
+// of it.
+func (s *session) relative(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// convertSymbols converts symbols with positions in the given encoding for
+// printing.
+func convertSymbols(content string, symbols []lsp.Symbol, encoding lsp.OffsetEncoding) []symbol {
+	out := make([]symbol, 0, len(symbols))
+	for _, sym := range symbols {
+		line, col := fromPosition(content, sym.SelectionRange.Start, encoding)
+		out = append(out, symbol{
+			Name:     sym.Name,
+			Detail:   sym.Detail,
+			Kind:     symbolKindName(sym.Kind),
+			Line:     line,
+			Column:   col,
+			Children: convertSymbols(content, sym.Children, encoding),
+		})
+	}
+	return out
+}
+
+// compareLocations orders locations by file, line and column.
+func compareLocations(a, b location) int {
+	if c := strings.Compare(a.File, b.File); c != 0 {
+		return c
+	}
+	if a.Line != b.Line {
+		return a.Line - b.Line
+	}
+	return a.Column - b.Column
+}
+
+// severityName returns the name of a diagnostic severity.
+func severityName(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	case protocol.SeverityInformation:
+		return "info"
+	case protocol.SeverityHint:
+		return "hint"
+	}
+	// Servers should treat a missing severity as an error.
+	return "error"
+}
+
+// symbolKindNames maps symbol kinds to their names.
+var symbolKindNames = map[protocol.SymbolKind]string{
+	protocol.File:          "file",
+	protocol.Module:        "module",
+	protocol.Namespace:     "namespace",
+	protocol.Package:       "package",
+	protocol.Class:         "class",
+	protocol.Method:        "method",
+	protocol.Property:      "property",
+	protocol.Field:         "field",
+	protocol.Constructor:   "constructor",
+	protocol.Enum:          "enum",
+	protocol.Interface:     "interface",
+	protocol.Function:      "function",
+	protocol.Variable:      "variable",
+	protocol.Constant:      "constant",
+	protocol.String:        "string",
+	protocol.Number:        "number",
+	protocol.Boolean:       "boolean",
+	protocol.Array:         "array",
+	protocol.Object:        "object",
+	protocol.Key:           "key",
+	protocol.Null:          "null",
+	protocol.EnumMember:    "enummember",
+	protocol.Struct:        "struct",
+	protocol.Event:         "event",
+	protocol.Operator:      "operator",
+	protocol.TypeParameter: "typeparameter",
+}
+
+// symbolKindName returns the name of a symbol kind.
+func symbolKindName(kind protocol.SymbolKind) string {
+	if name, ok := symbolKindNames[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", kind)
+}
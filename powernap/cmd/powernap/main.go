@@ -0,0 +1,221 @@
+// Package main provides the powernap command, which runs one-shot queries
+// against language servers from shell scripts and CI.
+//
+// Usage:
+//
+//	powernap <command> [flags] <args>
+//
+// Commands:
+//
+//	hover <file:line:col>         show hover information
+//	definition <file:line:col>    list definition locations
+//	references <file:line:col>    list reference locations
+//	symbols <file>                show the symbol outline of a file
+//	diagnostics [path...]         report diagnostics for files and directories
+//	format [-write] <file>        format a file
+//
+// format indents with 4 spaces by default. Earlier versions indented with
+// tabs; pass -insert-spaces=false to keep that, and -tab-size for other
+// widths.
+//
+// The -trace flag writes the JSON-RPC traffic to a file in the JSON format
+// understood by the LSP Inspector, which can be replayed in tests with
+// lsptest.Replay. Each entry records its server; use transport.FilterTrace
+// to replay the traffic of one server.
+//
+// Lines and columns are 1-based, and columns count bytes. The language server
+// is picked by file type unless -server is set. Server configurations are
+// the embedded defaults, layered with the files in $XDG_CONFIG_HOME/powernap
+// and the closest .powernap.{json,toml,yaml} file.
+//
+// The exit code is 0 on success, 1 if there was no result or diagnostics
+// contain errors, and 2 on failure.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/config"
+	"github.com/charmbracelet/x/powernap/pkg/registry"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitNoMatch = 1
+	exitFailure = 2
+)
+
+// errNoResult is returned by commands that found nothing to report.
+var errNoResult = errors.New("no result")
+
+// errDiagnostics is returned when the reported diagnostics contain errors.
+var errDiagnostics = errors.New("diagnostics contain errors")
+
+// options are the flags shared by all commands.
+type options struct {
+	server  string
+	json    bool
+	timeout time.Duration
+	verbose bool
+	trace   string
+}
+
+// command is a powernap subcommand.
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags func(fs *flag.FlagSet)
+	run   func(ctx context.Context, s *session, args []string) error
+}
+
+// app runs powernap commands.
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// newRegistry creates the registry used to start language servers.
+	newRegistry func(logger *slog.Logger) (*registry.Registry, error)
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	a := &app{
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		newRegistry: defaultRegistry,
+	}
+	code := a.run(ctx, os.Args[1:])
+	cancel()
+	os.Exit(code)
+}
+
+// defaultRegistry creates a registry with the embedded server
+// configurations, layered with the global and project configuration files.
+func defaultRegistry(logger *slog.Logger) (*registry.Registry, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	cfg := config.NewManager()
+	if err := cfg.Load(dir); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	r := registry.NewWithLogger(logger)
+	if err := r.LoadConfig(cfg); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return r, nil
+}
+
+// run runs the command line and returns the exit code.
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		a.usage()
+		if len(args) == 0 {
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	cmd, ok := a.commands()[args[0]]
+	if !ok {
+		fmt.Fprintf(a.stderr, "powernap: unknown command %q\n\n", args[0])
+		a.usage()
+		return exitFailure
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("powernap "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&opts.server, "server", "", "use the named language server instead of detecting it")
+	fs.BoolVar(&opts.json, "json", false, "print results as JSON")
+	fs.DurationVar(&opts.timeout, "timeout", time.Minute, "maximum time to wait for the language server")
+	fs.BoolVar(&opts.verbose, "v", false, "log language server activity to stderr")
+	fs.StringVar(&opts.trace, "trace", "", "write the JSON-RPC traffic to `file`")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: powernap %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitFailure
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	if opts.verbose {
+		logger = slog.New(slog.NewTextHandler(a.stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	slog.SetDefault(logger)
+
+	r, err := a.newRegistry(logger)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "powernap: %v\n", err)
+		return exitFailure
+	}
+
+	if opts.trace != "" {
+		f, err := os.Create(opts.trace)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "powernap: %v\n", err)
+			return exitFailure
+		}
+		defer f.Close() //nolint:errcheck
+		r.SetTracer(transport.NewTracer(f, transport.TraceJSON, transport.TraceVerbose))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	s := newSession(r, opts, a.stdout)
+	defer s.close(ctx)
+
+	switch err := cmd.run(ctx, s, fs.Args()); {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errNoResult), errors.Is(err, errDiagnostics):
+		return exitNoMatch
+	case errors.Is(err, errUsage):
+		fs.Usage()
+		return exitFailure
+	default:
+		fmt.Fprintf(a.stderr, "powernap %s: %v\n", cmd.name, err)
+		return exitFailure
+	}
+}
+
+// commands returns the available commands by name.
+func (a *app) commands() map[string]command {
+	cmds := make(map[string]command)
+	for _, cmd := range allCommands() {
+		cmds[cmd.name] = cmd
+	}
+	return cmds
+}
+
+// usage prints the top-level help.
+func (a *app) usage() {
+	fmt.Fprintln(a.stderr, "Usage: powernap <command> [flags] <args>")
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Commands:")
+	for _, cmd := range allCommands() {
+		fmt.Fprintf(a.stderr, "  %-12s %s\n", cmd.name, cmd.help)
+	}
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, `Run "powernap <command> -help" for the flags of a command.`)
+}
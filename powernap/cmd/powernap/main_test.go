@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/config"
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/registry"
+)
+
+const testSource = "package main\n\nfunc main() {\n\tprintln(\"héllo\", x)\n}\n"
+
+func TestHover(t *testing.T) {
+	dir, server := setup(t)
+	server.Handle(lsp.MethodTextDocumentHover, func(_ context.Context, params json.RawMessage) (any, error) {
+		var p protocol.HoverParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		// Column 20 is "x", after a two byte character.
+		if p.Position != (protocol.Position{Line: 3, Character: 18}) {
+			return nil, nil
+		}
+		return protocol.Hover{Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "var x int\n"}}, nil
+	})
+
+	code, stdout, _ := run(t, server, "hover", filepath.Join(dir, "main.go")+":4:20")
+	if code != exitOK || stdout != "var x int\n" {
+		t.Errorf("unexpected result %d: %q", code, stdout)
+	}
+
+	code, _, _ = run(t, server, "hover", filepath.Join(dir, "main.go")+":1:1")
+	if code != exitNoMatch {
+		t.Errorf("expected no result, got exit code %d", code)
+	}
+
+	code, _, stderr := run(t, server, "hover", "main.go")
+	if code != exitFailure || !strings.Contains(stderr, "Usage: powernap hover") {
+		t.Errorf("expected usage error, got %d: %q", code, stderr)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	dir, server := setup(t)
+	uri := protocol.URIFromPath(filepath.Join(dir, "main.go"))
+	server.Respond(lsp.MethodTextDocumentDefinition, []protocol.LocationLink{{
+		TargetURI:            uri,
+		TargetRange:          protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 4, Character: 1}},
+		TargetSelectionRange: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 9}},
+	}})
+
+	code, stdout, _ := run(t, server, "definition", "-json", filepath.Join(dir, "main.go")+":4:2")
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	var locs []location
+	if err := json.Unmarshal([]byte(stdout), &locs); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	if len(locs) != 1 || locs[0].Line != 3 || locs[0].Column != 6 || filepath.Base(locs[0].File) != "main.go" {
+		t.Errorf("unexpected locations: %+v", locs)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	dir, server := setup(t)
+	writeFile(t, filepath.Join(dir, "util.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, ".hidden", "skip.go"), "package skip\n")
+	writeFile(t, filepath.Join(dir, "README.txt"), "not go\n")
+
+	server.Handle(lsp.MethodTextDocumentDidOpen, func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p protocol.DidOpenTextDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		var diags []protocol.Diagnostic
+		if strings.HasSuffix(string(p.TextDocument.URI), "main.go") {
+			diags = []protocol.Diagnostic{
+				{Range: protocol.Range{Start: protocol.Position{Line: 3, Character: 18}}, Severity: protocol.SeverityError, Message: "undefined: x", Source: "compiler"},
+				{Range: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}}, Severity: protocol.SeverityHint, Message: "unused"},
+			}
+		}
+		go server.PublishDiagnostics(context.WithoutCancel(ctx), p.TextDocument.URI, p.TextDocument.Version, diags) //nolint:errcheck
+		return nil, nil
+	})
+
+	code, stdout, _ := run(t, server, "diagnostics", "-settle", "50ms", dir)
+	if code != exitNoMatch {
+		t.Errorf("expected exit code %d for errors, got %d", exitNoMatch, code)
+	}
+	main := filepath.Join(dir, "main.go")
+	want := main + ":3:6: hint: unused\n" + main + ":4:20: error: undefined: x (compiler)\n"
+	if stdout != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", stdout, want)
+	}
+
+	opened := server.ReceivedMethod(lsp.MethodTextDocumentDidOpen)
+	if len(opened) != 2 {
+		t.Errorf("expected main.go and util.go to be opened, got %d files", len(opened))
+	}
+
+	// Without errors the command succeeds.
+	code, stdout, _ = run(t, server, "diagnostics", "-settle", "50ms", "-json", filepath.Join(dir, "util.go"))
+	if code != exitOK || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("unexpected result %d: %q", code, stdout)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	dir, server := setup(t)
+	server.Respond(lsp.MethodTextDocumentDocumentSymbol, []protocol.DocumentSymbol{{
+		Name:           "main",
+		Kind:           protocol.Function,
+		Range:          protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 4, Character: 1}},
+		SelectionRange: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 9}},
+		Children: []protocol.DocumentSymbol{{
+			Name:           "x",
+			Kind:           protocol.Variable,
+			SelectionRange: protocol.Range{Start: protocol.Position{Line: 3, Character: 18}},
+		}},
+	}})
+
+	code, stdout, _ := run(t, server, "symbols", filepath.Join(dir, "main.go"))
+	if want := "function main 3:6\n  variable x 4:20\n"; code != exitOK || stdout != want {
+		t.Errorf("unexpected result %d:\n%s\nwant:\n%s", code, stdout, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir, server := setup(t)
+	server.Respond(lsp.MethodTextDocumentFormatting, []protocol.TextEdit{{
+		Range:   protocol.Range{Start: protocol.Position{Line: 3, Character: 0}, End: protocol.Position{Line: 3, Character: 1}},
+		NewText: "    ",
+	}})
+	path := filepath.Join(dir, "main.go")
+	want := strings.Replace(testSource, "\tprintln", "    println", 1)
+
+	code, stdout, _ := run(t, server, "format", path)
+	if code != exitOK || stdout != want {
+		t.Errorf("unexpected result %d: %q", code, stdout)
+	}
+
+	code, stdout, _ = run(t, server, "format", "-write", path)
+	if code != exitOK || stdout != "" {
+		t.Errorf("unexpected result %d: %q", code, stdout)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("unexpected file content: %q", data)
+	}
+}
+
+func TestFormat_Options(t *testing.T) {
+	dir, server := setup(t)
+	server.Respond(lsp.MethodTextDocumentFormatting, []protocol.TextEdit{})
+	path := filepath.Join(dir, "main.go")
+
+	for _, tt := range []struct {
+		args []string
+		want protocol.FormattingOptions
+	}{
+		{nil, protocol.FormattingOptions{TabSize: 4, InsertSpaces: true, InsertFinalNewline: true}},
+		{[]string{"-tab-size", "8", "-insert-spaces=false"}, protocol.FormattingOptions{TabSize: 8, InsertFinalNewline: true}},
+	} {
+		args := append(append([]string{"format"}, tt.args...), path)
+		if code, _, stderr := run(t, server, args...); code != exitOK {
+			t.Fatalf("%v: unexpected result %d: %s", tt.args, code, stderr)
+		}
+		requests := server.ReceivedMethod(lsp.MethodTextDocumentFormatting)
+		var params protocol.DocumentFormattingParams
+		if err := requests[len(requests)-1].Unmarshal(&params); err != nil {
+			t.Fatal(err)
+		}
+		if params.Options != tt.want {
+			t.Errorf("%v: got options %+v, want %+v", tt.args, params.Options, tt.want)
+		}
+	}
+
+	if code, _, _ := run(t, server, "format", "-tab-size", "0", path); code != exitFailure {
+		t.Errorf("expected failure for a zero tab size, got %d", code)
+	}
+}
+
+func TestServerOverride(t *testing.T) {
+	dir, server := setup(t)
+
+	code, _, stderr := run(t, server, "symbols", "-server", "missing", filepath.Join(dir, "main.go"))
+	if code != exitFailure || !strings.Contains(stderr, "missing") {
+		t.Errorf("expected failure for unknown server, got %d: %q", code, stderr)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	path, line, col, err := parseTarget("dir/file:name.go:12:3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "file:name.go" || line != 12 || col != 3 {
+		t.Errorf("unexpected target: %s %d %d", path, line, col)
+	}
+
+	for _, arg := range []string{"main.go", "main.go:1", "main.go:0:1", "main.go:1:x"} {
+		if _, _, _, err := parseTarget(arg); err == nil {
+			t.Errorf("expected error for %q", arg)
+		}
+	}
+}
+
+// setup creates a Go project with a single file and a fake server for it.
+func setup(t *testing.T) (string, *lsptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example\n")
+	writeFile(t, filepath.Join(dir, "main.go"), testSource)
+
+	server := lsptest.NewServer(protocol.ServerCapabilities{
+		TextDocumentSync:           protocol.Full,
+		HoverProvider:              &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+		DefinitionProvider:         &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+		ReferencesProvider:         &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+		DocumentSymbolProvider:     &protocol.Or_ServerCapabilities_documentSymbolProvider{Value: true},
+		DocumentFormattingProvider: &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true},
+	})
+	t.Cleanup(func() { _ = server.Close() })
+	return dir, server
+}
+
+// run runs powernap against the fake server.
+func run(t *testing.T, server *lsptest.Server, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	a := &app{
+		stdout: &out,
+		stderr: &errOut,
+		newRegistry: func(logger *slog.Logger) (*registry.Registry, error) {
+			cfg := config.NewManager()
+			err := cfg.LoadFromMap(map[string]any{
+				"servers": map[string]any{
+					"fake": map[string]any{
+						"command":      "fake",
+						"filetypes":    []string{"go"},
+						"root_markers": []string{"go.mod"},
+					},
+				},
+			})
+			if err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+			r := registry.NewWithLogger(logger)
+			if err := r.LoadConfig(cfg); err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+			r.SetDialer(func(ctx context.Context, _ string, _ *config.ServerConfig) (io.ReadWriteCloser, error) {
+				return server.Dial(ctx)
+			})
+			return r, nil
+		},
+	}
+	code = a.run(t.Context(), args)
+	return code, out.String(), errOut.String()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/registry"
+)
+
+// errUsage is returned by commands that were given invalid arguments.
+var errUsage = errors.New("invalid arguments")
+
+// session tracks the language servers and documents used by a command.
+type session struct {
+	registry *registry.Registry
+	opts     options
+	stdout   io.Writer
+
+	opened   map[*lsp.Client]map[string]bool // open document URIs by client
+	contents map[string]string               // file contents by path
+
+	mu          sync.Mutex
+	diagnostics map[protocol.DocumentURI]map[*lsp.Client][]protocol.Diagnostic
+	lastUpdate  time.Time
+}
+
+func newSession(r *registry.Registry, opts options, stdout io.Writer) *session {
+	return &session{
+		registry:    r,
+		opts:        opts,
+		stdout:      stdout,
+		opened:      make(map[*lsp.Client]map[string]bool),
+		contents:    make(map[string]string),
+		diagnostics: make(map[protocol.DocumentURI]map[*lsp.Client][]protocol.Diagnostic),
+	}
+}
+
+// clients returns the running clients for a file, starting them as needed.
+func (s *session) clients(ctx context.Context, path string) ([]*lsp.Client, error) {
+	var clients []*lsp.Client
+	if s.opts.server != "" {
+		client, err := s.registry.StartServer(ctx, s.opts.server, filepath.Dir(path))
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		clients = []*lsp.Client{client}
+	} else {
+		var err error
+		clients, err = s.registry.GetClientsForFile(ctx, path)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	for _, client := range clients {
+		if _, ok := s.opened[client]; ok {
+			continue
+		}
+		s.opened[client] = make(map[string]bool)
+		client.RegisterNotificationHandler(lsp.MethodTextDocumentDiagnostic, func(_ context.Context, _ string, params json.RawMessage) {
+			s.handleDiagnostics(client, params)
+		})
+	}
+	return clients, nil
+}
+
+// open opens a file in the given clients and returns its URI.
+func (s *session) open(ctx context.Context, clients []*lsp.Client, path string) (string, error) {
+	content, err := s.read(path)
+	if err != nil {
+		return "", err
+	}
+
+	uri := string(protocol.URIFromPath(path))
+	languageID := cmp.Or(string(lsp.DetectLanguage(path)), "plaintext")
+	for _, client := range clients {
+		if s.opened[client][uri] {
+			continue
+		}
+		if err := client.NotifyDidOpenTextDocument(ctx, uri, languageID, 1, content); err != nil {
+			return "", err //nolint:wrapcheck
+		}
+		s.opened[client][uri] = true
+	}
+	return uri, nil
+}
+
+// read returns the contents of a file, caching them for position conversions.
+func (s *session) read(path string) (string, error) {
+	if content, ok := s.contents[path]; ok {
+		return content, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	s.contents[path] = string(data)
+	return string(data), nil
+}
+
+// close shuts down all language servers.
+func (s *session) close(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_ = s.registry.StopAll(ctx)
+}
+
+// handleDiagnostics records published diagnostics.
+func (s *session) handleDiagnostics(client *lsp.Client, params json.RawMessage) {
+	var diags protocol.PublishDiagnosticsParams
+	if err := json.Unmarshal(params, &diags); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.diagnostics[diags.URI] == nil {
+		s.diagnostics[diags.URI] = make(map[*lsp.Client][]protocol.Diagnostic)
+	}
+	s.diagnostics[diags.URI][client] = diags.Diagnostics
+	s.lastUpdate = time.Now()
+}
+
+// waitForDiagnostics waits until diagnostics were published for all uris and
+// no new diagnostics arrived for the settle duration, or until the servers
+// have been quiet for a while even if some files never got diagnostics.
+func (s *session) waitForDiagnostics(ctx context.Context, uris []string, settle time.Duration) error {
+	start := time.Now()
+	ticker := time.NewTicker(min(settle/4, 50*time.Millisecond) + time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		last := cmp.Or(s.lastUpdate, start)
+		reported := 0
+		for _, uri := range uris {
+			if _, ok := s.diagnostics[protocol.DocumentURI(uri)]; ok {
+				reported++
+			}
+		}
+		s.mu.Unlock()
+
+		quiet := time.Since(last)
+		if (reported == len(uris) && quiet >= settle) || quiet >= 5*settle {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseTarget parses a file:line:col argument into an absolute path and a
+// 1-based line and column.
+func parseTarget(arg string) (path string, line, col int, err error) {
+	i := strings.LastIndexByte(arg, ':')
+	j := -1
+	if i > 0 {
+		j = strings.LastIndexByte(arg[:i], ':')
+	}
+	if j <= 0 {
+		return "", 0, 0, fmt.Errorf("expected file:line:col, got %q: %w", arg, errUsage)
+	}
+
+	line, err = strconv.Atoi(arg[j+1 : i])
+	if err != nil || line < 1 {
+		return "", 0, 0, fmt.Errorf("invalid line in %q: %w", arg, errUsage)
+	}
+	col, err = strconv.Atoi(arg[i+1:])
+	if err != nil || col < 1 {
+		return "", 0, 0, fmt.Errorf("invalid column in %q: %w", arg, errUsage)
+	}
+
+	path, err = filepath.Abs(arg[:j])
+	if err != nil {
+		return "", 0, 0, err //nolint:wrapcheck
+	}
+	return path, line, col, nil
+}
+
+// toPosition converts a 1-based line and byte column into an LSP position
+// using the server's encoding.
+func toPosition(content string, line, col int, encoding lsp.OffsetEncoding) protocol.Position {
+	mapper := lsp.NewPositionMapper(content, encoding)
+	start, err := mapper.Offset(protocol.Position{Line: uint32(line - 1)}) //nolint:gosec
+	if err != nil {
+		return protocol.Position{Line: uint32(line - 1)} //nolint:gosec
+	}
+	pos, _ := mapper.Position(start + min(col-1, len(lineText(content, line-1))))
+	return pos
+}
+
+// fromPosition converts an LSP position in the server's encoding into a
+// 1-based line and byte column.
+func fromPosition(content string, pos protocol.Position, encoding lsp.OffsetEncoding) (line, col int) {
+	mapper := lsp.NewPositionMapper(content, encoding)
+	start, err := mapper.Offset(protocol.Position{Line: pos.Line})
+	if err != nil {
+		return int(pos.Line) + 1, 1
+	}
+	offset, _ := mapper.Offset(pos)
+	return int(pos.Line) + 1, offset - start + 1
+}
+
+// lineText returns the text of a 0-based line, without the line ending.
+func lineText(content string, line int) string {
+	for range line {
+		i := strings.IndexByte(content, '\n')
+		if i < 0 {
+			return ""
+		}
+		content = content[i+1:]
+	}
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[:i]
+	}
+	return strings.TrimSuffix(content, "\r")
+}
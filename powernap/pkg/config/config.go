@@ -23,6 +23,14 @@ type ServerConfig struct {
 	InitOptions       map[string]any    `mapstructure:"init_options" json:"init_options,omitempty"`
 	EnableSnippets    bool              `mapstructure:"enable_snippets" json:"-"`
 	SingleFileSupport bool              `mapstructure:"single_file_support" json:"-"`
+
+	// Address connects to a running server at tcp://host:port or
+	// unix:///path instead of starting Command.
+	Address string `mapstructure:"address" json:"address,omitempty"`
+	// Listen makes the client listen on a tcp:// or unix:// address and
+	// start Command so that it connects back. The placeholders ${host},
+	// ${port}, and ${address} in Args are replaced with the listener address.
+	Listen string `mapstructure:"listen" json:"listen,omitempty"`
 }
 
 // Config represents the overall configuration.
@@ -80,20 +88,25 @@ func (m *Manager) RemoveServer(name string) {
 // applyDefaults applies default values to server configurations.
 func (m *Manager) applyDefaults() {
 	for name, server := range m.config.Servers {
-		if server.RootMarkers == nil {
-			server.RootMarkers = []string{".git"}
-		}
-
-		if server.Environment == nil {
-			server.Environment = make(map[string]string)
-		}
-
-		if server.Settings == nil {
-			server.Settings = make(map[string]any)
-		}
-		_, server.EnableSnippets = snippetSupport[name]
-		_, server.SingleFileSupport = singleFileSupport[name]
+		applyServerDefaults(name, server)
+	}
+}
+
+// applyServerDefaults applies default values to a server configuration.
+func applyServerDefaults(name string, server *ServerConfig) {
+	if server.RootMarkers == nil {
+		server.RootMarkers = []string{".git"}
+	}
+
+	if server.Environment == nil {
+		server.Environment = make(map[string]string)
+	}
+
+	if server.Settings == nil {
+		server.Settings = make(map[string]any)
 	}
+	_, server.EnableSnippets = snippetSupport[name]
+	_, server.SingleFileSupport = singleFileSupport[name]
 }
 
 // LoadFromMap loads configuration from a map (useful for testing).
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// Expand returns a copy of the configuration with ${env:NAME} replaced by
+// the value of the environment variable NAME and ${workspaceFolder}
+// replaced by workspaceFolder, in the command, arguments, environment,
+// addresses, settings, and initialization options. Other placeholders, such
+// as the ${host}, ${port}, and ${address} used with Listen, are left
+// untouched.
+func (s *ServerConfig) Expand(workspaceFolder string) *ServerConfig {
+	expand := func(v string) string {
+		return expandString(v, workspaceFolder)
+	}
+
+	out := *s
+	out.Command = expand(s.Command)
+	out.Address = expand(s.Address)
+	out.Listen = expand(s.Listen)
+	if s.Args != nil {
+		out.Args = make([]string, len(s.Args))
+		for i, arg := range s.Args {
+			out.Args[i] = expand(arg)
+		}
+	}
+	if s.Environment != nil {
+		out.Environment = make(map[string]string, len(s.Environment))
+		for k, v := range s.Environment {
+			out.Environment[k] = expand(v)
+		}
+	}
+	out.Settings, _ = expandValue(s.Settings, expand).(map[string]any)
+	out.InitOptions, _ = expandValue(s.InitOptions, expand).(map[string]any)
+	return &out
+}
+
+// expandValue expands the strings in a decoded JSON value.
+func expandValue(v any, expand func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		return expand(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = expandValue(item, expand)
+		}
+		return out
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = expandValue(item, expand)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// expandString replaces the placeholders powernap knows about in s.
+func expandString(s, workspaceFolder string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		switch {
+		case name == "workspaceFolder":
+			b.WriteString(workspaceFolder)
+		case strings.HasPrefix(name, "env:"):
+			b.WriteString(os.Getenv(strings.TrimPrefix(name, "env:")))
+		default:
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the base name of per-project configuration files,
+// e.g. .powernap.toml.
+const ProjectConfigName = ".powernap"
+
+// configExtensions are the supported configuration file formats, in the
+// order they are loaded when several exist in the same place.
+var configExtensions = []string{".json", ".toml", ".yaml", ".yml"}
+
+// ValidationError reports an invalid value in a configuration file.
+type ValidationError struct {
+	File string // path of the configuration file
+	Key  string // dotted path of the offending key, e.g. servers.gopls.args
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.File, e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// GlobalConfigDir returns the directory of the global configuration files,
+// $XDG_CONFIG_HOME/powernap, or ~/.config/powernap when XDG_CONFIG_HOME is
+// not set.
+func GlobalConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "powernap"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "powernap"), nil
+}
+
+// FindProjectConfig looks for .powernap.* files in dir and its parents, and
+// returns the ones in the closest directory that has any.
+func FindProjectConfig(dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for {
+		var files []string
+		for _, ext := range configExtensions {
+			path := filepath.Join(dir, ProjectConfigName+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load loads the embedded defaults, then the global configuration files,
+// then the project configuration files found from dir, each layered over
+// the previous ones. An empty dir skips the project configuration.
+func (m *Manager) Load(dir string) error {
+	if err := m.LoadDefaults(); err != nil {
+		return err
+	}
+	if err := m.LoadGlobal(); err != nil {
+		return err
+	}
+	if dir == "" {
+		return nil
+	}
+	return m.LoadProject(dir)
+}
+
+// LoadGlobal layers the configuration files of [GlobalConfigDir] over the
+// current configuration, in lexical order. A missing directory is not an
+// error.
+func (m *Manager) LoadGlobal() error {
+	dir, err := GlobalConfigDir()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(configExtensions, filepath.Ext(entry.Name())) {
+			continue
+		}
+		if err := m.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadProject layers the project configuration files found by
+// [FindProjectConfig] over the current configuration.
+func (m *Manager) LoadProject(dir string) error {
+	files, err := FindProjectConfig(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := m.LoadFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile layers a JSON, TOML, or YAML configuration file over the current
+// configuration. The file has a top-level "servers" table keyed by server
+// name, with the same keys as lsps.json. Keys of existing servers replace
+// the previous values, except for settings, init_options, and environment,
+// which are merged recursively. Setting "enabled" to false removes a server.
+func (m *Manager) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	raw, err := parseConfig(path, data)
+	if err != nil {
+		return &ValidationError{File: path, Err: err}
+	}
+	return m.merge(path, raw)
+}
+
+// parseConfig decodes a configuration file based on its extension.
+func parseConfig(path string, data []byte) (map[string]any, error) {
+	raw := make(map[string]any)
+	var err error
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid syntax: %w", err)
+	}
+	return raw, nil
+}
+
+// merge layers a decoded configuration file over the current configuration.
+// The configuration is left untouched when the file is invalid.
+func (m *Manager) merge(file string, raw map[string]any) error {
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		if key != "servers" {
+			return &ValidationError{File: file, Key: key, Err: errors.New("unknown key")}
+		}
+	}
+
+	servers, ok := raw["servers"].(map[string]any)
+	if !ok && raw["servers"] != nil {
+		return &ValidationError{File: file, Key: "servers", Err: errors.New("must be a table of servers")}
+	}
+
+	merged := maps.Clone(m.config.Servers)
+	if merged == nil {
+		merged = make(map[string]*ServerConfig)
+	}
+
+	// Process servers in a stable order so that errors are deterministic.
+	names := slices.Sorted(maps.Keys(servers))
+	for _, name := range names {
+		prefix := "servers." + name
+		entry, ok := servers[name].(map[string]any)
+		if !ok {
+			return &ValidationError{File: file, Key: prefix, Err: errors.New("must be a table")}
+		}
+
+		if enabled, ok := entry["enabled"]; ok {
+			b, ok := enabled.(bool)
+			if !ok {
+				return &ValidationError{File: file, Key: prefix + ".enabled", Err: errors.New("must be a boolean")}
+			}
+			if !b {
+				delete(merged, name)
+				continue
+			}
+		}
+
+		server, err := mergeServer(name, merged[name], entry)
+		if err != nil {
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				verr.File = file
+				verr.Key = prefix + "." + verr.Key
+				return verr
+			}
+			return &ValidationError{File: file, Key: prefix, Err: err}
+		}
+		merged[name] = server
+	}
+
+	m.config.Servers = merged
+	return nil
+}
+
+// mergeServer returns a copy of base with the keys of entry applied. A nil
+// base creates a new server.
+func mergeServer(name string, base *ServerConfig, entry map[string]any) (*ServerConfig, error) {
+	var server ServerConfig
+	if base != nil {
+		server = *base
+		server.Settings = deepCopy(base.Settings)
+		server.InitOptions = deepCopy(base.InitOptions)
+		server.Environment = maps.Clone(base.Environment)
+	} else {
+		applyServerDefaults(name, &server)
+	}
+
+	fields := serverFields()
+	v := reflect.ValueOf(&server).Elem()
+	for _, key := range slices.Sorted(maps.Keys(entry)) {
+		if key == "enabled" {
+			continue
+		}
+		index, ok := fields[key]
+		if !ok {
+			return nil, &ValidationError{Key: key, Err: errors.New("unknown key")}
+		}
+
+		field := v.Field(index)
+		value := reflect.New(field.Type())
+		if err := mapstructure.Decode(entry[key], value.Interface()); err != nil {
+			return nil, &ValidationError{Key: key, Err: decodeError(err)}
+		}
+
+		switch key {
+		case "settings", "init_options":
+			merged := mergeMaps(field.Interface().(map[string]any), value.Elem().Interface().(map[string]any))
+			field.Set(reflect.ValueOf(merged))
+		case "environment":
+			env := maps.Clone(field.Interface().(map[string]string))
+			if env == nil {
+				env = make(map[string]string)
+			}
+			maps.Copy(env, value.Elem().Interface().(map[string]string))
+			field.Set(reflect.ValueOf(env))
+		default:
+			field.Set(value.Elem())
+		}
+	}
+
+	if err := validateServer(&server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// validateServer checks that a merged server configuration can be started.
+func validateServer(server *ServerConfig) error {
+	if server.Command == "" && server.Address == "" {
+		return &ValidationError{Key: "command", Err: errors.New("a command or an address is required")}
+	}
+	if server.Address != "" && server.Listen != "" {
+		return &ValidationError{Key: "listen", Err: errors.New("cannot be combined with address")}
+	}
+	if server.Address != "" {
+		if _, _, err := transport.ParseAddress(server.Address); err != nil {
+			return &ValidationError{Key: "address", Err: err}
+		}
+	}
+	if server.Listen != "" {
+		if _, _, err := transport.ParseAddress(server.Listen); err != nil {
+			return &ValidationError{Key: "listen", Err: err}
+		}
+	}
+	return nil
+}
+
+// serverFields maps the keys of a server table to ServerConfig fields.
+func serverFields() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeFor[ServerConfig]()
+	for i := range t.NumField() {
+		if tag := t.Field(i).Tag.Get("mapstructure"); tag != "" && tag != "-" {
+			fields[tag] = i
+		}
+	}
+	return fields
+}
+
+// decodeError strips the field name mapstructure prefixes its errors with,
+// as the key is reported separately.
+func decodeError(err error) error {
+	var merr *mapstructure.Error
+	if !errors.As(err, &merr) || len(merr.Errors) == 0 {
+		return err
+	}
+	msgs := make([]string, len(merr.Errors))
+	for i, msg := range merr.Errors {
+		if strings.HasPrefix(msg, "'") {
+			if i := strings.Index(msg[1:], "'"); i >= 0 {
+				msg = strings.TrimLeft(msg[i+2:], ": ")
+			}
+		}
+		msgs[i] = msg
+	}
+	slices.Sort(msgs)
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// mergeMaps merges src into a copy of dst. Nested maps are merged
+// recursively, other values replace the previous ones, and nil values
+// remove keys.
+func mergeMaps(dst, src map[string]any) map[string]any {
+	out := deepCopy(dst)
+	if out == nil {
+		out = make(map[string]any)
+	}
+	for k, v := range src {
+		switch v := v.(type) {
+		case nil:
+			delete(out, k)
+		case map[string]any:
+			prev, _ := out[k].(map[string]any)
+			out[k] = mergeMaps(prev, v)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// deepCopy copies nested maps so that merging doesn't modify the original.
+func deepCopy(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]any); ok {
+			v = deepCopy(nested)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	// Global configuration in two formats, applied in lexical order.
+	writeFile(t, filepath.Join(home, "powernap", "10-go.toml"), `
+[servers.gopls]
+args = ["-remote=auto"]
+
+[servers.gopls.settings.gopls]
+staticcheck = true
+hints = { assignVariableTypes = true }
+`)
+	writeFile(t, filepath.Join(home, "powernap", "20-disable.json"), `{
+  "servers": {
+    "rust_analyzer": {"enabled": false},
+    "mylsp": {"command": "mylsp", "filetypes": ["mine"]}
+  }
+}`)
+	writeFile(t, filepath.Join(home, "powernap", "README.md"), "ignored")
+
+	// Project configuration, found from a subdirectory.
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, ".powernap.yaml"), `
+servers:
+  gopls:
+    settings:
+      gopls:
+        hints:
+          parameterNames: true
+        staticcheck: null
+    environment:
+      GOFLAGS: -tags=integration
+`)
+	sub := filepath.Join(project, "internal", "pkg")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager()
+	if err := m.Load(sub); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	gopls, ok := m.GetServer("gopls")
+	if !ok {
+		t.Fatal("expected gopls")
+	}
+	if gopls.Command != "gopls" {
+		t.Errorf("expected default command to be kept, got %q", gopls.Command)
+	}
+	if !reflect.DeepEqual(gopls.Args, []string{"-remote=auto"}) {
+		t.Errorf("unexpected args %v", gopls.Args)
+	}
+	wantSettings := map[string]any{
+		"gopls": map[string]any{
+			"hints": map[string]any{
+				"assignVariableTypes": true,
+				"parameterNames":      true,
+			},
+			"semanticTokens": true,
+		},
+	}
+	if !reflect.DeepEqual(gopls.Settings, wantSettings) {
+		t.Errorf("unexpected settings %#v", gopls.Settings)
+	}
+	if gopls.Environment["GOFLAGS"] != "-tags=integration" {
+		t.Errorf("unexpected environment %v", gopls.Environment)
+	}
+	if !gopls.SingleFileSupport {
+		t.Error("expected defaults of gopls to be kept")
+	}
+
+	if _, ok := m.GetServer("rust_analyzer"); ok {
+		t.Error("expected rust_analyzer to be disabled")
+	}
+
+	mine, ok := m.GetServer("mylsp")
+	if !ok {
+		t.Fatal("expected mylsp to be added")
+	}
+	if !reflect.DeepEqual(mine.RootMarkers, []string{".git"}) {
+		t.Errorf("expected default root markers, got %v", mine.RootMarkers)
+	}
+
+	// Loading the defaults again doesn't see the merged settings.
+	fresh := NewManager()
+	if err := fresh.LoadDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if gopls, _ := fresh.GetServer("gopls"); len(gopls.Args) != 0 {
+		t.Errorf("defaults were modified: %v", gopls.Args)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		key     string
+		msg     string
+	}{
+		{
+			name:    "syntax",
+			file:    "bad.json",
+			content: `{"servers": `,
+			msg:     "invalid syntax",
+		},
+		{
+			name:    "top-level key",
+			file:    "top.yaml",
+			content: "server:\n  gopls: {}\n",
+			key:     "server",
+			msg:     "unknown key",
+		},
+		{
+			name:    "unknown server key",
+			file:    "key.toml",
+			content: "[servers.gopls]\ncomand = \"gopls\"\n",
+			key:     "servers.gopls.comand",
+			msg:     "unknown key",
+		},
+		{
+			name:    "wrong type",
+			file:    "type.yaml",
+			content: "servers:\n  gopls:\n    args: 3\n",
+			key:     "servers.gopls.args",
+			msg:     "array or slice",
+		},
+		{
+			name:    "enabled type",
+			file:    "enabled.json",
+			content: `{"servers": {"gopls": {"enabled": "no"}}}`,
+			key:     "servers.gopls.enabled",
+			msg:     "must be a boolean",
+		},
+		{
+			name:    "missing command",
+			file:    "new.json",
+			content: `{"servers": {"new": {"filetypes": ["x"]}}}`,
+			key:     "servers.new.command",
+			msg:     "required",
+		},
+		{
+			name:    "bad address",
+			file:    "addr.yaml",
+			content: "servers:\n  gopls:\n    address: localhost:37374\n",
+			key:     "servers.gopls.address",
+			msg:     "missing scheme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeFile(t, path, tt.content)
+
+			m := NewManager()
+			if err := m.LoadDefaults(); err != nil {
+				t.Fatal(err)
+			}
+			err := m.LoadFile(path)
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if verr.File != path || verr.Key != tt.key {
+				t.Errorf("got file %q key %q, want %q %q", verr.File, verr.Key, path, tt.key)
+			}
+			if !strings.Contains(err.Error(), tt.msg) || !strings.HasPrefix(err.Error(), path) {
+				t.Errorf("unexpected error message %q", err)
+			}
+
+			// The configuration is untouched by invalid files.
+			if gopls, _ := m.GetServer("gopls"); gopls.Address != "" || len(gopls.Args) != 0 {
+				t.Errorf("invalid file modified the configuration: %+v", gopls)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("POWERNAP_TEST_TOKEN", "secret")
+
+	server := &ServerConfig{
+		Command:     "${workspaceFolder}/bin/lsp",
+		Args:        []string{"--port=${port}", "--root=${workspaceFolder}", "${unknown}"},
+		Environment: map[string]string{"TOKEN": "${env:POWERNAP_TEST_TOKEN}", "EMPTY": "${env:POWERNAP_TEST_UNSET}"},
+		Listen:      "unix://${workspaceFolder}/lsp.sock",
+		Settings: map[string]any{
+			"paths": []any{"${workspaceFolder}/vendor", 3},
+			"nested": map[string]any{
+				"token": "Bearer ${env:POWERNAP_TEST_TOKEN}",
+			},
+		},
+	}
+
+	got := server.Expand("/src/app")
+
+	if got.Command != "/src/app/bin/lsp" {
+		t.Errorf("unexpected command %q", got.Command)
+	}
+	if want := []string{"--port=${port}", "--root=/src/app", "${unknown}"}; !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("got args %q, want %q", got.Args, want)
+	}
+	if got.Environment["TOKEN"] != "secret" || got.Environment["EMPTY"] != "" {
+		t.Errorf("unexpected environment %v", got.Environment)
+	}
+	if got.Listen != "unix:///src/app/lsp.sock" {
+		t.Errorf("unexpected listen address %q", got.Listen)
+	}
+	wantSettings := map[string]any{
+		"paths":  []any{"/src/app/vendor", 3},
+		"nested": map[string]any{"token": "Bearer secret"},
+	}
+	if !reflect.DeepEqual(got.Settings, wantSettings) {
+		t.Errorf("unexpected settings %#v", got.Settings)
+	}
+
+	// The original is left untouched.
+	if server.Args[1] != "--root=${workspaceFolder}" || server.Settings["nested"].(map[string]any)["token"] != "Bearer ${env:POWERNAP_TEST_TOKEN}" {
+		t.Errorf("Expand modified the original: %+v", server)
+	}
+}
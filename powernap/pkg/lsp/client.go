@@ -3,14 +3,18 @@
 package lsp
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +42,30 @@ const (
 	MethodWorkspaceDidChangeConfiguration    = "workspace/didChangeConfiguration"
 	MethodWorkspaceDidChangeWorkspaceFolders = "workspace/didChangeWorkspaceFolders"
 	MethodWorkspaceDidChangeWatchedFiles     = "workspace/didChangeWatchedFiles"
+	MethodWorkspaceExecuteCommand            = "workspace/executeCommand"
+	MethodWorkspaceSymbol                    = "workspace/symbol"
+	MethodTextDocumentSignatureHelp          = "textDocument/signatureHelp"
+	MethodTextDocumentDocumentHighlight      = "textDocument/documentHighlight"
+	MethodTextDocumentDocumentSymbol         = "textDocument/documentSymbol"
+	MethodTextDocumentFormatting             = "textDocument/formatting"
+	MethodTextDocumentRangeFormatting        = "textDocument/rangeFormatting"
+	MethodTextDocumentCodeAction             = "textDocument/codeAction"
+	MethodTextDocumentPullDiagnostics        = "textDocument/diagnostic"
+	MethodTextDocumentRename                 = "textDocument/rename"
+	MethodTextDocumentSemanticTokens         = "textDocument/semanticTokens"
+	MethodTextDocumentSemanticTokensFull     = "textDocument/semanticTokens/full"
+	MethodTextDocumentSemanticTokensDelta    = "textDocument/semanticTokens/full/delta"
+	MethodTextDocumentSemanticTokensRange    = "textDocument/semanticTokens/range"
+	MethodTextDocumentInlayHint              = "textDocument/inlayHint"
+	MethodInlayHintResolve                   = "inlayHint/resolve"
+	MethodWorkspaceInlayHintRefresh          = "workspace/inlayHint/refresh"
+	MethodWindowWorkDoneProgressCreate       = "window/workDoneProgress/create"
+	MethodWindowShowMessageRequest           = "window/showMessageRequest"
+	MethodClientRegisterCapability           = "client/registerCapability"
+	MethodClientUnregisterCapability         = "client/unregisterCapability"
+	MethodProgress                           = "$/progress"
+	MethodSetTrace                           = "$/setTrace"
+	MethodLogTrace                           = "$/logTrace"
 )
 
 // NewClient creates a new LSP client with the given configuration.
@@ -45,8 +73,8 @@ func NewClient(config ClientConfig) (*Client, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	client := &Client{
-		ID:               config.Command, // Will be updated after initialization
-		Name:             config.Command,
+		ID:               cmp.Or(config.Command, config.Address), // Will be updated after initialization
+		Name:             cmp.Or(config.Command, config.Address),
 		ctx:              ctx,
 		cancel:           cancel,
 		rootURI:          config.RootURI,
@@ -54,20 +82,19 @@ func NewClient(config ClientConfig) (*Client, error) {
 		config:           config.Settings,
 		initOptions:      config.InitOptions,
 		offsetEncoding:   UTF16, // Default to UTF16
-	}
+		progress:         NewProgressTracker(),
 
-	// Start the language server process
-	stream, err := startServerProcess(ctx, config)
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to start language server: %w", err)
+		disableFileWatching: config.DisableFileWatching,
 	}
 
-	// Create transport connection
-	conn, err := transport.NewConnection(ctx, stream, slog.Default())
+	conn, err := connect(ctx, config)
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to create connection: %w", err)
+		return nil, err
+	}
+	conn.OnReconnect(client.reconnected)
+	if config.Tracer != nil {
+		conn.SetTracer(config.Tracer)
 	}
 
 	client.conn = conn
@@ -78,12 +105,101 @@ func NewClient(config ClientConfig) (*Client, error) {
 	return client, nil
 }
 
+// connect connects to the language server described by config.
+func connect(ctx context.Context, config ClientConfig) (*transport.Connection, error) {
+	if config.Address != "" {
+		dial := func(ctx context.Context) (io.ReadWriteCloser, error) {
+			ctx, cancel := context.WithTimeout(ctx, cmp.Or(config.Timeout, defaultConnectTimeout))
+			defer cancel()
+			return transport.DialAddress(ctx, config.Address)
+		}
+		conn, err := transport.NewReconnectingConnection(ctx, dial, slog.Default(), config.Reconnect)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to language server: %w", err)
+		}
+		return conn, nil
+	}
+
+	// Start the language server process unless we were given a stream
+	stream := config.Stream
+	if stream == nil {
+		var err error
+		if config.Listen != "" {
+			stream, err = listenForServer(ctx, config)
+		} else {
+			stream, err = startServerProcess(ctx, config)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to start language server: %w", err)
+		}
+	}
+
+	// Create transport connection
+	conn, err := transport.NewConnection(ctx, stream, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection: %w", err)
+	}
+	return conn, nil
+}
+
 // Initialize sends the initialize request to the language server.
 func (c *Client) Initialize(ctx context.Context, enableSnippets bool) error {
 	if c.initialized {
 		return fmt.Errorf("client already initialized")
 	}
 
+	c.enableSnippets = enableSnippets
+	if err := c.handshake(ctx); err != nil {
+		return err
+	}
+
+	c.initialized = true
+
+	return nil
+}
+
+// OnReconnect registers a function to call after the client reconnected to
+// a server reached through [ClientConfig.Address] and repeated the
+// initialize handshake. It can be used to restore server-side state, such as
+// open documents. The returned function unregisters fn.
+func (c *Client) OnReconnect(fn func(ctx context.Context)) (unregister func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hook := &fn
+	c.reconnectHooks = append(c.reconnectHooks, hook)
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.reconnectHooks = slices.DeleteFunc(c.reconnectHooks, func(h *func(context.Context)) bool { return h == hook })
+	}
+}
+
+// reconnected restores the session after the connection was re-established.
+// Registrations and cached results of the previous server are dropped.
+func (c *Client) reconnected(ctx context.Context) {
+	if !c.initialized || c.shutdown {
+		return
+	}
+
+	c.mu.Lock()
+	c.registrations = nil
+	c.semanticTokens = nil
+	hooks := slices.Clone(c.reconnectHooks)
+	c.mu.Unlock()
+
+	if err := c.handshake(ctx); err != nil {
+		slog.Error("Failed to initialize reconnected server", "server", c.Name, "error", err)
+		return
+	}
+	c.syncFileWatcher()
+
+	for _, fn := range hooks {
+		(*fn)(ctx)
+	}
+}
+
+// handshake sends the initialize request and the initialized notification.
+func (c *Client) handshake(ctx context.Context) error {
 	// Extract root path from URI
 	rootPath := ""
 	if c.rootURI != "" {
@@ -105,10 +221,10 @@ func (c *Client) Initialize(ctx context.Context, enableSnippets bool) error {
 		"locale":                "en-us",
 		"rootPath":              rootPath, // Deprecated but some servers still use it
 		"rootUri":               c.rootURI,
-		"capabilities":          c.makeClientCapabilities(enableSnippets),
+		"capabilities":          c.makeClientCapabilities(c.enableSnippets),
 		"workspaceFolders":      workspaceFolders,
 		"initializationOptions": c.initOptions, // Use the client's init options
-		"trace":                 "off",         // Can be "off", "messages", or "verbose"
+		"trace":                 c.traceLevel(),
 	}
 
 	// Log the initialization params for debugging
@@ -121,29 +237,27 @@ func (c *Client) Initialize(ctx context.Context, enableSnippets bool) error {
 		return fmt.Errorf("initialize request failed: %w", err)
 	}
 
-	// Store server capabilities
-	c.capabilities = result.Capabilities
-
-	// Handle offset encoding
-	if result.OffsetEncoding != "" {
-		switch result.OffsetEncoding {
-		case "utf-8":
-			c.offsetEncoding = UTF8
-		case "utf-16":
-			c.offsetEncoding = UTF16
-		case "utf-32":
-			c.offsetEncoding = UTF32
-		}
+	// Store server capabilities and the position encoding the server picked,
+	// falling back to the offsetEncoding extension of older clangd versions.
+	encoding := UTF16
+	if result.Capabilities.PositionEncoding != nil {
+		encoding, _ = parseOffsetEncoding(string(*result.Capabilities.PositionEncoding))
+	} else if result.OffsetEncoding != "" {
+		encoding, _ = parseOffsetEncoding(result.OffsetEncoding)
 	}
 
+	c.mu.Lock()
+	c.serverCapabilities = result.Capabilities
+	c.capabilities = applyRegistrations(result.Capabilities, c.registrations)
+	c.offsetEncoding = encoding
+	c.mu.Unlock()
+
 	// Send initialized notification
 	err = c.conn.Notify(ctx, MethodInitialized, map[string]any{})
 	if err != nil {
 		return fmt.Errorf("initialized notification failed: %w", err)
 	}
 
-	c.initialized = true
-
 	// For gopls, send workspace/didChangeConfiguration to ensure it's ready
 	// This helps gopls properly set up its workspace views
 	if strings.Contains(c.Name, "gopls") {
@@ -151,25 +265,33 @@ func (c *Client) Initialize(ctx context.Context, enableSnippets bool) error {
 			"settings": c.config,
 		}
 		_ = c.conn.Notify(ctx, MethodWorkspaceDidChangeConfiguration, configParams)
+	}
 
-		// Also send workspace/didChangeWatchedFiles to trigger gopls to scan the workspace
-		// This helps with the "no views" error
-		if c.rootURI != "" {
-			changesParams := map[string]any{
-				"changes": []map[string]any{
-					{
-						"uri":  c.rootURI,
-						"type": 1, // Created
-					},
-				},
-			}
-			_ = c.conn.Notify(ctx, "workspace/didChangeWatchedFiles", changesParams)
-		}
+	return nil
+}
+
+// SetTrace changes the trace setting of the server with $/setTrace, and the
+// level of the client tracer if there is one.
+func (c *Client) SetTrace(ctx context.Context, value protocol.TraceValue) error {
+	if t := c.conn.Tracer(); t != nil {
+		t.SetLevel(transport.TraceLevel(value))
 	}
 
+	params := protocol.SetTraceParams{Value: value}
+	if err := c.conn.Notify(ctx, MethodSetTrace, params); err != nil {
+		return fmt.Errorf("set trace notification failed: %w", err)
+	}
 	return nil
 }
 
+// traceLevel returns the trace setting announced in initialize.
+func (c *Client) traceLevel() protocol.TraceValue {
+	if t := c.conn.Tracer(); t != nil && t.Level() != "" {
+		return protocol.TraceValue(t.Level())
+	}
+	return protocol.Off
+}
+
 // Shutdown sends a shutdown request to the language server.
 func (c *Client) Shutdown(ctx context.Context) error {
 	if c.shutdown {
@@ -196,8 +318,23 @@ func (c *Client) Exit() error {
 	return nil
 }
 
-// GetCapabilities returns the server capabilities.
+// Close closes the connection to the language server without the shutdown
+// handshake, and stops the server process started by the client. It is
+// meant for clients that failed to initialize; stop running servers with
+// Shutdown and Exit.
+func (c *Client) Close() error {
+	c.cancel()
+	if err := c.conn.Close(); err != nil {
+		return fmt.Errorf("failed to close connection: %w", err)
+	}
+	return nil
+}
+
+// GetCapabilities returns the server capabilities, including capabilities
+// registered dynamically after initialization.
 func (c *Client) GetCapabilities() protocol.ServerCapabilities {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.capabilities
 }
 
@@ -256,6 +393,8 @@ func (c *Client) NotifyDidCloseTextDocument(ctx context.Context, uri string) err
 		return fmt.Errorf("client not initialized")
 	}
 
+	c.forgetSemanticTokens(uri)
+
 	params := protocol.DidCloseTextDocumentParams{
 		TextDocument: protocol.TextDocumentIdentifier{
 			URI: protocol.DocumentURI(uri),
@@ -285,7 +424,8 @@ func (c *Client) NotifyDidChangeTextDocument(ctx context.Context, uri string, ve
 }
 
 // NotifyDidChangeWatchedFiles notifies the server that watched files have
-// changed.
+// changed. Changes matching the watchers registered by the server are sent
+// automatically unless file watching is disabled.
 func (c *Client) NotifyDidChangeWatchedFiles(ctx context.Context, changes []protocol.FileEvent) error {
 	if !c.initialized {
 		return fmt.Errorf("client not initialized")
@@ -387,6 +527,149 @@ func (c *Client) RequestHover(ctx context.Context, uri string, position protocol
 	return &result, nil
 }
 
+// RequestSignatureHelp requests information about the signature of the
+// call at the given position. sigCtx describes how the request was
+// triggered and may be nil for explicit invocations. A nil result means there
+// is no signature to show.
+func (c *Client) RequestSignatureHelp(ctx context.Context, uri string, position protocol.Position, sigCtx *protocol.SignatureHelpContext) (*protocol.SignatureHelp, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.SignatureHelpParams{
+		Context: sigCtx,
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI(uri),
+			},
+			Position: position,
+		},
+	}
+
+	var result *protocol.SignatureHelp
+	err := c.conn.Call(ctx, MethodTextDocumentSignatureHelp, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("signature help request failed: %w", err)
+	}
+	return result, nil
+}
+
+// SignatureHelpTriggerCharacters returns the characters that should trigger
+// signature help when typed, and those that should re-trigger it while it is
+// already showing. Trigger characters also count as re-trigger characters.
+func (c *Client) SignatureHelpTriggerCharacters() (triggers, retriggers []string) {
+	provider := c.GetCapabilities().SignatureHelpProvider
+	if provider == nil {
+		return nil, nil
+	}
+	return provider.TriggerCharacters, provider.RetriggerCharacters
+}
+
+// RequestDocumentHighlights requests the ranges of a document to highlight
+// for the symbol at the given position, such as its reads and writes.
+func (c *Client) RequestDocumentHighlights(ctx context.Context, uri string, position protocol.Position) ([]protocol.DocumentHighlight, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI(uri),
+			},
+			Position: position,
+		},
+	}
+
+	var result []protocol.DocumentHighlight
+	err := c.conn.Call(ctx, MethodTextDocumentDocumentHighlight, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("document highlight request failed: %w", err)
+	}
+	return result, nil
+}
+
+// RequestCodeActions requests the code actions for a range of a document,
+// such as quick fixes for the given diagnostics. only restricts the kinds of
+// actions returned and may be nil. Commands returned instead of code actions
+// are wrapped in a code action with the same title.
+func (c *Client) RequestCodeActions(ctx context.Context, uri string, rng protocol.Range, diagnostics []protocol.Diagnostic, only []protocol.CodeActionKind) ([]protocol.CodeAction, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	params := protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentURI(uri),
+		},
+		Range: rng,
+		Context: protocol.CodeActionContext{
+			Diagnostics: diagnostics,
+			Only:        only,
+		},
+	}
+
+	var result []json.RawMessage
+	err := c.conn.Call(ctx, MethodTextDocumentCodeAction, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("code action request failed: %w", err)
+	}
+
+	actions := make([]protocol.CodeAction, 0, len(result))
+	for _, raw := range result {
+		// A command has a string in place of the command of a code action.
+		var probe struct {
+			Command json.RawMessage `json:"command"`
+		}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if len(probe.Command) > 0 && probe.Command[0] == '"' {
+			var cmd protocol.Command
+			if err := json.Unmarshal(raw, &cmd); err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+			actions = append(actions, protocol.CodeAction{Title: cmd.Title, Command: &cmd})
+			continue
+		}
+		var action protocol.CodeAction
+		if err := json.Unmarshal(raw, &action); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// RequestDiagnostics pulls the diagnostics of a document from a server that
+// supports textDocument/diagnostic. Servers that push diagnostics instead
+// send them to the textDocument/publishDiagnostics notification handler.
+func (c *Client) RequestDiagnostics(ctx context.Context, uri string) ([]protocol.Diagnostic, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentURI(uri),
+		},
+	}
+
+	// Without a previous result ID the server always sends a full report.
+	var result struct {
+		Kind  string                `json:"kind"`
+		Items []protocol.Diagnostic `json:"items"`
+	}
+	err := c.conn.Call(ctx, MethodTextDocumentPullDiagnostics, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("diagnostic request failed: %w", err)
+	}
+	return result.Items, nil
+}
+
 // FindReferences finds all references to the symbol at the given position.
 func (c *Client) FindReferences(ctx context.Context, filepath string, line, character int, includeDeclaration bool) ([]protocol.Location, error) {
 	uri := string(protocol.URIFromPath(filepath))
@@ -413,26 +696,86 @@ func (c *Client) FindReferences(ctx context.Context, filepath string, line, char
 	return result, nil
 }
 
-// setupHandlers registers handlers for server-initiated requests.
-func (c *Client) setupHandlers() {
-	// Handle workspace/configuration requests
-	c.conn.RegisterHandler(MethodWorkspaceConfiguration, func(_ context.Context, _ string, params json.RawMessage) (any, error) {
-		var configParams protocol.ConfigurationParams
-		if err := json.Unmarshal(params, &configParams); err != nil {
+// RequestDefinition requests the definition locations of the symbol at the
+// given position. Location links are converted to locations pointing at the
+// target selection range.
+func (c *Client) RequestDefinition(ctx context.Context, uri string, position protocol.Position) ([]protocol.Location, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI(uri),
+			},
+			Position: position,
+		},
+	}
+
+	var result json.RawMessage
+	err := c.conn.Call(ctx, MethodTextDocumentDefinition, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("definition request failed: %w", err)
+	}
+
+	return parseLocations(result)
+}
+
+// RequestFormatting requests the edits needed to format a whole document.
+func (c *Client) RequestFormatting(ctx context.Context, uri string, options protocol.FormattingOptions) ([]protocol.TextEdit, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentURI(uri),
+		},
+		Options: options,
+	}
+
+	var result []protocol.TextEdit
+	err := c.conn.Call(ctx, MethodTextDocumentFormatting, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("formatting request failed: %w", err)
+	}
+	return result, nil
+}
+
+// parseLocations parses a Location, []Location or []LocationLink result.
+func parseLocations(data json.RawMessage) ([]protocol.Location, error) {
+	type locationOrLink struct {
+		protocol.Location
+		TargetURI            protocol.DocumentURI `json:"targetUri"`
+		TargetSelectionRange protocol.Range       `json:"targetSelectionRange"`
+	}
+
+	var items []locationOrLink
+	switch trimmed := strings.TrimSpace(string(data)); {
+	case trimmed == "" || trimmed == "null":
+		return nil, nil
+	case strings.HasPrefix(trimmed, "["):
+		if err := json.Unmarshal(data, &items); err != nil {
 			return nil, err //nolint:wrapcheck
 		}
-
-		// Return configuration for each requested item
-		result := make([]any, len(configParams.Items))
-		for i := range configParams.Items {
-			result[i] = c.config
+	default:
+		var item locationOrLink
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err //nolint:wrapcheck
 		}
+		items = append(items, item)
+	}
 
-		return result, nil
-	})
-
-	// Handle other common server requests
-	// Add more handlers as needed
+	locations := make([]protocol.Location, 0, len(items))
+	for _, item := range items {
+		if item.TargetURI != "" {
+			locations = append(locations, protocol.Location{URI: item.TargetURI, Range: item.TargetSelectionRange})
+			continue
+		}
+		locations = append(locations, item.Location)
+	}
+	return locations, nil
 }
 
 // makeClientCapabilities creates the client capabilities for initialization.
@@ -467,6 +810,17 @@ func (c *Client) makeClientCapabilities(enableSnippets bool) map[string]any {
 				"dynamicRegistration": true,
 				"contentFormat":       []string{"markdown", "plaintext"},
 			},
+			"signatureHelp": map[string]any{
+				"dynamicRegistration": true,
+				"signatureInformation": map[string]any{
+					"documentationFormat": []string{"markdown", "plaintext"},
+					"parameterInformation": map[string]any{
+						"labelOffsetSupport": true,
+					},
+					"activeParameterSupport": true,
+				},
+				"contextSupport": true,
+			},
 			"definition": map[string]any{
 				"dynamicRegistration": true,
 				"linkSupport":         true,
@@ -491,6 +845,39 @@ func (c *Client) makeClientCapabilities(enableSnippets bool) map[string]any {
 				"dynamicRegistration": true,
 				"prepareSupport":      true,
 			},
+			"semanticTokens": map[string]any{
+				"dynamicRegistration": true,
+				"requests": map[string]any{
+					"range": true,
+					"full": map[string]any{
+						"delta": true,
+					},
+				},
+				"tokenTypes": []string{
+					"namespace", "type", "class", "enum", "interface",
+					"struct", "typeParameter", "parameter", "variable", "property",
+					"enumMember", "event", "function", "method", "macro",
+					"keyword", "modifier", "comment", "string", "number",
+					"regexp", "operator", "decorator",
+				},
+				"tokenModifiers": []string{
+					"declaration", "definition", "readonly", "static", "deprecated",
+					"abstract", "async", "modification", "documentation", "defaultLibrary",
+				},
+				"formats":                 []string{"relative"},
+				"overlappingTokenSupport": true,
+				"multilineTokenSupport":   true,
+			},
+			"inlayHint": map[string]any{
+				"dynamicRegistration": true,
+				"resolveSupport": map[string]any{
+					"properties": []string{"tooltip", "textEdits", "label.tooltip", "label.location", "label.command"},
+				},
+			},
+			"diagnostic": map[string]any{
+				"dynamicRegistration":    true,
+				"relatedDocumentSupport": false,
+			},
 			"publishDiagnostics": map[string]any{
 				"relatedInformation":     true,
 				"versionSupport":         true,
@@ -538,6 +925,12 @@ func (c *Client) makeClientCapabilities(enableSnippets bool) map[string]any {
 			"symbol": map[string]any{
 				"dynamicRegistration": true,
 			},
+			"semanticTokens": map[string]any{
+				"refreshSupport": false,
+			},
+			"inlayHint": map[string]any{
+				"refreshSupport": true,
+			},
 			"configuration":    true,
 			"workspaceFolders": true,
 			"fileOperations": map[string]any{
@@ -570,14 +963,18 @@ func (c *Client) makeClientCapabilities(enableSnippets bool) map[string]any {
 				"parser":  "marked",
 				"version": "1.1.0",
 			},
-			"positionEncodings": []string{"utf-16"},
+			"positionEncodings": positionEncodingNames(),
 		},
 	}
 }
 
-// startServerProcess starts the language server process.
-func startServerProcess(ctx context.Context, config ClientConfig) (io.ReadWriteCloser, error) {
-	cmd := exec.CommandContext(ctx, config.Command, config.Args...) //nolint:gosec
+// defaultConnectTimeout is how long to wait for a socket connection when
+// the configuration doesn't set a timeout.
+const defaultConnectTimeout = 10 * time.Second
+
+// serverCommand creates the command that runs the language server.
+func serverCommand(ctx context.Context, config ClientConfig, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, config.Command, args...) //nolint:gosec
 
 	// Set environment variables
 	if config.Environment != nil {
@@ -586,6 +983,30 @@ func startServerProcess(ctx context.Context, config ClientConfig) (io.ReadWriteC
 			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 		}
 	}
+	return cmd
+}
+
+// monitorStderr logs the output of the language server until stderr is
+// closed.
+func monitorStderr(command string, stderr io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := stderr.Read(buf)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) {
+				slog.Error("Error reading stderr", "error", err)
+			}
+			break
+		}
+		if n > 0 {
+			slog.Error("Language server stderr", "command", command, "output", string(buf[:n]))
+		}
+	}
+}
+
+// startServerProcess starts the language server process.
+func startServerProcess(ctx context.Context, config ClientConfig) (io.ReadWriteCloser, error) {
+	cmd := serverCommand(ctx, config, config.Args)
 
 	// Create pipes
 	stdin, err := cmd.StdinPipe()
@@ -610,21 +1031,7 @@ func startServerProcess(ctx context.Context, config ClientConfig) (io.ReadWriteC
 	}
 
 	// Monitor stderr
-	go func() {
-		buf := make([]byte, 4096)
-		for {
-			n, err := stderr.Read(buf)
-			if err != nil {
-				if err != io.EOF {
-					slog.Error("Error reading stderr", "error", err)
-				}
-				break
-			}
-			if n > 0 {
-				slog.Error("Language server stderr", "command", config.Command, "output", string(buf[:n]))
-			}
-		}
-	}()
+	go monitorStderr(config.Command, stderr)
 
 	closer := &processCloser{
 		cmd:    cmd,
@@ -636,6 +1043,99 @@ func startServerProcess(ctx context.Context, config ClientConfig) (io.ReadWriteC
 	return transport.NewStreamTransport(stdout, stdin, closer), nil
 }
 
+// listenForServer listens on config.Listen, starts the language server
+// process and waits for it to connect back.
+func listenForServer(ctx context.Context, config ClientConfig) (io.ReadWriteCloser, error) {
+	l, err := transport.ListenAddress(ctx, config.Listen)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer l.Close() //nolint:errcheck
+
+	// Tell the server where to connect
+	var host, port, address string
+	switch addr := l.Addr().(type) {
+	case *net.TCPAddr:
+		host, port = addr.IP.String(), strconv.Itoa(addr.Port)
+		address = net.JoinHostPort(host, port)
+	default:
+		address = addr.String()
+	}
+	replacer := strings.NewReplacer("${host}", host, "${port}", port, "${address}", address)
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := serverCommand(ctx, config, args)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+	go monitorStderr(config.Command, stderr)
+
+	closer := &socketProcessCloser{
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+	go func() {
+		closer.waitErr = cmd.Wait()
+		close(closer.done)
+	}()
+
+	// Wait for the server to connect, or to exit without doing so
+	acceptCtx, cancel := context.WithTimeout(ctx, cmp.Or(config.Timeout, defaultConnectTimeout))
+	defer cancel()
+	go func() {
+		select {
+		case <-closer.done:
+			cancel()
+		case <-acceptCtx.Done():
+		}
+	}()
+
+	conn, err := transport.Accept(acceptCtx, l)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-closer.done
+		return nil, fmt.Errorf("language server did not connect to %s: %w", transport.ListenerAddress(l), err)
+	}
+
+	closer.conn = conn
+	return transport.NewStreamTransport(conn, conn, closer), nil
+}
+
+// socketProcessCloser closes the connection to a language server that
+// connected back to the client and waits for its process to exit.
+type socketProcessCloser struct {
+	conn      net.Conn
+	cmd       *exec.Cmd
+	done      chan struct{} // closed when the process exited
+	waitErr   error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (c *socketProcessCloser) Close() error {
+	c.closeOnce.Do(func() {
+		errs := []error{c.conn.Close()}
+
+		select {
+		case <-c.done:
+		case <-time.After(5 * time.Second):
+			errs = append(errs, c.cmd.Process.Kill())
+			<-c.done
+		}
+		errs = append(errs, c.waitErr)
+
+		c.closeErr = errors.Join(errs...)
+	})
+	return c.closeErr
+}
+
 type processCloser struct {
 	cmd       *exec.Cmd
 	stdin     io.WriteCloser
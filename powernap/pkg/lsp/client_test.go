@@ -2,8 +2,13 @@ package lsp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
 )
 
 func TestProcessCloser_ConcurrentClose(t *testing.T) {
@@ -70,3 +75,163 @@ func TestProcessCloser_ConcurrentCancelAndClose(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestClient_SignatureHelp(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		SignatureHelpProvider: &protocol.SignatureHelpOptions{
+			TriggerCharacters:   []string{"(", ","},
+			RetriggerCharacters: []string{")"},
+		},
+	})
+	server.Respond(MethodTextDocumentSignatureHelp, protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{{
+			Label: "func Println(a ...any) (n int, err error)",
+		}},
+	})
+
+	triggers, retriggers := client.SignatureHelpTriggerCharacters()
+	if len(triggers) != 2 || len(retriggers) != 1 {
+		t.Errorf("unexpected trigger characters %q and %q", triggers, retriggers)
+	}
+
+	help, err := client.RequestSignatureHelp(t.Context(), "file:///tmp/main.go", protocol.Position{Line: 4, Character: 13}, &protocol.SignatureHelpContext{
+		TriggerKind:      protocol.SigTriggerCharacter,
+		TriggerCharacter: "(",
+	})
+	if err != nil {
+		t.Fatalf("RequestSignatureHelp failed: %v", err)
+	}
+	if help == nil || len(help.Signatures) != 1 {
+		t.Fatalf("unexpected signature help %+v", help)
+	}
+
+	var params protocol.SignatureHelpParams
+	if err := server.ReceivedMethod(MethodTextDocumentSignatureHelp)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Context == nil || params.Context.TriggerCharacter != "(" || params.Position.Character != 13 {
+		t.Errorf("unexpected params %+v", params)
+	}
+
+	// No signature at the position.
+	server.Respond(MethodTextDocumentSignatureHelp, nil)
+	help, err = client.RequestSignatureHelp(t.Context(), "file:///tmp/main.go", protocol.Position{}, nil)
+	if err != nil || help != nil {
+		t.Errorf("expected no signature help, got %+v, %v", help, err)
+	}
+}
+
+func TestClient_DocumentHighlights(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+	server.Respond(MethodTextDocumentDocumentHighlight, []protocol.DocumentHighlight{
+		{Range: protocol.Range{Start: protocol.Position{Line: 1}}, Kind: protocol.Write},
+		{Range: protocol.Range{Start: protocol.Position{Line: 3}}, Kind: protocol.Read},
+	})
+
+	highlights, err := client.RequestDocumentHighlights(t.Context(), "file:///tmp/main.go", protocol.Position{Line: 1})
+	if err != nil {
+		t.Fatalf("RequestDocumentHighlights failed: %v", err)
+	}
+	if len(highlights) != 2 || highlights[0].Kind != protocol.Write || highlights[1].Kind != protocol.Read {
+		t.Errorf("unexpected highlights %+v", highlights)
+	}
+}
+
+func TestClient_CancelRequest(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+
+	release := make(chan struct{})
+	server.Handle(MethodTextDocumentHover, func(context.Context, json.RawMessage) (any, error) {
+		<-release
+		return nil, nil
+	})
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.RequestHover(ctx, "file:///tmp/main.go", protocol.Position{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	msgs, err := server.WaitFor(t.Context(), "$/cancelRequest", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params protocol.CancelParams
+	if err := msgs[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.ID == nil {
+		t.Errorf("expected the request id, got %s", msgs[0].Params)
+	}
+}
+
+func TestClient_CodeActions(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{CodeActionProvider: true})
+
+	// Servers may answer with code actions or bare commands.
+	server.Respond(MethodTextDocumentCodeAction, json.RawMessage(`[
+		{"title": "Organize imports", "kind": "source.organizeImports", "edit": {"changes": {}}},
+		{"title": "Run test", "command": "gopls.run_tests", "arguments": ["TestX"]}
+	]`))
+
+	if !client.Supports(MethodTextDocumentCodeAction) {
+		t.Error("expected code actions to be supported")
+	}
+
+	actions, err := client.RequestCodeActions(t.Context(), "file:///tmp/main.go", protocol.Range{}, nil, []protocol.CodeActionKind{protocol.SourceOrganizeImports})
+	if err != nil {
+		t.Fatalf("RequestCodeActions failed: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %+v", actions)
+	}
+	if actions[0].Kind != protocol.SourceOrganizeImports || actions[0].Edit == nil {
+		t.Errorf("unexpected code action %+v", actions[0])
+	}
+	if actions[1].Title != "Run test" || actions[1].Command == nil || actions[1].Command.Command != "gopls.run_tests" {
+		t.Errorf("unexpected command action %+v", actions[1])
+	}
+
+	var params protocol.CodeActionParams
+	if err := server.ReceivedMethod(MethodTextDocumentCodeAction)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Context.Diagnostics == nil || len(params.Context.Only) != 1 {
+		t.Errorf("unexpected context %+v", params.Context)
+	}
+}
+
+func TestClient_PullDiagnostics(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+	if client.Supports(MethodTextDocumentPullDiagnostics) {
+		t.Fatal("expected pull diagnostics to be unsupported before registration")
+	}
+
+	if err := server.Call(t.Context(), MethodClientRegisterCapability, protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:              "1",
+			Method:          MethodTextDocumentPullDiagnostics,
+			RegisterOptions: map[string]any{"interFileDependencies": false, "workspaceDiagnostics": false},
+		}},
+	}, nil); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	if !client.Supports(MethodTextDocumentPullDiagnostics) {
+		t.Fatal("expected pull diagnostics to be supported after registration")
+	}
+
+	server.Respond(MethodTextDocumentPullDiagnostics, json.RawMessage(`{
+		"kind": "full",
+		"resultId": "1",
+		"items": [{"range": {"start": {"line": 2, "character": 1}, "end": {"line": 2, "character": 4}}, "severity": 1, "message": "undefined: x"}]
+	}`))
+
+	diagnostics, err := client.RequestDiagnostics(t.Context(), "file:///tmp/main.go")
+	if err != nil {
+		t.Fatalf("RequestDiagnostics failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Message != "undefined: x" || diagnostics[0].Severity != protocol.SeverityError {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+}
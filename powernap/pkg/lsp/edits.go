@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// ApplyTextEdits applies text edits to content. Positions are interpreted as
+// UTF-16 code units, the LSP default. Edits must not overlap.
+func ApplyTextEdits(content string, edits []protocol.TextEdit) (string, error) {
+	return ApplyTextEditsWithEncoding(content, edits, UTF16)
+}
+
+// ApplyTextEditsWithEncoding applies text edits whose positions use the given
+// encoding, such as the one of [Client.OffsetEncoding], to content. Edits
+// must not overlap.
+func ApplyTextEditsWithEncoding(content string, edits []protocol.TextEdit, encoding OffsetEncoding) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+
+	mapper := NewPositionMapper(content, encoding)
+	spans := make([]span, 0, len(edits))
+	for _, edit := range edits {
+		start, end, err := mapper.Offsets(edit.Range)
+		if err != nil {
+			return "", fmt.Errorf("invalid edit: %w", err)
+		}
+		spans = append(spans, span{start, end, edit.NewText})
+	}
+
+	// Edits starting at the same position are applied in the order given.
+	slices.SortStableFunc(spans, func(a, b span) int {
+		return a.start - b.start
+	})
+
+	var sb strings.Builder
+	pos := 0
+	for _, s := range spans {
+		if s.start < pos {
+			return "", fmt.Errorf("overlapping edits at offset %d", s.start)
+		}
+		sb.WriteString(content[pos:s.start])
+		sb.WriteString(s.text)
+		pos = s.end
+	}
+	sb.WriteString(content[pos:])
+
+	return sb.String(), nil
+}
@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestApplyTextEdits(t *testing.T) {
+	pos := func(line, char uint32) protocol.Position {
+		return protocol.Position{Line: line, Character: char}
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		edits   []protocol.TextEdit
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "replace",
+			content: "hello world\n",
+			edits:   []protocol.TextEdit{{Range: protocol.Range{Start: pos(0, 6), End: pos(0, 11)}, NewText: "there"}},
+			want:    "hello there\n",
+		},
+		{
+			name:    "multiple edits in any order",
+			content: "a\nb\nc\n",
+			edits: []protocol.TextEdit{
+				{Range: protocol.Range{Start: pos(2, 0), End: pos(2, 1)}, NewText: "C"},
+				{Range: protocol.Range{Start: pos(0, 0), End: pos(0, 1)}, NewText: "A"},
+			},
+			want: "A\nb\nC\n",
+		},
+		{
+			name:    "inserts at the same position keep their order",
+			content: "x",
+			edits: []protocol.TextEdit{
+				{Range: protocol.Range{Start: pos(0, 0), End: pos(0, 0)}, NewText: "1"},
+				{Range: protocol.Range{Start: pos(0, 0), End: pos(0, 0)}, NewText: "2"},
+			},
+			want: "12x",
+		},
+		{
+			name:    "utf-16 positions",
+			content: "é😀x\n",
+			edits:   []protocol.TextEdit{{Range: protocol.Range{Start: pos(0, 3), End: pos(0, 4)}, NewText: "y"}},
+			want:    "é😀y\n",
+		},
+		{
+			name:    "append after the last line",
+			content: "a\n",
+			edits:   []protocol.TextEdit{{Range: protocol.Range{Start: pos(1, 0), End: pos(1, 0)}, NewText: "b\n"}},
+			want:    "a\nb\n",
+		},
+		{
+			name:    "delete lines",
+			content: "a\nb\nc\n",
+			edits:   []protocol.TextEdit{{Range: protocol.Range{Start: pos(1, 0), End: pos(2, 0)}}},
+			want:    "a\nc\n",
+		},
+		{
+			name:    "overlapping",
+			content: "abcdef",
+			edits: []protocol.TextEdit{
+				{Range: protocol.Range{Start: pos(0, 0), End: pos(0, 3)}},
+				{Range: protocol.Range{Start: pos(0, 2), End: pos(0, 4)}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "line out of range",
+			content: "a\n",
+			edits:   []protocol.TextEdit{{Range: protocol.Range{Start: pos(5, 0), End: pos(5, 0)}}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ApplyTextEdits(tc.content, tc.edits)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyTextEdits failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob compiles an LSP glob pattern into a regular expression.
+//
+// The supported syntax follows the specification:
+//   - `*` matches zero or more characters in a path segment
+//   - `?` matches one character in a path segment
+//   - `**` matches any number of path segments, including none
+//   - `{}` groups conditions, e.g. `**/*.{ts,js}`
+//   - `[]` declares a range of characters, `[!...]` negates it
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	braces := 0
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '{':
+			braces++
+			sb.WriteString("(?:")
+		case '}':
+			if braces == 0 {
+				sb.WriteString(`\}`)
+				continue
+			}
+			braces--
+			sb.WriteString(")")
+		case ',':
+			if braces > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	if braces > 0 {
+		return nil, fmt.Errorf("unbalanced braces in glob pattern: %q", pattern)
+	}
+
+	sb.WriteString("$")
+	return regexp.Compile(sb.String()) //nolint:wrapcheck
+}
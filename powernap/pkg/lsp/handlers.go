@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// MessageRequestHandler prompts the user with a window/showMessageRequest.
+// It returns the action the user picked, or nil if the request was dismissed.
+type MessageRequestHandler func(ctx context.Context, params protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error)
+
+// SetMessageRequestHandler sets the hook used to answer
+// window/showMessageRequest requests. Without a hook, requests are logged and
+// answered with no selected action.
+func (c *Client) SetMessageRequestHandler(handler MessageRequestHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messageRequestHandler = handler
+}
+
+// Progress returns the tracker holding the server's work done progress
+// tasks.
+func (c *Client) Progress() *ProgressTracker {
+	return c.progress
+}
+
+// setupHandlers registers handlers for server-initiated requests.
+func (c *Client) setupHandlers() {
+	c.conn.RegisterHandler(MethodWorkspaceConfiguration, c.handleWorkspaceConfiguration)
+	c.conn.RegisterHandler(MethodWindowWorkDoneProgressCreate, c.handleWorkDoneProgressCreate)
+	c.conn.RegisterHandler(MethodWindowShowMessageRequest, c.handleShowMessageRequest)
+	c.conn.RegisterHandler(MethodClientRegisterCapability, c.handleRegisterCapability)
+	c.conn.RegisterHandler(MethodClientUnregisterCapability, c.handleUnregisterCapability)
+	c.conn.RegisterHandler(MethodWorkspaceInlayHintRefresh, c.handleInlayHintRefresh)
+	c.conn.RegisterNotificationHandler(MethodProgress, c.handleProgress)
+}
+
+// handleWorkspaceConfiguration answers workspace/configuration requests with
+// the matching section of the client settings.
+func (c *Client) handleWorkspaceConfiguration(_ context.Context, _ string, params json.RawMessage) (any, error) {
+	var configParams protocol.ConfigurationParams
+	if err := json.Unmarshal(params, &configParams); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	result := make([]any, len(configParams.Items))
+	for i, item := range configParams.Items {
+		result[i] = lookupSection(c.config, item.Section)
+	}
+
+	return result, nil
+}
+
+// handleWorkDoneProgressCreate handles window/workDoneProgress/create.
+func (c *Client) handleWorkDoneProgressCreate(_ context.Context, _ string, params json.RawMessage) (any, error) {
+	var createParams protocol.WorkDoneProgressCreateParams
+	if err := json.Unmarshal(params, &createParams); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	c.progress.Create(progressTokenString(createParams.Token))
+	return nil, nil
+}
+
+// handleProgress handles $/progress notifications.
+func (c *Client) handleProgress(_ context.Context, _ string, params json.RawMessage) {
+	var progressParams struct {
+		Token protocol.ProgressToken `json:"token"`
+		Value json.RawMessage        `json:"value"`
+	}
+	if err := json.Unmarshal(params, &progressParams); err != nil {
+		slog.Debug("Invalid progress notification", "error", err)
+		return
+	}
+
+	token := progressTokenString(progressParams.Token)
+	if err := c.progress.Update(token, progressParams.Value); err != nil {
+		slog.Debug("Failed to update progress", "token", token, "error", err)
+	}
+}
+
+// handleShowMessageRequest handles window/showMessageRequest by delegating to
+// the configured [MessageRequestHandler].
+func (c *Client) handleShowMessageRequest(ctx context.Context, _ string, params json.RawMessage) (any, error) {
+	var msgParams protocol.ShowMessageRequestParams
+	if err := json.Unmarshal(params, &msgParams); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	c.mu.RLock()
+	handler := c.messageRequestHandler
+	c.mu.RUnlock()
+
+	if handler == nil {
+		slog.Info("Language server message", "server", c.Name, "type", msgParams.Type, "message", msgParams.Message)
+		return nil, nil
+	}
+
+	action, err := handler(ctx, msgParams)
+	if err != nil {
+		return nil, err
+	}
+	if action == nil {
+		return nil, nil
+	}
+	return action, nil
+}
+
+// handleRegisterCapability handles client/registerCapability.
+func (c *Client) handleRegisterCapability(_ context.Context, _ string, params json.RawMessage) (any, error) {
+	var regParams protocol.RegistrationParams
+	if err := json.Unmarshal(params, &regParams); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	c.register(regParams.Registrations)
+	return nil, nil
+}
+
+// handleUnregisterCapability handles client/unregisterCapability.
+func (c *Client) handleUnregisterCapability(_ context.Context, _ string, params json.RawMessage) (any, error) {
+	var unregParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregParams); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	c.unregister(unregParams.Unregisterations)
+	return nil, nil
+}
+
+// lookupSection resolves a dotted configuration section, such as
+// "python.analysis", against the given settings. An empty section returns
+// the whole settings map and unknown sections return nil.
+func lookupSection(settings map[string]any, section string) any {
+	if section == "" {
+		return settings
+	}
+
+	var current any = settings
+	for part := range strings.SplitSeq(section, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if current, ok = m[part]; !ok {
+			return nil
+		}
+	}
+	return current
+}
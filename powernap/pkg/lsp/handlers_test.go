@@ -0,0 +1,150 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestProgressTracker_Lifecycle(t *testing.T) {
+	tracker := NewProgressTracker()
+
+	var updates []ProgressTask
+	tracker.OnUpdate(func(task ProgressTask) {
+		updates = append(updates, task)
+	})
+
+	tracker.Create("1")
+	if err := tracker.Update("1", json.RawMessage(`{"kind":"begin","title":"Indexing","percentage":0}`)); err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	if err := tracker.Update("1", json.RawMessage(`{"kind":"report","message":"3/4 files","percentage":75}`)); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+
+	active := tracker.Active()
+	if len(active) != 1 {
+		t.Fatalf("expected 1 active task, got %d", len(active))
+	}
+	task := active[0]
+	if task.Title != "Indexing" || task.Message != "3/4 files" {
+		t.Errorf("unexpected task: %+v", task)
+	}
+	if !task.HasPercentage || task.Percentage != 75 {
+		t.Errorf("expected 75%%, got %d (has=%v)", task.Percentage, task.HasPercentage)
+	}
+
+	if err := tracker.Update("1", json.RawMessage(`{"kind":"end","message":"done"}`)); err != nil {
+		t.Fatalf("end failed: %v", err)
+	}
+	if len(tracker.Active()) != 0 {
+		t.Error("expected no active tasks after end")
+	}
+	if len(updates) != 3 || !updates[2].Done {
+		t.Errorf("expected 3 updates ending with done, got %+v", updates)
+	}
+}
+
+func TestProgressTracker_WithoutPercentage(t *testing.T) {
+	tracker := NewProgressTracker()
+	if err := tracker.Update("tok", json.RawMessage(`{"kind":"begin","title":"Loading"}`)); err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+
+	task, ok := tracker.Get("tok")
+	if !ok {
+		t.Fatal("expected task to be tracked without create")
+	}
+	if task.HasPercentage {
+		t.Error("expected no percentage")
+	}
+
+	if err := tracker.Update("tok", json.RawMessage(`{"kind":"bogus"}`)); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestProgressTracker_UnknownToken(t *testing.T) {
+	tracker := NewProgressTracker()
+	for _, value := range []string{
+		`{"kind":"report","message":"1/2"}`,
+		`{"kind":"end"}`,
+		`{"kind":"bogus"}`,
+	} {
+		if err := tracker.Update("stray", json.RawMessage(value)); err == nil {
+			t.Errorf("expected error for %s on an unknown token", value)
+		}
+	}
+	if active := tracker.Active(); len(active) != 0 {
+		t.Errorf("expected no active tasks, got %+v", active)
+	}
+}
+
+func TestLookupSection(t *testing.T) {
+	settings := map[string]any{
+		"gopls": map[string]any{"gofumpt": true},
+		"python": map[string]any{
+			"analysis": map[string]any{"typeCheckingMode": "basic"},
+		},
+	}
+
+	tests := []struct {
+		section string
+		want    any
+	}{
+		{"gopls.gofumpt", true},
+		{"python.analysis.typeCheckingMode", "basic"},
+		{"python.missing", nil},
+		{"gopls.gofumpt.nested", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.section, func(t *testing.T) {
+			if got := lookupSection(settings, tc.section); got != tc.want {
+				t.Errorf("lookupSection(%q) = %v, want %v", tc.section, got, tc.want)
+			}
+		})
+	}
+
+	if got, ok := lookupSection(settings, "").(map[string]any); !ok || len(got) != 2 {
+		t.Errorf("expected empty section to return all settings, got %v", got)
+	}
+}
+
+func TestClient_DynamicRegistration(t *testing.T) {
+	c := &Client{}
+
+	c.register([]protocol.Registration{
+		{ID: "hover", Method: MethodTextDocumentHover},
+		{
+			ID:     "watch",
+			Method: MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: map[string]any{
+				"watchers": []any{
+					map[string]any{"globPattern": "**/*.go"},
+				},
+			},
+		},
+	})
+
+	caps := c.GetCapabilities()
+	if caps.HoverProvider == nil || caps.HoverProvider.Value != true {
+		t.Errorf("expected hover to be registered, got %+v", caps.HoverProvider)
+	}
+
+	watchers := c.FileWatchers()
+	if len(watchers) != 1 {
+		t.Fatalf("expected 1 watcher, got %d", len(watchers))
+	}
+	if pattern, _ := watchers[0].GlobPattern.Value.(string); pattern != "**/*.go" {
+		t.Errorf("unexpected glob pattern: %v", watchers[0].GlobPattern.Value)
+	}
+
+	c.unregister([]protocol.Unregistration{{ID: "hover", Method: MethodTextDocumentHover}})
+	if c.GetCapabilities().HoverProvider != nil {
+		t.Error("expected hover to be unregistered")
+	}
+	if len(c.Registrations()) != 1 {
+		t.Errorf("expected 1 remaining registration, got %d", len(c.Registrations()))
+	}
+}
@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/x/gitignore"
+)
+
+// ignoreMatcher reports whether paths under a root directory are ignored by
+// the .gitignore files found in that tree.
+type ignoreMatcher struct {
+	root string
+
+	mu      sync.RWMutex
+	entries []ignoreEntry // sorted by depth, parents first
+}
+
+// ignoreEntry holds the patterns of a single .gitignore file.
+type ignoreEntry struct {
+	domain   []string
+	patterns []gitignore.Pattern
+}
+
+// newIgnoreMatcher creates a matcher for the given root directory and loads
+// its top-level .gitignore file.
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	m := &ignoreMatcher{root: root}
+	m.load(root)
+	return m
+}
+
+// load (re)reads the .gitignore file in dir. Missing files clear any patterns
+// previously loaded for dir.
+func (m *ignoreMatcher) load(dir string) {
+	domain := m.split(dir)
+	if domain == nil && dir != m.root {
+		return
+	}
+
+	patterns := readGitignore(filepath.Join(dir, ".gitignore"), domain)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = slices.DeleteFunc(m.entries, func(e ignoreEntry) bool {
+		return slices.Equal(e.domain, domain)
+	})
+	if len(patterns) == 0 {
+		return
+	}
+	m.entries = append(m.entries, ignoreEntry{domain: domain, patterns: patterns})
+	slices.SortStableFunc(m.entries, func(a, b ignoreEntry) int {
+		return len(a.domain) - len(b.domain)
+	})
+}
+
+// ignored reports whether path is ignored. The .git directory is always
+// ignored.
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	parts := m.split(path)
+	if len(parts) == 0 {
+		return false
+	}
+	if slices.Contains(parts, ".git") {
+		return true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The last matching pattern wins, and deeper .gitignore files take
+	// precedence over their parents.
+	result := gitignore.NoMatch
+	for _, entry := range m.entries {
+		for _, p := range entry.patterns {
+			if r := p.Match(parts, isDir); r != gitignore.NoMatch {
+				result = r
+			}
+		}
+	}
+	return result == gitignore.Exclude
+}
+
+// split returns the path components of path relative to the root, or nil if
+// path is outside of the root.
+func (m *ignoreMatcher) split(path string) []string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// readGitignore parses the patterns of a .gitignore file.
+func readGitignore(path string, domain []string) []gitignore.Pattern {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close() //nolint:errcheck
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	return patterns
+}
@@ -0,0 +1,112 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// inlayHintResult is an inlay hint as sent by the server, whose label may be
+// a plain string or a list of label parts.
+type inlayHintResult struct {
+	protocol.InlayHint
+	Label protocol.Or_InlayHint_label `json:"label"`
+}
+
+// hint returns the inlay hint with its label converted to label parts.
+func (r inlayHintResult) hint() protocol.InlayHint {
+	hint := r.InlayHint
+	switch label := r.Label.Value.(type) {
+	case string:
+		hint.Label = []protocol.InlayHintLabelPart{{Value: label}}
+	case []protocol.InlayHintLabelPart:
+		hint.Label = label
+	}
+	return hint
+}
+
+// RequestInlayHints requests the inlay hints of a range of a document.
+// Labels sent as plain strings are returned as a single label part.
+func (c *Client) RequestInlayHints(ctx context.Context, uri string, rng protocol.Range) ([]protocol.InlayHint, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri)},
+		Range:        rng,
+	}
+
+	var result []inlayHintResult
+	err := c.conn.Call(ctx, MethodTextDocumentInlayHint, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("inlay hint request failed: %w", err)
+	}
+
+	hints := make([]protocol.InlayHint, len(result))
+	for i, r := range result {
+		hints[i] = r.hint()
+	}
+	return hints, nil
+}
+
+// ResolveInlayHint asks the server to fill in the properties of a hint that
+// are computed lazily, such as its tooltip or text edits. The hint must come
+// from [Client.RequestInlayHints] so that it carries the server's data.
+func (c *Client) ResolveInlayHint(ctx context.Context, hint protocol.InlayHint) (protocol.InlayHint, error) {
+	if !c.initialized {
+		return hint, fmt.Errorf("client not initialized")
+	}
+	if opts, ok := c.inlayHintOptions(); !ok || !opts.ResolveProvider {
+		return hint, fmt.Errorf("server does not support resolving inlay hints")
+	}
+
+	var result inlayHintResult
+	err := c.conn.Call(ctx, MethodInlayHintResolve, hint, &result)
+	if err != nil {
+		return hint, fmt.Errorf("inlay hint resolve request failed: %w", err)
+	}
+	return result.hint(), nil
+}
+
+// OnInlayHintRefresh registers a function to call when the server asks the
+// client to refresh all inlay hints, for example after a configuration
+// change. Functions run in registration order before the request is
+// answered.
+func (c *Client) OnInlayHintRefresh(fn func(ctx context.Context)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inlayHintRefreshHooks = append(c.inlayHintRefreshHooks, fn)
+}
+
+// inlayHintOptions returns the inlay hint options of the server.
+func (c *Client) inlayHintOptions() (protocol.InlayHintOptions, bool) {
+	provider := c.GetCapabilities().InlayHintProvider
+	if provider == nil {
+		return protocol.InlayHintOptions{}, false
+	}
+	if enabled, ok := provider.(bool); ok {
+		return protocol.InlayHintOptions{}, enabled
+	}
+	opts, err := convertTo[protocol.InlayHintOptions](provider)
+	if err != nil {
+		return protocol.InlayHintOptions{}, false
+	}
+	return opts, true
+}
+
+// handleInlayHintRefresh answers workspace/inlayHint/refresh requests by
+// running the refresh hooks.
+func (c *Client) handleInlayHintRefresh(ctx context.Context, _ string, _ json.RawMessage) (any, error) {
+	c.mu.RLock()
+	hooks := slices.Clone(c.inlayHintRefreshHooks)
+	c.mu.RUnlock()
+
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+	return nil, nil
+}
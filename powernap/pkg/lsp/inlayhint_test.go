@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestClient_InlayHints(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		InlayHintProvider: protocol.InlayHintOptions{ResolveProvider: true},
+	})
+
+	// Labels may be plain strings or label parts.
+	server.Respond(MethodTextDocumentInlayHint, json.RawMessage(`[
+		{"position": {"line": 1, "character": 4}, "label": ": int", "kind": 1, "data": 7},
+		{"position": {"line": 2, "character": 8}, "label": [{"value": "x"}, {"value": ":"}], "kind": 2}
+	]`))
+	server.Handle(MethodInlayHintResolve, func(_ context.Context, params json.RawMessage) (any, error) {
+		var hint map[string]any
+		if err := json.Unmarshal(params, &hint); err != nil {
+			return nil, err
+		}
+		hint["tooltip"] = "resolved " + hint["label"].([]any)[0].(map[string]any)["value"].(string)
+		return hint, nil
+	})
+
+	ctx := t.Context()
+	hints, err := client.RequestInlayHints(ctx, "file:///tmp/main.go", protocol.Range{End: protocol.Position{Line: 10}})
+	if err != nil {
+		t.Fatalf("RequestInlayHints failed: %v", err)
+	}
+	if len(hints) != 2 {
+		t.Fatalf("expected 2 hints, got %+v", hints)
+	}
+	if want := []protocol.InlayHintLabelPart{{Value: ": int"}}; !reflect.DeepEqual(hints[0].Label, want) {
+		t.Errorf("got label %+v, want %+v", hints[0].Label, want)
+	}
+	if want := []protocol.InlayHintLabelPart{{Value: "x"}, {Value: ":"}}; !reflect.DeepEqual(hints[1].Label, want) {
+		t.Errorf("got label %+v, want %+v", hints[1].Label, want)
+	}
+	if hints[0].Kind != protocol.Type || hints[1].Kind != protocol.Parameter {
+		t.Errorf("unexpected kinds %d and %d", hints[0].Kind, hints[1].Kind)
+	}
+
+	resolved, err := client.ResolveInlayHint(ctx, hints[0])
+	if err != nil {
+		t.Fatalf("ResolveInlayHint failed: %v", err)
+	}
+	if resolved.Tooltip == nil || resolved.Tooltip.Value != "resolved : int" {
+		t.Errorf("unexpected tooltip %+v", resolved.Tooltip)
+	}
+	if resolved.Data != float64(7) {
+		t.Errorf("expected data to round-trip, got %v", resolved.Data)
+	}
+}
+
+func TestClient_InlayHintsUnsupported(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{InlayHintProvider: true})
+	server.Respond(MethodTextDocumentInlayHint, nil)
+
+	hints, err := client.RequestInlayHints(t.Context(), "file:///tmp/main.go", protocol.Range{})
+	if err != nil {
+		t.Fatalf("RequestInlayHints failed: %v", err)
+	}
+	if len(hints) != 0 {
+		t.Errorf("expected no hints, got %+v", hints)
+	}
+	if _, err := client.ResolveInlayHint(t.Context(), protocol.InlayHint{}); err == nil {
+		t.Error("expected error when the server can't resolve hints")
+	}
+}
+
+func TestClient_InlayHintRefresh(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+
+	var init protocol.InitializeParams
+	if err := server.ReceivedMethod(MethodInitialize)[0].Unmarshal(&init); err != nil {
+		t.Fatalf("failed to decode initialize: %v", err)
+	}
+	if init.Capabilities.Workspace.InlayHint == nil || !init.Capabilities.Workspace.InlayHint.RefreshSupport {
+		t.Error("expected inlay hint refresh support to be advertised")
+	}
+
+	refreshed := make(chan struct{}, 1)
+	client.OnInlayHintRefresh(func(context.Context) {
+		refreshed <- struct{}{}
+	})
+
+	if err := server.Call(t.Context(), MethodWorkspaceInlayHintRefresh, nil, nil); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	select {
+	case <-refreshed:
+	default:
+		t.Error("expected refresh hook to run before the request was answered")
+	}
+}
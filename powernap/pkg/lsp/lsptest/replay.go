@@ -0,0 +1,292 @@
+package lsptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ErrReplayFinished is returned to requests the client sends after the
+// whole trace has been replayed.
+var ErrReplayFinished = errors.New("lsptest: replay finished")
+
+// Replay is a fake language server that plays back the server side of a
+// trace captured with a [transport.Tracer] in [transport.TraceJSON] format.
+// It waits for the client to send the messages the client sent in the
+// trace, answers its requests with the recorded responses, and sends the
+// recorded server notifications and requests. This allows reproducing
+// server misbehavior without the server.
+//
+// Messages from the client are matched by kind and method, not by
+// position, so that clients sending concurrent messages in a different order
+// still replay.
+type Replay struct {
+	entries []transport.TraceEntry
+
+	// StepTimeout is how long to wait for each client message. Zero means
+	// 5s.
+	StepTimeout time.Duration
+
+	mu       sync.Mutex
+	conn     *jsonrpc2.Conn
+	queue    []*jsonrpc2.Request // client messages not matched yet
+	arrived  chan struct{}       // closed and replaced whenever a message arrives
+	finished bool
+
+	done chan struct{}
+	err  error
+}
+
+// NewReplay creates a replay of the given trace entries.
+func NewReplay(entries []transport.TraceEntry) *Replay {
+	return &Replay{
+		entries: entries,
+		arrived: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// ReadReplay reads a JSON trace log and creates a replay of it.
+func ReadReplay(r io.Reader) (*Replay, error) {
+	entries, err := transport.ReadTrace(r)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return NewReplay(entries), nil
+}
+
+// Dial connects the client and starts replaying. It can only be called
+// once.
+func (r *Replay) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		return nil, errors.New("lsptest: replay already started")
+	}
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	serverStream := transport.NewStreamTransport(serverReader, serverWriter, pipeCloser{serverReader, serverWriter})
+	r.conn = jsonrpc2.NewConn(ctx, serverStream.ObjectStream(), handlerFunc(r.handle))
+
+	go func() {
+		r.err = r.play(ctx)
+		r.mu.Lock()
+		r.finished = true
+		pending := r.queue
+		r.queue = nil
+		r.mu.Unlock()
+		for _, req := range pending {
+			r.reject(ctx, req)
+		}
+		close(r.done)
+	}()
+
+	return transport.NewStreamTransport(clientReader, clientWriter, pipeCloser{clientReader, clientWriter}), nil
+}
+
+// Wait waits until the whole trace has been replayed and returns the first
+// mismatch between the trace and the client, if any.
+func (r *Replay) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	}
+}
+
+// Close closes the connection to the client.
+func (r *Replay) Close() error {
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	if err := conn.Close(); err != nil && !errors.Is(err, jsonrpc2.ErrClosed) {
+		return err //nolint:wrapcheck
+	}
+	return nil
+}
+
+// handle queues messages from the client for the replay to match.
+func (r *Replay) handle(ctx context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	r.mu.Lock()
+	if r.finished {
+		r.mu.Unlock()
+		r.reject(ctx, req)
+		return
+	}
+	r.queue = append(r.queue, req)
+	close(r.arrived)
+	r.arrived = make(chan struct{})
+	r.mu.Unlock()
+}
+
+// reject fails a client request that has no recorded response.
+func (r *Replay) reject(ctx context.Context, req *jsonrpc2.Request) {
+	if req.Notif {
+		return
+	}
+	_ = r.conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
+		Code:    jsonrpc2.CodeInternalError,
+		Message: fmt.Sprintf("%v: no recorded response for %s", ErrReplayFinished, req.Method),
+	})
+}
+
+// play replays the trace entries in order.
+func (r *Replay) play(ctx context.Context) error {
+	requests := make(map[string]*jsonrpc2.Request) // client requests by recorded ID
+	calls := make(map[string]chan error)           // server requests by recorded ID
+
+	// Don't leave the client waiting for responses the trace doesn't have.
+	defer func() {
+		for _, req := range requests {
+			r.reject(ctx, req)
+		}
+	}()
+
+	for i, entry := range r.entries {
+		msg, err := entry.Decode()
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if msg.ID == nil && (entry.Type == transport.TraceSendRequest ||
+			entry.Type == transport.TraceReceiveRequest ||
+			entry.Type == transport.TraceSendResponse ||
+			entry.Type == transport.TraceReceiveResponse) {
+			return fmt.Errorf("entry %d: %s without id", i+1, entry.Type)
+		}
+
+		switch entry.Type {
+		case transport.TraceSendRequest, transport.TraceSendNotification:
+			notif := entry.Type == transport.TraceSendNotification
+			req, err := r.expect(ctx, msg.Method, notif)
+			if err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+			if !notif {
+				requests[msg.ID.String()] = req
+			}
+
+		case transport.TraceReceiveResponse:
+			req, ok := requests[msg.ID.String()]
+			if !ok {
+				return fmt.Errorf("entry %d: response to unknown request %s", i+1, msg.ID)
+			}
+			delete(requests, msg.ID.String())
+			if msg.Error != nil {
+				err = r.conn.ReplyWithError(ctx, req.ID, msg.Error)
+			} else {
+				err = r.conn.Reply(ctx, req.ID, rawOrNil(msg.Result))
+			}
+			if err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+
+		case transport.TraceReceiveNotification:
+			if err := r.conn.Notify(ctx, msg.Method, rawOrNil(msg.Params)); err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+
+		case transport.TraceReceiveRequest:
+			ch := make(chan error, 1)
+			calls[msg.ID.String()] = ch
+			go func() {
+				var result json.RawMessage
+				ch <- r.conn.Call(ctx, msg.Method, rawOrNil(msg.Params), &result)
+			}()
+
+		case transport.TraceSendResponse:
+			ch, ok := calls[msg.ID.String()]
+			if !ok {
+				return fmt.Errorf("entry %d: response to unknown request %s", i+1, msg.ID)
+			}
+			if err := r.waitCall(ctx, ch); err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+
+		default:
+			return fmt.Errorf("entry %d: unknown entry type %q", i+1, entry.Type)
+		}
+	}
+	return nil
+}
+
+// expect waits for the client to send a request or notification for
+// method, and removes it from the queue.
+func (r *Replay) expect(ctx context.Context, method string, notif bool) (*jsonrpc2.Request, error) {
+	kind := "request"
+	if notif {
+		kind = "notification"
+	}
+
+	timer := time.NewTimer(r.stepTimeout())
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		for i, req := range r.queue {
+			if req.Method == method && req.Notif == notif {
+				r.queue = append(r.queue[:i], r.queue[i+1:]...)
+				r.mu.Unlock()
+				return req, nil
+			}
+		}
+		arrived := r.arrived
+		r.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return nil, fmt.Errorf("client did not send %s %q", kind, method)
+		case <-ctx.Done():
+			return nil, ctx.Err() //nolint:wrapcheck
+		}
+	}
+}
+
+// waitCall waits for the client to answer a replayed server request. The
+// response itself doesn't matter, as long as the client answered.
+func (r *Replay) waitCall(ctx context.Context, ch <-chan error) error {
+	timer := time.NewTimer(r.stepTimeout())
+	defer timer.Stop()
+
+	select {
+	case err := <-ch:
+		var rpcErr *jsonrpc2.Error
+		if err != nil && !errors.As(err, &rpcErr) {
+			return err
+		}
+		return nil
+	case <-timer.C:
+		return errors.New("client did not answer request")
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	}
+}
+
+func (r *Replay) stepTimeout() time.Duration {
+	if r.StepTimeout > 0 {
+		return r.StepTimeout
+	}
+	return 5 * time.Second
+}
+
+// rawOrNil returns raw as a JSON value, or nil if it is empty so that the
+// field is omitted.
+func rawOrNil(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
+}
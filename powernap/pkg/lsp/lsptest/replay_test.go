@@ -0,0 +1,159 @@
+package lsptest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// session drives a client through a short session: it opens a document,
+// waits for diagnostics, and asks for hover information.
+func session(t *testing.T, stream io.ReadWriteCloser, tracer *transport.Tracer) (*protocol.Hover, []protocol.Diagnostic) {
+	t.Helper()
+
+	client, err := lsp.NewClient(lsp.ClientConfig{
+		Command:             "lsptest",
+		RootURI:             "file:///tmp",
+		Stream:              stream,
+		Tracer:              tracer,
+		DisableFileWatching: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	diags := make(chan []protocol.Diagnostic, 1)
+	client.RegisterNotificationHandler(lsp.MethodTextDocumentDiagnostic, func(_ context.Context, _ string, params json.RawMessage) {
+		var p protocol.PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil {
+			diags <- p.Diagnostics
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Initialize(ctx, false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.NotifyDidOpenTextDocument(ctx, "file:///tmp/main.go", "go", 1, "package main\n"); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+
+	var got []protocol.Diagnostic
+	select {
+	case got = <-diags:
+	case <-ctx.Done():
+		t.Fatal("no diagnostics")
+	}
+
+	hover, err := client.RequestHover(ctx, "file:///tmp/main.go", protocol.Position{Line: 0, Character: 8})
+	if err != nil {
+		t.Fatalf("RequestHover failed: %v", err)
+	}
+	if err := client.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	return hover, got
+}
+
+func TestReplay(t *testing.T) {
+	// Record a session with a scripted server.
+	server := lsptest.NewServer(protocol.ServerCapabilities{
+		HoverProvider: &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+	})
+	server.Respond(lsp.MethodTextDocumentHover, protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "package main"},
+	})
+	server.Handle(lsp.MethodTextDocumentDidOpen, func(ctx context.Context, _ json.RawMessage) (any, error) {
+		go func() {
+			_ = server.PublishDiagnostics(ctx, "file:///tmp/main.go", 1, []protocol.Diagnostic{{
+				Severity: protocol.SeverityWarning,
+				Message:  "recorded",
+			}})
+		}()
+		return nil, nil
+	})
+	stream, err := server.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	var trace bytes.Buffer
+	wantHover, wantDiags := session(t, stream, transport.NewTracer(&trace, transport.TraceJSON, transport.TraceVerbose))
+
+	// Replay it without the server.
+	replay, err := lsptest.ReadReplay(&trace)
+	if err != nil {
+		t.Fatalf("ReadReplay failed: %v", err)
+	}
+	stream, err = replay.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = replay.Close() })
+
+	hover, diags := session(t, stream, nil)
+	if err := replay.Wait(t.Context()); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	if hover.Contents.Value != wantHover.Contents.Value {
+		t.Errorf("got hover %q, want %q", hover.Contents.Value, wantHover.Contents.Value)
+	}
+	if len(diags) != 1 || diags[0].Message != wantDiags[0].Message {
+		t.Errorf("got diagnostics %+v, want %+v", diags, wantDiags)
+	}
+}
+
+func TestReplay_Mismatch(t *testing.T) {
+	log := strings.Join([]string{
+		`{"isLSPMessage":true,"type":"send-request","message":{"jsonrpc":"2.0","id":0,"method":"textDocument/hover","params":{}},"timestamp":0}`,
+		`{"isLSPMessage":true,"type":"receive-response","message":{"jsonrpc":"2.0","id":0,"result":null},"timestamp":0}`,
+	}, "\n")
+	replay, err := lsptest.ReadReplay(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ReadReplay failed: %v", err)
+	}
+	replay.StepTimeout = 50 * time.Millisecond
+
+	stream, err := replay.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = replay.Close() })
+
+	conn, err := transport.NewConnection(t.Context(), stream, nil)
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	// The client asks for something the trace doesn't have.
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.Call(t.Context(), lsp.MethodTextDocumentDefinition, map[string]any{}, nil)
+	}()
+
+	if err := replay.Wait(t.Context()); err == nil || !strings.Contains(err.Error(), "textDocument/hover") {
+		t.Errorf("expected mismatch error, got %v", err)
+	}
+
+	// Requests without a recorded response fail instead of hanging.
+	var rpcErr *jsonrpc2.Error
+	if err := <-done; !errors.As(err, &rpcErr) || !strings.Contains(rpcErr.Message, lsptest.ErrReplayFinished.Error()) {
+		t.Errorf("expected replay finished error, got %v", err)
+	}
+}
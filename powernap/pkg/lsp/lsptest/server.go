@@ -0,0 +1,329 @@
+// Package lsptest provides an in-process, scriptable language server for
+// testing code built on top of the lsp package without a real server.
+package lsptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ErrClosed is returned when talking to a server that has no open
+// connection.
+var ErrClosed = errors.New("lsptest: server is not connected")
+
+// Handler computes the response to a request, or handles a notification in
+// which case the result is ignored.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Message is a request or notification received by the server.
+type Message struct {
+	Method       string
+	Params       json.RawMessage
+	Notification bool
+}
+
+// Unmarshal decodes the message parameters into v.
+func (m Message) Unmarshal(v any) error {
+	return json.Unmarshal(m.Params, v) //nolint:wrapcheck
+}
+
+// Server is a fake language server. It answers initialize with the
+// configured capabilities, records every message it receives, and replies to
+// requests with canned or handler-computed responses. Requests without a
+// handler fail with a method not found error. The connection is closed when
+// the client sends exit.
+type Server struct {
+	capabilities protocol.ServerCapabilities
+
+	mu       sync.Mutex
+	changed  chan struct{} // closed and replaced whenever a message arrives
+	handlers map[string]Handler
+	received []Message
+	conn     *jsonrpc2.Conn
+}
+
+// NewServer creates a server that announces the given capabilities.
+func NewServer(capabilities protocol.ServerCapabilities) *Server {
+	s := &Server{
+		capabilities: capabilities,
+		changed:      make(chan struct{}),
+		handlers:     make(map[string]Handler),
+	}
+
+	s.handlers["initialize"] = func(context.Context, json.RawMessage) (any, error) {
+		return protocol.InitializeResult{
+			Capabilities: s.capabilities,
+			ServerInfo:   &protocol.ServerInfo{Name: "lsptest"},
+		}, nil
+	}
+	s.handlers["shutdown"] = func(context.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	}
+
+	return s
+}
+
+// Handle sets the handler for the given method, replacing any previous
+// handler or canned response.
+func (s *Server) Handle(method string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// Respond makes the server answer requests for method with result.
+func (s *Server) Respond(method string, result any) {
+	s.Handle(method, func(context.Context, json.RawMessage) (any, error) {
+		return result, nil
+	})
+}
+
+// Fail makes the server answer requests for method with an error.
+func (s *Server) Fail(method string, code int64, message string) {
+	s.Handle(method, func(context.Context, json.RawMessage) (any, error) {
+		return nil, &jsonrpc2.Error{Code: code, Message: message}
+	})
+}
+
+// Dial connects a new client to the server and returns the client side of
+// the connection. Any previous connection is closed.
+func (s *Server) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	s.Serve(ctx, transport.NewStreamTransport(serverReader, serverWriter, pipeCloser{serverReader, serverWriter}))
+
+	return transport.NewStreamTransport(clientReader, clientWriter, pipeCloser{clientReader, clientWriter}), nil
+}
+
+// Serve serves a client connected through rwc, such as a connection
+// accepted on a socket, in the background. Any previous connection is
+// closed.
+func (s *Server) Serve(ctx context.Context, rwc io.ReadWriteCloser) {
+	stream := jsonrpc2.NewBufferedStream(rwc, jsonrpc2.VSCodeObjectCodec{})
+	conn := jsonrpc2.NewConn(ctx, stream, handlerFunc(s.handle))
+
+	s.mu.Lock()
+	prev := s.conn
+	s.conn = conn
+	s.mu.Unlock()
+
+	if prev != nil {
+		_ = prev.Close()
+	}
+}
+
+// Close closes the current connection, if any.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return s.closeConn(conn)
+}
+
+// closeConn closes a connection, forgetting it if it is the current one.
+func (s *Server) closeConn(conn *jsonrpc2.Conn) error {
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+
+	if err := conn.Close(); err != nil && !errors.Is(err, jsonrpc2.ErrClosed) {
+		return err //nolint:wrapcheck
+	}
+	return nil
+}
+
+// Received returns every message received so far, in arrival order.
+func (s *Server) Received() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := make([]Message, len(s.received))
+	copy(msgs, s.received)
+	return msgs
+}
+
+// ReceivedMethod returns the messages received so far for method.
+func (s *Server) ReceivedMethod(method string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filter(s.received, method)
+}
+
+// WaitFor blocks until at least n messages for method have been received and
+// returns them.
+func (s *Server) WaitFor(ctx context.Context, method string, n int) ([]Message, error) {
+	for {
+		s.mu.Lock()
+		msgs := filter(s.received, method)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(msgs) >= n {
+			return msgs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return msgs, fmt.Errorf("waiting for %d %q messages, got %d: %w", n, method, len(msgs), ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// Notify sends a notification to the client.
+func (s *Server) Notify(ctx context.Context, method string, params any) error {
+	conn, err := s.current()
+	if err != nil {
+		return err
+	}
+	return conn.Notify(ctx, method, params) //nolint:wrapcheck
+}
+
+// Call sends a request to the client and waits for the response.
+func (s *Server) Call(ctx context.Context, method string, params, result any) error {
+	conn, err := s.current()
+	if err != nil {
+		return err
+	}
+	return conn.Call(ctx, method, params, result) //nolint:wrapcheck
+}
+
+// PublishDiagnostics pushes diagnostics for a document to the client.
+func (s *Server) PublishDiagnostics(ctx context.Context, uri protocol.DocumentURI, version int32, diagnostics []protocol.Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+	return s.Notify(ctx, "textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
+		URI:         uri,
+		Version:     version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// CreateProgress asks the client to create a work done progress token.
+func (s *Server) CreateProgress(ctx context.Context, token string) error {
+	return s.Call(ctx, "window/workDoneProgress/create", protocol.WorkDoneProgressCreateParams{
+		Token: protocol.ProgressToken{Value: token},
+	}, nil)
+}
+
+// Progress reports progress for a token. The value is usually one of
+// [protocol.WorkDoneProgressBegin], [protocol.WorkDoneProgressReport] or
+// [protocol.WorkDoneProgressEnd].
+func (s *Server) Progress(ctx context.Context, token string, value any) error {
+	return s.Notify(ctx, "$/progress", protocol.ProgressParams{
+		Token: protocol.ProgressToken{Value: token},
+		Value: value,
+	})
+}
+
+// RegisterCapability dynamically registers capabilities with the client.
+func (s *Server) RegisterCapability(ctx context.Context, registrations ...protocol.Registration) error {
+	return s.Call(ctx, "client/registerCapability", protocol.RegistrationParams{
+		Registrations: registrations,
+	}, nil)
+}
+
+// handle records an incoming message and dispatches it. Notifications are
+// handled in order; requests are handled concurrently so that handlers can
+// call back into the client.
+func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params json.RawMessage
+	if req.Params != nil {
+		params = append(json.RawMessage(nil), *req.Params...)
+	}
+
+	s.mu.Lock()
+	s.received = append(s.received, Message{
+		Method:       req.Method,
+		Params:       params,
+		Notification: req.Notif,
+	})
+	close(s.changed)
+	s.changed = make(chan struct{})
+	handler, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+
+	if req.Notif {
+		if ok {
+			_, _ = handler(ctx, params)
+		}
+		if req.Method == "exit" {
+			go s.closeConn(conn)
+		}
+		return
+	}
+
+	go func() {
+		if !ok {
+			_ = conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeMethodNotFound,
+				Message: "method not found: " + req.Method,
+			})
+			return
+		}
+
+		result, err := handler(ctx, params)
+		if err != nil {
+			var rpcErr *jsonrpc2.Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
+			}
+			_ = conn.ReplyWithError(ctx, req.ID, rpcErr)
+			return
+		}
+		_ = conn.Reply(ctx, req.ID, result)
+	}()
+}
+
+// current returns the open connection.
+func (s *Server) current() (*jsonrpc2.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil, ErrClosed
+	}
+	return s.conn, nil
+}
+
+// filter returns the messages for method.
+func filter(msgs []Message, method string) []Message {
+	var result []Message
+	for _, msg := range msgs {
+		if msg.Method == method {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// handlerFunc adapts a function to [jsonrpc2.Handler].
+type handlerFunc func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request)
+
+func (f handlerFunc) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	f(ctx, conn, req)
+}
+
+// pipeCloser closes both ends of one side of an in-memory connection.
+type pipeCloser struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func (p pipeCloser) Close() error {
+	return errors.Join(p.reader.Close(), p.writer.Close())
+}
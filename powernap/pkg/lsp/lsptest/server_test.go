@@ -0,0 +1,205 @@
+package lsptest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func TestServer_Requests(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{
+		HoverProvider: &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+	})
+	server.Respond(lsp.MethodTextDocumentHover, protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "canned"},
+	})
+	server.Handle(lsp.MethodTextDocumentReferences, func(_ context.Context, params json.RawMessage) (any, error) {
+		var ref protocol.ReferenceParams
+		if err := json.Unmarshal(params, &ref); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		return []protocol.Location{{URI: ref.TextDocument.URI, Range: protocol.Range{Start: ref.Position, End: ref.Position}}}, nil
+	})
+
+	client := newClient(t, server)
+
+	caps := client.GetCapabilities()
+	if caps.HoverProvider == nil || caps.HoverProvider.Value != true {
+		t.Errorf("expected hover capability, got %+v", caps.HoverProvider)
+	}
+
+	hover, err := client.RequestHover(t.Context(), "file:///tmp/main.go", protocol.Position{Line: 1, Character: 2})
+	if err != nil {
+		t.Fatalf("RequestHover failed: %v", err)
+	}
+	if hover.Contents.Value != "canned" {
+		t.Errorf("unexpected hover: %+v", hover.Contents)
+	}
+
+	refs, err := client.FindReferences(t.Context(), "/tmp/main.go", 3, 4, true)
+	if err != nil {
+		t.Fatalf("FindReferences failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Range.Start.Line != 3 || refs[0].Range.Start.Character != 4 {
+		t.Errorf("unexpected references: %+v", refs)
+	}
+
+	_, err = client.RequestCompletion(t.Context(), "file:///tmp/main.go", protocol.Position{})
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc2.CodeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+
+	msgs := server.ReceivedMethod(lsp.MethodTextDocumentHover)
+	if len(msgs) != 1 || msgs[0].Notification {
+		t.Fatalf("expected one hover request, got %+v", msgs)
+	}
+	var params protocol.HoverParams
+	if err := msgs[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Position.Line != 1 || params.Position.Character != 2 {
+		t.Errorf("unexpected hover params: %+v", params)
+	}
+}
+
+func TestServer_PublishDiagnostics(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{})
+	client := newClient(t, server)
+
+	got := make(chan protocol.PublishDiagnosticsParams, 1)
+	client.RegisterNotificationHandler(lsp.MethodTextDocumentDiagnostic, func(_ context.Context, _ string, params json.RawMessage) {
+		var diags protocol.PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &diags); err == nil {
+			got <- diags
+		}
+	})
+
+	err := server.PublishDiagnostics(t.Context(), "file:///tmp/main.go", 3, []protocol.Diagnostic{
+		{Severity: protocol.SeverityError, Message: "undefined: foo"},
+	})
+	if err != nil {
+		t.Fatalf("PublishDiagnostics failed: %v", err)
+	}
+
+	select {
+	case diags := <-got:
+		if diags.URI != "file:///tmp/main.go" || diags.Version != 3 {
+			t.Errorf("unexpected diagnostics target: %+v", diags)
+		}
+		if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Message != "undefined: foo" {
+			t.Errorf("unexpected diagnostics: %+v", diags.Diagnostics)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for diagnostics")
+	}
+}
+
+func TestServer_Progress(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{})
+	client := newClient(t, server)
+
+	done := make(chan lsp.ProgressTask, 1)
+	client.Progress().OnUpdate(func(task lsp.ProgressTask) {
+		if task.Done {
+			done <- task
+		}
+	})
+
+	ctx := t.Context()
+	if err := server.CreateProgress(ctx, "index"); err != nil {
+		t.Fatalf("CreateProgress failed: %v", err)
+	}
+	if err := server.Progress(ctx, "index", protocol.WorkDoneProgressBegin{Kind: "begin", Title: "Indexing"}); err != nil {
+		t.Fatalf("Progress failed: %v", err)
+	}
+	if err := server.Progress(ctx, "index", protocol.WorkDoneProgressEnd{Kind: "end", Message: "done"}); err != nil {
+		t.Fatalf("Progress failed: %v", err)
+	}
+
+	select {
+	case task := <-done:
+		if task.Title != "Indexing" || task.Message != "done" {
+			t.Errorf("unexpected task: %+v", task)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for progress")
+	}
+}
+
+func TestServer_RegisterCapability(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{})
+	client := newClient(t, server)
+
+	if client.GetCapabilities().DefinitionProvider != nil {
+		t.Fatal("expected no definition capability before registration")
+	}
+	err := server.RegisterCapability(t.Context(), protocol.Registration{
+		ID:     "def",
+		Method: lsp.MethodTextDocumentDefinition,
+	})
+	if err != nil {
+		t.Fatalf("RegisterCapability failed: %v", err)
+	}
+	if client.GetCapabilities().DefinitionProvider == nil {
+		t.Error("expected definition capability after registration")
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{})
+	client := newClient(t, server)
+
+	if err := client.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := client.Exit(); err != nil {
+		t.Fatalf("Exit failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := server.WaitFor(ctx, lsp.MethodExit, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection is torn down once the client exits.
+	for server.Notify(ctx, "window/logMessage", map[string]any{}) == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatal("expected the connection to be closed after exit")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func newClient(t *testing.T, server *lsptest.Server) *lsp.Client {
+	t.Helper()
+
+	stream, err := server.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	client, err := lsp.NewClient(lsp.ClientConfig{
+		Command:             "lsptest",
+		RootURI:             "file:///tmp",
+		Stream:              stream,
+		DisableFileWatching: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	if err := client.Initialize(t.Context(), false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return client
+}
@@ -0,0 +1,347 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/rivo/uniseg"
+)
+
+// positionEncodings are the encodings the client supports, in order of
+// preference. Byte offsets need no conversion, so UTF-8 comes first.
+var positionEncodings = []OffsetEncoding{UTF8, UTF16, UTF32}
+
+// String returns the LSP name of the encoding, such as "utf-16".
+func (e OffsetEncoding) String() string {
+	switch e {
+	case UTF8:
+		return string(protocol.UTF8)
+	case UTF32:
+		return string(protocol.UTF32)
+	default:
+		return string(protocol.UTF16)
+	}
+}
+
+// parseOffsetEncoding parses the LSP name of an encoding.
+func parseOffsetEncoding(name string) (OffsetEncoding, bool) {
+	switch protocol.PositionEncodingKind(strings.ToLower(name)) {
+	case protocol.UTF8:
+		return UTF8, true
+	case protocol.UTF16:
+		return UTF16, true
+	case protocol.UTF32:
+		return UTF32, true
+	}
+	return UTF16, false
+}
+
+// positionEncodingNames returns the LSP names of the supported encodings,
+// as advertised in the client capabilities.
+func positionEncodingNames() []string {
+	names := make([]string, len(positionEncodings))
+	for i, e := range positionEncodings {
+		names[i] = e.String()
+	}
+	return names
+}
+
+// units returns the number of code units r, which is size bytes long in
+// UTF-8, takes up in the encoding.
+func (e OffsetEncoding) units(r rune, size int) uint32 {
+	switch e {
+	case UTF8:
+		return uint32(size) //nolint:gosec
+	case UTF32:
+		return 1
+	default:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+}
+
+// OffsetEncoding returns the encoding the server uses for the character
+// offsets of positions, as negotiated during initialization. It is UTF-16
+// unless the server picked another one.
+func (c *Client) OffsetEncoding() OffsetEncoding {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offsetEncoding
+}
+
+// PositionMapper converts between byte offsets in a document and LSP
+// positions with character offsets in a given encoding. The line index is
+// built once, so converting many positions of the same content is cheap.
+type PositionMapper struct {
+	content    string
+	encoding   OffsetEncoding
+	lineStarts []int
+}
+
+// NewPositionMapper creates a mapper for content using encoding.
+func NewPositionMapper(content string, encoding OffsetEncoding) *PositionMapper {
+	lineStarts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &PositionMapper{
+		content:    content,
+		encoding:   encoding,
+		lineStarts: lineStarts,
+	}
+}
+
+// Encoding returns the encoding of the mapper's positions.
+func (m *PositionMapper) Encoding() OffsetEncoding {
+	return m.encoding
+}
+
+// Offset converts a position into a byte offset. Characters past the end of
+// a line clamp to the end of the line, and the line after the last one is
+// the end of the document.
+func (m *PositionMapper) Offset(pos protocol.Position) (int, error) {
+	line := int(pos.Line)
+	if line == len(m.lineStarts) {
+		return len(m.content), nil
+	}
+	if line > len(m.lineStarts) {
+		return 0, fmt.Errorf("line %d out of range", pos.Line)
+	}
+
+	offset, end := m.lineStarts[line], m.lineEnd(line)
+	var units uint32
+	for offset < end && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(m.content[offset:end])
+		units += m.encoding.units(r, size)
+		offset += size
+	}
+	return offset, nil
+}
+
+// Position converts a byte offset into a position. Offsets inside of a
+// character or a line ending point at the start of it.
+func (m *PositionMapper) Position(offset int) (protocol.Position, error) {
+	if offset < 0 || offset > len(m.content) {
+		return protocol.Position{}, fmt.Errorf("offset %d out of range", offset)
+	}
+
+	line := sort.Search(len(m.lineStarts), func(i int) bool {
+		return m.lineStarts[i] > offset
+	}) - 1
+	end := m.lineEnd(line)
+
+	var units uint32
+	for i := m.lineStarts[line]; i < min(offset, end); {
+		r, size := utf8.DecodeRuneInString(m.content[i:end])
+		if i+size > offset {
+			break
+		}
+		units += m.encoding.units(r, size)
+		i += size
+	}
+	return protocol.Position{
+		Line:      uint32(line), //nolint:gosec
+		Character: units,
+	}, nil
+}
+
+// Range converts a pair of byte offsets into a range.
+func (m *PositionMapper) Range(start, end int) (protocol.Range, error) {
+	if end < start {
+		return protocol.Range{}, fmt.Errorf("invalid range: %d-%d", start, end)
+	}
+	s, err := m.Position(start)
+	if err != nil {
+		return protocol.Range{}, err
+	}
+	e, err := m.Position(end)
+	if err != nil {
+		return protocol.Range{}, err
+	}
+	return protocol.Range{Start: s, End: e}, nil
+}
+
+// Offsets converts a range into a pair of byte offsets.
+func (m *PositionMapper) Offsets(rng protocol.Range) (start, end int, err error) {
+	start, err = m.Offset(rng.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = m.Offset(rng.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("invalid range: %v", rng)
+	}
+	return start, end, nil
+}
+
+// GraphemePosition converts a 0-based line and column counted in grapheme
+// clusters, as a cursor moves in an editor, into a position. Columns past
+// the end of the line clamp to the end of the line.
+func (m *PositionMapper) GraphemePosition(line, col int) (protocol.Position, error) {
+	if line < 0 || line >= len(m.lineStarts) {
+		return protocol.Position{}, fmt.Errorf("line %d out of range", line)
+	}
+
+	offset, end := m.lineStarts[line], m.lineEnd(line)
+	state := -1
+	for ; col > 0 && offset < end; col-- {
+		var cluster string
+		cluster, _, _, state = uniseg.FirstGraphemeClusterInString(m.content[offset:end], state)
+		offset += len(cluster)
+	}
+	return m.Position(offset)
+}
+
+// GraphemeColumn converts a position into a 0-based line and column counted
+// in grapheme clusters. A position inside of a cluster maps to the column of
+// the cluster.
+func (m *PositionMapper) GraphemeColumn(pos protocol.Position) (line, col int, err error) {
+	offset, err := m.Offset(pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	p, err := m.Position(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	line = int(p.Line)
+	start, end := m.lineStarts[line], m.lineEnd(line)
+	state := -1
+	for start < offset {
+		var cluster string
+		cluster, _, _, state = uniseg.FirstGraphemeClusterInString(m.content[start:end], state)
+		if start+len(cluster) > offset {
+			break
+		}
+		start += len(cluster)
+		col++
+	}
+	return line, col, nil
+}
+
+// lineEnd returns the byte offset of the end of a line, before its line
+// ending. A carriage return ending the content counts as a line ending too.
+func (m *PositionMapper) lineEnd(line int) int {
+	end := len(m.content)
+	if line+1 < len(m.lineStarts) {
+		end = m.lineStarts[line+1] - 1
+	}
+	if end > m.lineStarts[line] && m.content[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// Mapper returns a position mapper for the current content of an open
+// document, using the encoding negotiated with the server.
+func (m *TextDocumentSyncManager) Mapper(uri string) (*PositionMapper, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	doc, exists := m.documents[uri]
+	if !exists {
+		return nil, fmt.Errorf("document not open: %s", uri)
+	}
+	return NewPositionMapper(doc.Content, m.client.OffsetEncoding()), nil
+}
+
+// PositionAt converts a byte offset in an open document into a position
+// in the server's encoding.
+func (m *TextDocumentSyncManager) PositionAt(uri string, offset int) (protocol.Position, error) {
+	mapper, err := m.Mapper(uri)
+	if err != nil {
+		return protocol.Position{}, err
+	}
+	return mapper.Position(offset)
+}
+
+// OffsetOf converts a position in the server's encoding into a byte offset
+// in an open document.
+func (m *TextDocumentSyncManager) OffsetOf(uri string, pos protocol.Position) (int, error) {
+	mapper, err := m.Mapper(uri)
+	if err != nil {
+		return 0, err
+	}
+	return mapper.Offset(pos)
+}
+
+// Edit replaces the bytes between start and end of an open document with
+// text, and sends the change to the server as an incremental or full
+// change, depending on what the server supports.
+func (m *TextDocumentSyncManager) Edit(uri string, start, end int, text string) error {
+	m.mu.Lock()
+	doc, exists := m.documents[uri]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("document not open: %s", uri)
+	}
+	content := doc.Content
+	m.mu.Unlock()
+
+	if start < 0 || end < start || end > len(content) {
+		return fmt.Errorf("invalid range: %d-%d", start, end)
+	}
+
+	if m.syncKind != protocol.Incremental {
+		return m.Change(uri, CreateFullDocumentChange(content[:start]+text+content[end:]))
+	}
+
+	rng, err := NewPositionMapper(content, m.client.OffsetEncoding()).Range(start, end)
+	if err != nil {
+		return err
+	}
+	return m.Change(uri, []protocol.TextDocumentContentChangeEvent{{
+		Value: protocol.TextDocumentContentChangePartial{Range: &rng, Text: text},
+	}})
+}
+
+// HoverAt requests hover information at a byte offset of an open document.
+func (m *TextDocumentSyncManager) HoverAt(ctx context.Context, uri string, offset int) (*protocol.Hover, error) {
+	pos, err := m.PositionAt(uri, offset)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.RequestHover(ctx, uri, pos)
+}
+
+// CompletionAt requests completion items at a byte offset of an open
+// document.
+func (m *TextDocumentSyncManager) CompletionAt(ctx context.Context, uri string, offset int) (*protocol.CompletionList, error) {
+	pos, err := m.PositionAt(uri, offset)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.RequestCompletion(ctx, uri, pos)
+}
+
+// DefinitionAt requests the definition locations of the symbol at a byte
+// offset of an open document. The locations use the server's encoding.
+func (m *TextDocumentSyncManager) DefinitionAt(ctx context.Context, uri string, offset int) ([]protocol.Location, error) {
+	pos, err := m.PositionAt(uri, offset)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.RequestDefinition(ctx, uri, pos)
+}
+
+// SignatureHelpAt requests signature help at a byte offset of an open
+// document.
+func (m *TextDocumentSyncManager) SignatureHelpAt(ctx context.Context, uri string, offset int, sigCtx *protocol.SignatureHelpContext) (*protocol.SignatureHelp, error) {
+	pos, err := m.PositionAt(uri, offset)
+	if err != nil {
+		return nil, err
+	}
+	return m.client.RequestSignatureHelp(ctx, uri, pos, sigCtx)
+}
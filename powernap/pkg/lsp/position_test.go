@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestPositionMapper(t *testing.T) {
+	// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 UTF-16 units.
+	content := "aé😀b\r\nx\n"
+
+	tests := []struct {
+		encoding OffsetEncoding
+		offset   int
+		pos      protocol.Position
+	}{
+		{UTF8, 0, protocol.Position{Line: 0, Character: 0}},
+		{UTF8, 3, protocol.Position{Line: 0, Character: 3}},
+		{UTF8, 7, protocol.Position{Line: 0, Character: 7}},
+		{UTF8, 8, protocol.Position{Line: 0, Character: 8}},
+		{UTF8, 10, protocol.Position{Line: 1, Character: 0}},
+		{UTF16, 3, protocol.Position{Line: 0, Character: 2}},
+		{UTF16, 7, protocol.Position{Line: 0, Character: 4}},
+		{UTF16, 8, protocol.Position{Line: 0, Character: 5}},
+		{UTF16, 11, protocol.Position{Line: 1, Character: 1}},
+		{UTF16, 12, protocol.Position{Line: 2, Character: 0}},
+		{UTF32, 7, protocol.Position{Line: 0, Character: 3}},
+		{UTF32, 8, protocol.Position{Line: 0, Character: 4}},
+	}
+	for _, tc := range tests {
+		m := NewPositionMapper(content, tc.encoding)
+		pos, err := m.Position(tc.offset)
+		if err != nil {
+			t.Fatalf("%s: Position(%d) failed: %v", tc.encoding, tc.offset, err)
+		}
+		if pos != tc.pos {
+			t.Errorf("%s: Position(%d) = %+v, want %+v", tc.encoding, tc.offset, pos, tc.pos)
+		}
+		offset, err := m.Offset(tc.pos)
+		if err != nil {
+			t.Fatalf("%s: Offset(%+v) failed: %v", tc.encoding, tc.pos, err)
+		}
+		if offset != tc.offset {
+			t.Errorf("%s: Offset(%+v) = %d, want %d", tc.encoding, tc.pos, offset, tc.offset)
+		}
+	}
+
+	m := NewPositionMapper(content, UTF16)
+
+	// Offsets inside of a character or the line ending round down.
+	if pos, _ := m.Position(5); pos.Character != 2 {
+		t.Errorf("expected offset inside of the emoji to map to its start, got %+v", pos)
+	}
+	if pos, _ := m.Position(9); pos != (protocol.Position{Line: 0, Character: 5}) {
+		t.Errorf("expected offset inside of CRLF to map to the end of the line, got %+v", pos)
+	}
+
+	// Characters past the end of a line clamp to the line ending.
+	if offset, _ := m.Offset(protocol.Position{Line: 0, Character: 100}); offset != 8 {
+		t.Errorf("expected clamped offset 8, got %d", offset)
+	}
+	if offset, _ := m.Offset(protocol.Position{Line: 3}); offset != len(content) {
+		t.Errorf("expected the line after the last one to be the end, got %d", offset)
+	}
+	if _, err := m.Offset(protocol.Position{Line: 4}); err == nil {
+		t.Error("expected error for line out of range")
+	}
+	if _, err := m.Position(len(content) + 1); err == nil {
+		t.Error("expected error for offset out of range")
+	}
+
+	rng, err := m.Range(1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start, end, _ := m.Offsets(rng); start != 1 || end != 7 {
+		t.Errorf("range did not round-trip: %+v -> %d-%d", rng, start, end)
+	}
+
+	// The last line of a CRLF file without a final newline ends before the
+	// carriage return.
+	m = NewPositionMapper("ab\r\ncd\r", UTF16)
+	if offset, _ := m.Offset(protocol.Position{Line: 1, Character: 100}); offset != 6 {
+		t.Errorf("expected clamped offset 6, got %d", offset)
+	}
+	if pos, _ := m.Position(7); pos != (protocol.Position{Line: 1, Character: 2}) {
+		t.Errorf("expected the end of content to map to the end of the line, got %+v", pos)
+	}
+	if _, col, _ := m.GraphemeColumn(protocol.Position{Line: 1, Character: 100}); col != 2 {
+		t.Errorf("expected grapheme column 2, got %d", col)
+	}
+}
+
+func TestPositionMapper_Graphemes(t *testing.T) {
+	// The family emoji is a single grapheme cluster of 7 code points (11
+	// UTF-16 units), and "é" is an e with a combining accent.
+	content := "x👨‍👩‍👧éy\n"
+	m := NewPositionMapper(content, UTF16)
+
+	tests := []struct {
+		col  int
+		char uint32
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 9},
+		{3, 11},
+		{4, 12},
+	}
+	for _, tc := range tests {
+		pos, err := m.GraphemePosition(0, tc.col)
+		if err != nil {
+			t.Fatalf("GraphemePosition(0, %d) failed: %v", tc.col, err)
+		}
+		if pos.Character != tc.char {
+			t.Errorf("GraphemePosition(0, %d) = %d, want %d", tc.col, pos.Character, tc.char)
+		}
+		line, col, err := m.GraphemeColumn(pos)
+		if err != nil || line != 0 || col != tc.col {
+			t.Errorf("GraphemeColumn(%+v) = %d, %d, %v, want 0, %d", pos, line, col, err, tc.col)
+		}
+	}
+
+	// Columns past the end of the line clamp, positions inside of a cluster
+	// map to it.
+	if pos, _ := m.GraphemePosition(0, 50); pos.Character != 12 {
+		t.Errorf("expected clamped character 12, got %d", pos.Character)
+	}
+	if _, col, _ := m.GraphemeColumn(protocol.Position{Character: 4}); col != 1 {
+		t.Errorf("expected column 1 inside of the emoji, got %d", col)
+	}
+	if _, err := m.GraphemePosition(5, 0); err == nil {
+		t.Error("expected error for line out of range")
+	}
+}
+
+func TestApplyTextEditsWithEncoding(t *testing.T) {
+	content := "é😀x\n"
+	edit := func(start, end uint32) []protocol.TextEdit {
+		return []protocol.TextEdit{{
+			Range:   protocol.Range{Start: protocol.Position{Character: start}, End: protocol.Position{Character: end}},
+			NewText: "y",
+		}}
+	}
+
+	for _, tc := range []struct {
+		encoding   OffsetEncoding
+		start, end uint32
+	}{
+		{UTF8, 6, 7},
+		{UTF16, 3, 4},
+		{UTF32, 2, 3},
+	} {
+		got, err := ApplyTextEditsWithEncoding(content, edit(tc.start, tc.end), tc.encoding)
+		if err != nil {
+			t.Fatalf("%s: ApplyTextEditsWithEncoding failed: %v", tc.encoding, err)
+		}
+		if got != "é😀y\n" {
+			t.Errorf("%s: got %q", tc.encoding, got)
+		}
+	}
+}
+
+func TestClient_PositionEncoding(t *testing.T) {
+	utf8 := protocol.UTF8
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		PositionEncoding: &utf8,
+		TextDocumentSync: protocol.Incremental,
+	})
+
+	var init struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := server.ReceivedMethod(MethodInitialize)[0].Unmarshal(&init); err != nil {
+		t.Fatal(err)
+	}
+	if got := init.Capabilities.General.PositionEncodings; len(got) != 3 || got[0] != "utf-8" {
+		t.Errorf("unexpected advertised encodings %q", got)
+	}
+	if client.OffsetEncoding() != UTF8 {
+		t.Fatalf("expected negotiated utf-8, got %s", client.OffsetEncoding())
+	}
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/main.go"
+	if err := m.Open(uri, "go", "s := \"😀\"\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Byte offsets are sent as-is to a UTF-8 server.
+	pos, err := m.PositionAt(uri, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Character != 10 {
+		t.Errorf("expected character 10, got %d", pos.Character)
+	}
+
+	// Replace the emoji using byte offsets.
+	if err := m.Edit(uri, 6, 10, "x"); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	doc, _ := m.GetDocument(uri)
+	if doc.Content != "s := \"x\"\n" {
+		t.Errorf("unexpected content %q", doc.Content)
+	}
+
+	var params struct {
+		ContentChanges []struct {
+			Range protocol.Range `json:"range"`
+			Text  string         `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if err := json.Unmarshal(server.ReceivedMethod(MethodTextDocumentDidChange)[0].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if want := (protocol.Range{Start: protocol.Position{Character: 6}, End: protocol.Position{Character: 10}}); params.ContentChanges[0].Range != want {
+		t.Errorf("got range %+v, want %+v", params.ContentChanges[0].Range, want)
+	}
+
+	if _, err := m.OffsetOf("file:///tmp/other.go", protocol.Position{}); err == nil {
+		t.Error("expected error for a document that isn't open")
+	}
+}
+
+func TestTextDocumentSyncManager_EditUTF16(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		TextDocumentSync: protocol.Incremental,
+	})
+	if client.OffsetEncoding() != UTF16 {
+		t.Fatalf("expected utf-16 by default, got %s", client.OffsetEncoding())
+	}
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/main.go"
+	if err := m.Open(uri, "go", "// 😀 é\nx\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert after the "é" on the first line.
+	if err := m.Edit(uri, 10, 10, "!"); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	var params struct {
+		ContentChanges []struct {
+			Range protocol.Range `json:"range"`
+		} `json:"contentChanges"`
+	}
+	if err := server.ReceivedMethod(MethodTextDocumentDidChange)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if got := params.ContentChanges[0].Range.Start; got != (protocol.Position{Character: 7}) {
+		t.Errorf("expected utf-16 position 0:7, got %+v", got)
+	}
+
+	// Changes in UTF-16 positions are applied to the right bytes.
+	if err := m.Change(uri, []protocol.TextDocumentContentChangeEvent{{
+		Value: protocol.TextDocumentContentChangePartial{
+			Range: &protocol.Range{Start: protocol.Position{Character: 3}, End: protocol.Position{Character: 5}},
+			Text:  ":)",
+		},
+	}}); err != nil {
+		t.Fatalf("Change failed: %v", err)
+	}
+	doc, _ := m.GetDocument(uri)
+	if doc.Content != "// :) é!\nx\n" {
+		t.Errorf("unexpected content %q", doc.Content)
+	}
+}
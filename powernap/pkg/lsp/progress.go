@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// Work done progress kinds.
+const (
+	ProgressKindBegin  = "begin"
+	ProgressKindReport = "report"
+	ProgressKindEnd    = "end"
+)
+
+// ProgressTask represents a long running operation reported by the server via
+// work done progress.
+type ProgressTask struct {
+	Token         string
+	Title         string
+	Message       string
+	Percentage    uint32
+	HasPercentage bool
+	Cancellable   bool
+	Done          bool
+	StartedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// ProgressTracker keeps track of work done progress tasks created by the
+// server.
+type ProgressTracker struct {
+	mu       sync.RWMutex
+	tasks    map[string]*ProgressTask
+	order    []string
+	onUpdate func(ProgressTask)
+}
+
+// NewProgressTracker creates a new progress tracker.
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{
+		tasks: make(map[string]*ProgressTask),
+	}
+}
+
+// OnUpdate sets a callback that is invoked every time a task changes.
+func (t *ProgressTracker) OnUpdate(fn func(ProgressTask)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onUpdate = fn
+}
+
+// Create registers a new progress token. Servers create tokens with
+// window/workDoneProgress/create before reporting progress on them.
+func (t *ProgressTracker) Create(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.tasks[token]; exists {
+		return
+	}
+	t.tasks[token] = &ProgressTask{Token: token}
+	t.order = append(t.order, token)
+}
+
+// Update applies a $/progress value to the task identified by token. Tasks
+// are removed once they end. Reports and ends for tokens that are not
+// tracked are rejected, as are unknown kinds.
+func (t *ProgressTracker) Update(token string, value json.RawMessage) error {
+	var header struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(value, &header); err != nil {
+		return fmt.Errorf("invalid progress value: %w", err)
+	}
+	switch header.Kind {
+	case ProgressKindBegin, ProgressKindReport, ProgressKindEnd:
+	default:
+		return fmt.Errorf("unknown progress kind: %q", header.Kind)
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	task, exists := t.tasks[token]
+	if !exists {
+		if header.Kind != ProgressKindBegin {
+			t.mu.Unlock()
+			return fmt.Errorf("progress %s for unknown token %q", header.Kind, token)
+		}
+		// Some servers begin progress on tokens they never created.
+		task = &ProgressTask{Token: token}
+		t.tasks[token] = task
+		t.order = append(t.order, token)
+	}
+
+	switch header.Kind {
+	case ProgressKindBegin:
+		var begin protocol.WorkDoneProgressBegin
+		if err := json.Unmarshal(value, &begin); err != nil {
+			t.mu.Unlock()
+			return fmt.Errorf("invalid progress begin: %w", err)
+		}
+		task.Title = begin.Title
+		task.Message = begin.Message
+		task.Cancellable = begin.Cancellable
+		task.Percentage, task.HasPercentage = percentage(value, begin.Percentage)
+		task.StartedAt = now
+	case ProgressKindReport:
+		var report protocol.WorkDoneProgressReport
+		if err := json.Unmarshal(value, &report); err != nil {
+			t.mu.Unlock()
+			return fmt.Errorf("invalid progress report: %w", err)
+		}
+		if report.Message != "" {
+			task.Message = report.Message
+		}
+		task.Cancellable = report.Cancellable
+		if p, ok := percentage(value, report.Percentage); ok {
+			task.Percentage, task.HasPercentage = p, true
+		}
+	case ProgressKindEnd:
+		var end protocol.WorkDoneProgressEnd
+		if err := json.Unmarshal(value, &end); err != nil {
+			t.mu.Unlock()
+			return fmt.Errorf("invalid progress end: %w", err)
+		}
+		if end.Message != "" {
+			task.Message = end.Message
+		}
+		task.Done = true
+		delete(t.tasks, token)
+		t.order = slices.DeleteFunc(t.order, func(s string) bool { return s == token })
+	}
+	task.UpdatedAt = now
+
+	snapshot := *task
+	onUpdate := t.onUpdate
+	t.mu.Unlock()
+
+	if onUpdate != nil {
+		onUpdate(snapshot)
+	}
+	return nil
+}
+
+// Get returns the task for the given token.
+func (t *ProgressTracker) Get(token string) (ProgressTask, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	task, exists := t.tasks[token]
+	if !exists {
+		return ProgressTask{}, false
+	}
+	return *task, true
+}
+
+// Active returns all tasks that have not ended yet, in creation order.
+func (t *ProgressTracker) Active() []ProgressTask {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tasks := make([]ProgressTask, 0, len(t.order))
+	for _, token := range t.order {
+		tasks = append(tasks, *t.tasks[token])
+	}
+	return tasks
+}
+
+// percentage reports whether the progress value carries a percentage, since
+// a zero percentage is indistinguishable from a missing one after decoding.
+func percentage(value json.RawMessage, p uint32) (uint32, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return 0, false
+	}
+	if _, ok := fields["percentage"]; !ok {
+		return 0, false
+	}
+	return min(p, 100), true
+}
+
+// progressTokenString normalizes a progress token, which can either be an
+// integer or a string, into a string.
+func progressTokenString(token protocol.ProgressToken) string {
+	if token.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(token.Value)
+}
@@ -0,0 +1,257 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// Registrations returns the capabilities dynamically registered by the server
+// via client/registerCapability, in registration order.
+func (c *Client) Registrations() []protocol.Registration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.registrations)
+}
+
+// FileWatchers returns the file system watchers the server registered for
+// workspace/didChangeWatchedFiles.
+func (c *Client) FileWatchers() []protocol.FileSystemWatcher {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var watchers []protocol.FileSystemWatcher
+	for _, reg := range c.registrations {
+		if reg.Method != MethodWorkspaceDidChangeWatchedFiles {
+			continue
+		}
+		opts, err := convertTo[protocol.DidChangeWatchedFilesRegistrationOptions](reg.RegisterOptions)
+		if err != nil {
+			continue
+		}
+		watchers = append(watchers, opts.Watchers...)
+	}
+	return watchers
+}
+
+// Supports reports whether the server can handle requests for method, based
+// on its static capabilities and dynamic registrations. It returns false for
+// methods it doesn't know about.
+func (c *Client) Supports(method string) bool {
+	caps := c.GetCapabilities()
+	switch method {
+	case MethodTextDocumentCompletion:
+		return caps.CompletionProvider != nil
+	case MethodTextDocumentHover:
+		return caps.HoverProvider != nil && caps.HoverProvider.Value != false
+	case MethodTextDocumentDefinition:
+		return caps.DefinitionProvider != nil && caps.DefinitionProvider.Value != false
+	case MethodTextDocumentReferences:
+		return caps.ReferencesProvider != nil && caps.ReferencesProvider.Value != false
+	case MethodTextDocumentDocumentSymbol:
+		return caps.DocumentSymbolProvider != nil && caps.DocumentSymbolProvider.Value != false
+	case MethodTextDocumentFormatting:
+		return caps.DocumentFormattingProvider != nil && caps.DocumentFormattingProvider.Value != false
+	case MethodTextDocumentSignatureHelp:
+		return caps.SignatureHelpProvider != nil
+	case MethodTextDocumentCodeAction:
+		return caps.CodeActionProvider != nil && caps.CodeActionProvider != false
+	case MethodTextDocumentPullDiagnostics:
+		return caps.DiagnosticProvider != nil && caps.DiagnosticProvider.Value != nil
+	}
+	return false
+}
+
+// register records dynamic registrations and recomputes the effective server
+// capabilities.
+func (c *Client) register(regs []protocol.Registration) {
+	c.mu.Lock()
+	for _, reg := range regs {
+		c.registrations = slices.DeleteFunc(c.registrations, func(r protocol.Registration) bool {
+			return r.ID == reg.ID
+		})
+		c.registrations = append(c.registrations, reg)
+	}
+	c.capabilities = applyRegistrations(c.serverCapabilities, c.registrations)
+	c.mu.Unlock()
+
+	c.syncFileWatcher()
+}
+
+// unregister drops dynamic registrations and recomputes the effective server
+// capabilities.
+func (c *Client) unregister(unregs []protocol.Unregistration) {
+	c.mu.Lock()
+	for _, unreg := range unregs {
+		c.registrations = slices.DeleteFunc(c.registrations, func(r protocol.Registration) bool {
+			return r.ID == unreg.ID
+		})
+	}
+	c.capabilities = applyRegistrations(c.serverCapabilities, c.registrations)
+	c.mu.Unlock()
+
+	c.syncFileWatcher()
+}
+
+// syncFileWatcher updates the workspace file watcher with the currently
+// registered watchers, starting it on the first registration.
+func (c *Client) syncFileWatcher() {
+	if c.disableFileWatching {
+		return
+	}
+
+	watchers := c.FileWatchers()
+
+	c.mu.Lock()
+	w := c.watcher
+	start := false
+	if w == nil && len(watchers) > 0 {
+		roots := c.workspaceRoots()
+		if len(roots) == 0 {
+			c.mu.Unlock()
+			return
+		}
+		w = NewFileWatcher(roots, c.NotifyDidChangeWatchedFiles, FileWatcherOptions{})
+		c.watcher = w
+		start = true
+	}
+	c.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	w.SetWatchers(watchers)
+	if start {
+		if err := w.Start(c.ctx); err != nil {
+			slog.Error("Failed to start file watcher", "server", c.Name, "error", err)
+		}
+	}
+}
+
+// workspaceRoots returns the file system paths of the workspace folders, or
+// of the root URI if there are none.
+func (c *Client) workspaceRoots() []string {
+	uris := make([]string, 0, len(c.workspaceFolders)+1)
+	for _, folder := range c.workspaceFolders {
+		uris = append(uris, folder.URI)
+	}
+	if len(uris) == 0 && c.rootURI != "" {
+		uris = append(uris, c.rootURI)
+	}
+
+	var roots []string
+	for _, uri := range uris {
+		path, err := protocol.DocumentURI(uri).Path()
+		if err != nil {
+			continue
+		}
+		roots = append(roots, path)
+	}
+	return roots
+}
+
+// applyRegistrations layers dynamic registrations on top of the capabilities
+// the server announced during initialization.
+func applyRegistrations(caps protocol.ServerCapabilities, regs []protocol.Registration) protocol.ServerCapabilities {
+	for _, reg := range regs {
+		if err := applyRegistration(&caps, reg); err != nil {
+			slog.Debug("Ignoring registration options", "method", reg.Method, "error", err)
+		}
+	}
+	return caps
+}
+
+// applyRegistration marks the capability behind a registration as supported.
+func applyRegistration(caps *protocol.ServerCapabilities, reg protocol.Registration) error {
+	opts := reg.RegisterOptions
+	if opts == nil {
+		opts = true
+	}
+
+	switch reg.Method {
+	case MethodTextDocumentDidChange:
+		change, err := convertTo[protocol.TextDocumentChangeRegistrationOptions](reg.RegisterOptions)
+		if err != nil {
+			return err
+		}
+		caps.TextDocumentSync = map[string]any{
+			"openClose": true,
+			"change":    float64(change.SyncKind),
+		}
+	case MethodTextDocumentCompletion:
+		completion, err := convertTo[protocol.CompletionOptions](reg.RegisterOptions)
+		if err != nil {
+			return err
+		}
+		caps.CompletionProvider = &completion
+	case MethodTextDocumentSignatureHelp:
+		signature, err := convertTo[protocol.SignatureHelpOptions](reg.RegisterOptions)
+		if err != nil {
+			return err
+		}
+		caps.SignatureHelpProvider = &signature
+	case MethodWorkspaceExecuteCommand:
+		execute, err := convertTo[protocol.ExecuteCommandOptions](reg.RegisterOptions)
+		if err != nil {
+			return err
+		}
+		caps.ExecuteCommandProvider = &execute
+	case MethodTextDocumentHover:
+		caps.HoverProvider = &protocol.Or_ServerCapabilities_hoverProvider{Value: true}
+	case MethodTextDocumentDefinition:
+		caps.DefinitionProvider = &protocol.Or_ServerCapabilities_definitionProvider{Value: true}
+	case MethodTextDocumentReferences:
+		caps.ReferencesProvider = &protocol.Or_ServerCapabilities_referencesProvider{Value: true}
+	case MethodTextDocumentDocumentHighlight:
+		caps.DocumentHighlightProvider = &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true}
+	case MethodTextDocumentDocumentSymbol:
+		caps.DocumentSymbolProvider = &protocol.Or_ServerCapabilities_documentSymbolProvider{Value: true}
+	case MethodTextDocumentFormatting:
+		caps.DocumentFormattingProvider = &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true}
+	case MethodTextDocumentRangeFormatting:
+		caps.DocumentRangeFormattingProvider = &protocol.Or_ServerCapabilities_documentRangeFormattingProvider{Value: true}
+	case MethodWorkspaceSymbol:
+		caps.WorkspaceSymbolProvider = &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true}
+	case MethodTextDocumentCodeAction:
+		caps.CodeActionProvider = opts
+	case MethodTextDocumentRename:
+		caps.RenameProvider = opts
+	case MethodTextDocumentSemanticTokens:
+		caps.SemanticTokensProvider = opts
+	case MethodTextDocumentInlayHint:
+		caps.InlayHintProvider = opts
+	case MethodTextDocumentPullDiagnostics:
+		diagnostic, err := convertTo[protocol.DiagnosticOptions](reg.RegisterOptions)
+		if err != nil {
+			return err
+		}
+		caps.DiagnosticProvider = &protocol.Or_ServerCapabilities_diagnosticProvider{Value: diagnostic}
+	case MethodWorkspaceDidChangeWatchedFiles:
+		// Watchers are exposed through [Client.FileWatchers].
+		if _, err := convertTo[protocol.DidChangeWatchedFilesRegistrationOptions](reg.RegisterOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// convertTo converts a loosely typed JSON value, such as registration
+// options, into T.
+func convertTo[T any](v any) (T, error) {
+	var result T
+	if v == nil {
+		return result, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("failed to unmarshal into %T: %w", result, err)
+	}
+	return result, nil
+}
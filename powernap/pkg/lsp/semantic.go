@@ -0,0 +1,250 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// SemanticToken is a decoded semantic token with an absolute position.
+type SemanticToken struct {
+	Line      uint32
+	StartChar uint32
+	Length    uint32
+	Type      string
+	Modifiers []string
+}
+
+// semanticTokensState holds the last full token set received for a
+// document, which delta responses are applied to.
+type semanticTokensState struct {
+	resultID string
+	data     []uint32
+}
+
+// SemanticTokensLegend returns the legend the server uses to encode semantic
+// tokens, and whether the server supports semantic tokens at all.
+func (c *Client) SemanticTokensLegend() (protocol.SemanticTokensLegend, bool) {
+	opts, ok := c.semanticTokensOptions()
+	return opts.Legend, ok
+}
+
+// RequestSemanticTokensFull requests the semantic tokens of a whole
+// document. The result is remembered so later calls to
+// [Client.RequestSemanticTokensDelta] only need to transfer the changes.
+func (c *Client) RequestSemanticTokensFull(ctx context.Context, uri string) ([]SemanticToken, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	opts, ok := c.semanticTokensOptions()
+	if !ok {
+		return nil, fmt.Errorf("server does not support semantic tokens")
+	}
+
+	params := protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri)},
+	}
+
+	var result *protocol.SemanticTokens
+	err := c.conn.Call(ctx, MethodTextDocumentSemanticTokensFull, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("semantic tokens request failed: %w", err)
+	}
+	if result == nil {
+		c.forgetSemanticTokens(uri)
+		return nil, nil
+	}
+
+	c.storeSemanticTokens(uri, result.ResultID, result.Data)
+	return DecodeSemanticTokens(result.Data, opts.Legend), nil
+}
+
+// RequestSemanticTokensRange requests the semantic tokens of a range of a
+// document. Range results don't affect the state used for delta requests.
+func (c *Client) RequestSemanticTokensRange(ctx context.Context, uri string, rng protocol.Range) ([]SemanticToken, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	opts, ok := c.semanticTokensOptions()
+	if !ok {
+		return nil, fmt.Errorf("server does not support semantic tokens")
+	}
+
+	params := protocol.SemanticTokensRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri)},
+		Range:        rng,
+	}
+
+	var result *protocol.SemanticTokens
+	err := c.conn.Call(ctx, MethodTextDocumentSemanticTokensRange, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("semantic tokens range request failed: %w", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	return DecodeSemanticTokens(result.Data, opts.Legend), nil
+}
+
+// RequestSemanticTokensDelta requests the semantic tokens of a whole
+// document relative to the previous result for that document. It falls back
+// to a full request when there is no previous result or the server doesn't
+// support deltas.
+func (c *Client) RequestSemanticTokensDelta(ctx context.Context, uri string) ([]SemanticToken, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	opts, ok := c.semanticTokensOptions()
+	if !ok {
+		return nil, fmt.Errorf("server does not support semantic tokens")
+	}
+
+	c.mu.RLock()
+	prev, hasPrev := c.semanticTokens[uri]
+	c.mu.RUnlock()
+
+	if !hasPrev || prev.resultID == "" || !supportsSemanticTokensDelta(opts) {
+		return c.RequestSemanticTokensFull(ctx, uri)
+	}
+
+	params := protocol.SemanticTokensDeltaParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri)},
+		PreviousResultID: prev.resultID,
+	}
+
+	// The server may answer with either a delta or a full token set.
+	var result *struct {
+		ResultID string                         `json:"resultId"`
+		Data     *[]uint32                      `json:"data"`
+		Edits    *[]protocol.SemanticTokensEdit `json:"edits"`
+	}
+	err := c.conn.Call(ctx, MethodTextDocumentSemanticTokensDelta, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("semantic tokens delta request failed: %w", err)
+	}
+	if result == nil {
+		c.forgetSemanticTokens(uri)
+		return nil, nil
+	}
+
+	var data []uint32
+	switch {
+	case result.Data != nil:
+		data = *result.Data
+	case result.Edits != nil:
+		data, err = applySemanticTokensEdits(prev.data, *result.Edits)
+		if err != nil {
+			c.forgetSemanticTokens(uri)
+			return nil, err
+		}
+	default:
+		data = prev.data
+	}
+
+	c.storeSemanticTokens(uri, result.ResultID, data)
+	return DecodeSemanticTokens(data, opts.Legend), nil
+}
+
+// DecodeSemanticTokens decodes the relative, packed integer encoding of
+// semantic tokens into absolute tokens using the given legend. Token types
+// and modifiers missing from the legend are left empty.
+func DecodeSemanticTokens(data []uint32, legend protocol.SemanticTokensLegend) []SemanticToken {
+	tokens := make([]SemanticToken, 0, len(data)/5)
+
+	var line, start uint32
+	for i := 0; i+5 <= len(data); i += 5 {
+		deltaLine, deltaStart := data[i], data[i+1]
+		if deltaLine > 0 {
+			start = 0
+		}
+		line += deltaLine
+		start += deltaStart
+
+		token := SemanticToken{
+			Line:      line,
+			StartChar: start,
+			Length:    data[i+2],
+		}
+		if typ := int(data[i+3]); typ < len(legend.TokenTypes) {
+			token.Type = legend.TokenTypes[typ]
+		}
+		for bit, modifier := range legend.TokenModifiers {
+			if bit < 32 && data[i+4]&(1<<bit) != 0 {
+				token.Modifiers = append(token.Modifiers, modifier)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+// applySemanticTokensEdits applies delta edits to a previous token set. All
+// edit offsets refer to the previous data.
+func applySemanticTokensEdits(data []uint32, edits []protocol.SemanticTokensEdit) ([]uint32, error) {
+	edits = slices.Clone(edits)
+	slices.SortStableFunc(edits, func(a, b protocol.SemanticTokensEdit) int {
+		return int(a.Start) - int(b.Start)
+	})
+
+	result := make([]uint32, 0, len(data))
+	var pos uint32
+	for _, edit := range edits {
+		end := edit.Start + edit.DeleteCount
+		if edit.Start < pos || end > uint32(len(data)) { //nolint:gosec
+			return nil, fmt.Errorf("invalid semantic tokens edit: start %d, delete %d, length %d", edit.Start, edit.DeleteCount, len(data))
+		}
+		result = append(result, data[pos:edit.Start]...)
+		result = append(result, edit.Data...)
+		pos = end
+	}
+	result = append(result, data[pos:]...)
+
+	return result, nil
+}
+
+// semanticTokensOptions returns the semantic tokens options of the server.
+func (c *Client) semanticTokensOptions() (protocol.SemanticTokensOptions, bool) {
+	provider := c.GetCapabilities().SemanticTokensProvider
+	if provider == nil {
+		return protocol.SemanticTokensOptions{}, false
+	}
+	opts, err := convertTo[protocol.SemanticTokensOptions](provider)
+	if err != nil {
+		return protocol.SemanticTokensOptions{}, false
+	}
+	return opts, true
+}
+
+// supportsSemanticTokensDelta reports whether the server accepts delta
+// requests for full documents.
+func supportsSemanticTokensDelta(opts protocol.SemanticTokensOptions) bool {
+	if opts.Full == nil {
+		return false
+	}
+	full, ok := opts.Full.Value.(protocol.SemanticTokensFullDelta)
+	return ok && full.Delta
+}
+
+// storeSemanticTokens remembers the latest token set of a document.
+func (c *Client) storeSemanticTokens(uri, resultID string, data []uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.semanticTokens == nil {
+		c.semanticTokens = make(map[string]semanticTokensState)
+	}
+	c.semanticTokens[uri] = semanticTokensState{resultID: resultID, data: data}
+}
+
+// forgetSemanticTokens drops the delta state of a document.
+func (c *Client) forgetSemanticTokens(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.semanticTokens, uri)
+}
@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+var testLegend = protocol.SemanticTokensLegend{
+	TokenTypes:     []string{"keyword", "function", "variable"},
+	TokenModifiers: []string{"declaration", "readonly"},
+}
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	data := []uint32{
+		0, 0, 7, 0, 0, // "package" at 0:0
+		2, 5, 4, 1, 1, // "main" at 2:5, function declaration
+		0, 6, 1, 2, 3, // "x" at 2:11, variable declaration readonly
+		1, 2, 3, 9, 0, // unknown type at 3:2
+	}
+
+	got := DecodeSemanticTokens(data, testLegend)
+	want := []SemanticToken{
+		{Line: 0, StartChar: 0, Length: 7, Type: "keyword"},
+		{Line: 2, StartChar: 5, Length: 4, Type: "function", Modifiers: []string{"declaration"}},
+		{Line: 2, StartChar: 11, Length: 1, Type: "variable", Modifiers: []string{"declaration", "readonly"}},
+		{Line: 3, StartChar: 2, Length: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeSemanticTokens() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestApplySemanticTokensEdits(t *testing.T) {
+	data := []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	got, err := applySemanticTokensEdits(data, []protocol.SemanticTokensEdit{
+		{Start: 5, DeleteCount: 5, Data: []uint32{50, 60}},
+		{Start: 0, DeleteCount: 1},
+		{Start: 2, DeleteCount: 0, Data: []uint32{20}},
+	})
+	if err != nil {
+		t.Fatalf("applySemanticTokensEdits failed: %v", err)
+	}
+	want := []uint32{1, 20, 2, 3, 4, 50, 60}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := applySemanticTokensEdits(data, []protocol.SemanticTokensEdit{{Start: 8, DeleteCount: 5}}); err == nil {
+		t.Error("expected error for out of range edit")
+	}
+}
+
+func TestClient_SemanticTokensDelta(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		SemanticTokensProvider: protocol.SemanticTokensOptions{
+			Legend: testLegend,
+			Full:   &protocol.Or_SemanticTokensOptions_full{Value: protocol.SemanticTokensFullDelta{Delta: true}},
+			Range:  &protocol.Or_SemanticTokensOptions_range{Value: true},
+		},
+	})
+	server.Respond(MethodTextDocumentSemanticTokensFull, protocol.SemanticTokens{
+		ResultID: "1",
+		Data:     []uint32{0, 0, 7, 0, 0, 2, 5, 4, 1, 1},
+	})
+	server.Respond(MethodTextDocumentSemanticTokensDelta, protocol.SemanticTokensDelta{
+		ResultID: "2",
+		Edits:    []protocol.SemanticTokensEdit{{Start: 5, DeleteCount: 0, Data: []uint32{1, 0, 3, 2, 0}}},
+	})
+	server.Respond(MethodTextDocumentSemanticTokensRange, protocol.SemanticTokens{
+		Data: []uint32{2, 5, 4, 1, 0},
+	})
+
+	ctx := t.Context()
+	uri := "file:///tmp/main.go"
+
+	legend, ok := client.SemanticTokensLegend()
+	if !ok || !reflect.DeepEqual(legend, testLegend) {
+		t.Fatalf("unexpected legend: %+v", legend)
+	}
+
+	// Without previous state the delta request falls back to a full request.
+	tokens, err := client.RequestSemanticTokensDelta(ctx, uri)
+	if err != nil {
+		t.Fatalf("RequestSemanticTokensDelta failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %+v", tokens)
+	}
+
+	tokens, err = client.RequestSemanticTokensRange(ctx, uri, protocol.Range{End: protocol.Position{Line: 3}})
+	if err != nil {
+		t.Fatalf("RequestSemanticTokensRange failed: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].Type != "function" || tokens[0].Modifiers != nil {
+		t.Fatalf("unexpected range tokens: %+v", tokens)
+	}
+
+	tokens, err = client.RequestSemanticTokensDelta(ctx, uri)
+	if err != nil {
+		t.Fatalf("RequestSemanticTokensDelta failed: %v", err)
+	}
+	want := []SemanticToken{
+		{Line: 0, StartChar: 0, Length: 7, Type: "keyword"},
+		{Line: 1, StartChar: 0, Length: 3, Type: "variable"},
+		{Line: 3, StartChar: 5, Length: 4, Type: "function", Modifiers: []string{"declaration"}},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("unexpected tokens after delta:\n%+v\nwant\n%+v", tokens, want)
+	}
+
+	deltas := server.ReceivedMethod(MethodTextDocumentSemanticTokensDelta)
+	if len(deltas) != 1 {
+		t.Fatalf("expected 1 delta request, got %d", len(deltas))
+	}
+	var params protocol.SemanticTokensDeltaParams
+	if err := deltas[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.PreviousResultID != "1" {
+		t.Errorf("expected previous result id 1, got %q", params.PreviousResultID)
+	}
+
+	// Closing the document drops the delta state.
+	if err := client.NotifyDidCloseTextDocument(ctx, uri); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RequestSemanticTokensDelta(ctx, uri); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.ReceivedMethod(MethodTextDocumentSemanticTokensFull)); n != 2 {
+		t.Errorf("expected a full request after close, got %d full requests", n)
+	}
+}
+
+func TestClient_SemanticTokensUnsupported(t *testing.T) {
+	client, _ := newTestClient(t, protocol.ServerCapabilities{})
+	if _, ok := client.SemanticTokensLegend(); ok {
+		t.Error("expected no legend")
+	}
+	if _, err := client.RequestSemanticTokensFull(t.Context(), "file:///tmp/main.go"); err == nil {
+		t.Error("expected error when the server lacks semantic tokens")
+	}
+}
@@ -0,0 +1,217 @@
+package lsp
+
+import (
+	"context"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+)
+
+// serveListener serves every connection accepted on l with server.
+func serveListener(t *testing.T, l net.Listener, server *lsptest.Server) {
+	t.Helper()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			server.Serve(t.Context(), conn)
+		}
+	}()
+}
+
+func TestClient_Address(t *testing.T) {
+	l, err := transport.ListenAddress(t.Context(), "tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAddress failed: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	server := lsptest.NewServer(protocol.ServerCapabilities{
+		TextDocumentSync: protocol.Incremental,
+	})
+	serveListener(t, l, server)
+
+	client, err := NewClient(ClientConfig{
+		Address:             transport.ListenerAddress(l),
+		RootURI:             "file:///tmp",
+		DisableFileWatching: true,
+		Reconnect: transport.ReconnectOptions{
+			InitialBackoff: 10 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Exit() })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Initialize(ctx, false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	reconnected := make(chan struct{}, 1)
+	client.OnReconnect(func(context.Context) {
+		reconnected <- struct{}{}
+	})
+	unregister := client.OnReconnect(func(context.Context) {
+		t.Error("unregistered reconnect hook was called")
+	})
+	unregister()
+
+	// A closed manager no longer reopens its documents.
+	stale := NewTextDocumentSyncManager(client)
+	if err := stale.Open("file:///tmp/old.go", "go", "package old\n"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := stale.CloseAll(); err != nil {
+		t.Fatalf("CloseAll failed: %v", err)
+	}
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/main.go"
+	if err := m.Open(uri, "go", "package main\n"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := server.WaitFor(ctx, MethodTextDocumentDidOpen, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop the connection; the client should reconnect and restore the
+	// session.
+	if err := server.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("client did not reconnect")
+	}
+
+	if _, err := server.WaitFor(ctx, MethodInitialized, 2); err != nil {
+		t.Fatal(err)
+	}
+	opens, err := server.WaitFor(ctx, MethodTextDocumentDidOpen, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A round trip makes sure every reopened document reached the server.
+	_, _ = client.RequestHover(ctx, uri, protocol.Position{})
+	if n := len(server.ReceivedMethod(MethodTextDocumentDidOpen)); n != 3 {
+		t.Errorf("expected one document to be reopened, got %d opens", n)
+	}
+	var params protocol.DidOpenTextDocumentParams
+	if err := opens[2].Unmarshal(&params); err != nil {
+		t.Fatalf("failed to decode didOpen: %v", err)
+	}
+	if params.TextDocument.URI != protocol.DocumentURI(uri) || params.TextDocument.Text != "package main\n" {
+		t.Errorf("unexpected reopened document: %+v", params.TextDocument)
+	}
+
+	if !client.IsRunning() {
+		t.Error("expected client to be running after reconnect")
+	}
+	if err := client.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
+
+func TestClient_Listen(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find test binary: %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{
+		Command:             exe,
+		Args:                []string{"-test.run=^TestHelperServer$", "--", "${address}"},
+		Environment:         map[string]string{"GO_WANT_HELPER_SERVER": "1"},
+		Listen:              "tcp://127.0.0.1:0",
+		RootURI:             "file:///tmp",
+		Timeout:             10 * time.Second,
+		DisableFileWatching: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := client.Initialize(ctx, false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	hover, err := client.RequestHover(ctx, "file:///tmp/main.go", protocol.Position{})
+	if err != nil {
+		t.Fatalf("RequestHover failed: %v", err)
+	}
+	if hover == nil || hover.Contents.Value != "helper" {
+		t.Errorf("unexpected hover: %+v", hover)
+	}
+
+	if err := client.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := client.Exit(); err != nil {
+		t.Errorf("Exit failed: %v", err)
+	}
+}
+
+func TestClient_ListenTimeout(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find test binary: %v", err)
+	}
+
+	// The helper exits without connecting when no address is given.
+	_, err = NewClient(ClientConfig{
+		Command:     exe,
+		Args:        []string{"-test.run=^TestHelperServer$", "--"},
+		Environment: map[string]string{"GO_WANT_HELPER_SERVER": "1"},
+		Listen:      "tcp://127.0.0.1:0",
+		Timeout:     10 * time.Second,
+	})
+	if err == nil {
+		t.Fatal("expected error when the server never connects")
+	}
+}
+
+// TestHelperServer is not a real test. It runs an lsptest server that
+// connects to the address given after "--" when started by TestClient_Listen.
+func TestHelperServer(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_SERVER") != "1" {
+		t.Skip("helper process")
+	}
+
+	i := slices.Index(os.Args, "--")
+	if i < 0 || i+1 >= len(os.Args) {
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("tcp", os.Args[i+1])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	server := lsptest.NewServer(protocol.ServerCapabilities{
+		HoverProvider: &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+	})
+	server.Respond(MethodTextDocumentHover, protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.PlainText, Value: "helper"},
+	})
+	server.Serve(context.Background(), conn)
+
+	if _, err := server.WaitFor(context.Background(), MethodExit, 1); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,189 @@
+package lsp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// Symbol is a document or workspace symbol. Servers may answer symbol
+// requests with flat SymbolInformation lists, hierarchical DocumentSymbol
+// trees, or WorkspaceSymbol lists; all of them are normalized into Symbol.
+type Symbol struct {
+	Name          string
+	Detail        string
+	Kind          protocol.SymbolKind
+	Tags          []protocol.SymbolTag
+	Deprecated    bool
+	ContainerName string
+
+	// URI is the document containing the symbol.
+	URI protocol.DocumentURI
+	// Range encloses the whole symbol, including its body.
+	Range protocol.Range
+	// SelectionRange is the range to reveal when the symbol is picked, e.g.
+	// its name. It equals Range when the server doesn't provide one.
+	SelectionRange protocol.Range
+
+	Children []Symbol
+}
+
+// rawSymbol can hold any of the symbol representations servers return.
+type rawSymbol struct {
+	Name           string               `json:"name"`
+	Detail         string               `json:"detail"`
+	Kind           protocol.SymbolKind  `json:"kind"`
+	Tags           []protocol.SymbolTag `json:"tags"`
+	Deprecated     bool                 `json:"deprecated"`
+	ContainerName  string               `json:"containerName"`
+	Range          *protocol.Range      `json:"range"`
+	SelectionRange *protocol.Range      `json:"selectionRange"`
+	Children       []rawSymbol          `json:"children"`
+	Location       *struct {
+		URI   protocol.DocumentURI `json:"uri"`
+		Range *protocol.Range      `json:"range"`
+	} `json:"location"`
+}
+
+// RequestDocumentSymbols requests the symbols of a document as a tree. Flat
+// results are nested by range containment.
+func (c *Client) RequestDocumentSymbols(ctx context.Context, uri string) ([]Symbol, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri)},
+	}
+
+	var result []rawSymbol
+	err := c.conn.Call(ctx, MethodTextDocumentDocumentSymbol, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("document symbol request failed: %w", err)
+	}
+
+	symbols := normalizeSymbols(result, protocol.DocumentURI(uri))
+	if slices.ContainsFunc(result, func(s rawSymbol) bool { return s.Location != nil }) {
+		symbols = nestSymbols(symbols)
+	}
+	return symbols, nil
+}
+
+// RequestWorkspaceSymbols searches the workspace for symbols matching query.
+// The result is a flat list.
+func (c *Client) RequestWorkspaceSymbols(ctx context.Context, query string) ([]Symbol, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	params := protocol.WorkspaceSymbolParams{
+		Query: query,
+	}
+
+	var result []rawSymbol
+	err := c.conn.Call(ctx, MethodWorkspaceSymbol, params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("workspace symbol request failed: %w", err)
+	}
+
+	return normalizeSymbols(result, ""), nil
+}
+
+// WalkSymbols calls fn for every symbol in the tree in depth-first order,
+// with the depth of the symbol. Returning false skips the children of the
+// symbol.
+func WalkSymbols(symbols []Symbol, fn func(sym Symbol, depth int) bool) {
+	walkSymbols(symbols, 0, fn)
+}
+
+func walkSymbols(symbols []Symbol, depth int, fn func(Symbol, int) bool) {
+	for _, sym := range symbols {
+		if fn(sym, depth) {
+			walkSymbols(sym.Children, depth+1, fn)
+		}
+	}
+}
+
+// normalizeSymbols converts raw symbols into [Symbol]s, using uri for
+// symbols that don't carry their own location.
+func normalizeSymbols(raw []rawSymbol, uri protocol.DocumentURI) []Symbol {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	symbols := make([]Symbol, 0, len(raw))
+	for _, r := range raw {
+		sym := Symbol{
+			Name:          r.Name,
+			Detail:        r.Detail,
+			Kind:          r.Kind,
+			Tags:          r.Tags,
+			Deprecated:    r.Deprecated,
+			ContainerName: r.ContainerName,
+			URI:           uri,
+		}
+		if r.Range != nil {
+			sym.Range = *r.Range
+		}
+		if r.Location != nil {
+			sym.URI = r.Location.URI
+			if r.Location.Range != nil {
+				sym.Range = *r.Location.Range
+			}
+		}
+		sym.SelectionRange = sym.Range
+		if r.SelectionRange != nil {
+			sym.SelectionRange = *r.SelectionRange
+		}
+		sym.Children = normalizeSymbols(r.Children, sym.URI)
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+// nestSymbols builds a tree out of a flat symbol list, making each symbol a
+// child of the innermost symbol whose range contains it.
+func nestSymbols(flat []Symbol) []Symbol {
+	sorted := slices.Clone(flat)
+	slices.SortStableFunc(sorted, func(a, b Symbol) int {
+		if c := comparePosition(a.Range.Start, b.Range.Start); c != 0 {
+			return c
+		}
+		// Enclosing symbols come before the symbols they contain.
+		return comparePosition(b.Range.End, a.Range.End)
+	})
+
+	var roots []Symbol
+	var stack []*Symbol
+	for _, sym := range sorted {
+		for len(stack) > 0 && !containsRange(stack[len(stack)-1].Range, sym.Range) {
+			stack = stack[:len(stack)-1]
+		}
+
+		var siblings *[]Symbol
+		if len(stack) == 0 {
+			siblings = &roots
+		} else {
+			siblings = &stack[len(stack)-1].Children
+		}
+		*siblings = append(*siblings, sym)
+		stack = append(stack, &(*siblings)[len(*siblings)-1])
+	}
+	return roots
+}
+
+// containsRange reports whether outer contains inner.
+func containsRange(outer, inner protocol.Range) bool {
+	return comparePosition(outer.Start, inner.Start) <= 0 && comparePosition(inner.End, outer.End) <= 0
+}
+
+// comparePosition orders positions by line, then character.
+func comparePosition(a, b protocol.Position) int {
+	if c := cmp.Compare(a.Line, b.Line); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Character, b.Character)
+}
@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestClient_DocumentSymbolsHierarchical(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+	server.Respond(MethodTextDocumentDocumentSymbol, []protocol.DocumentSymbol{
+		{
+			Name:           "Server",
+			Kind:           protocol.Struct,
+			Range:          lineRange(2, 10),
+			SelectionRange: lineRange(2, 2),
+			Children: []protocol.DocumentSymbol{
+				{Name: "addr", Kind: protocol.Field, Range: lineRange(3, 3), SelectionRange: lineRange(3, 3)},
+			},
+		},
+		{Name: "main", Kind: protocol.Function, Detail: "func()", Range: lineRange(12, 14), SelectionRange: lineRange(12, 12)},
+	})
+
+	symbols, err := client.RequestDocumentSymbols(t.Context(), "file:///tmp/main.go")
+	if err != nil {
+		t.Fatalf("RequestDocumentSymbols failed: %v", err)
+	}
+
+	if got, want := outline(symbols), "Server\n  addr\nmain\n"; got != want {
+		t.Errorf("unexpected outline:\n%s\nwant:\n%s", got, want)
+	}
+	if symbols[0].URI != "file:///tmp/main.go" || symbols[0].Children[0].URI != "file:///tmp/main.go" {
+		t.Errorf("expected symbols to carry the document URI, got %q", symbols[0].URI)
+	}
+	if symbols[0].SelectionRange != lineRange(2, 2) || symbols[1].Detail != "func()" {
+		t.Errorf("unexpected symbol details: %+v", symbols)
+	}
+}
+
+func TestClient_DocumentSymbolsFlat(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+	uri := protocol.DocumentURI("file:///tmp/main.go")
+	server.Respond(MethodTextDocumentDocumentSymbol, []protocol.SymbolInformation{
+		{Name: "main", Kind: protocol.Function, Location: protocol.Location{URI: uri, Range: lineRange(12, 14)}},
+		{Name: "addr", Kind: protocol.Field, ContainerName: "Server", Location: protocol.Location{URI: uri, Range: lineRange(3, 3)}},
+		{Name: "Server", Kind: protocol.Struct, Location: protocol.Location{URI: uri, Range: lineRange(2, 10)}},
+		{Name: "Start", Kind: protocol.Method, ContainerName: "Server", Location: protocol.Location{URI: uri, Range: lineRange(5, 9)}},
+		{Name: "err", Kind: protocol.Variable, Location: protocol.Location{URI: uri, Range: lineRange(6, 6)}},
+	})
+
+	symbols, err := client.RequestDocumentSymbols(t.Context(), string(uri))
+	if err != nil {
+		t.Fatalf("RequestDocumentSymbols failed: %v", err)
+	}
+
+	want := "Server\n  addr\n  Start\n    err\nmain\n"
+	if got := outline(symbols); got != want {
+		t.Errorf("unexpected outline:\n%s\nwant:\n%s", got, want)
+	}
+	if symbols[0].Children[0].ContainerName != "Server" {
+		t.Errorf("expected container name to be kept, got %q", symbols[0].Children[0].ContainerName)
+	}
+}
+
+func TestClient_WorkspaceSymbols(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{})
+	server.Respond(MethodWorkspaceSymbol, []any{
+		protocol.SymbolInformation{
+			Name:     "Server",
+			Kind:     protocol.Struct,
+			Location: protocol.Location{URI: "file:///tmp/server.go", Range: lineRange(2, 10)},
+		},
+		map[string]any{
+			"name":     "Client",
+			"kind":     protocol.Struct,
+			"location": map[string]any{"uri": "file:///tmp/client.go"},
+		},
+	})
+
+	symbols, err := client.RequestWorkspaceSymbols(t.Context(), "er")
+	if err != nil {
+		t.Fatalf("RequestWorkspaceSymbols failed: %v", err)
+	}
+	if len(symbols) != 2 {
+		t.Fatalf("expected 2 symbols, got %+v", symbols)
+	}
+	if symbols[0].URI != "file:///tmp/server.go" || symbols[0].Range != lineRange(2, 10) {
+		t.Errorf("unexpected first symbol: %+v", symbols[0])
+	}
+	if symbols[1].URI != "file:///tmp/client.go" || symbols[1].Range != (protocol.Range{}) {
+		t.Errorf("unexpected second symbol: %+v", symbols[1])
+	}
+
+	var params protocol.WorkspaceSymbolParams
+	if err := server.ReceivedMethod(MethodWorkspaceSymbol)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Query != "er" {
+		t.Errorf("expected query %q, got %q", "er", params.Query)
+	}
+}
+
+// lineRange returns a range spanning whole lines.
+func lineRange(start, end uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: start},
+		End:   protocol.Position{Line: end, Character: 80},
+	}
+}
+
+// outline renders a symbol tree as indented names.
+func outline(symbols []Symbol) string {
+	var sb strings.Builder
+	WalkSymbols(symbols, func(sym Symbol, depth int) bool {
+		sb.WriteString(strings.Repeat("  ", depth) + sym.Name + "\n")
+		return true
+	})
+	return sb.String()
+}
@@ -2,9 +2,13 @@ package lsp
 
 import (
 	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
 )
@@ -12,8 +16,11 @@ import (
 // TextDocumentSyncManager manages text document synchronization with the language server.
 type TextDocumentSyncManager struct {
 	client    *Client
+	mu        sync.Mutex
 	documents map[string]*Document
 	syncKind  protocol.TextDocumentSyncKind
+
+	stopReopen func() // unregisters the reconnect hook
 }
 
 // Document represents an open text document.
@@ -29,7 +36,7 @@ func NewTextDocumentSyncManager(client *Client) *TextDocumentSyncManager {
 	syncKind := protocol.Full // Default to full sync
 
 	// Extract sync kind from capabilities
-	switch v := client.capabilities.TextDocumentSync.(type) {
+	switch v := client.GetCapabilities().TextDocumentSync.(type) {
 	case float64:
 		syncKind = protocol.TextDocumentSyncKind(int(v)) //nolint:gosec
 	case int:
@@ -43,15 +50,33 @@ func NewTextDocumentSyncManager(client *Client) *TextDocumentSyncManager {
 		syncKind = v.Change
 	}
 
-	return &TextDocumentSyncManager{
+	m := &TextDocumentSyncManager{
 		client:    client,
 		documents: make(map[string]*Document),
 		syncKind:  syncKind,
 	}
+	m.stopReopen = client.OnReconnect(m.reopen)
+	return m
+}
+
+// reopen opens all documents again after the client reconnected to a server
+// that doesn't know about them.
+func (m *TextDocumentSyncManager) reopen(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range m.documents {
+		if err := m.client.NotifyDidOpenTextDocument(ctx, doc.URI, doc.LanguageID, doc.Version, doc.Content); err != nil {
+			slog.Error("Failed to reopen document", "server", m.client.Name, "uri", doc.URI, "error", err)
+		}
+	}
 }
 
 // Open opens a new text document.
 func (m *TextDocumentSyncManager) Open(uri, languageID, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if _, exists := m.documents[uri]; exists {
 		return fmt.Errorf("document already open: %s", uri)
 	}
@@ -70,6 +95,9 @@ func (m *TextDocumentSyncManager) Open(uri, languageID, content string) error {
 
 // Change applies changes to an open document.
 func (m *TextDocumentSyncManager) Change(uri string, changes []protocol.TextDocumentContentChangeEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	doc, exists := m.documents[uri]
 	if !exists {
 		return fmt.Errorf("document not open: %s", uri)
@@ -101,11 +129,15 @@ func (m *TextDocumentSyncManager) Change(uri string, changes []protocol.TextDocu
 
 // Close closes a text document.
 func (m *TextDocumentSyncManager) Close(uri string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if _, exists := m.documents[uri]; !exists {
 		return fmt.Errorf("document not open: %s", uri)
 	}
 
 	delete(m.documents, uri)
+	m.client.forgetSemanticTokens(uri)
 
 	// Send didClose notification
 	params := map[string]any{
@@ -117,8 +149,32 @@ func (m *TextDocumentSyncManager) Close(uri string) error {
 	return m.client.conn.Notify(m.client.ctx, MethodTextDocumentDidClose, params) //nolint:wrapcheck
 }
 
+// CloseAll closes every open document and stops reopening documents when
+// the client reconnects. The manager must not be used afterwards.
+func (m *TextDocumentSyncManager) CloseAll() error {
+	m.stopReopen()
+
+	m.mu.Lock()
+	uris := make([]string, 0, len(m.documents))
+	for uri := range m.documents {
+		uris = append(uris, uri)
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, uri := range uris {
+		if err := m.Close(uri); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Save notifies the server that a document was saved.
 func (m *TextDocumentSyncManager) Save(uri string, includeText bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	doc, exists := m.documents[uri]
 	if !exists {
 		return fmt.Errorf("document not open: %s", uri)
@@ -139,6 +195,9 @@ func (m *TextDocumentSyncManager) Save(uri string, includeText bool) error {
 
 // GetDocument returns the document for the given URI.
 func (m *TextDocumentSyncManager) GetDocument(uri string) (*Document, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	doc, exists := m.documents[uri]
 	return doc, exists
 }
@@ -163,33 +222,14 @@ func (m *TextDocumentSyncManager) applyIncrementalChange(doc *Document, change p
 		return nil
 	}
 
-	// Convert content to lines for easier manipulation
-	lines := strings.Split(doc.Content, "\n")
-	lineC := uint32(len(lines)) //nolint:gosec
-
-	// Validate range
-	if partial.Range.Start.Line >= lineC {
-		return fmt.Errorf("invalid start line: %d", partial.Range.Start.Line)
-	}
-	if partial.Range.End.Line >= lineC {
-		return fmt.Errorf("invalid end line: %d", partial.Range.End.Line)
-	}
-
-	// Calculate the start and end positions in the document
-	var startPos uint32
-	for i := uint32(0); i < partial.Range.Start.Line; i++ {
-		startPos += uint32(len(lines[i])) + 1 //nolint:gosec
-	}
-	startPos += partial.Range.Start.Character
-
-	var endPos uint32
-	for i := uint32(0); i < partial.Range.End.Line; i++ {
-		endPos += uint32(len(lines[i])) + 1 //nolint:gosec
+	mapper := NewPositionMapper(doc.Content, m.client.OffsetEncoding())
+	start, end, err := mapper.Offsets(*partial.Range)
+	if err != nil {
+		return fmt.Errorf("invalid change: %w", err)
 	}
-	endPos += partial.Range.End.Character
 
 	// Apply the partial
-	newContent := doc.Content[:startPos] + partial.Text + doc.Content[endPos:]
+	newContent := doc.Content[:start] + partial.Text + doc.Content[end:]
 	doc.Content = newContent
 
 	return nil
@@ -0,0 +1,183 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestTextDocumentSyncManager_Incremental(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		TextDocumentSync: protocol.Incremental,
+	})
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/main.go"
+	if err := m.Open(uri, "go", "package main\n\nfunc main() {}\n"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	change := []protocol.TextDocumentContentChangeEvent{{
+		Value: protocol.TextDocumentContentChangePartial{
+			Range: &protocol.Range{
+				Start: protocol.Position{Line: 2, Character: 5},
+				End:   protocol.Position{Line: 2, Character: 9},
+			},
+			Text: "run",
+		},
+	}}
+	if err := m.Change(uri, change); err != nil {
+		t.Fatalf("Change failed: %v", err)
+	}
+	if err := m.Save(uri, false); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := m.Close(uri); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := server.WaitFor(ctx, MethodTextDocumentDidClose, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var methods []string
+	for _, msg := range server.Received() {
+		methods = append(methods, msg.Method)
+	}
+	want := []string{
+		MethodInitialize,
+		MethodInitialized,
+		MethodTextDocumentDidOpen,
+		MethodTextDocumentDidChange,
+		MethodTextDocumentDidSave,
+		MethodTextDocumentDidClose,
+	}
+	if len(methods) != len(want) {
+		t.Fatalf("expected messages %v, got %v", want, methods)
+	}
+	for i := range want {
+		if methods[i] != want[i] {
+			t.Fatalf("expected messages %v, got %v", want, methods)
+		}
+	}
+
+	var params struct {
+		TextDocument   protocol.VersionedTextDocumentIdentifier `json:"textDocument"`
+		ContentChanges []struct {
+			Range *protocol.Range `json:"range"`
+			Text  string          `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if err := server.ReceivedMethod(MethodTextDocumentDidChange)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.TextDocument.Version != 2 {
+		t.Errorf("expected version 2, got %d", params.TextDocument.Version)
+	}
+	if len(params.ContentChanges) != 1 || params.ContentChanges[0].Range == nil || params.ContentChanges[0].Text != "run" {
+		t.Errorf("unexpected content changes: %+v", params.ContentChanges)
+	}
+}
+
+func TestTextDocumentSyncManager_Full(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		TextDocumentSync: map[string]any{"openClose": true, "change": protocol.Full},
+	})
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/notes.txt"
+	if err := m.Open(uri, "plaintext", "hello"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := m.Change(uri, CreateFullDocumentChange("hello, world")); err != nil {
+		t.Fatalf("Change failed: %v", err)
+	}
+
+	doc, ok := m.GetDocument(uri)
+	if !ok || doc.Content != "hello, world" || doc.Version != 2 {
+		t.Errorf("unexpected document state: %+v", doc)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msgs, err := server.WaitFor(ctx, MethodTextDocumentDidChange, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var params struct {
+		ContentChanges []map[string]any `json:"contentChanges"`
+	}
+	if err := msgs[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if len(params.ContentChanges) != 1 || params.ContentChanges[0]["text"] != "hello, world" {
+		t.Fatalf("unexpected content changes: %+v", params.ContentChanges)
+	}
+	if _, ok := params.ContentChanges[0]["range"]; ok {
+		t.Errorf("expected whole document change, got %+v", params.ContentChanges[0])
+	}
+}
+
+func TestTextDocumentSyncManager_None(t *testing.T) {
+	client, server := newTestClient(t, protocol.ServerCapabilities{
+		TextDocumentSync: protocol.None,
+	})
+
+	m := NewTextDocumentSyncManager(client)
+	uri := "file:///tmp/main.go"
+	if err := m.Open(uri, "go", "package main\n"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := m.Change(uri, CreateFullDocumentChange("package other\n")); err != nil {
+		t.Fatalf("Change failed: %v", err)
+	}
+	if err := m.Close(uri); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := server.WaitFor(ctx, MethodTextDocumentDidClose, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.ReceivedMethod(MethodTextDocumentDidChange)); n != 0 {
+		t.Errorf("expected no didChange notifications, got %d", n)
+	}
+}
+
+// newTestClient returns an initialized client connected to an in-process
+// server announcing the given capabilities.
+func newTestClient(t *testing.T, caps protocol.ServerCapabilities) (*Client, *lsptest.Server) {
+	t.Helper()
+
+	server := lsptest.NewServer(caps)
+	stream, err := server.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{
+		Command:             "lsptest",
+		RootURI:             "file:///tmp",
+		Stream:              stream,
+		DisableFileWatching: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Exit()
+		_ = server.Close()
+	})
+
+	if err := client.Initialize(t.Context(), false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return client, server
+}
@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+)
+
+func TestClient_SetTrace(t *testing.T) {
+	server := lsptest.NewServer(protocol.ServerCapabilities{})
+	stream, err := server.Dial(t.Context())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	tracer := transport.NewTracer(&buf, transport.TraceText, transport.TraceMessages)
+	client, err := NewClient(ClientConfig{
+		Command:             "lsptest",
+		RootURI:             "file:///tmp",
+		Stream:              stream,
+		Tracer:              tracer,
+		DisableFileWatching: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Exit()
+		_ = server.Close()
+	})
+
+	if err := client.Initialize(t.Context(), false); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	var init protocol.InitializeParams
+	if err := server.ReceivedMethod(MethodInitialize)[0].Unmarshal(&init); err != nil {
+		t.Fatalf("failed to decode initialize: %v", err)
+	}
+	if init.Trace == nil || *init.Trace != protocol.Messages {
+		t.Errorf("expected initial trace %q, got %v", protocol.Messages, init.Trace)
+	}
+	if !strings.Contains(buf.String(), "Sending request 'initialize - (1)'.") {
+		t.Errorf("expected initialize in trace, got:\n%s", buf.String())
+	}
+
+	if err := client.SetTrace(t.Context(), protocol.Off); err != nil {
+		t.Fatalf("SetTrace failed: %v", err)
+	}
+	msgs, err := server.WaitFor(t.Context(), MethodSetTrace, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params protocol.SetTraceParams
+	if err := msgs[0].Unmarshal(&params); err != nil {
+		t.Fatalf("failed to decode $/setTrace: %v", err)
+	}
+	if params.Value != protocol.Off {
+		t.Errorf("expected trace value %q, got %q", protocol.Off, params.Value)
+	}
+	if tracer.Level() != transport.TraceOff {
+		t.Errorf("expected tracer to be off, got %q", tracer.Level())
+	}
+
+	// Logs from the server are dropped while tracing is off.
+	before := buf.Len()
+	if err := server.Notify(t.Context(), MethodLogTrace, protocol.LogTraceParams{Message: "hidden"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if _, err := client.RequestHover(t.Context(), "file:///tmp/main.go", protocol.Position{}); err == nil {
+		t.Fatal("expected hover to fail")
+	}
+	if buf.Len() != before {
+		t.Errorf("expected no trace output, got:\n%s", buf.String()[before:])
+	}
+}
@@ -2,6 +2,8 @@ package lsp
 
 import (
 	"context"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
@@ -29,12 +31,25 @@ type Client struct {
 	cancel           context.CancelFunc
 	initialized      bool
 	shutdown         bool
-	capabilities     protocol.ServerCapabilities
 	offsetEncoding   OffsetEncoding
 	rootURI          string
 	workspaceFolders []protocol.WorkspaceFolder
 	config           map[string]any
 	initOptions      map[string]any
+	progress         *ProgressTracker
+
+	disableFileWatching bool
+	enableSnippets      bool
+
+	mu                    sync.RWMutex
+	serverCapabilities    protocol.ServerCapabilities // as announced in the initialize result
+	capabilities          protocol.ServerCapabilities // including dynamic registrations
+	registrations         []protocol.Registration
+	messageRequestHandler MessageRequestHandler
+	watcher               *FileWatcher
+	semanticTokens        map[string]semanticTokensState // by document URI
+	reconnectHooks        []*func(ctx context.Context)
+	inlayHintRefreshHooks []func(ctx context.Context)
 }
 
 // ClientConfig represents the configuration for creating a new LSP client.
@@ -47,4 +62,33 @@ type ClientConfig struct {
 	Settings         map[string]any
 	Environment      map[string]string
 	Timeout          time.Duration
+
+	// Stream, if set, is used to talk to the language server instead of
+	// starting Command. This is mostly useful for in-process servers, such
+	// as the ones provided by the lsptest package.
+	Stream io.ReadWriteCloser
+
+	// Address, if set, connects to a language server that is already
+	// listening on a socket, such as tcp://127.0.0.1:9000 or
+	// unix:///run/gopls.sock, instead of starting Command. The connection is
+	// re-established according to Reconnect when it drops.
+	Address string
+
+	// Listen, if set, makes the client listen on the given socket address
+	// and start Command so that it connects back. The placeholders
+	// ${host}, ${port}, and ${address} in Args are replaced with the host,
+	// port, and address (host:port or socket path) of the listener, which
+	// allows listening on port 0.
+	Listen string
+
+	// Reconnect controls reconnecting to servers reached through Address.
+	Reconnect transport.ReconnectOptions
+
+	// Tracer, if set, logs the JSON-RPC traffic with the server. Its level
+	// is also sent to the server as the initial trace setting.
+	Tracer *transport.Tracer
+
+	// DisableFileWatching stops the client from watching the workspace for
+	// the files the server registers interest in.
+	DisableFileWatching bool
 }
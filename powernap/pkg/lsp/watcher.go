@@ -0,0 +1,494 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Default file watcher timings.
+const (
+	DefaultWatchDebounce     = 100 * time.Millisecond
+	DefaultWatchPollInterval = 2 * time.Second
+)
+
+// FileWatcherOptions configures a [FileWatcher].
+type FileWatcherOptions struct {
+	// Debounce is how long the watcher waits for more changes before sending
+	// a batch. Defaults to [DefaultWatchDebounce].
+	Debounce time.Duration
+	// PollInterval is how often the workspace is scanned when native file
+	// notifications are unavailable. Defaults to [DefaultWatchPollInterval].
+	PollInterval time.Duration
+	// ForcePolling disables native file notifications.
+	ForcePolling bool
+}
+
+// FileWatcher watches workspace directories and reports changes matching the
+// server's registered [protocol.FileSystemWatcher] patterns. Paths ignored by
+// .gitignore files are skipped.
+type FileWatcher struct {
+	roots  []string
+	notify func(context.Context, []protocol.FileEvent) error
+	opts   FileWatcherOptions
+
+	ignores map[string]*ignoreMatcher
+
+	mu       sync.Mutex
+	watchers []compiledWatcher
+	pending  map[string]protocol.FileChangeType
+	timer    *time.Timer
+	ctx      context.Context
+
+	fsw       *fsnotify.Watcher
+	watched   map[string]bool      // non-ignored paths seen natively, true for directories
+	snapshot  map[string]fileState // used when polling
+	started   bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// compiledWatcher is a file system watcher with its glob pattern compiled.
+type compiledWatcher struct {
+	re   *regexp.Regexp
+	base string // empty for patterns matched against absolute paths
+	kind protocol.WatchKind
+}
+
+// fileState is the state of a file recorded while polling.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// NewFileWatcher creates a watcher over the given root directories. Batches
+// of matching changes are passed to notify.
+func NewFileWatcher(roots []string, notify func(context.Context, []protocol.FileEvent) error, opts FileWatcherOptions) *FileWatcher {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultWatchDebounce
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultWatchPollInterval
+	}
+
+	w := &FileWatcher{
+		notify:  notify,
+		opts:    opts,
+		ignores: make(map[string]*ignoreMatcher),
+		pending: make(map[string]protocol.FileChangeType),
+		watched: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if _, exists := w.ignores[root]; exists {
+			continue
+		}
+		w.roots = append(w.roots, root)
+		w.ignores[root] = newIgnoreMatcher(root)
+	}
+	return w
+}
+
+// SetWatchers replaces the patterns changes are matched against. Invalid
+// patterns are skipped.
+func (w *FileWatcher) SetWatchers(watchers []protocol.FileSystemWatcher) {
+	compiled := make([]compiledWatcher, 0, len(watchers))
+	for _, fw := range watchers {
+		pattern, err := fw.GlobPattern.AsPattern()
+		if err != nil {
+			slog.Debug("Skipping file watcher", "error", err)
+			continue
+		}
+		re, err := compileGlob(pattern.GetPattern())
+		if err != nil {
+			slog.Debug("Skipping file watcher", "pattern", pattern.GetPattern(), "error", err)
+			continue
+		}
+		kind := protocol.WatchCreate | protocol.WatchChange | protocol.WatchDelete
+		if fw.Kind != nil {
+			kind = *fw.Kind
+		}
+		compiled = append(compiled, compiledWatcher{
+			re:   re,
+			base: pattern.GetBasePath(),
+			kind: kind,
+		})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watchers = compiled
+}
+
+// Start starts watching the workspace until ctx is done or the watcher is
+// closed. The initial scan of the workspace happens in the background.
+func (w *FileWatcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return errors.New("file watcher already started")
+	}
+	w.started = true
+	w.ctx = ctx
+	w.mu.Unlock()
+
+	if !w.opts.ForcePolling {
+		fsw, err := fsnotify.NewWatcher()
+		if err == nil {
+			w.fsw = fsw
+			go w.watch(ctx)
+			return nil
+		}
+		slog.Debug("Native file watching unavailable, polling instead", "error", err)
+	}
+
+	go w.poll(ctx)
+	return nil
+}
+
+// Close stops the watcher. Pending changes are discarded.
+func (w *FileWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+
+		w.mu.Lock()
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.mu.Unlock()
+
+		if w.fsw != nil {
+			err = w.fsw.Close()
+		}
+	})
+	return err //nolint:wrapcheck
+}
+
+// watch runs the fsnotify event loop, falling back to polling if the
+// workspace can't be watched natively.
+func (w *FileWatcher) watch(ctx context.Context) {
+	for _, root := range w.roots {
+		if err := w.addTree(root, false); err != nil {
+			slog.Debug("Native file watching failed, polling instead", "root", root, "error", err)
+			_ = w.fsw.Close()
+			w.poll(ctx)
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+			return
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(ev)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			slog.Debug("File watcher error", "error", err)
+		}
+	}
+}
+
+// handleEvent translates an fsnotify event into a file change. Removing or
+// renaming a directory deletes everything that was under it.
+func (w *FileWatcher) handleEvent(ev fsnotify.Event) {
+	path := filepath.Clean(ev.Name)
+
+	switch {
+	case ev.Has(fsnotify.Create):
+		info, err := os.Lstat(path)
+		if err != nil {
+			return
+		}
+		if w.ignored(path, info.IsDir()) {
+			return
+		}
+		w.watched[path] = info.IsDir()
+		w.enqueue(path, protocol.Created)
+		if info.IsDir() {
+			// Files may have been created before the directory was watched.
+			if err := w.addTree(path, true); err != nil {
+				slog.Debug("Failed to watch directory", "path", path, "error", err)
+			}
+		}
+	case ev.Has(fsnotify.Write):
+		if w.ignored(path, false) {
+			return
+		}
+		w.watched[path] = false
+		w.enqueue(path, protocol.Changed)
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		// Paths that were never seen are ignored.
+		isDir, ok := w.watched[path]
+		if !ok || w.ignored(path, isDir) {
+			return
+		}
+		if isDir {
+			prefix := path + string(filepath.Separator)
+			for p := range w.watched {
+				if strings.HasPrefix(p, prefix) {
+					delete(w.watched, p)
+					w.enqueue(p, protocol.Deleted)
+				}
+			}
+		}
+		delete(w.watched, path)
+		w.enqueue(path, protocol.Deleted)
+	}
+
+	if filepath.Base(path) == ".gitignore" {
+		if m := w.ignoreFor(path); m != nil {
+			m.load(filepath.Dir(path))
+		}
+	}
+}
+
+// addTree watches dir and all of its non-ignored subdirectories. When report
+// is set, files found along the way are reported as created.
+func (w *FileWatcher) addTree(dir string, report bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error { //nolint:wrapcheck
+		if err != nil {
+			// Skip entries that vanished or can't be read.
+			return nil
+		}
+		if path != dir && w.ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		w.watched[path] = d.IsDir()
+		if !d.IsDir() {
+			if report {
+				w.enqueue(path, protocol.Created)
+			}
+			return nil
+		}
+		if path != dir && report {
+			w.enqueue(path, protocol.Created)
+		}
+		if m := w.ignoreFor(path); m != nil {
+			m.load(path)
+		}
+		return w.fsw.Add(path) //nolint:wrapcheck
+	})
+}
+
+// poll periodically scans the workspace and reports differences between
+// scans.
+func (w *FileWatcher) poll(ctx context.Context) {
+	w.snapshot = w.scan()
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+			return
+		case <-w.done:
+			return
+		case <-ticker.C:
+			current := w.scan()
+			for path, state := range current {
+				prev, existed := w.snapshot[path]
+				switch {
+				case !existed:
+					w.enqueue(path, protocol.Created)
+				case !state.isDir && (!prev.modTime.Equal(state.modTime) || prev.size != state.size):
+					w.enqueue(path, protocol.Changed)
+				}
+			}
+			for path := range w.snapshot {
+				if _, exists := current[path]; !exists {
+					w.enqueue(path, protocol.Deleted)
+				}
+			}
+			w.snapshot = current
+		}
+	}
+}
+
+// scan records the state of every non-ignored file in the workspace.
+func (w *FileWatcher) scan() map[string]fileState {
+	states := make(map[string]fileState)
+	for _, root := range w.roots {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != root && w.ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if m := w.ignoreFor(path); m != nil {
+					m.load(path)
+				}
+				if path == root {
+					return nil
+				}
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			states[path] = fileState{
+				modTime: info.ModTime(),
+				size:    info.Size(),
+				isDir:   d.IsDir(),
+			}
+			return nil
+		})
+	}
+	return states
+}
+
+// enqueue records a change and (re)arms the debounce timer. Consecutive
+// changes to the same path are coalesced.
+func (w *FileWatcher) enqueue(path string, change protocol.FileChangeType) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.matches(path, change) {
+		return
+	}
+
+	prev, exists := w.pending[path]
+	switch {
+	case !exists:
+		w.pending[path] = change
+	case prev == protocol.Created && change == protocol.Changed:
+		// Still a creation as far as the server is concerned.
+	case prev == protocol.Created && change == protocol.Deleted:
+		delete(w.pending, path)
+	case prev == protocol.Deleted && change == protocol.Created:
+		w.pending[path] = protocol.Changed
+	default:
+		w.pending[path] = change
+	}
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.opts.Debounce, w.flush)
+	} else {
+		w.timer.Reset(w.opts.Debounce)
+	}
+}
+
+// flush sends all pending changes to the server.
+func (w *FileWatcher) flush() {
+	w.mu.Lock()
+	if len(w.pending) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	events := make([]protocol.FileEvent, 0, len(w.pending))
+	for path, change := range w.pending {
+		events = append(events, protocol.FileEvent{
+			URI:  protocol.URIFromPath(path),
+			Type: change,
+		})
+	}
+	clear(w.pending)
+	ctx := w.ctx
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	slices.SortFunc(events, func(a, b protocol.FileEvent) int {
+		return strings.Compare(string(a.URI), string(b.URI))
+	})
+	if err := w.notify(ctx, events); err != nil {
+		slog.Debug("Failed to send watched file changes", "error", err)
+	}
+}
+
+// matches reports whether any registered watcher is interested in the change.
+// It must be called with w.mu held.
+func (w *FileWatcher) matches(path string, change protocol.FileChangeType) bool {
+	var kind protocol.WatchKind
+	switch change {
+	case protocol.Created:
+		kind = protocol.WatchCreate
+	case protocol.Changed:
+		kind = protocol.WatchChange
+	case protocol.Deleted:
+		kind = protocol.WatchDelete
+	}
+
+	abs := filepath.ToSlash(path)
+	for _, cw := range w.watchers {
+		if cw.kind&kind == 0 {
+			continue
+		}
+		if cw.base != "" {
+			rel, err := filepath.Rel(cw.base, path)
+			if err == nil && !strings.HasPrefix(rel, "..") && cw.re.MatchString(filepath.ToSlash(rel)) {
+				return true
+			}
+			continue
+		}
+		if cw.re.MatchString(abs) {
+			return true
+		}
+		// Also accept patterns written relative to a workspace root.
+		for _, root := range w.roots {
+			if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+				if cw.re.MatchString(filepath.ToSlash(rel)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ignored reports whether path is ignored by the .gitignore files of its
+// workspace root.
+func (w *FileWatcher) ignored(path string, isDir bool) bool {
+	m := w.ignoreFor(path)
+	return m != nil && m.ignored(path, isDir)
+}
+
+// ignoreFor returns the ignore matcher of the root containing path.
+func (w *FileWatcher) ignoreFor(path string) *ignoreMatcher {
+	var best *ignoreMatcher
+	for _, root := range w.roots {
+		if path != root && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			continue
+		}
+		if best == nil || len(root) > len(best.root) {
+			best = w.ignores[root]
+		}
+	}
+	return best
+}
@@ -0,0 +1,287 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/*.go", "/home/user/project/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "main.gox", false},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.{go,mod}", "go.mod", true},
+		{"**/*.{go,mod}", "go.sum", false},
+		{"src/**/test?.ts", "src/a/b/test1.ts", true},
+		{"src/**/test?.ts", "src/test12.ts", false},
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[!0-9].txt", "file7.txt", false},
+		{"**/node_modules/**", "/a/node_modules/b/c.js", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern+"|"+tc.path, func(t *testing.T) {
+			re, err := compileGlob(tc.pattern)
+			if err != nil {
+				t.Fatalf("compileGlob(%q) failed: %v", tc.pattern, err)
+			}
+			if got := re.MatchString(tc.path); got != tc.want {
+				t.Errorf("match(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := compileGlob("**/*.{go"); err == nil {
+		t.Error("expected error for unbalanced braces")
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "build/\n*.log\n!keep.log\n")
+	writeFile(t, filepath.Join(root, "sub", ".gitignore"), "generated.go\n")
+
+	m := newIgnoreMatcher(root)
+	m.load(filepath.Join(root, "sub"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"build/out.bin", false, true},
+		{"debug.log", false, true},
+		{"keep.log", false, false},
+		{"main.go", false, false},
+		{"sub/generated.go", false, true},
+		{"generated.go", false, false},
+		{".git/HEAD", false, true},
+	}
+	for _, tc := range tests {
+		if got := m.ignored(filepath.Join(root, tc.path), tc.isDir); got != tc.want {
+			t.Errorf("ignored(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestFileWatcher(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		name := "native"
+		if polling {
+			name = "polling"
+		}
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, ".gitignore"), "vendor/\n")
+			writeFile(t, filepath.Join(root, "existing.go"), "package main\n")
+			if err := os.Mkdir(filepath.Join(root, "vendor"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			var batches [][]protocol.FileEvent
+			notify := func(_ context.Context, events []protocol.FileEvent) error {
+				mu.Lock()
+				defer mu.Unlock()
+				batches = append(batches, events)
+				return nil
+			}
+
+			w := NewFileWatcher([]string{root}, notify, FileWatcherOptions{
+				Debounce:     20 * time.Millisecond,
+				PollInterval: 20 * time.Millisecond,
+				ForcePolling: polling,
+			})
+			w.SetWatchers([]protocol.FileSystemWatcher{
+				{GlobPattern: protocol.GlobPattern{Value: "**/*.go"}},
+			})
+			if err := w.Start(t.Context()); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			defer w.Close() //nolint:errcheck
+
+			// Give the watcher time to set up before making changes.
+			time.Sleep(100 * time.Millisecond)
+
+			writeFile(t, filepath.Join(root, "new.go"), "package main\n")
+			writeFile(t, filepath.Join(root, "notes.txt"), "ignored by pattern")
+			writeFile(t, filepath.Join(root, "vendor", "dep.go"), "ignored by .gitignore")
+			if err := os.Remove(filepath.Join(root, "existing.go")); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[protocol.DocumentURI]protocol.FileChangeType{
+				protocol.URIFromPath(filepath.Join(root, "new.go")):      protocol.Created,
+				protocol.URIFromPath(filepath.Join(root, "existing.go")): protocol.Deleted,
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				mu.Lock()
+				got := make(map[protocol.DocumentURI]protocol.FileChangeType)
+				for _, batch := range batches {
+					for _, ev := range batch {
+						got[ev.URI] = ev.Type
+					}
+				}
+				mu.Unlock()
+
+				if len(got) >= len(want) || time.Now().After(deadline) {
+					for uri, typ := range want {
+						if got[uri] != typ {
+							t.Errorf("expected %s to be %d, got %d", uri, typ, got[uri])
+						}
+					}
+					for uri := range got {
+						if _, ok := want[uri]; !ok {
+							t.Errorf("unexpected event for %s", uri)
+						}
+					}
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestFileWatcher_RemoveDir(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		name := "native"
+		if polling {
+			name = "polling"
+		}
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, ".gitignore"), "build/\n")
+			writeFile(t, filepath.Join(root, "build", "out.go"), "ignored by .gitignore")
+			writeFile(t, filepath.Join(root, "pkg", "a.go"), "package pkg\n")
+			writeFile(t, filepath.Join(root, "pkg", "sub", "b.go"), "package sub\n")
+
+			var mu sync.Mutex
+			got := make(map[protocol.DocumentURI]protocol.FileChangeType)
+			notify := func(_ context.Context, events []protocol.FileEvent) error {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, ev := range events {
+					got[ev.URI] = ev.Type
+				}
+				return nil
+			}
+
+			w := NewFileWatcher([]string{root}, notify, FileWatcherOptions{
+				Debounce:     20 * time.Millisecond,
+				PollInterval: 20 * time.Millisecond,
+				ForcePolling: polling,
+			})
+			w.SetWatchers([]protocol.FileSystemWatcher{
+				{GlobPattern: protocol.GlobPattern{Value: "**/*"}},
+			})
+			if err := w.Start(t.Context()); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			defer w.Close() //nolint:errcheck
+
+			// Give the watcher time to set up before making changes.
+			time.Sleep(100 * time.Millisecond)
+
+			// Moving a directory out of the workspace reports no events for
+			// its children.
+			if err := os.Rename(filepath.Join(root, "pkg"), filepath.Join(t.TempDir(), "pkg")); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.RemoveAll(filepath.Join(root, "build")); err != nil {
+				t.Fatal(err)
+			}
+
+			want := make(map[protocol.DocumentURI]protocol.FileChangeType)
+			for _, path := range []string{"pkg", "pkg/a.go", "pkg/sub", "pkg/sub/b.go"} {
+				want[protocol.URIFromPath(filepath.Join(root, path))] = protocol.Deleted
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				mu.Lock()
+				done := len(got) >= len(want)
+				mu.Unlock()
+				if done || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			// Wait for stray events about the ignored directory.
+			time.Sleep(100 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for uri, typ := range want {
+				if got[uri] != typ {
+					t.Errorf("expected %s to be %d, got %d", uri, typ, got[uri])
+				}
+			}
+			for uri := range got {
+				if _, ok := want[uri]; !ok {
+					t.Errorf("unexpected event for %s", uri)
+				}
+			}
+		})
+	}
+}
+
+func TestFileWatcher_Coalesce(t *testing.T) {
+	w := NewFileWatcher(nil, func(context.Context, []protocol.FileEvent) error { return nil }, FileWatcherOptions{
+		Debounce: time.Hour,
+	})
+	kind := protocol.WatchCreate | protocol.WatchChange | protocol.WatchDelete
+	w.SetWatchers([]protocol.FileSystemWatcher{
+		{GlobPattern: protocol.GlobPattern{Value: "**/*"}, Kind: &kind},
+	})
+	defer w.Close() //nolint:errcheck
+
+	w.enqueue("/tmp/a", protocol.Created)
+	w.enqueue("/tmp/a", protocol.Changed)
+	w.enqueue("/tmp/b", protocol.Created)
+	w.enqueue("/tmp/b", protocol.Deleted)
+	w.enqueue("/tmp/c", protocol.Deleted)
+	w.enqueue("/tmp/c", protocol.Created)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	if !slices.Equal(paths, []string{"/tmp/a", "/tmp/c"}) {
+		t.Fatalf("unexpected pending paths: %v", paths)
+	}
+	if w.pending["/tmp/a"] != protocol.Created {
+		t.Errorf("expected create+change to coalesce into create, got %d", w.pending["/tmp/a"])
+	}
+	if w.pending["/tmp/c"] != protocol.Changed {
+		t.Errorf("expected delete+create to coalesce into change, got %d", w.pending["/tmp/c"])
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+}
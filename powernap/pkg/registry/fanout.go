@@ -0,0 +1,282 @@
+package registry
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// DefaultFanoutTimeout is how long a fan-out request waits for each server
+// unless [Fanout.Timeout] is set.
+const DefaultFanoutTimeout = 2 * time.Second
+
+// Fanout sends requests about a file to every language server handling it
+// that supports the request, and merges their results. Each server gets its
+// own timeout, so a slow server doesn't hold up the others: its results are
+// left out and its error is reported instead.
+//
+// Positions are given as byte offsets into the content of the document and
+// converted to the encoding of each server. Ranges in the results are in the
+// encoding of the server they came from, see [Attributed]. Documents must be
+// opened and kept in sync with each server by the caller.
+type Fanout struct {
+	// Timeout bounds the request to each server. Zero means
+	// [DefaultFanoutTimeout].
+	Timeout time.Duration
+
+	registry *Registry
+}
+
+// NewFanout creates a fan-out layer over the servers of a registry.
+func NewFanout(r *Registry) *Fanout {
+	return &Fanout{registry: r}
+}
+
+// Attributed is a result item along with the server it came from.
+type Attributed[T any] struct {
+	// Server is the name of the server in the registry.
+	Server string
+	// Encoding is the position encoding of the server, which applies to the
+	// ranges of the item.
+	Encoding lsp.OffsetEncoding
+	Item     T
+}
+
+// ServerError is the error of a single server in a fan-out request.
+type ServerError struct {
+	Server string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Server, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+// CompletionResult holds the merged completion items of all servers.
+type CompletionResult struct {
+	Items []Attributed[protocol.CompletionItem]
+	// IsIncomplete is set if any server's list is incomplete.
+	IsIncomplete bool
+}
+
+// Completion requests completion items at a byte offset of a document from
+// all servers. Items with the same label, kind and insert text are only kept
+// from the first server, in the order of server names.
+//
+// The returned error joins the [ServerError]s of the servers that failed or
+// timed out; the results of the other servers are returned along with it.
+func (f *Fanout) Completion(ctx context.Context, path, content string, offset int) (*CompletionResult, error) {
+	var incomplete atomic.Bool
+	items, err := fanout(ctx, f, path, lsp.MethodTextDocumentCompletion, func(ctx context.Context, s server, uri string) ([]protocol.CompletionItem, error) {
+		pos, err := lsp.NewPositionMapper(content, s.client.OffsetEncoding()).Position(offset)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		list, err := s.client.RequestCompletion(ctx, uri, pos)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if list.IsIncomplete {
+			incomplete.Store(true)
+		}
+		return list.Items, nil
+	})
+
+	items = dedupe(items, func(a Attributed[protocol.CompletionItem]) string {
+		return fmt.Sprintf("%s\x00%d\x00%s", a.Item.Label, a.Item.Kind, a.Item.InsertText)
+	})
+	return &CompletionResult{Items: items, IsIncomplete: incomplete.Load()}, err
+}
+
+// Hover requests hover information at a byte offset of a document from all
+// servers. Empty hovers and hovers with the same contents as one of an
+// earlier server are left out.
+//
+// The returned error joins the [ServerError]s of the servers that failed or
+// timed out; the results of the other servers are returned along with it.
+func (f *Fanout) Hover(ctx context.Context, path, content string, offset int) ([]Attributed[protocol.Hover], error) {
+	hovers, err := fanout(ctx, f, path, lsp.MethodTextDocumentHover, func(ctx context.Context, s server, uri string) ([]protocol.Hover, error) {
+		pos, err := lsp.NewPositionMapper(content, s.client.OffsetEncoding()).Position(offset)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		hover, err := s.client.RequestHover(ctx, uri, pos)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if hover == nil || strings.TrimSpace(hover.Contents.Value) == "" {
+			return nil, nil
+		}
+		return []protocol.Hover{*hover}, nil
+	})
+
+	hovers = dedupe(hovers, func(a Attributed[protocol.Hover]) string {
+		return strings.TrimSpace(a.Item.Contents.Value)
+	})
+	return hovers, err
+}
+
+// CodeActions requests the code actions for the bytes between start and end
+// of a document from all servers. Each server is sent only the diagnostics
+// that came from it, so the diagnostics returned by [Fanout.Diagnostics] can
+// be passed as-is. Actions with the same title and kind are only kept from
+// the first server.
+//
+// The returned error joins the [ServerError]s of the servers that failed or
+// timed out; the results of the other servers are returned along with it.
+func (f *Fanout) CodeActions(ctx context.Context, path, content string, start, end int, diagnostics []Attributed[protocol.Diagnostic]) ([]Attributed[protocol.CodeAction], error) {
+	actions, err := fanout(ctx, f, path, lsp.MethodTextDocumentCodeAction, func(ctx context.Context, s server, uri string) ([]protocol.CodeAction, error) {
+		rng, err := lsp.NewPositionMapper(content, s.client.OffsetEncoding()).Range(start, end)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		var own []protocol.Diagnostic
+		for _, d := range diagnostics {
+			if d.Server == s.name {
+				own = append(own, d.Item)
+			}
+		}
+		return s.client.RequestCodeActions(ctx, uri, rng, own, nil) //nolint:wrapcheck
+	})
+
+	actions = dedupe(actions, func(a Attributed[protocol.CodeAction]) string {
+		return a.Item.Title + "\x00" + string(a.Item.Kind)
+	})
+	return actions, err
+}
+
+// Diagnostics pulls the diagnostics of a document from all servers that
+// support textDocument/diagnostic, sorted by their start. Diagnostics with
+// the same range, severity and message are only kept from the first server,
+// even if the servers use different position encodings.
+//
+// The returned error joins the [ServerError]s of the servers that failed or
+// timed out; the results of the other servers are returned along with it.
+func (f *Fanout) Diagnostics(ctx context.Context, path, content string) ([]Attributed[protocol.Diagnostic], error) {
+	diagnostics, err := fanout(ctx, f, path, lsp.MethodTextDocumentPullDiagnostics, func(ctx context.Context, s server, uri string) ([]protocol.Diagnostic, error) {
+		return s.client.RequestDiagnostics(ctx, uri) //nolint:wrapcheck
+	})
+
+	// Compare ranges as byte offsets, since servers may count characters
+	// differently.
+	mappers := make(map[lsp.OffsetEncoding]*lsp.PositionMapper)
+	offsets := func(a Attributed[protocol.Diagnostic]) (int, int) {
+		m, ok := mappers[a.Encoding]
+		if !ok {
+			m = lsp.NewPositionMapper(content, a.Encoding)
+			mappers[a.Encoding] = m
+		}
+		start, end, err := m.Offsets(a.Item.Range)
+		if err != nil {
+			return -1, -1
+		}
+		return start, end
+	}
+
+	diagnostics = dedupe(diagnostics, func(a Attributed[protocol.Diagnostic]) string {
+		start, end := offsets(a)
+		return fmt.Sprintf("%d\x00%d\x00%d\x00%s", start, end, a.Item.Severity, a.Item.Message)
+	})
+
+	// Compute the start offsets once rather than on every comparison.
+	type keyed struct {
+		start int
+		diag  Attributed[protocol.Diagnostic]
+	}
+	sorted := make([]keyed, len(diagnostics))
+	for i, d := range diagnostics {
+		start, _ := offsets(d)
+		sorted[i] = keyed{start, d}
+	}
+	slices.SortStableFunc(sorted, func(a, b keyed) int {
+		return cmp.Compare(a.start, b.start)
+	})
+	for i, k := range sorted {
+		diagnostics[i] = k.diag
+	}
+	return diagnostics, err
+}
+
+// fanout runs req concurrently for every server of the file that supports
+// method, each with its own timeout, and collects the results in the order
+// of server names.
+func fanout[T any](ctx context.Context, f *Fanout, path, method string, req func(ctx context.Context, s server, uri string) ([]T, error)) ([]Attributed[T], error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	servers, err := f.registry.serversForFile(ctx, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = DefaultFanoutTimeout
+	}
+	uri := string(protocol.URIFromPath(absPath))
+
+	results := make([][]Attributed[T], len(servers))
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		if !s.client.Supports(method) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			items, err := req(ctx, s, uri)
+			if err != nil {
+				errs[i] = &ServerError{Server: s.name, Err: err}
+				return
+			}
+			encoding := s.client.OffsetEncoding()
+			for _, item := range items {
+				results[i] = append(results[i], Attributed[T]{Server: s.name, Encoding: encoding, Item: item})
+			}
+		}()
+	}
+	wg.Wait()
+
+	var merged []Attributed[T]
+	for _, items := range results {
+		merged = append(merged, items...)
+	}
+	return merged, errors.Join(errs...)
+}
+
+// dedupe drops the items whose key matches the key of an earlier item.
+func dedupe[T any](items []Attributed[T], key func(Attributed[T]) string) []Attributed[T] {
+	seen := make(map[string]bool, len(items))
+	out := items[:0]
+	for _, item := range items {
+		k := key(item)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, item)
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+)
+
+// The emoji takes 4 bytes and 2 UTF-16 units, so the servers count the
+// characters after it differently.
+const fanoutContent = "package main\n\nvar s = \"😀\" + x\n"
+
+func TestFanout_Completion(t *testing.T) {
+	f, servers, path := newTestFanout(t, protocol.ServerCapabilities{
+		CompletionProvider: &protocol.CompletionOptions{},
+	})
+	servers["gopls"].Respond(lsp.MethodTextDocumentCompletion, protocol.CompletionList{
+		IsIncomplete: true,
+		Items: []protocol.CompletionItem{
+			{Label: "x", Kind: protocol.VariableCompletion},
+			{Label: "xor", Kind: protocol.FunctionCompletion},
+		},
+	})
+	servers["golangci-lint"].Respond(lsp.MethodTextDocumentCompletion, []protocol.CompletionItem{
+		{Label: "x", Kind: protocol.VariableCompletion},
+		{Label: "nolint", Kind: protocol.KeywordCompletion},
+	})
+
+	offset := strings.Index(fanoutContent, "x")
+	result, err := f.Completion(t.Context(), path, fanoutContent, offset)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if !result.IsIncomplete {
+		t.Error("expected the merged list to be incomplete")
+	}
+
+	var got []string
+	for _, item := range result.Items {
+		got = append(got, item.Server+":"+item.Item.Label)
+	}
+	if want := "golangci-lint:x golangci-lint:nolint gopls:xor"; strings.Join(got, " ") != want {
+		t.Errorf("got items %q, want %q", got, want)
+	}
+
+	// Each server is sent the position in its own encoding.
+	for name, want := range map[string]uint32{"gopls": 15, "golangci-lint": 17} {
+		var params protocol.CompletionParams
+		if err := servers[name].ReceivedMethod(lsp.MethodTextDocumentCompletion)[0].Unmarshal(&params); err != nil {
+			t.Fatal(err)
+		}
+		if params.Position != (protocol.Position{Line: 2, Character: want}) {
+			t.Errorf("%s: got position %+v, want 2:%d", name, params.Position, want)
+		}
+	}
+}
+
+func TestFanout_HoverTimeout(t *testing.T) {
+	f, servers, path := newTestFanout(t, protocol.ServerCapabilities{
+		HoverProvider: &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+	})
+	f.Timeout = 50 * time.Millisecond
+
+	servers["gopls"].Respond(lsp.MethodTextDocumentHover, protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "var x int"},
+	})
+	release := make(chan struct{})
+	servers["golangci-lint"].Handle(lsp.MethodTextDocumentHover, func(context.Context, json.RawMessage) (any, error) {
+		<-release
+		return nil, nil
+	})
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	hovers, err := f.Hover(t.Context(), path, fanoutContent, strings.Index(fanoutContent, "x"))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the slow server to time out, took %s", elapsed)
+	}
+
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Server != "golangci-lint" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout of golangci-lint, got %v", err)
+	}
+	if len(hovers) != 1 || hovers[0].Server != "gopls" || hovers[0].Item.Contents.Value != "var x int" {
+		t.Errorf("expected the hover of gopls, got %+v", hovers)
+	}
+}
+
+func TestFanout_Diagnostics(t *testing.T) {
+	f, servers, path := newTestFanout(t, protocol.ServerCapabilities{
+		DiagnosticProvider: &protocol.Or_ServerCapabilities_diagnosticProvider{Value: protocol.DiagnosticOptions{}},
+	})
+
+	// Both servers report the undefined x, at different characters.
+	undefined := func(char uint32) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2, Character: char},
+				End:   protocol.Position{Line: 2, Character: char + 1},
+			},
+			Severity: protocol.SeverityError,
+			Message:  "undefined: x",
+		}
+	}
+	report := func(diagnostics ...protocol.Diagnostic) protocol.FullDocumentDiagnosticReport {
+		return protocol.FullDocumentDiagnosticReport{Kind: "full", Items: diagnostics}
+	}
+	servers["gopls"].Respond(lsp.MethodTextDocumentPullDiagnostics, report(undefined(15)))
+	servers["golangci-lint"].Respond(lsp.MethodTextDocumentPullDiagnostics, report(
+		protocol.Diagnostic{Severity: protocol.SeverityWarning, Message: "package comment missing"},
+		undefined(17),
+	))
+
+	diagnostics, err := f.Diagnostics(t.Context(), path, fanoutContent)
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %+v", diagnostics)
+	}
+	if diagnostics[0].Server != "golangci-lint" || diagnostics[0].Item.Message != "package comment missing" {
+		t.Errorf("unexpected first diagnostic %+v", diagnostics[0])
+	}
+	if diagnostics[1].Server != "golangci-lint" || diagnostics[1].Encoding != lsp.UTF8 {
+		t.Errorf("expected the undefined x of golangci-lint, got %+v", diagnostics[1])
+	}
+
+	// Servers without hover support aren't asked.
+	hovers, err := f.Hover(t.Context(), path, fanoutContent, 0)
+	if err != nil || len(hovers) != 0 {
+		t.Errorf("expected no hovers, got %+v, %v", hovers, err)
+	}
+	for name, s := range servers {
+		if n := len(s.ReceivedMethod(lsp.MethodTextDocumentHover)); n != 0 {
+			t.Errorf("%s: expected no hover requests, got %d", name, n)
+		}
+	}
+}
+
+func TestFanout_CodeActions(t *testing.T) {
+	f, servers, path := newTestFanout(t, protocol.ServerCapabilities{CodeActionProvider: true})
+	servers["gopls"].Respond(lsp.MethodTextDocumentCodeAction, []protocol.CodeAction{
+		{Title: "Organize imports", Kind: protocol.SourceOrganizeImports},
+		{Title: "Declare x", Kind: protocol.QuickFix},
+	})
+	servers["golangci-lint"].Respond(lsp.MethodTextDocumentCodeAction, []protocol.CodeAction{
+		{Title: "Organize imports", Kind: protocol.SourceOrganizeImports},
+	})
+
+	diagnostic := Attributed[protocol.Diagnostic]{
+		Server:   "gopls",
+		Encoding: lsp.UTF16,
+		Item:     protocol.Diagnostic{Message: "undefined: x"},
+	}
+	start := strings.Index(fanoutContent, "x")
+	actions, err := f.CodeActions(t.Context(), path, fanoutContent, start, start+1, []Attributed[protocol.Diagnostic]{diagnostic})
+	if err != nil {
+		t.Fatalf("CodeActions failed: %v", err)
+	}
+
+	var got []string
+	for _, action := range actions {
+		got = append(got, action.Server+":"+action.Item.Title)
+	}
+	if want := "golangci-lint:Organize imports gopls:Declare x"; strings.Join(got, " ") != want {
+		t.Errorf("got actions %q, want %q", got, want)
+	}
+
+	for name, want := range map[string]int{"gopls": 1, "golangci-lint": 0} {
+		var params protocol.CodeActionParams
+		if err := servers[name].ReceivedMethod(lsp.MethodTextDocumentCodeAction)[0].Unmarshal(&params); err != nil {
+			t.Fatal(err)
+		}
+		if len(params.Context.Diagnostics) != want {
+			t.Errorf("%s: expected %d diagnostics, got %+v", name, want, params.Context.Diagnostics)
+		}
+	}
+}
+
+func newTestFanout(t *testing.T, caps protocol.ServerCapabilities) (*Fanout, map[string]*lsptest.Server, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example\n"), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	utf8Caps := caps
+	utf8 := protocol.UTF8
+	utf8Caps.PositionEncoding = &utf8
+	servers := map[string]*lsptest.Server{
+		"gopls":         lsptest.NewServer(caps),
+		"golangci-lint": lsptest.NewServer(utf8Caps),
+	}
+	server := func(command string) map[string]any {
+		return map[string]any{
+			"command":      command,
+			"filetypes":    []string{"go"},
+			"root_markers": []string{"go.mod"},
+		}
+	}
+	r := newTestRegistry(t, servers, map[string]any{
+		"servers": map[string]any{
+			"gopls":         server("gopls"),
+			"golangci-lint": server("golangci-lint-langserver"),
+		},
+	})
+	return NewFanout(r), servers, filepath.Join(root, "main.go")
+}
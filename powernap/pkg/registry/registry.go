@@ -11,20 +11,28 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/charmbracelet/x/powernap/pkg/config"
 	"github.com/charmbracelet/x/powernap/pkg/lsp"
 	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
 )
 
+// Dialer opens a stream to the language server with the given name and
+// configuration, instead of starting its command.
+type Dialer func(ctx context.Context, name string, cfg *config.ServerConfig) (io.ReadWriteCloser, error)
+
 // Registry manages multiple language server instances.
 type Registry struct {
 	mu      sync.RWMutex
 	clients map[string]*lsp.Client
 	configs map[string]*config.ServerConfig
 	logger  *slog.Logger
+	dialer  Dialer
+	tracer  *transport.Tracer
 }
 
 // New creates a new registry.
@@ -54,6 +62,23 @@ func (r *Registry) LoadConfig(cfg *config.Manager) error {
 	return nil
 }
 
+// SetDialer sets the function used to connect to language servers. By
+// default, servers are started as child processes using their configured
+// command.
+func (r *Registry) SetDialer(dialer Dialer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dialer = dialer
+}
+
+// SetTracer sets the tracer used to log the traffic of the servers started
+// afterwards. Each server is traced with its own [transport.Tracer.ForServer].
+func (r *Registry) SetTracer(tracer *transport.Tracer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tracer = tracer
+}
+
 // StartServer starts a language server for the given name and project path.
 func (r *Registry) StartServer(ctx context.Context, name string, projectPath string) (*lsp.Client, error) {
 	r.mu.Lock()
@@ -80,6 +105,9 @@ func (r *Registry) StartServer(ctx context.Context, name string, projectPath str
 		rootPath = projectPath
 	}
 
+	// Substitute ${workspaceFolder} and ${env:...} in the configuration
+	serverCfg = serverCfg.Expand(rootPath)
+
 	// Create workspace folders
 	workspaceFolders := []protocol.WorkspaceFolder{
 		{
@@ -97,16 +125,34 @@ func (r *Registry) StartServer(ctx context.Context, name string, projectPath str
 		InitOptions:      serverCfg.InitOptions,
 		Settings:         serverCfg.Settings,
 		Environment:      serverCfg.Environment,
+		Address:          serverCfg.Address,
+		Listen:           serverCfg.Listen,
+	}
+	if r.tracer != nil {
+		clientCfg.Tracer = r.tracer.ForServer(name)
+	}
+
+	// Connect through the dialer if one was set
+	if r.dialer != nil {
+		stream, err := r.dialer(ctx, name, serverCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to server: %w", err)
+		}
+		clientCfg.Stream = stream
 	}
 
 	// Create and initialize client
 	client, err := lsp.NewClient(clientCfg)
 	if err != nil {
+		if clientCfg.Stream != nil {
+			_ = clientCfg.Stream.Close()
+		}
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
-	// Initialize the client
+	// Initialize the client, closing the connection if the server fails
 	if err := client.Initialize(ctx, serverCfg.EnableSnippets); err != nil {
+		_ = client.Close()
 		return nil, fmt.Errorf("failed to initialize client: %w", err)
 	}
 
@@ -171,6 +217,27 @@ func (r *Registry) GetClient(name string) (*lsp.Client, bool) {
 // GetClientsForFile returns all appropriate clients for the given file.
 // This allows multiple language servers to handle the same file type (e.g., gopls and golangci-lint for Go files).
 func (r *Registry) GetClientsForFile(ctx context.Context, filePath string) ([]*lsp.Client, error) {
+	servers, err := r.serversForFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]*lsp.Client, len(servers))
+	for i, s := range servers {
+		clients[i] = s.client
+	}
+	return clients, nil
+}
+
+// server is a running client along with the name of its server.
+type server struct {
+	name   string
+	client *lsp.Client
+}
+
+// serversForFile starts or gets the servers for the given file, in the
+// order of their names.
+func (r *Registry) serversForFile(ctx context.Context, filePath string) ([]server, error) {
 	// Convert to absolute path
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
@@ -183,35 +250,20 @@ func (r *Registry) GetClientsForFile(ctx context.Context, filePath string) ([]*l
 		return nil, fmt.Errorf("unsupported file type: %s", filepath.Ext(absPath))
 	}
 
-	r.mu.RLock()
-
-	// Find all servers that support this language
-	var serverNames []string
-	for name, cfg := range r.configs {
-		for _, ft := range cfg.FileTypes {
-			// Match by extension or language ID
-			ext := filepath.Ext(absPath)
-			if ft == language || ft == strings.TrimPrefix(ext, ".") || "."+ft == ext {
-				serverNames = append(serverNames, name)
-				break
-			}
-		}
-	}
-
-	r.mu.RUnlock()
+	serverNames := r.ServersForFile(absPath)
 
 	if len(serverNames) == 0 {
 		return nil, fmt.Errorf("no language servers found for language: %s", language)
 	}
 
-	var clients []*lsp.Client
+	var servers []server
 	projectDir := filepath.Dir(absPath)
 
 	// Start or get each server
 	for _, serverName := range serverNames {
 		// Check if server is already running
 		if client, exists := r.GetClient(serverName); exists {
-			clients = append(clients, client)
+			servers = append(servers, server{serverName, client})
 		} else {
 			// Start the server
 			client, err := r.StartServer(ctx, serverName, projectDir)
@@ -219,15 +271,40 @@ func (r *Registry) GetClientsForFile(ctx context.Context, filePath string) ([]*l
 				r.logger.Warn("Failed to start server", "name", serverName, "error", err)
 				continue
 			}
-			clients = append(clients, client)
+			servers = append(servers, server{serverName, client})
 		}
 	}
 
-	if len(clients) == 0 {
+	if len(servers) == 0 {
 		return nil, fmt.Errorf("failed to start any language servers for language: %s", language)
 	}
 
-	return clients, nil
+	return servers, nil
+}
+
+// ServersForFile returns the sorted names of the configured servers that
+// handle the given file.
+func (r *Registry) ServersForFile(filePath string) []string {
+	language := string(lsp.DetectLanguage(filePath))
+	ext := filepath.Ext(filePath)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var serverNames []string
+	for name, cfg := range r.configs {
+		for _, ft := range cfg.FileTypes {
+			// Match by extension or language ID
+			if (language != "" && ft == language) || ft == strings.TrimPrefix(ext, ".") || "."+ft == ext {
+				serverNames = append(serverNames, name)
+				break
+			}
+		}
+	}
+
+	// Keep the order stable across calls
+	slices.Sort(serverNames)
+	return serverNames
 }
 
 // GetClientForFile returns a single appropriate client for the given file.
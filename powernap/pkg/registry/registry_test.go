@@ -0,0 +1,265 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/powernap/pkg/config"
+	"github.com/charmbracelet/x/powernap/pkg/lsp"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/lsptest"
+	"github.com/charmbracelet/x/powernap/pkg/lsp/protocol"
+	"github.com/charmbracelet/x/powernap/pkg/transport"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func TestRegistry_StartStop(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example\n"), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	servers := map[string]*lsptest.Server{
+		"gopls":         lsptest.NewServer(protocol.ServerCapabilities{}),
+		"golangci-lint": lsptest.NewServer(protocol.ServerCapabilities{}),
+	}
+	r := newTestRegistry(t, servers, map[string]any{
+		"servers": map[string]any{
+			"gopls": map[string]any{
+				"command":      "gopls",
+				"filetypes":    []string{"go"},
+				"root_markers": []string{"go.mod"},
+			},
+			"golangci-lint": map[string]any{
+				"command":      "golangci-lint-langserver",
+				"filetypes":    []string{"go"},
+				"root_markers": []string{"go.mod"},
+			},
+		},
+	})
+
+	clients, err := r.GetClientsForFile(t.Context(), filepath.Join(root, "main.go"))
+	if err != nil {
+		t.Fatalf("GetClientsForFile failed: %v", err)
+	}
+	if len(clients) != 2 {
+		t.Fatalf("expected 2 clients, got %d", len(clients))
+	}
+
+	names := r.ListClients()
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"golangci-lint", "gopls"}) {
+		t.Errorf("unexpected running servers: %v", names)
+	}
+
+	// A second lookup reuses the running clients.
+	client, err := r.StartServer(t.Context(), "gopls", root)
+	if err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+	if c, _ := r.GetClient("gopls"); c != client {
+		t.Error("expected StartServer to return the running client")
+	}
+	if n := len(servers["gopls"].ReceivedMethod(lsp.MethodInitialize)); n != 1 {
+		t.Errorf("expected gopls to be initialized once, got %d", n)
+	}
+
+	var params protocol.InitializeParams
+	if err := servers["gopls"].ReceivedMethod(lsp.MethodInitialize)[0].Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if want := protocol.DocumentURI("file://" + root); params.RootURI != want {
+		t.Errorf("expected root %q, got %q", want, params.RootURI)
+	}
+
+	if err := r.StopServer(t.Context(), "gopls"); err != nil {
+		t.Fatalf("StopServer failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := servers["gopls"].WaitFor(ctx, lsp.MethodExit, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(servers["gopls"].ReceivedMethod(lsp.MethodShutdown)); n != 1 {
+		t.Errorf("expected a shutdown request, got %d", n)
+	}
+	if _, ok := r.GetClient("gopls"); ok {
+		t.Error("expected gopls to be removed from the registry")
+	}
+
+	if err := r.StopAll(t.Context()); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+	if len(r.ListClients()) != 0 {
+		t.Errorf("expected no running servers, got %v", r.ListClients())
+	}
+}
+
+func TestRegistry_RequiresRoot(t *testing.T) {
+	servers := map[string]*lsptest.Server{
+		"fake": lsptest.NewServer(protocol.ServerCapabilities{}),
+	}
+	r := newTestRegistry(t, servers, map[string]any{
+		"servers": map[string]any{
+			"fake": map[string]any{
+				"command":      "fake",
+				"filetypes":    []string{"go"},
+				"root_markers": []string{"go.mod.missing"},
+			},
+		},
+	})
+
+	if _, err := r.StartServer(t.Context(), "fake", t.TempDir()); err == nil {
+		t.Error("expected an error without a project root")
+	}
+	if n := len(servers["fake"].Received()); n != 0 {
+		t.Errorf("expected no messages to be sent, got %d", n)
+	}
+}
+
+func TestRegistry_InitializeFails(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example\n"), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	servers := map[string]*lsptest.Server{
+		"fake": lsptest.NewServer(protocol.ServerCapabilities{}),
+	}
+	servers["fake"].Fail(lsp.MethodInitialize, jsonrpc2.CodeInternalError, "broken")
+	r := newTestRegistry(t, servers, map[string]any{
+		"servers": map[string]any{
+			"fake": map[string]any{"command": "fake", "root_markers": []string{"go.mod"}},
+		},
+	})
+	stream := &closeRecorder{}
+	r.SetDialer(func(ctx context.Context, name string, _ *config.ServerConfig) (io.ReadWriteCloser, error) {
+		rwc, err := servers[name].Dial(ctx)
+		stream.ReadWriteCloser = rwc
+		return stream, err
+	})
+
+	if _, err := r.StartServer(t.Context(), "fake", root); err == nil {
+		t.Fatal("expected an error when initialize fails")
+	}
+	if !stream.closed.Load() {
+		t.Error("expected the connection to be closed")
+	}
+	if _, ok := r.GetClient("fake"); ok {
+		t.Error("expected the server not to be registered")
+	}
+}
+
+// closeRecorder records whether a stream was closed.
+type closeRecorder struct {
+	io.ReadWriteCloser
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return c.ReadWriteCloser.Close() //nolint:wrapcheck
+}
+
+func TestRegistry_SharedTracer(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example\n"), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	servers := map[string]*lsptest.Server{
+		"gopls":         lsptest.NewServer(protocol.ServerCapabilities{}),
+		"golangci-lint": lsptest.NewServer(protocol.ServerCapabilities{}),
+	}
+	r := newTestRegistry(t, servers, map[string]any{
+		"servers": map[string]any{
+			"gopls":         map[string]any{"command": "gopls", "root_markers": []string{"go.mod"}},
+			"golangci-lint": map[string]any{"command": "golangci-lint-langserver", "root_markers": []string{"go.mod"}},
+		},
+	})
+	var buf bytes.Buffer
+	r.SetTracer(transport.NewTracer(&buf, transport.TraceText, transport.TraceMessages))
+
+	gopls, err := r.StartServer(t.Context(), "gopls", root)
+	if err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+	lint, err := r.StartServer(t.Context(), "golangci-lint", root)
+	if err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+
+	// The hover request to gopls stays in flight while golangci-lint answers
+	// a request with the same ID.
+	release := make(chan struct{})
+	servers["gopls"].Handle(lsp.MethodTextDocumentHover, func(context.Context, json.RawMessage) (any, error) {
+		<-release
+		return protocol.Hover{}, nil
+	})
+	servers["golangci-lint"].Respond(lsp.MethodTextDocumentDefinition, []protocol.Location{})
+
+	uri := "file://" + filepath.Join(root, "main.go")
+	hovered := make(chan error, 1)
+	go func() {
+		_, err := gopls.RequestHover(t.Context(), uri, protocol.Position{})
+		hovered <- err
+	}()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := servers["gopls"].WaitFor(ctx, lsp.MethodTextDocumentHover, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lint.RequestDefinition(t.Context(), uri, protocol.Position{}); err != nil {
+		t.Fatalf("RequestDefinition failed: %v", err)
+	}
+	close(release)
+	if err := <-hovered; err != nil {
+		t.Fatalf("RequestHover failed: %v", err)
+	}
+	if err := r.StopAll(t.Context()); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+
+	log := buf.String()
+	for _, want := range []string{
+		"[gopls] Received response 'textDocument/hover - (",
+		"[golangci-lint] Received response 'textDocument/definition - (",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected the trace to contain %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "'unknown - ") {
+		t.Errorf("expected every response to be matched to its request:\n%s", log)
+	}
+}
+
+func newTestRegistry(t *testing.T, servers map[string]*lsptest.Server, cfg map[string]any) *Registry {
+	t.Helper()
+
+	mgr := config.NewManager()
+	if err := mgr.LoadFromMap(cfg); err != nil {
+		t.Fatalf("LoadFromMap failed: %v", err)
+	}
+
+	r := New()
+	if err := r.LoadConfig(mgr); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	r.SetDialer(func(ctx context.Context, name string, _ *config.ServerConfig) (io.ReadWriteCloser, error) {
+		return servers[name].Dial(ctx)
+	})
+	t.Cleanup(func() {
+		for _, s := range servers {
+			_ = s.Close()
+		}
+	})
+	return r
+}
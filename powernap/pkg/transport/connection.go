@@ -3,21 +3,66 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/sourcegraph/jsonrpc2"
 )
 
+// methodCancelRequest is the notification that cancels a pending request.
+const methodCancelRequest = "$/cancelRequest"
+
+// Dialer opens a new stream to a language server.
+type Dialer func(ctx context.Context) (io.ReadWriteCloser, error)
+
+// ReconnectOptions controls how a connection is re-established after the
+// server goes away.
+type ReconnectOptions struct {
+	// MaxAttempts is the number of times to try reconnecting before giving
+	// up. Zero means 5, and a negative value disables reconnecting.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first attempt, doubled after
+	// every failed attempt. Zero means 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Zero means 5s.
+	MaxBackoff time.Duration
+}
+
+func (o ReconnectOptions) withDefaults() ReconnectOptions {
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = 5
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Second
+	}
+	return o
+}
+
 // Connection represents a managed connection to a language server.
 type Connection struct {
-	conn      jsonrpc2.JSONRPC2
+	ctx       context.Context
+	connMu    sync.RWMutex
+	conn      *jsonrpc2.Conn
 	transport *Transport
 	router    *Router
 	logger    *slog.Logger
+	tracer    atomic.Pointer[Tracer]
+
+	// Reconnection
+	dial         Dialer
+	reconnect    ReconnectOptions
+	reconnecting atomic.Bool
+	hooksMu      sync.Mutex
+	onReconnect  []*func(ctx context.Context)
 
 	// State management
 	closed   atomic.Bool
@@ -25,6 +70,7 @@ type Connection struct {
 	closeErr error
 
 	// Request tracking
+	nextID    atomic.Uint64
 	requestMu sync.Mutex
 	requests  map[jsonrpc2.ID]chan *Message
 }
@@ -32,31 +78,96 @@ type Connection struct {
 // NewConnection creates a new managed connection.
 func NewConnection(ctx context.Context, stream io.ReadWriteCloser, logger *slog.Logger) (*Connection, error) {
 	c := &Connection{
+		ctx:      ctx,
 		router:   NewRouter(),
 		logger:   logger,
 		requests: make(map[jsonrpc2.ID]chan *Message),
 	}
 
-	// Create JSON-RPC connection
-	conn := jsonrpc2.NewConn(
-		ctx,
-		jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}),
-		jsonrpc2.HandlerWithError(c.handleRequest),
-	)
+	c.setConn(c.newConn(stream))
 
-	c.conn = conn
-	c.transport = NewWithConn(conn)
+	return c, nil
+}
+
+// NewReconnectingConnection creates a managed connection that dials the
+// server with dial, and dials it again with exponential backoff whenever the
+// connection drops. Handlers stay registered across reconnects; use
+// [Connection.OnReconnect] to restore server state such as the LSP
+// handshake. The connection is closed once reconnecting fails or ctx is done.
+func NewReconnectingConnection(ctx context.Context, dial Dialer, logger *slog.Logger, opts ReconnectOptions) (*Connection, error) {
+	stream, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewConnection(ctx, stream, logger)
+	if err != nil {
+		_ = stream.Close()
+		return nil, err
+	}
+
+	c.dial = dial
+	c.reconnect = opts.withDefaults()
+	if c.reconnect.MaxAttempts > 0 {
+		go c.monitor(c.current())
+	}
 
 	return c, nil
 }
 
+// OnReconnect registers a function to call after the connection has been
+// re-established, before it reports being connected again. Functions run in
+// registration order. The returned function unregisters fn.
+func (c *Connection) OnReconnect(fn func(ctx context.Context)) (unregister func()) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	hook := &fn
+	c.onReconnect = append(c.onReconnect, hook)
+	return func() {
+		c.hooksMu.Lock()
+		defer c.hooksMu.Unlock()
+		c.onReconnect = slices.DeleteFunc(c.onReconnect, func(h *func(context.Context)) bool { return h == hook })
+	}
+}
+
+// SetTracer starts writing the traffic of the connection to t, or stops
+// tracing if t is nil.
+func (c *Connection) SetTracer(t *Tracer) {
+	c.tracer.Store(t)
+}
+
+// Tracer returns the tracer of the connection, if any.
+func (c *Connection) Tracer() *Tracer {
+	return c.tracer.Load()
+}
+
 // Call makes a request to the language server and waits for a response.
+// If ctx is done before the response arrives, the server is told to stop
+// working on the request with $/cancelRequest and ctx's error is returned.
 func (c *Connection) Call(ctx context.Context, method string, params any, result any) error {
 	if c.closed.Load() {
 		return fmt.Errorf("connection is closed")
 	}
 
-	return c.conn.Call(ctx, method, params, result) //nolint:wrapcheck
+	// Request IDs are picked here rather than by jsonrpc2 so that they are
+	// known when the request has to be cancelled. They start at 1 because
+	// jsonrpc2 treats a picked ID of 0 as unset.
+	conn := c.current()
+	id := jsonrpc2.ID{Num: c.nextID.Add(1)}
+	err := conn.Call(ctx, method, params, result, jsonrpc2.PickID(id))
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		c.cancelRequest(conn, id)
+	}
+	return err //nolint:wrapcheck
+}
+
+// cancelRequest asks the server to stop working on a request the caller
+// gave up on. The server may still answer it; the late response is dropped.
+func (c *Connection) cancelRequest(conn *jsonrpc2.Conn, id jsonrpc2.ID) {
+	params := map[string]any{"id": id}
+	if err := conn.Notify(context.WithoutCancel(c.ctx), methodCancelRequest, params); err != nil {
+		c.log("Failed to cancel request", "id", id.String(), "error", err)
+	}
 }
 
 // Notify sends a notification to the language server.
@@ -65,7 +176,113 @@ func (c *Connection) Notify(ctx context.Context, method string, params any) erro
 		return fmt.Errorf("connection is closed")
 	}
 
-	return c.conn.Notify(ctx, method, params) //nolint:wrapcheck
+	return c.current().Notify(ctx, method, params) //nolint:wrapcheck
+}
+
+// newConn creates a JSON-RPC connection over stream that dispatches to the
+// router.
+func (c *Connection) newConn(stream io.ReadWriteCloser) *jsonrpc2.Conn {
+	return jsonrpc2.NewConn(
+		c.ctx,
+		jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.HandlerWithError(c.handleRequest),
+		jsonrpc2.OnSend(func(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+			if t := c.tracer.Load(); t != nil {
+				t.onSend(req, resp)
+			}
+		}),
+		jsonrpc2.OnRecv(func(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+			if t := c.tracer.Load(); t != nil {
+				t.onRecv(req, resp)
+			}
+		}),
+	)
+}
+
+// current returns the current JSON-RPC connection.
+func (c *Connection) current() *jsonrpc2.Conn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn
+}
+
+// setConn replaces the current JSON-RPC connection.
+func (c *Connection) setConn(conn *jsonrpc2.Conn) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.conn = conn
+	c.transport = NewWithConn(conn)
+}
+
+// monitor waits for conn to drop and reconnects.
+func (c *Connection) monitor(conn *jsonrpc2.Conn) {
+	select {
+	case <-conn.DisconnectNotify():
+	case <-c.ctx.Done():
+		_ = c.Close()
+		return
+	}
+	if c.closed.Load() {
+		return
+	}
+
+	c.reconnecting.Store(true)
+	defer c.reconnecting.Store(false)
+
+	backoff := c.reconnect.InitialBackoff
+	for attempt := 1; attempt <= c.reconnect.MaxAttempts; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			timer.Stop()
+			_ = c.Close()
+			return
+		}
+		backoff = min(backoff*2, c.reconnect.MaxBackoff)
+
+		if c.closed.Load() {
+			return
+		}
+
+		stream, err := c.dial(c.ctx)
+		if err != nil {
+			c.log("Reconnect failed", "attempt", attempt, "error", err)
+			continue
+		}
+
+		conn = c.newConn(stream)
+		c.closeMu.Lock()
+		if c.closed.Load() {
+			c.closeMu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		c.setConn(conn)
+		c.closeMu.Unlock()
+
+		c.log("Reconnected", "attempt", attempt)
+		c.hooksMu.Lock()
+		hooks := slices.Clone(c.onReconnect)
+		c.hooksMu.Unlock()
+		for _, fn := range hooks {
+			(*fn)(c.ctx)
+		}
+
+		go c.monitor(conn)
+		return
+	}
+
+	if c.logger != nil {
+		c.logger.Warn("Giving up reconnecting", "attempts", c.reconnect.MaxAttempts)
+	}
+	_ = c.Close()
+}
+
+func (c *Connection) log(msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.Debug(msg, args...)
+	}
 }
 
 // handleRequest handles incoming requests from the language server.
@@ -99,8 +316,12 @@ func (c *Connection) Close() error {
 	c.closed.Store(true)
 
 	// Close the JSON-RPC connection
-	if c.conn != nil {
-		c.closeErr = c.conn.Close()
+	if conn := c.current(); conn != nil {
+		c.closeErr = conn.Close()
+		if errors.Is(c.closeErr, jsonrpc2.ErrClosed) {
+			// The server went away first, e.g. while reconnecting.
+			c.closeErr = nil
+		}
 	}
 
 	// Close any pending requests
@@ -114,7 +335,8 @@ func (c *Connection) Close() error {
 	return c.closeErr
 }
 
-// IsConnected returns true if the connection is still active.
+// IsConnected returns true if the connection is still active. It returns
+// false while a reconnecting connection is being re-established.
 func (c *Connection) IsConnected() bool {
-	return !c.closed.Load()
+	return !c.closed.Load() && !c.reconnecting.Load()
 }
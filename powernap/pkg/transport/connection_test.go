@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// pingServer accepts connections on l and answers "ping" requests with the
+// number of the connection they arrived on. It returns a function that drops
+// the current connection.
+func pingServer(t *testing.T, l net.Listener) (drop func()) {
+	t.Helper()
+
+	conns := make(chan *jsonrpc2.Conn, 10)
+	var n atomic.Int64
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			id := n.Add(1)
+			handler := jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+				return id, nil
+			})
+			stream := jsonrpc2.NewBufferedStream(nc, jsonrpc2.VSCodeObjectCodec{})
+			conns <- jsonrpc2.NewConn(t.Context(), stream, handler)
+		}
+	}()
+
+	return func() {
+		select {
+		case conn := <-conns:
+			_ = conn.Close()
+		case <-time.After(time.Second):
+			t.Fatal("no connection to drop")
+		}
+	}
+}
+
+func dialer(l net.Listener) Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		return DialAddress(ctx, ListenerAddress(l))
+	}
+}
+
+func TestReconnectingConnection(t *testing.T) {
+	l, err := ListenAddress(t.Context(), "tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAddress failed: %v", err)
+	}
+	defer l.Close() //nolint:errcheck
+	drop := pingServer(t, l)
+
+	conn, err := NewReconnectingConnection(t.Context(), dialer(l), nil, ReconnectOptions{
+		InitialBackoff: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewReconnectingConnection failed: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	reconnected := make(chan struct{}, 1)
+	conn.OnReconnect(func(ctx context.Context) {
+		// The new connection is usable from the hook.
+		var id int
+		if err := conn.Call(ctx, "ping", nil, &id); err != nil {
+			t.Errorf("ping from hook failed: %v", err)
+		}
+		reconnected <- struct{}{}
+	})
+
+	var id int
+	if err := conn.Call(t.Context(), "ping", nil, &id); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("got connection %d, want 1", id)
+	}
+
+	drop()
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not re-established")
+	}
+
+	if err := conn.Call(t.Context(), "ping", nil, &id); err != nil {
+		t.Fatalf("ping after reconnect failed: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("got connection %d, want 2", id)
+	}
+	if !conn.IsConnected() {
+		t.Error("expected connection to be connected")
+	}
+}
+
+func TestReconnectingConnection_GivesUp(t *testing.T) {
+	l, err := ListenAddress(t.Context(), "tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAddress failed: %v", err)
+	}
+	drop := pingServer(t, l)
+
+	conn, err := NewReconnectingConnection(t.Context(), dialer(l), nil, ReconnectOptions{
+		MaxAttempts:    2,
+		InitialBackoff: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewReconnectingConnection failed: %v", err)
+	}
+
+	// Stop accepting connections, then drop the current one.
+	_ = l.Close()
+	drop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.IsConnected() || !conn.closed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection was not closed after failed reconnects")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := conn.Call(t.Context(), "ping", nil, nil); err == nil {
+		t.Error("expected call on closed connection to fail")
+	}
+}
+
+func TestReconnectingConnection_Disabled(t *testing.T) {
+	l, err := ListenAddress(t.Context(), "tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAddress failed: %v", err)
+	}
+	defer l.Close() //nolint:errcheck
+	drop := pingServer(t, l)
+
+	conn, err := NewReconnectingConnection(t.Context(), dialer(l), nil, ReconnectOptions{
+		MaxAttempts: -1,
+	})
+	if err != nil {
+		t.Fatalf("NewReconnectingConnection failed: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	conn.OnReconnect(func(context.Context) {
+		t.Error("unexpected reconnect")
+	})
+
+	drop()
+	time.Sleep(50 * time.Millisecond)
+	if err := conn.Call(t.Context(), "ping", nil, nil); err == nil {
+		t.Error("expected call on dropped connection to fail")
+	}
+}
+
+func TestConnection_CancelRequest(t *testing.T) {
+	client, server := net.Pipe()
+
+	started := make(chan jsonrpc2.ID, 1)
+	cancelled := make(chan string, 1)
+	release := make(chan struct{})
+	handler := jsonrpc2.HandlerWithError(func(ctx context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		switch req.Method {
+		case "slow":
+			started <- req.ID
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return "late", nil
+		case methodCancelRequest:
+			cancelled <- string(*req.Params)
+			close(release)
+		}
+		return nil, nil
+	})
+	stream := jsonrpc2.NewBufferedStream(server, jsonrpc2.VSCodeObjectCodec{})
+	serverConn := jsonrpc2.NewConn(t.Context(), stream, jsonrpc2.AsyncHandler(handler))
+	defer serverConn.Close() //nolint:errcheck
+
+	conn, err := NewConnection(t.Context(), client, nil)
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.Call(ctx, "slow", nil, nil)
+	}()
+
+	var id jsonrpc2.ID
+	select {
+	case id = <-started:
+	case <-time.After(time.Second):
+		t.Fatal("request not received")
+	}
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case params := <-cancelled:
+		if want := `{"id":` + id.String() + `}`; params != want {
+			t.Errorf("got cancel params %s, want %s", params, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no $/cancelRequest sent")
+	}
+
+	// Completed requests are not cancelled.
+	if err := conn.Call(t.Context(), "fast", nil, nil); err != nil {
+		t.Fatalf("fast failed: %v", err)
+	}
+	select {
+	case params := <-cancelled:
+		t.Errorf("unexpected cancellation %s", params)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
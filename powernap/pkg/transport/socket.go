@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseAddress splits a socket address of the form tcp://host:port or
+// unix:///path/to/socket into a network and an address suitable for the net
+// package.
+func ParseAddress(addr string) (network, address string, err error) {
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid address %q: missing scheme", addr)
+	}
+
+	switch scheme {
+	case "tcp", "tcp4", "tcp6":
+		if _, _, err := net.SplitHostPort(rest); err != nil {
+			return "", "", fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+		return scheme, rest, nil
+	case "unix":
+		if rest == "" {
+			return "", "", fmt.Errorf("invalid address %q: missing socket path", addr)
+		}
+		return scheme, rest, nil
+	default:
+		return "", "", fmt.Errorf("invalid address %q: unsupported scheme %q", addr, scheme)
+	}
+}
+
+// DialAddress connects to a language server listening on a socket address.
+func DialAddress(ctx context.Context, addr string) (net.Conn, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// ListenAddress listens on a socket address, for servers that connect back
+// to the client.
+func ListenAddress(ctx context.Context, addr string) (net.Listener, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var lc net.ListenConfig
+	l, err := lc.Listen(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return l, nil
+}
+
+// ListenerAddress formats the address of a listener using the same syntax
+// accepted by [ParseAddress]. This is useful when listening on port 0.
+func ListenerAddress(l net.Listener) string {
+	addr := l.Addr()
+	return addr.Network() + "://" + addr.String()
+}
+
+// Accept waits for a single connection on l, giving up when ctx is done.
+func Accept(ctx context.Context, l net.Listener) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := l.Accept()
+		ch <- result{conn, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to accept connection: %w", r.err)
+		}
+		return r.conn, nil
+	case <-ctx.Done():
+		// Unblock Accept, and drop a connection that raced with us.
+		_ = l.Close()
+		if r := <-ch; r.conn != nil {
+			_ = r.conn.Close()
+		}
+		return nil, ctx.Err() //nolint:wrapcheck
+	}
+}
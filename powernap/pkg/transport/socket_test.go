@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network string
+		address string
+		wantErr bool
+	}{
+		{addr: "tcp://127.0.0.1:9000", network: "tcp", address: "127.0.0.1:9000"},
+		{addr: "tcp://localhost:0", network: "tcp", address: "localhost:0"},
+		{addr: "tcp6://[::1]:9000", network: "tcp6", address: "[::1]:9000"},
+		{addr: "unix:///run/gopls.sock", network: "unix", address: "/run/gopls.sock"},
+		{addr: "unix://gopls.sock", network: "unix", address: "gopls.sock"},
+		{addr: "127.0.0.1:9000", wantErr: true},
+		{addr: "tcp://127.0.0.1", wantErr: true},
+		{addr: "unix://", wantErr: true},
+		{addr: "http://localhost:80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			network, address, err := ParseAddress(tt.addr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q %q", network, address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAddress failed: %v", err)
+			}
+			if network != tt.network || address != tt.address {
+				t.Errorf("got %q %q, want %q %q", network, address, tt.network, tt.address)
+			}
+		})
+	}
+}
+
+func TestDialAddress(t *testing.T) {
+	for _, addr := range []string{
+		"tcp://127.0.0.1:0",
+		"unix://" + filepath.Join(t.TempDir(), "server.sock"),
+	} {
+		t.Run(addr[:4], func(t *testing.T) {
+			l, err := ListenAddress(t.Context(), addr)
+			if err != nil {
+				t.Fatalf("ListenAddress failed: %v", err)
+			}
+			defer l.Close() //nolint:errcheck
+
+			accepted := make(chan error, 1)
+			go func() {
+				conn, err := Accept(t.Context(), l)
+				if err == nil {
+					_, err = conn.Write([]byte("hello"))
+					_ = conn.Close()
+				}
+				accepted <- err
+			}()
+
+			conn, err := DialAddress(t.Context(), ListenerAddress(l))
+			if err != nil {
+				t.Fatalf("DialAddress failed: %v", err)
+			}
+			defer conn.Close() //nolint:errcheck
+
+			data, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if string(data) != "hello" {
+				t.Errorf("got %q, want %q", data, "hello")
+			}
+			if err := <-accepted; err != nil {
+				t.Errorf("Accept failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestAcceptTimeout(t *testing.T) {
+	l, err := ListenAddress(t.Context(), "tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenAddress failed: %v", err)
+	}
+	defer l.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := Accept(ctx, l); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
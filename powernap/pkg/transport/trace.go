@@ -0,0 +1,351 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// TraceLevel is the LSP trace setting, as sent in initialize and $/setTrace.
+type TraceLevel string
+
+// Trace levels.
+const (
+	TraceOff      TraceLevel = "off"
+	TraceMessages TraceLevel = "messages"
+	TraceVerbose  TraceLevel = "verbose"
+)
+
+// TraceFormat is the format of a trace log.
+type TraceFormat int
+
+const (
+	// TraceText writes the human-readable format of the vscode-languageserver
+	// output channel.
+	TraceText TraceFormat = iota
+	// TraceJSON writes one JSON object per message, as understood by the LSP
+	// Inspector and by [ReadTrace].
+	TraceJSON
+)
+
+// TraceType is the kind of message in a trace entry, from the point of view
+// of the client.
+type TraceType string
+
+// Trace entry types.
+const (
+	TraceSendRequest         TraceType = "send-request"
+	TraceReceiveRequest      TraceType = "receive-request"
+	TraceSendNotification    TraceType = "send-notification"
+	TraceReceiveNotification TraceType = "receive-notification"
+	TraceSendResponse        TraceType = "send-response"
+	TraceReceiveResponse     TraceType = "receive-response"
+)
+
+// methodLogTrace is the notification servers use to write to the trace log.
+const methodLogTrace = "$/logTrace"
+
+// TraceEntry is a message in a JSON trace log.
+type TraceEntry struct {
+	IsLSPMessage bool            `json:"isLSPMessage"`
+	Type         TraceType       `json:"type"`
+	Message      json.RawMessage `json:"message"`
+	Timestamp    int64           `json:"timestamp"`        // milliseconds since the epoch
+	Server       string          `json:"server,omitempty"` // set by tracers from [Tracer.ForServer]
+}
+
+// Decode decodes the JSON-RPC message of the entry.
+func (e TraceEntry) Decode() (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(e.Message, &msg); err != nil {
+		return nil, fmt.Errorf("invalid %s message: %w", e.Type, err)
+	}
+	return &msg, nil
+}
+
+// Tracer writes the JSON-RPC traffic of a [Connection] to a log. Messages
+// are only written when the level is not [TraceOff], and parameters and
+// results are only included in text logs at [TraceVerbose]. Messages
+// servers send with $/logTrace are written as log lines.
+//
+// A tracer follows the requests of a single connection. To log several
+// connections to the same writer, give each one a tracer from
+// [Tracer.ForServer].
+type Tracer struct {
+	mu      *sync.Mutex // shared with the tracers of ForServer
+	w       io.Writer
+	format  TraceFormat
+	level   TraceLevel
+	server  string
+	now     func() time.Time
+	pending map[traceKey]pendingRequest
+}
+
+// traceKey identifies a request in flight. Requests in both directions
+// share the same ID space.
+type traceKey struct {
+	id       jsonrpc2.ID
+	incoming bool
+}
+
+type pendingRequest struct {
+	method string
+	start  time.Time
+}
+
+// NewTracer creates a tracer writing to w.
+func NewTracer(w io.Writer, format TraceFormat, level TraceLevel) *Tracer {
+	return &Tracer{
+		mu:      &sync.Mutex{},
+		w:       w,
+		format:  format,
+		level:   level,
+		now:     time.Now,
+		pending: make(map[traceKey]pendingRequest),
+	}
+}
+
+// ForServer returns a tracer for the connection to the named server. It
+// writes to the same log as t, with the server name on each entry, and
+// starts at the current level of t. Requests are matched to their
+// responses per tracer, since every connection numbers its own requests.
+func (t *Tracer) ForServer(name string) *Tracer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &Tracer{
+		mu:      t.mu,
+		w:       t.w,
+		format:  t.format,
+		level:   t.level,
+		server:  name,
+		now:     t.now,
+		pending: make(map[traceKey]pendingRequest),
+	}
+}
+
+// Level returns the current trace level.
+func (t *Tracer) Level() TraceLevel {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.level
+}
+
+// SetLevel changes the trace level.
+func (t *Tracer) SetLevel(level TraceLevel) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.level = level
+}
+
+// Log writes a free-form message, with optional verbose details that are
+// only written at [TraceVerbose].
+func (t *Tracer) Log(message, verbose string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.log(message, verbose)
+}
+
+func (t *Tracer) log(message, verbose string) {
+	if t.level == TraceOff || t.level == "" {
+		return
+	}
+	if t.level != TraceVerbose {
+		verbose = ""
+	}
+	t.writeText(message, verbose)
+}
+
+// onSend is called for every message written to the connection.
+func (t *Tracer) onSend(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	switch {
+	case req != nil && req.Notif:
+		t.trace(TraceSendNotification, req,
+			fmt.Sprintf("Sending notification '%s'.", req.Method),
+			paramsText(req.Params))
+	case req != nil:
+		t.pending[traceKey{req.ID, false}] = pendingRequest{req.Method, now}
+		t.trace(TraceSendRequest, req,
+			fmt.Sprintf("Sending request '%s - (%s)'.", req.Method, req.ID),
+			paramsText(req.Params))
+	case resp != nil:
+		key := traceKey{resp.ID, true}
+		p, ok := t.pending[key]
+		delete(t.pending, key)
+		if !ok {
+			p = pendingRequest{"unknown", now}
+		}
+		t.trace(TraceSendResponse, resp,
+			fmt.Sprintf("Sending response '%s - (%s)'. Processing request took %dms", p.method, resp.ID, now.Sub(p.start).Milliseconds()),
+			resultText(resp))
+	}
+}
+
+// onRecv is called for every message read from the connection, with the
+// original request for responses.
+func (t *Tracer) onRecv(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	switch {
+	case resp != nil:
+		key := traceKey{resp.ID, false}
+		p, ok := t.pending[key]
+		delete(t.pending, key)
+		if !ok {
+			p = pendingRequest{"unknown", now}
+		}
+		header := fmt.Sprintf("Received response '%s - (%s)' in %dms.", p.method, resp.ID, now.Sub(p.start).Milliseconds())
+		if resp.Error != nil {
+			header += fmt.Sprintf(" Request failed: %s (%d).", resp.Error.Message, resp.Error.Code)
+		}
+		t.trace(TraceReceiveResponse, resp, header, resultText(resp))
+	case req.Notif && req.Method == methodLogTrace && t.format == TraceText:
+		var params struct {
+			Message string `json:"message"`
+			Verbose string `json:"verbose"`
+		}
+		if req.Params != nil {
+			_ = json.Unmarshal(*req.Params, &params)
+		}
+		t.log(params.Message, params.Verbose)
+	case req.Notif:
+		t.trace(TraceReceiveNotification, req,
+			fmt.Sprintf("Received notification '%s'.", req.Method),
+			paramsText(req.Params))
+	default:
+		t.pending[traceKey{req.ID, true}] = pendingRequest{req.Method, now}
+		t.trace(TraceReceiveRequest, req,
+			fmt.Sprintf("Received request '%s - (%s)'.", req.Method, req.ID),
+			paramsText(req.Params))
+	}
+}
+
+// trace writes a message in the configured format. The header and details
+// are only used by text logs.
+func (t *Tracer) trace(typ TraceType, msg any, header, details string) {
+	if t.level == TraceOff || t.level == "" {
+		return
+	}
+
+	if t.format == TraceJSON {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return
+		}
+		entry, err := json.Marshal(TraceEntry{
+			IsLSPMessage: true,
+			Type:         typ,
+			Message:      data,
+			Timestamp:    t.now().UnixMilli(),
+			Server:       t.server,
+		})
+		if err != nil {
+			return
+		}
+		_, _ = t.w.Write(append(entry, '\n'))
+		return
+	}
+
+	if t.level != TraceVerbose {
+		details = ""
+	}
+	t.writeText(header, details)
+}
+
+// writeText writes a log line in the text format.
+func (t *Tracer) writeText(message, details string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Trace - %s] ", t.now().Format("3:04:05 PM"))
+	if t.server != "" {
+		fmt.Fprintf(&b, "[%s] ", t.server)
+	}
+	b.WriteString(message)
+	b.WriteByte('\n')
+	if details != "" {
+		b.WriteString(details)
+		b.WriteString("\n\n\n")
+	}
+	_, _ = io.WriteString(t.w, b.String())
+}
+
+// paramsText formats request or notification parameters for text logs.
+func paramsText(params *json.RawMessage) string {
+	if params == nil || len(*params) == 0 || string(*params) == "null" {
+		return "No parameters provided."
+	}
+	return "Params: " + indentJSON(*params)
+}
+
+// resultText formats a response for text logs.
+func resultText(resp *jsonrpc2.Response) string {
+	switch {
+	case resp.Error != nil && resp.Error.Data != nil:
+		return "Error data: " + indentJSON(*resp.Error.Data)
+	case resp.Error != nil:
+		return ""
+	case resp.Result != nil && string(*resp.Result) != "null":
+		return "Result: " + indentJSON(*resp.Result)
+	default:
+		return "No result returned."
+	}
+}
+
+func indentJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "    "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// FilterTrace returns the entries of a trace log that were written for the
+// named server, as by a tracer from [Tracer.ForServer].
+func FilterTrace(entries []TraceEntry, server string) []TraceEntry {
+	var filtered []TraceEntry
+	for _, entry := range entries {
+		if entry.Server == server {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// ReadTrace reads the entries of a trace log written in [TraceJSON]
+// format. Lines that are not JSON objects, such as the timestamps some
+// editors prefix entries with, are skipped.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] != '{' {
+			continue
+		}
+
+		var entry TraceEntry
+		if err := json.Unmarshal(text, &entry); err != nil {
+			return nil, fmt.Errorf("line %d: invalid trace entry: %w", line, err)
+		}
+		if entry.Type == "" || len(entry.Message) == 0 {
+			return nil, fmt.Errorf("line %d: trace entry has no message", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,254 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// echoServer serves a connection that answers "echo" with its params, fails
+// "fail", and sends "$/logTrace" and a "window/logMessage" request back when
+// it gets "poke".
+func echoServer(t *testing.T, nc net.Conn) {
+	t.Helper()
+	handler := jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+		switch req.Method {
+		case "echo":
+			return req.Params, nil
+		case "fail":
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "bad params"}
+		case "poke":
+			_ = conn.Notify(ctx, methodLogTrace, map[string]string{"message": "poked", "verbose": "details"})
+			var result string
+			err := conn.Call(ctx, "window/logMessage", map[string]string{"message": "hi"}, &result)
+			return result, err //nolint:wrapcheck
+		}
+		return nil, nil
+	})
+	stream := jsonrpc2.NewBufferedStream(nc, jsonrpc2.VSCodeObjectCodec{})
+	conn := jsonrpc2.NewConn(t.Context(), stream, jsonrpc2.AsyncHandler(handler))
+	t.Cleanup(func() { _ = conn.Close() })
+}
+
+// tracedConnection returns a connection to an echo server traced by tracer,
+// using a fixed clock.
+func tracedConnection(t *testing.T, tracer *Tracer) *Connection {
+	t.Helper()
+
+	clock := time.Date(2024, 5, 1, 14, 3, 9, 0, time.UTC)
+	tracer.now = func() time.Time { return clock }
+
+	client, server := net.Pipe()
+	echoServer(t, server)
+
+	conn, err := NewConnection(t.Context(), client, nil)
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	conn.RegisterHandler("window/logMessage", func(context.Context, string, json.RawMessage) (any, error) {
+		return "ok", nil
+	})
+	conn.SetTracer(tracer)
+	return conn
+}
+
+func TestTracer_Text(t *testing.T) {
+	var buf bytes.Buffer
+	conn := tracedConnection(t, NewTracer(&buf, TraceText, TraceVerbose))
+
+	var result map[string]int
+	if err := conn.Call(t.Context(), "echo", map[string]int{"a": 1}, &result); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if err := conn.Call(t.Context(), "fail", nil, nil); err == nil {
+		t.Fatal("expected fail to fail")
+	}
+	if err := conn.Notify(t.Context(), "initialized", nil); err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+	var poked string
+	if err := conn.Call(t.Context(), "poke", nil, &poked); err != nil {
+		t.Fatalf("poke failed: %v", err)
+	}
+
+	want := `[Trace - 2:03:09 PM] Sending request 'echo - (1)'.
+Params: {
+    "a": 1
+}
+
+
+[Trace - 2:03:09 PM] Received response 'echo - (1)' in 0ms.
+Result: {
+    "a": 1
+}
+
+
+[Trace - 2:03:09 PM] Sending request 'fail - (2)'.
+No parameters provided.
+
+
+[Trace - 2:03:09 PM] Received response 'fail - (2)' in 0ms. Request failed: bad params (-32602).
+[Trace - 2:03:09 PM] Sending notification 'initialized'.
+No parameters provided.
+
+
+[Trace - 2:03:09 PM] Sending request 'poke - (3)'.
+No parameters provided.
+
+
+[Trace - 2:03:09 PM] poked
+details
+
+
+[Trace - 2:03:09 PM] Received request 'window/logMessage - (0)'.
+Params: {
+    "message": "hi"
+}
+
+
+[Trace - 2:03:09 PM] Sending response 'window/logMessage - (0)'. Processing request took 0ms
+Result: "ok"
+
+
+[Trace - 2:03:09 PM] Received response 'poke - (3)' in 0ms.
+Result: "ok"
+
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected trace:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTracer_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf, TraceText, TraceOff)
+	conn := tracedConnection(t, tracer)
+
+	if err := conn.Call(t.Context(), "echo", 1, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output when off, got %q", buf.String())
+	}
+
+	tracer.SetLevel(TraceMessages)
+	if err := conn.Call(t.Context(), "echo", 1, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	want := "[Trace - 2:03:09 PM] Sending request 'echo - (2)'.\n" +
+		"[Trace - 2:03:09 PM] Received response 'echo - (2)' in 0ms.\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected trace:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	conn.SetTracer(nil)
+	if err := conn.Call(t.Context(), "echo", 1, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without tracer, got %q", buf.String())
+	}
+}
+
+func TestTracer_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	conn := tracedConnection(t, NewTracer(&buf, TraceJSON, TraceMessages))
+
+	if err := conn.Call(t.Context(), "echo", []int{1, 2}, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	var poked string
+	if err := conn.Call(t.Context(), "poke", nil, &poked); err != nil {
+		t.Fatalf("poke failed: %v", err)
+	}
+
+	// Editors prefix entries with timestamps; those lines are skipped.
+	log := "[LSP   - 2:03:09 PM]\n" + buf.String()
+	entries, err := ReadTrace(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ReadTrace failed: %v", err)
+	}
+
+	want := []struct {
+		typ    TraceType
+		method string
+	}{
+		{TraceSendRequest, "echo"},
+		{TraceReceiveResponse, ""},
+		{TraceSendRequest, "poke"},
+		{TraceReceiveNotification, methodLogTrace},
+		{TraceReceiveRequest, "window/logMessage"},
+		{TraceSendResponse, ""},
+		{TraceReceiveResponse, ""},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d:\n%s", len(entries), len(want), buf.String())
+	}
+	for i, entry := range entries {
+		msg, err := entry.Decode()
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		if entry.Type != want[i].typ || msg.Method != want[i].method {
+			t.Errorf("entry %d: got %s %q, want %s %q", i, entry.Type, msg.Method, want[i].typ, want[i].method)
+		}
+		if !entry.IsLSPMessage || entry.Timestamp != 1714572189000 {
+			t.Errorf("entry %d: unexpected metadata %+v", i, entry)
+		}
+	}
+
+	if msg, _ := entries[1].Decode(); string(msg.Result) != "[1,2]" {
+		t.Errorf("unexpected echo result %s", msg.Result)
+	}
+}
+
+func TestTracer_ForServer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf, TraceJSON, TraceMessages)
+	gopls := tracedConnection(t, tracer.ForServer("gopls"))
+	lint := tracedConnection(t, tracer.ForServer("golangci-lint"))
+
+	// Both connections number their requests from the same ID.
+	if err := gopls.Call(t.Context(), "echo", nil, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if err := lint.Call(t.Context(), "echo", nil, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if err := gopls.Call(t.Context(), "echo", nil, nil); err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+
+	entries, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrace failed: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("got %d entries, want 6", len(entries))
+	}
+	for server, want := range map[string]int{"gopls": 4, "golangci-lint": 2, "": 0} {
+		if got := len(FilterTrace(entries, server)); got != want {
+			t.Errorf("got %d entries for %q, want %d", got, server, want)
+		}
+	}
+}
+
+func TestReadTrace_Invalid(t *testing.T) {
+	for _, log := range []string{
+		"{not json}\n",
+		`{"type":"send-request"}` + "\n",
+	} {
+		if _, err := ReadTrace(strings.NewReader(log)); err == nil {
+			t.Errorf("expected error for %q", log)
+		}
+	}
+}